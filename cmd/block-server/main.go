@@ -24,15 +24,23 @@
 package main
 
 import (
+	"log"
+
 	"skybox-backend/configs"
 	"skybox-backend/docs"
 	"skybox-backend/internal/blockserver/app"
+	"skybox-backend/internal/shared/storage"
 )
 
 func main() {
 	// Load Configurations
 	configs.LoadConfig()
 
+	// The store of the memory backend is not shared with the other server
+	if err := storage.CheckSeparateProcess(); err != nil {
+		log.Fatal(err)
+	}
+
 	// Initialize Swagger
 	docs.SwaggerInfo.Host = configs.Config.BlockServerHost + ":" + configs.Config.BlockServerPort
 
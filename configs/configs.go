@@ -5,6 +5,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -17,11 +18,12 @@ type AppConfig struct {
 	ServerHost string
 
 	// BlockServer Config
-	BlockServerPort  string
-	BlockServerHost  string
-	MaxWorkers       int
-	DefaultChunkSize int64
-	MaxChunkSize     int64
+	BlockServerPort        string
+	BlockServerHost        string
+	MaxWorkers             int
+	DefaultChunkSize       int64
+	MaxChunkSize           int64
+	DownloadPrefetchChunks int // Number of chunks fetched ahead of the one being sent, 0 disables prefetching
 
 	// Allowed Origins
 	AllowedOrigins []string
@@ -40,6 +42,47 @@ type AppConfig struct {
 	AWSSessionToken string
 	AWSBucket       string
 	AWSRegion       string
+
+	// Storage Config
+	StorageBackend   string // "s3", "local" or "memory", memory needs both servers in one process
+	LocalStoragePath string
+
+	// Upload Session Config
+	UploadSessionTTL     time.Duration // Time a pending upload session stays valid
+	UploadReaperInterval time.Duration // Interval between two runs of the expired sessions reaper
+
+	// Block Collector Config
+	BlockReleaseGracePeriod time.Duration // Time a block is kept after its last reference is released or it is acquired for an upload
+	BlockCollectInterval    time.Duration // Interval between two runs of the released blocks collector
+
+	// Trash Config
+	TrashRetention     time.Duration // Time a trashed item is kept before it is purged
+	TrashPurgeInterval time.Duration // Interval between two runs of the trash purger
+
+	// File Version Config
+	FileMaxVersions          int           // Number of versions kept per file, 0 keeps every version
+	FileVersionRetention     time.Duration // Time an old version is kept, 0 keeps old versions forever
+	FileVersionPruneInterval time.Duration // Interval between two runs of the version pruner
+
+	// Folder Stats Config
+	RepairFolderStats bool // Recompute the statistics of every folder before the API server accepts requests
+
+	// Storage Quota Config
+	DefaultStorageQuota             int64 // Storage quota in bytes of the default plan, given to the users without a quota of their own, 0 is unlimited
+	DefaultOrganizationStorageQuota int64 // Storage quota in bytes shared by the drives of an organization without a quota of its own, 0 is unlimited
+
+	// Mailer Config
+	MailerBackend string // "smtp" or "file"
+	MailFrom      string
+	SMTPHost      string
+	SMTPPort      string
+	SMTPUsername  string
+	SMTPPassword  string
+	MailDropPath  string // Directory where the file mailer writes the emails
+
+	// Share Invitation Config
+	InvitationTTL time.Duration // Time an invitation sent to an email without an account stays valid
+	AppURL        string        // URL of the web application, used in the links of the emails
 }
 
 // Config is the global application configuration
@@ -51,7 +94,32 @@ var Config AppConfig = AppConfig{
 	DefaultChunkSize: 5242880,   // 5MB
 	MaxChunkSize:     104857600, // 100MB
 
+	DownloadPrefetchChunks: 1,
+
 	JWTSecret: "secret",
+
+	UploadSessionTTL:     24 * time.Hour,
+	UploadReaperInterval: 15 * time.Minute,
+
+	BlockReleaseGracePeriod: 24 * time.Hour,
+	BlockCollectInterval:    time.Hour,
+
+	TrashRetention:     30 * 24 * time.Hour,
+	TrashPurgeInterval: time.Hour,
+
+	FileMaxVersions:          10,
+	FileVersionRetention:     0,
+	FileVersionPruneInterval: time.Hour,
+
+	DefaultStorageQuota:             16106127360,   // 15GB
+	DefaultOrganizationStorageQuota: 1099511627776, // 1TB
+
+	MailerBackend: "file",
+	MailFrom:      "SkyBox <no-reply@skybox.local>",
+	MailDropPath:  "tmp/mail",
+
+	InvitationTTL: 30 * 24 * time.Hour,
+	AppURL:        "http://localhost:3000",
 }
 
 func LoadConfig() {
@@ -80,6 +148,25 @@ func LoadConfig() {
 
 	// AWS Config
 	configAWS()
+
+	// Storage Config
+	configStorage()
+
+	// Upload Session Config
+	configUploadSession()
+	configBlockCollector()
+	configTrash()
+	configFileVersion()
+
+	// Folder Stats Config
+	Config.RepairFolderStats = getEnv("REPAIR_FOLDER_STATS", "false") == "true"
+
+	// Storage Quota Config
+	configStorageQuota()
+
+	// Mailer Config
+	configMailer()
+	configInvitation()
 }
 
 func configAPIServer() {
@@ -111,6 +198,11 @@ func configBlockServer() {
 		log.Println("MAX_CHUNK_SIZE should be greater than or equal to DEFAULT_CHUNK_SIZE. Setting MAX_CHUNK_SIZE to DEFAULT_CHUNK_SIZE.")
 		Config.MaxChunkSize = Config.DefaultChunkSize
 	}
+	Config.DownloadPrefetchChunks, err = strconv.Atoi(getEnv("DOWNLOAD_PREFETCH_CHUNKS", "1"))
+	if err != nil || Config.DownloadPrefetchChunks < 0 {
+		log.Println("Invalid DOWNLOAD_PREFETCH_CHUNKS value, using default value of 1")
+		Config.DownloadPrefetchChunks = 1
+	}
 }
 
 func configAWS() {
@@ -122,6 +214,119 @@ func configAWS() {
 	Config.AWSRegion = getEnv("AWS_REGION", "us-east-1")
 }
 
+func configStorage() {
+	// Default to S3 when AWS is enabled to keep the previous behaviour
+	defaultBackend := "local"
+	if Config.AWSEnabled {
+		defaultBackend = "s3"
+	}
+
+	Config.StorageBackend = strings.ToLower(getEnv("STORAGE_BACKEND", defaultBackend))
+	Config.LocalStoragePath = getEnv("LOCAL_STORAGE_PATH", "tmp")
+}
+
+func configUploadSession() {
+	var err error
+
+	Config.UploadSessionTTL, err = time.ParseDuration(getEnv("UPLOAD_SESSION_TTL", "24h"))
+	if err != nil || Config.UploadSessionTTL <= 0 {
+		log.Println("Invalid UPLOAD_SESSION_TTL value, using default value of 24h")
+		Config.UploadSessionTTL = 24 * time.Hour
+	}
+	Config.UploadReaperInterval, err = time.ParseDuration(getEnv("UPLOAD_REAPER_INTERVAL", "15m"))
+	if err != nil || Config.UploadReaperInterval <= 0 {
+		log.Println("Invalid UPLOAD_REAPER_INTERVAL value, using default value of 15m")
+		Config.UploadReaperInterval = 15 * time.Minute
+	}
+}
+
+func configBlockCollector() {
+	var err error
+
+	Config.BlockReleaseGracePeriod, err = time.ParseDuration(getEnv("BLOCK_RELEASE_GRACE_PERIOD", "24h"))
+	if err != nil || Config.BlockReleaseGracePeriod <= 0 {
+		log.Println("Invalid BLOCK_RELEASE_GRACE_PERIOD value, using default value of 24h")
+		Config.BlockReleaseGracePeriod = 24 * time.Hour
+	}
+	Config.BlockCollectInterval, err = time.ParseDuration(getEnv("BLOCK_COLLECT_INTERVAL", "1h"))
+	if err != nil || Config.BlockCollectInterval <= 0 {
+		log.Println("Invalid BLOCK_COLLECT_INTERVAL value, using default value of 1h")
+		Config.BlockCollectInterval = time.Hour
+	}
+}
+
+func configTrash() {
+	var err error
+
+	Config.TrashRetention, err = time.ParseDuration(getEnv("TRASH_RETENTION", "720h"))
+	if err != nil || Config.TrashRetention <= 0 {
+		log.Println("Invalid TRASH_RETENTION value, using default value of 720h")
+		Config.TrashRetention = 30 * 24 * time.Hour
+	}
+	Config.TrashPurgeInterval, err = time.ParseDuration(getEnv("TRASH_PURGE_INTERVAL", "1h"))
+	if err != nil || Config.TrashPurgeInterval <= 0 {
+		log.Println("Invalid TRASH_PURGE_INTERVAL value, using default value of 1h")
+		Config.TrashPurgeInterval = time.Hour
+	}
+}
+
+func configFileVersion() {
+	var err error
+
+	Config.FileMaxVersions, err = strconv.Atoi(getEnv("FILE_MAX_VERSIONS", "10"))
+	if err != nil || Config.FileMaxVersions < 0 {
+		log.Println("Invalid FILE_MAX_VERSIONS value, using default value of 10")
+		Config.FileMaxVersions = 10
+	}
+	Config.FileVersionRetention, err = time.ParseDuration(getEnv("FILE_VERSION_RETENTION", "0s"))
+	if err != nil || Config.FileVersionRetention < 0 {
+		log.Println("Invalid FILE_VERSION_RETENTION value, using default value of 0s")
+		Config.FileVersionRetention = 0
+	}
+	Config.FileVersionPruneInterval, err = time.ParseDuration(getEnv("FILE_VERSION_PRUNE_INTERVAL", "1h"))
+	if err != nil || Config.FileVersionPruneInterval <= 0 {
+		log.Println("Invalid FILE_VERSION_PRUNE_INTERVAL value, using default value of 1h")
+		Config.FileVersionPruneInterval = time.Hour
+	}
+}
+
+func configStorageQuota() {
+	var err error
+
+	Config.DefaultStorageQuota, err = strconv.ParseInt(getEnv("DEFAULT_STORAGE_QUOTA", "16106127360"), 10, 64) // 15GB
+	if err != nil || Config.DefaultStorageQuota < 0 {
+		log.Println("Invalid DEFAULT_STORAGE_QUOTA value, using default value of 15GB")
+		Config.DefaultStorageQuota = 16106127360 // 15GB
+	}
+
+	Config.DefaultOrganizationStorageQuota, err = strconv.ParseInt(getEnv("DEFAULT_ORGANIZATION_STORAGE_QUOTA", "1099511627776"), 10, 64) // 1TB
+	if err != nil || Config.DefaultOrganizationStorageQuota < 0 {
+		log.Println("Invalid DEFAULT_ORGANIZATION_STORAGE_QUOTA value, using default value of 1TB")
+		Config.DefaultOrganizationStorageQuota = 1099511627776 // 1TB
+	}
+}
+
+func configMailer() {
+	Config.MailerBackend = strings.ToLower(getEnv("MAILER_BACKEND", "file"))
+	Config.MailFrom = getEnv("MAIL_FROM", "SkyBox <no-reply@skybox.local>")
+	Config.SMTPHost = getEnv("SMTP_HOST", "localhost")
+	Config.SMTPPort = getEnv("SMTP_PORT", "25")
+	Config.SMTPUsername = getEnv("SMTP_USERNAME", "")
+	Config.SMTPPassword = getEnv("SMTP_PASSWORD", "")
+	Config.MailDropPath = getEnv("MAIL_DROP_PATH", "tmp/mail")
+}
+
+func configInvitation() {
+	var err error
+
+	Config.InvitationTTL, err = time.ParseDuration(getEnv("INVITATION_TTL", "720h"))
+	if err != nil || Config.InvitationTTL <= 0 {
+		log.Println("Invalid INVITATION_TTL value, using default value of 720h")
+		Config.InvitationTTL = 30 * 24 * time.Hour
+	}
+	Config.AppURL = strings.TrimSuffix(getEnv("APP_URL", "http://localhost:3000"), "/")
+}
+
 // getEnv retrieves the value of an environment variable or returns a fallback value if not set
 func getEnv(key, fallback string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -1,15 +1,18 @@
 package app
 
 import (
+	"context"
 	"fmt"
 
 	"skybox-backend/configs"
+	"skybox-backend/internal/shared/storage"
 
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type Application struct {
 	Mongo *mongo.Client
+	Store storage.BlockStore // Used to delete the released chunks of cancelled, expired and purged files
 }
 
 func NewApplication() Application {
@@ -18,6 +21,13 @@ func NewApplication() Application {
 	// Connect to MongoDB
 	app.Mongo = NewMongoDatabase()
 
+	// Connect to the block store shared with the block server
+	store, err := storage.NewBlockStore()
+	if err != nil {
+		panic(fmt.Errorf("failed to create block store: %w", err))
+	}
+	app.Store = store
+
 	return *app
 }
 
@@ -39,6 +49,16 @@ func StartServer() {
 		panic(err)
 	}
 
+	// Repair the folder statistics before serving any request
+	if configs.Config.RepairFolderStats {
+		RepairFolderStats(context.Background(), db)
+	}
+
+	// Start the background jobs
+	jobsCtx, cancelJobs := context.WithCancel(context.Background())
+	defer cancelJobs()
+	StartJobs(jobsCtx, db, application.Store)
+
 	// Start the server
 	ginServer := NewServer()
 
@@ -47,7 +67,7 @@ func StartServer() {
 	ginServer.GlobalErrorHandler()
 	ginServer.SecurityMiddleware()
 	ginServer.RateLimitMiddleware()
-	ginServer.RouteMiddleware(db)
+	ginServer.RouteMiddleware(db, application.Store)
 
 	// Start the server
 	ginServer.StartServer()
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"skybox-backend/configs"
+	"skybox-backend/internal/api/repositories"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -67,6 +68,34 @@ func CreateIndexes(db *mongo.Database) error {
 				{Key: "name", Value: -1},            // Sort by name descending
 			}, // Sort by created_at descending
 		},
+		{
+			Keys: bson.D{
+				{Key: "is_deleted", Value: 1}, // Index on is_deleted
+				{Key: "deleted_at", Value: 1}, // Used by the trash purger
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "trashed_with", Value: 1}, // Used to restore the content of a trashed folder
+			},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "parent_folder_id", Value: 1}, // The files of a folder have unique names
+				{Key: "file_name", Value: 1},
+			},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"is_deleted":       false,
+				"parent_folder_id": bson.M{"$exists": true},
+			}),
+		},
+		{
+			Keys: bson.D{
+				{Key: "organization_id", Value: 1}, // Used to search an organization
+			},
+			Options: options.Index().SetSparse(true),
+		},
 	}
 
 	// Define the indexes for the "folders" collection
@@ -78,6 +107,40 @@ func CreateIndexes(db *mongo.Database) error {
 				{Key: "name", Value: -1},            // Sort by name descending
 			}, // Sort by created_at descending
 		},
+		{
+			Keys: bson.D{
+				{Key: "is_deleted", Value: 1}, // Index on is_deleted
+				{Key: "deleted_at", Value: 1}, // Used by the trash purger
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "trashed_with", Value: 1}, // Used to restore the content of a trashed folder
+			},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "parent_folder_id", Value: 1}, // The subfolders of a folder have unique names, root folders have no parent
+				{Key: "name", Value: 1},
+			},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"is_deleted":       false,
+				"parent_folder_id": bson.M{"$exists": true},
+			}),
+		},
+		{
+			Keys: bson.D{
+				{Key: "ancestor_ids", Value: 1}, // Used to find the subtree of a folder
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "organization_id", Value: 1}, // Used to list the shared drives and search an organization
+				{Key: "is_root", Value: 1},
+			},
+			Options: options.Index().SetSparse(true),
+		},
 	}
 
 	// Define the indexes for the "user_tokens" collection
@@ -103,6 +166,213 @@ func CreateIndexes(db *mongo.Database) error {
 				{Key: "session_token", Value: 1}, // Index on session_token
 			},
 		},
+		{
+			Keys: bson.D{
+				{Key: "status", Value: 1},     // Index on status
+				{Key: "expires_at", Value: 1}, // Used by the expired sessions reaper
+			},
+		},
+	}
+
+	// Define the indexes for the "chunks" collection
+	indexes["chunks"] = []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "file_id", Value: 1},     // Index on file_id
+				{Key: "chunk_index", Value: 1}, // Sort by chunk_index ascending
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "chunk_hash", Value: 1}, // Index on chunk_hash
+			},
+		},
+	}
+
+	// Define the indexes for the "blocks" collection
+	indexes["blocks"] = []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "ref_count", Value: 1},  // Index on ref_count
+				{Key: "updated_at", Value: 1}, // Released blocks past the grace period
+			},
+		},
+	}
+
+	// Define the indexes for the "folder_shared_users" collection
+	indexes["folder_shared_users"] = []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},   // Used to resolve the grants inherited from the ancestors
+				{Key: "folder_id", Value: 1}, // Index on folder_id
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "folder_id", Value: 1}, // Used to list the users of a folder
+			},
+		},
+	}
+
+	// Define the indexes for the "file_shared_users" collection
+	indexes["file_shared_users"] = []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "file_id", Value: 1}, // Index on file_id
+				{Key: "user_id", Value: 1}, // A user has one grant per file
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1}, // Used by the shared with me listing
+			},
+		},
+	}
+
+	// Define the indexes for the "share_links" collection
+	indexes["share_links"] = []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "token", Value: 1}, // Used to open a link
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "item_type", Value: 1}, // Used to list the links of an item
+				{Key: "item_id", Value: 1},
+			},
+		},
+	}
+
+	// Define the indexes for the "share_invitations" collection
+	indexes["share_invitations"] = []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "email", Value: 1}, // Used to accept the invitations at registration
+				{Key: "status", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "item_type", Value: 1}, // An email has one pending invitation per item
+				{Key: "item_id", Value: 1},
+				{Key: "email", Value: 1},
+			},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"status": "pending",
+			}),
+		},
+	}
+
+	// Define the indexes for the "ownership_transfers" collection
+	indexes["ownership_transfers"] = []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "to_user_id", Value: 1}, // Used to list the transfers of a user
+				{Key: "status", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "from_user_id", Value: 1},
+				{Key: "status", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "item_type", Value: 1}, // An item has one pending transfer
+				{Key: "item_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"status": "pending",
+			}),
+		},
+	}
+
+	// Define the indexes for the "group_members" collection
+	indexes["group_members"] = []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "group_id", Value: 1}, // A user is a member of a group once
+				{Key: "user_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1}, // Used to resolve the groups of a user on every permission check
+			},
+		},
+	}
+
+	// Define the indexes for the "organization_members" collection
+	indexes["organization_members"] = []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "organization_id", Value: 1}, // A user is a member of an organization once
+				{Key: "user_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1}, // Used to list the organizations of a user
+			},
+		},
+	}
+
+	// Define the indexes for the "folder_shared_groups" collection
+	indexes["folder_shared_groups"] = []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "folder_id", Value: 1}, // A group has one grant per folder
+				{Key: "group_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "group_id", Value: 1}, // Used to delete the grants of a group
+			},
+		},
+	}
+
+	// Define the indexes for the "file_shared_groups" collection
+	indexes["file_shared_groups"] = []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "file_id", Value: 1}, // A group has one grant per file
+				{Key: "group_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "group_id", Value: 1},
+			},
+		},
+	}
+
+	// The names saved before the unique name indexes existed can have duplicates
+	if err := repositories.RenameDuplicateNames(ctx, db); err != nil {
+		return fmt.Errorf("failed to rename the duplicate names: %v", err)
+	}
+
+	// The users saved before the storage usage was recorded need their usage computed once
+	if err := repositories.BackfillStorageUsed(ctx, db); err != nil {
+		return fmt.Errorf("failed to compute the storage usage: %v", err)
+	}
+
+	// The grants saved before the share roles existed have a boolean permission
+	if err := repositories.MigrateShareRoles(ctx, db); err != nil {
+		return fmt.Errorf("failed to migrate the share roles: %v", err)
+	}
+
+	// The folders saved before the ancestors were recorded need their ancestors computed once, after the roles are migrated
+	if err := repositories.BackfillFolderAncestors(ctx, db); err != nil {
+		return fmt.Errorf("failed to compute the folder ancestors: %v", err)
 	}
 
 	// Create the indexes for each collection using goroutines
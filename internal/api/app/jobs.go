@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+
+	"skybox-backend/internal/api/jobs"
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/api/repositories"
+	"skybox-backend/internal/shared/storage"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// StartJobs starts the background jobs of the API server, they stop when the context is cancelled
+func StartJobs(ctx context.Context, db *mongo.Database, store storage.BlockStore) {
+	uploadSessionRepository := repositories.NewUploadSessionRepository(db, models.CollectionUploadSessions)
+	trashRepository := repositories.NewTrashRepository(db)
+	fileVersionRepository := repositories.NewFileVersionRepository(db)
+	blockRepository := repositories.NewBlockRepository(db, models.CollectionBlocks)
+
+	// Expire the abandoned upload sessions
+	go jobs.NewUploadSessionReaper(uploadSessionRepository, store).Start(ctx)
+
+	// Delete the blocks released before the grace period from the block store
+	go jobs.NewBlockCollector(blockRepository, store).Start(ctx)
+
+	// Purge the items past the trash retention period
+	go jobs.NewTrashPurger(trashRepository, store).Start(ctx)
+
+	// Delete the old file versions according to the version policy
+	go jobs.NewFileVersionPruner(fileVersionRepository, store).Start(ctx)
+}
+
+// RepairFolderStats recomputes the folder statistics of an existing database
+// It must run before the server accepts requests: the repair overwrites the statistics of each tree,
+// so the updates made by concurrent requests would be lost
+func RepairFolderStats(ctx context.Context, db *mongo.Database) {
+	jobs.NewFolderStatsRepairer(repositories.NewFolderStatsRepository(db)).Start(ctx)
+}
@@ -8,6 +8,7 @@ import (
 	"skybox-backend/configs"
 	"skybox-backend/internal/api/routes"
 	"skybox-backend/internal/shared/middlewares"
+	"skybox-backend/internal/shared/storage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ulule/limiter/v3"
@@ -88,8 +89,8 @@ func (s *Server) RateLimitMiddleware() {
 }
 
 // routeMiddleware sets up the routes and the corresponding handlers
-func (s *Server) RouteMiddleware(db *mongo.Database) {
-	s.app = routes.SetupRouter(db, s.app)
+func (s *Server) RouteMiddleware(db *mongo.Database, store storage.BlockStore) {
+	s.app = routes.SetupRouter(db, s.app, store)
 }
 
 // globalErrorHandler set up a centralized error handler with secure defaults
@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"skybox-backend/configs"
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/api/services"
+	"skybox-backend/internal/shared"
+	"skybox-backend/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ArchiveController handles the zip archive downloads
+type ArchiveController struct {
+	ArchiveService   *services.ArchiveService
+	FolderController *FolderController // Checks the permissions of the archived folders
+}
+
+// NewArchiveController creates a new instance of ArchiveController
+func NewArchiveController(archiveService *services.ArchiveService, folderController *FolderController) *ArchiveController {
+	return &ArchiveController{
+		ArchiveService:   archiveService,
+		FolderController: folderController,
+	}
+}
+
+// parseArchiveToken validates the download token of an archive
+// The token is issued to a user, the user is set on the context like the JWT middleware does
+// so the permissions are checked for that user
+func parseArchiveToken(c *gin.Context) (map[string]string, bool) {
+	data, err := utils.GetKeysFromToken(c.Query("token"), configs.Config.JWTSecret)
+	if err != nil {
+		return nil, false
+	}
+
+	userID, err := primitive.ObjectIDFromHex(data["userId"])
+	if err != nil {
+		return nil, false
+	}
+	c.Set("x-user-id", data["userId"])
+	c.Set("x-user-id-hex", userID)
+
+	return data, true
+}
+
+// viewChecker returns the function checking if the user can view a folder of an archive
+func (ac *ArchiveController) viewChecker(c *gin.Context, userID string) func(folderID string) bool {
+	return func(folderID string) bool {
+		hasPermission, err := ac.FolderController.CheckFolderPermission(c, folderID, userID, "view")
+		return err == nil && hasPermission
+	}
+}
+
+// fileViewChecker returns the function checking if the user can view a selected file
+// The grants on the file itself are consulted, a file can be shared without its folder
+func (ac *ArchiveController) fileViewChecker(c *gin.Context, userID string) func(file *models.File) bool {
+	return func(file *models.File) bool {
+		hasPermission, err := ac.FolderController.CheckFilePermission(c, file, userID, "view")
+		return err == nil && hasPermission
+	}
+}
+
+// splitIDs splits the comma separated IDs of a token claim
+func splitIDs(ids string) []string {
+	if ids == "" {
+		return []string{}
+	}
+
+	return strings.Split(ids, ",")
+}
+
+// DownloadFolderHandler godoc
+//
+// @Summary Redirect to download a folder as a zip archive
+// @Description Redirects the client to the block server, which streams a zip archive of every uploaded file in the folder tree. The subfolders the user cannot view are left out. The archive is assembled on the fly from the stored chunks, large trees use zip64.
+// @Security Bearer
+// @Tags Folders
+// @Accept */*
+// @Produce */*
+// @Param folderId path string true "Folder ID" example(1234567890abcdef12345678)
+// @Success 302 {string} string "Redirect to download URL"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/folders/{folderId}/download [get]
+func (ac *ArchiveController) DownloadFolderHandler(c *gin.Context) {
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID).Hex()
+
+	downloadURL, err := services.FolderArchiveURL(c.Param("folderId"), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Redirect(http.StatusFound, downloadURL)
+}
+
+// GetFolderArchiveHandler godoc
+//
+// @Summary Get the content of a folder archive
+// @Description Get the folders and the files of a folder archive with the chunks of every file. The block server uses the list to stream the archive. The request is authenticated by the download token instead of the Authorization header.
+// @Tags Folders
+// @Accept json
+// @Produce json
+// @Param folderId path string true "Folder ID" example(1234567890abcdef12345678)
+// @Param token query string true "Download token"
+// @Success 200 {object} models.ArchiveResponse "Folder archive retrieved successfully"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/folders/{folderId}/archive [get]
+func (ac *ArchiveController) GetFolderArchiveHandler(c *gin.Context) {
+	folderID := c.Param("folderId")
+
+	// Validate the download token, it must be issued for this folder
+	data, ok := parseArchiveToken(c)
+	if !ok || data["folderId"] != folderID {
+		shared.RespondJson(c, http.StatusUnauthorized, "error", "Invalid token", nil)
+		return
+	}
+
+	// The permissions may have changed since the token was issued
+	userID := data["userId"]
+	if hasPermission, err := ac.FolderController.CheckFolderPermission(c, folderID, userID, "view"); err != nil || !hasPermission {
+		shared.RespondJson(c, http.StatusForbidden, "error", "You do not have the required permission for this folder.", nil)
+		return
+	}
+
+	archive, err := ac.ArchiveService.GetFolderArchive(c, folderID, ac.viewChecker(c, userID))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Folder archive retrieved successfully", archive)
+}
+
+// CreateArchiveHandler godoc
+//
+// @Summary Download a selection of files and folders as a zip archive
+// @Description Check that the user can view every selected file and folder and return the block server URL streaming a zip archive of the selection. The selected items are at the top of the archive, the names are numbered when they collide. The subfolders the user cannot view are left out.
+// @Security Bearer
+// @Tags Files
+// @Accept json
+// @Produce json
+// @Param request body models.CreateArchiveRequest true "Selected files and folders"
+// @Success 200 {object} models.CreateArchiveResponse "Archive created successfully"
+// @Failure 400 {string} string "Invalid selection"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/archives [post]
+func (ac *ArchiveController) CreateArchiveHandler(c *gin.Context) {
+	var request models.CreateArchiveRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request body", nil)
+		return
+	}
+
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID).Hex()
+	if err := ac.ArchiveService.CheckSelection(c, request.FileIDs, request.FolderIDs, ac.viewChecker(c, userID), ac.fileViewChecker(c, userID)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	downloadURL, err := services.SelectionArchiveURL(request.FileIDs, request.FolderIDs, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Archive created successfully", models.CreateArchiveResponse{DownloadURL: downloadURL})
+}
+
+// GetSelectionArchiveHandler godoc
+//
+// @Summary Get the content of a selection archive
+// @Description Get the folders and the files of a selection archive with the chunks of every file. The block server uses the list to stream the archive. The request is authenticated by the download token instead of the Authorization header.
+// @Tags Files
+// @Accept json
+// @Produce json
+// @Param token query string true "Download token"
+// @Success 200 {object} models.ArchiveResponse "Archive retrieved successfully"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/archives/content [get]
+func (ac *ArchiveController) GetSelectionArchiveHandler(c *gin.Context) {
+	data, ok := parseArchiveToken(c)
+	if !ok {
+		shared.RespondJson(c, http.StatusUnauthorized, "error", "Invalid token", nil)
+		return
+	}
+
+	// The permissions are checked again, they may have changed since the token was issued
+	archive, err := ac.ArchiveService.GetSelectionArchive(c, splitIDs(data["fileIds"]), splitIDs(data["folderIds"]), ac.viewChecker(c, data["userId"]), ac.fileViewChecker(c, data["userId"]))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Archive retrieved successfully", archive)
+}
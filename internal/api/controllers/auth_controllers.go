@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"log"
 	"net/http"
 	"time"
 
@@ -15,14 +16,16 @@ import (
 )
 
 type AuthController struct {
-	AuthService      *services.AuthService
-	UserTokenService *services.UserTokenService
+	AuthService       *services.AuthService
+	UserTokenService  *services.UserTokenService
+	InvitationService *services.InvitationService // Accepts the invitations of the registered emails
 }
 
-func NewAuthController(authService *services.AuthService, userTokenService *services.UserTokenService) *AuthController {
+func NewAuthController(authService *services.AuthService, userTokenService *services.UserTokenService, invitationService *services.InvitationService) *AuthController {
 	return &AuthController{
-		AuthService:      authService,
-		UserTokenService: userTokenService,
+		AuthService:       authService,
+		UserTokenService:  userTokenService,
+		InvitationService: invitationService,
 	}
 }
 
@@ -147,7 +150,7 @@ func (ac *AuthController) LoginHandler(c *gin.Context) {
 // RegisterHandler godoc
 //
 //		@Summary		Registers a new user
-//		@Description	This endpoint registers a new user by creating a new user record in the database.
+//		@Description	This endpoint registers a new user by creating a new user record in the database. The invitations sent to the email are accepted when the token of one of their links is given.
 //		@Tags			Authentication
 //		@Accept			json
 //		@Produce		json
@@ -207,6 +210,14 @@ func (ac *AuthController) RegisterHandler(c *gin.Context) {
 		return
 	}
 
+	// Grant the items shared with the email before it had an account, when the token of an invitation link is given
+	// The user is registered even if it fails, the invitations stay pending and can be accepted later
+	if request.InvitationToken != "" {
+		if _, err := ac.InvitationService.AcceptInvitations(c, user.ID, request.InvitationToken); err != nil {
+			log.Printf("Failed to accept the invitations of %s: %v", user.Email, err)
+		}
+	}
+
 	// Send the response
 	respondJson(c, http.StatusCreated, "success", "User registered successfully.", nil)
 }
@@ -70,6 +70,14 @@ func (m *MockUserRepository) UpdateUserLastLogin(ctx context.Context, id string)
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) GetStorageBreakdown(ctx context.Context, id string) ([]models.MimeTypeUsage, error) {
+	args := m.Called(ctx, id)
+	if breakdown, ok := args.Get(0).([]models.MimeTypeUsage); ok {
+		return breakdown, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 type MockUserTokenRepository struct {
 	mock.Mock
 }
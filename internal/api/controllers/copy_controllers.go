@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"net/http"
+
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/api/services"
+	"skybox-backend/internal/shared"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CopyController handles the copy of files and folders
+type CopyController struct {
+	CopyService      *services.CopyService
+	FolderController *FolderController // Checks the permissions of the destination and of the copied subfolders
+}
+
+// NewCopyController creates a new instance of CopyController
+func NewCopyController(copyService *services.CopyService, folderController *FolderController) *CopyController {
+	return &CopyController{
+		CopyService:      copyService,
+		FolderController: folderController,
+	}
+}
+
+// canEditDestination checks if the user can add the copy to the destination folder
+func (cc *CopyController) canEditDestination(c *gin.Context, destFolderID string) bool {
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID).Hex()
+
+	hasPermission, err := cc.FolderController.CheckFolderPermission(c, destFolderID, userID, "edit")
+	if err != nil || !hasPermission {
+		shared.RespondJson(c, http.StatusForbidden, "error", "You do not have the required permission for the destination folder.", nil)
+		return false
+	}
+
+	return true
+}
+
+// CopyFileHandler godoc
+//
+// @Summary Copy a file to a folder
+// @Description Copy the current version of a file into the destination folder. The copy references the stored chunks of the file, no data is uploaded again. The user must be able to view the file and to edit the destination folder.
+// @Security Bearer
+// @Tags Files
+// @Accept json
+// @Produce json
+// @Param fileId path string true "File ID" example(1234567890abcdef12345678)
+// @Param request body models.CopyFileRequest true "Copy file request"
+// @Success 201 {object} models.File "File copied successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 404 {string} string "File not found"
+// @Failure 409 {string} string "A file with the same name already exists"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/files/{fileId}/copy [post]
+func (cc *CopyController) CopyFileHandler(c *gin.Context) {
+	var request models.CopyFileRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request body", nil)
+		return
+	}
+
+	if !cc.canEditDestination(c, request.NewParentID) {
+		return
+	}
+
+	file, err := cc.CopyService.CopyFile(c, c.Param("fileId"), request.NewParentID, request.NewName, request.Conflict)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusCreated, "success", "File copied successfully", file)
+}
+
+// CopyFolderHandler godoc
+//
+// @Summary Copy a folder to another folder
+// @Description Copy a folder, its subfolders and their uploaded files into the destination folder. The copies reference the stored chunks of the files, no data is uploaded again. The subfolders the user cannot view are not copied. The user must be able to edit the destination folder.
+// @Security Bearer
+// @Tags Folders
+// @Accept json
+// @Produce json
+// @Param folderId path string true "Folder ID" minlength(24) maxlength(24)
+// @Param request body models.CopyFolderRequest true "Copy folder request"
+// @Success 201 {object} models.Folder "Folder copied successfully."
+// @Failure 400 {string} string "Invalid request."
+// @Failure 403 {string} string "Permission denied."
+// @Failure 404 {string} string "Folder not found."
+// @Failure 409 {string} string "A folder with the same name already exists."
+// @Failure 500 {string} string "Internal server error."
+// @Router /api/v1/folders/{folderId}/copy [post]
+func (cc *CopyController) CopyFolderHandler(c *gin.Context) {
+	var request models.CopyFolderRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request.", nil)
+		return
+	}
+
+	if !cc.canEditDestination(c, request.NewParentID) {
+		return
+	}
+
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID).Hex()
+	canView := func(folderID string) bool {
+		hasPermission, err := cc.FolderController.CheckFolderPermission(c, folderID, userID, "view")
+		return err == nil && hasPermission
+	}
+
+	folder, err := cc.CopyService.CopyFolder(c, c.Param("folderId"), request.NewParentID, request.NewName, request.Conflict, canView)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusCreated, "success", "Folder copied successfully.", folder)
+}
@@ -1,8 +1,8 @@
 package controllers
 
 import (
-	"fmt"
 	"net/http"
+	"strconv"
 
 	"skybox-backend/configs"
 	"skybox-backend/internal/api/models"
@@ -11,17 +11,22 @@ import (
 	"skybox-backend/pkg/utils"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // FileController handles file-related requests
 type FileController struct {
-	FileService *services.FileService
+	FileService      *services.FileService
+	ChunkService     *services.ChunkService
+	FolderController *FolderController // Checks the permissions of the destination folder of a move
 }
 
 // NewFileController creates a new instance of FileController
-func NewFileController(fileService *services.FileService) *FileController {
+func NewFileController(fileService *services.FileService, chunkService *services.ChunkService, folderController *FolderController) *FileController {
 	return &FileController{
-		FileService: fileService,
+		FileService:      fileService,
+		ChunkService:     chunkService,
+		FolderController: folderController,
 	}
 }
 
@@ -102,6 +107,7 @@ func (fc *FileController) DeleteFileHandler(c *gin.Context) {
 // @Success 200 {string} string "File renamed successfully"
 // @Failure 400 {string} string "Invalid request body"
 // @Failure 404 {string} string "File not found"
+// @Failure 409 {string} string "A file with the same name already exists"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/files/{fileId}/rename [put]
 // @Router /api/v1/files/{fileId}/rename [patch]
@@ -122,7 +128,7 @@ func (fc *FileController) RenameFileHandler(c *gin.Context) {
 	}
 
 	// Rename the file using the service
-	err = fc.FileService.RenameFile(c, fileID, requestBody.NewName)
+	err = fc.FileService.RenameFile(c, fileID, requestBody.NewName, requestBody.Conflict)
 	if err != nil {
 		c.Error(err)
 		return
@@ -135,7 +141,7 @@ func (fc *FileController) RenameFileHandler(c *gin.Context) {
 // MoveFileHandler godoc
 //
 // @Summary Move a file to a new folder
-// @Description Move a file to a new folder by providing the new parent folder ID.
+// @Description Move a file to a new folder by providing the new parent folder ID. The user must be able to edit the file and the new parent folder.
 // @Security		Bearer
 // @Tags Files
 // @Accept json
@@ -144,7 +150,9 @@ func (fc *FileController) RenameFileHandler(c *gin.Context) {
 // @Param request body models.MoveFileRequest true "Move file request"
 // @Success 200 {string} string "File moved successfully"
 // @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Permission denied"
 // @Failure 404 {string} string "File not found"
+// @Failure 409 {string} string "A file with the same name already exists"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/files/{fileId}/move [put]
 func (fc *FileController) MoveFileHandler(c *gin.Context) {
@@ -163,8 +171,16 @@ func (fc *FileController) MoveFileHandler(c *gin.Context) {
 		return
 	}
 
+	// The file is added to the new parent folder, the user must be able to edit it
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID).Hex()
+	hasPermission, err := fc.FolderController.CheckFolderPermission(c, requestBody.NewParentID, userID, "edit")
+	if err != nil || !hasPermission {
+		shared.RespondJson(c, http.StatusForbidden, "error", "You do not have the required permission for the destination folder.", nil)
+		return
+	}
+
 	// Move the file using the service
-	err = fc.FileService.MoveFile(c, fileID, requestBody.NewParentID)
+	err = fc.FileService.MoveFile(c, fileID, requestBody.NewParentID, requestBody.Conflict)
 	if err != nil {
 		c.Error(err)
 		return
@@ -185,6 +201,7 @@ func (fc *FileController) MoveFileHandler(c *gin.Context) {
 // @Param fileId path string true "File ID" example(1234567890abcdef12345678)
 // @Success 302 {string} string "Redirect to download URL"
 // @Failure 400 {string} string "Bad request"
+// @Failure 403 {string} string "Permission denied"
 // @Failure 404 {string} string "File not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/v1/files/{fileId}/download [get]
@@ -210,29 +227,163 @@ func (fc *FileController) FullDownloadFileHandler(c *gin.Context) {
 		return
 	}
 
-	// Generate token
-	token, err := utils.GenerateToken(
-		map[string]string{
-			"fileId":      fileID,
-			"ownerId":     file.OwnerID.Hex(),
-			"totalChunks": fmt.Sprintf("%d", file.TotalChunks),
-			"fileName":    file.FileName,
-			"fileSize":    fmt.Sprintf("%d", file.Size),
-		},
-		configs.Config.JWTSecret,
-		1,
-	)
+	// Generate the download URL for the block server
+	downloadURL, err := services.FileDownloadURL(file, file.GetVersion(0))
 	if err != nil {
 		c.Error(err)
 		return
 	}
-
-	// Generate the download URL for the block server
-	downloadURL := fmt.Sprintf("http://%s:%s/download/%s?token=%s",
-		configs.Config.BlockServerHost,
-		configs.Config.BlockServerPort,
-		fileID,
-		token,
-	)
 	c.Redirect(http.StatusFound, downloadURL)
 }
+
+// GetFileChunksHandler godoc
+//
+// @Summary Get the chunk list of a file
+// @Description Get the ordered chunk list of the file version the download token was issued for, including the hash of every chunk. The block server uses the hashes to locate the content-addressed blocks. The request is authenticated by the download token instead of the Authorization header.
+// @Tags Files
+// @Accept json
+// @Produce json
+// @Param fileId path string true "File ID" example(1234567890abcdef12345678)
+// @Param token query string true "Download token"
+// @Success 200 {object} models.FileChunksResponse "File chunks retrieved successfully"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/files/{fileId}/chunks [get]
+func (fc *FileController) GetFileChunksHandler(c *gin.Context) {
+	// Get the file ID from the URL parameters
+	fileID := c.Param("fileId")
+	if fileID == "" {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "File ID is required", nil)
+		return
+	}
+
+	// Validate the download token, it must be issued for this file
+	token := c.Query("token")
+	if token == "" {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Token is required", nil)
+		return
+	}
+	data, err := utils.GetKeysFromToken(token, configs.Config.JWTSecret)
+	if err != nil || data["fileId"] != fileID {
+		shared.RespondJson(c, http.StatusUnauthorized, "error", "Invalid token", nil)
+		return
+	}
+
+	// Tokens issued before versioning have no version, they read the current version
+	version, _ := strconv.Atoi(data["version"])
+	chunks, err := fc.ChunkService.GetChunksByFileVersion(c, fileID, version)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response := models.FileChunksResponse{
+		FileID:      fileID,
+		TotalChunks: len(chunks),
+		Chunks:      chunks,
+	}
+
+	// Send the response
+	shared.RespondJson(c, http.StatusOK, "success", "File chunks retrieved successfully", response)
+}
+
+// ShareFileHandler godoc
+//
+// @Summary Share a file
+// @Description Share a single file with a user by providing the user ID and role: viewer, commenter, editor or co-owner. The user does not get access to the other files of the folder. Only the owner and the co-owners can share the file.
+// @Security Bearer
+// @Tags Files
+// @Accept json
+// @Produce json
+// @Param fileId path string true "File ID" example(1234567890abcdef12345678)
+// @Param request body models.ShareFileRequest true "Share file request"
+// @Success 200 {array} models.SharedUserResponse "File shared successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/files/{fileId}/share [post]
+func (fc *FileController) ShareFileHandler(c *gin.Context) {
+	fileID := c.Param("fileId")
+
+	var request models.ShareFileRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request body", nil)
+		return
+	}
+
+	// The share permission is checked by the middleware, the owner and the co-owners have it
+	if err := fc.FileService.ShareFile(c, fileID, request.UserID, request.GetRole()); err != nil {
+		c.Error(err)
+		return
+	}
+
+	sharedUsers, err := fc.FileService.GetFileSharedUsers(c, fileID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "File shared successfully.", sharedUsers)
+}
+
+// RemoveFileShareHandler godoc
+//
+// @Summary Remove a file share
+// @Description Remove the grant of a user on a single file. The permissions the user has on the parent folder are not changed.
+// @Security Bearer
+// @Tags Files
+// @Accept json
+// @Produce json
+// @Param fileId path string true "File ID" example(1234567890abcdef12345678)
+// @Param request body models.RemoveFileShareRequest true "Remove file share request"
+// @Success 200 {array} models.SharedUserResponse "File share removed successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/files/{fileId}/share [delete]
+func (fc *FileController) RemoveFileShareHandler(c *gin.Context) {
+	fileID := c.Param("fileId")
+
+	var request models.RemoveFileShareRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request body", nil)
+		return
+	}
+
+	if err := fc.FileService.RemoveFileShare(c, fileID, request.UserID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	sharedUsers, err := fc.FileService.GetFileSharedUsers(c, fileID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "File share removed successfully.", sharedUsers)
+}
+
+// GetFileSharedUsersHandler godoc
+//
+// @Summary Get the users a file is shared with
+// @Description Get the users the file itself is shared with, with their role and the permissions given by the role. The users of the shared parent folders are not listed.
+// @Security Bearer
+// @Tags Files
+// @Accept json
+// @Produce json
+// @Param fileId path string true "File ID" example(1234567890abcdef12345678)
+// @Success 200 {array} models.SharedUserResponse "Shared users retrieved successfully"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/files/{fileId}/shared-users [get]
+func (fc *FileController) GetFileSharedUsersHandler(c *gin.Context) {
+	sharedUsers, err := fc.FileService.GetFileSharedUsers(c, c.Param("fileId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Shared users retrieved successfully.", sharedUsers)
+}
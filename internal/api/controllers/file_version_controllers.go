@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/api/services"
+	"skybox-backend/internal/shared"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FileVersionController handles the file version requests
+type FileVersionController struct {
+	FileVersionService *services.FileVersionService
+}
+
+// NewFileVersionController creates a new instance of FileVersionController
+func NewFileVersionController(fileVersionService *services.FileVersionService) *FileVersionController {
+	return &FileVersionController{
+		FileVersionService: fileVersionService,
+	}
+}
+
+// parseVersionParam reads the version from the URL parameters
+func parseVersionParam(c *gin.Context) (int, bool) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version <= 0 {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid version", nil)
+		return 0, false
+	}
+
+	return version, true
+}
+
+// GetFileVersionsHandler godoc
+//
+// @Summary List the versions of a file
+// @Description Get the uploaded versions of a file, oldest first, and the version returned by downloads.
+// @Security Bearer
+// @Tags Files
+// @Accept json
+// @Produce json
+// @Param fileId path string true "File ID" example(1234567890abcdef12345678)
+// @Success 200 {object} models.FileVersionsResponse "File versions retrieved successfully"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 404 {string} string "File not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/files/{fileId}/versions [get]
+func (fvc *FileVersionController) GetFileVersionsHandler(c *gin.Context) {
+	response, err := fvc.FileVersionService.GetFileVersions(c, c.Param("fileId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "File versions retrieved successfully", response)
+}
+
+// CreateFileVersionHandler godoc
+//
+// @Summary Upload a new version of a file
+// @Description Create the upload session of a new version of the file. The chunks are uploaded to the block server with the returned upload URL, the version becomes the current version when the session is completed.
+// @Security Bearer
+// @Tags Files
+// @Accept json
+// @Produce json
+// @Param fileId path string true "File ID" example(1234567890abcdef12345678)
+// @Param request body models.CreateFileVersionRequest true "Size of the new version"
+// @Success 201 {object} models.CreateFileVersionResponse "File version created successfully"
+// @Failure 400 {string} string "Invalid request"
+// @Failure 403 {string} string "Another upload of the file is pending"
+// @Failure 404 {string} string "File not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/files/{fileId}/versions [post]
+func (fvc *FileVersionController) CreateFileVersionHandler(c *gin.Context) {
+	var request models.CreateFileVersionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request body", nil)
+		return
+	}
+
+	response, err := fvc.FileVersionService.CreateFileVersion(c, c.Param("fileId"), request.FileSize, c.GetString("x-user-id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusCreated, "success", "File version created successfully", response)
+}
+
+// DownloadFileVersionHandler godoc
+//
+// @Summary Redirect to download a version of a file
+// @Description Redirects the client to the block server's file streaming endpoint with a download token issued for the version.
+// @Security Bearer
+// @Tags Files
+// @Accept */*
+// @Produce */*
+// @Param fileId path string true "File ID" example(1234567890abcdef12345678)
+// @Param version path int true "Version"
+// @Success 302 {string} string "Redirect to download URL"
+// @Failure 400 {string} string "Invalid version"
+// @Failure 404 {string} string "File version not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/files/{fileId}/versions/{version}/download [get]
+func (fvc *FileVersionController) DownloadFileVersionHandler(c *gin.Context) {
+	version, ok := parseVersionParam(c)
+	if !ok {
+		return
+	}
+
+	downloadURL, err := fvc.FileVersionService.GetFileVersionDownloadURL(c, c.Param("fileId"), version)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, downloadURL)
+}
+
+// RestoreFileVersionHandler godoc
+//
+// @Summary Restore a version of a file
+// @Description Make an older version the current version of the file. The other versions are kept.
+// @Security Bearer
+// @Tags Files
+// @Accept json
+// @Produce json
+// @Param fileId path string true "File ID" example(1234567890abcdef12345678)
+// @Param version path int true "Version"
+// @Success 200 {object} models.File "File version restored successfully"
+// @Failure 400 {string} string "Invalid version"
+// @Failure 404 {string} string "File version not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/files/{fileId}/versions/{version}/restore [post]
+func (fvc *FileVersionController) RestoreFileVersionHandler(c *gin.Context) {
+	version, ok := parseVersionParam(c)
+	if !ok {
+		return
+	}
+
+	file, err := fvc.FileVersionService.RestoreFileVersion(c, c.Param("fileId"), version)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "File version restored successfully", file)
+}
@@ -72,6 +72,7 @@ func (fc *FolderController) GetFolderHandler(c *gin.Context) {
 // @Success 201 {object} models.CreateFolderResponse
 // @Failure 400 {string} string "Invalid request.
 // @Failure 404 {string} string "Folder not found."
+// @Failure 409 {string} string "A folder with the same name already exists."
 // @Failure 500 {string} string "Internal server error."
 // @Router /api/v1/folders/{folderId}/create [post]
 func (fc *FolderController) CreateFolderHandler(c *gin.Context) {
@@ -115,7 +116,7 @@ func (fc *FolderController) CreateFolderHandler(c *gin.Context) {
 	}
 
 	// Create the folder in the database
-	folderResult, err := fc.FolderService.CreateFolder(c, folder)
+	folderResult, err := fc.FolderService.CreateFolder(c, folder, request.Conflict)
 	if err != nil {
 		c.Error(err)
 		return
@@ -247,6 +248,7 @@ func (fc *FolderController) DeleteFolderHandler(c *gin.Context) {
 // @Success 200 {string} string "Folder renamed successfully."
 // @Failure 400 {string} string "Invalid request."
 // @Failure 404 {string} string "Folder not found."
+// @Failure 409 {string} string "A folder with the same name already exists."
 // @Failure 500 {string} string "Internal server error."
 // @Router /api/v1/folders/{folderId}/rename [put]
 // @Router /api/v1/folders/{folderId}/rename [patch]
@@ -269,7 +271,7 @@ func (fc *FolderController) RenameFolderHandler(c *gin.Context) {
 	}
 
 	// Rename the folder using the service
-	err = fc.FolderService.RenameFolder(c, folderId, request.NewName)
+	err = fc.FolderService.RenameFolder(c, folderId, request.NewName, request.Conflict)
 	if err != nil {
 		c.Error(err)
 		return
@@ -292,6 +294,7 @@ func (fc *FolderController) RenameFolderHandler(c *gin.Context) {
 // @Success 200 {string} string "Folder moved successfully."
 // @Failure 400 {string} string "Invalid request."
 // @Failure 404 {string} string "Folder not found."
+// @Failure 409 {string} string "A folder with the same name already exists."
 // @Failure 500 {string} string "Internal server error."
 // @Router /api/v1/folders/{folderId}/move [put]
 func (fc *FolderController) MoveFolderHandler(c *gin.Context) {
@@ -313,7 +316,7 @@ func (fc *FolderController) MoveFolderHandler(c *gin.Context) {
 	}
 
 	// Move the folder using the service
-	err = fc.FolderService.MoveFolder(c, folderId, request.NewParentID)
+	err = fc.FolderService.MoveFolder(c, folderId, request.NewParentID, request.Conflict)
 	if err != nil {
 		c.Error(err)
 		return
@@ -335,7 +338,10 @@ func (fc *FolderController) MoveFolderHandler(c *gin.Context) {
 // @Param request body models.UploadFileMetadataRequest true "Upload File Metadata Request"
 // @Success 201 {object} models.UploadFileMetadataResponse
 // @Failure 400 {string} string "Invalid request."
+// @Failure 403 {string} string "Permission denied."
 // @Failure 404 {string} string "Folder not found."
+// @Failure 409 {string} string "A file with the same name already exists."
+// @Failure 413 {string} string "Storage quota exceeded."
 // @Failure 500 {string} string "Internal server error."
 // @Router /api/v1/folders/{folderId}/upload [post]
 func (fc *FolderController) UploadFileMetadataHandler(c *gin.Context) {
@@ -365,15 +371,32 @@ func (fc *FolderController) UploadFileMetadataHandler(c *gin.Context) {
 
 	// Get information from the request context
 	ownerIdHex := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+
+	// Replacing a file changes the files of the other users, the uploaders can only add files
+	if request.Conflict == models.ConflictReplace {
+		hasPermission, err := fc.CheckFolderPermission(c, folderId, ownerIdHex.Hex(), models.PermissionEdit)
+		if err != nil || !hasPermission {
+			shared.RespondJson(c, http.StatusForbidden, "error", "You do not have the required permission to replace files in this folder.", nil)
+			return
+		}
+	}
 	ownerUsername := c.MustGet("x-username").(string)
 	ownerEmail := c.MustGet("x-email").(string)
 
 	// Get the file extension from the file name
 	fileExtension := filepath.Ext(request.FileName)
 
+	// The files of a shared drive belong to its organization and use the storage quota of the organization
+	parentFolder, err := fc.FolderService.GetFolderByID(c, folderId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
 	// Create the file object
 	file := &models.File{
 		OwnerID:        ownerIdHex,
+		OrganizationID: parentFolder.OrganizationID,
 		ParentFolderID: fileIdHex,
 		FileName:       request.FileName,
 		Size:           request.FileSize,
@@ -386,9 +409,10 @@ func (fc *FolderController) UploadFileMetadataHandler(c *gin.Context) {
 		UpdatedAt:      time.Now(),
 	}
 
-	fileMetadata, uploadURL, err := fc.FileService.UploadFileMetadata(c, file)
+	fileMetadata, uploadSession, err := fc.FileService.UploadFileMetadata(c, file, request.Conflict)
 	if err != nil {
 		c.Error(err)
+		return
 	}
 
 	// Create the response object
@@ -406,7 +430,8 @@ func (fc *FolderController) UploadFileMetadataHandler(c *gin.Context) {
 			CreatedAt:      fileMetadata.CreatedAt,
 			UpdatedAt:      fileMetadata.UpdatedAt,
 		},
-		UploadURL: uploadURL,
+		UploadURL:    services.UploadSessionURL(uploadSession.SessionToken),
+		SessionToken: uploadSession.SessionToken,
 	}
 
 	// Send a success response
@@ -414,25 +439,50 @@ func (fc *FolderController) UploadFileMetadataHandler(c *gin.Context) {
 }
 
 func (fc *FolderController) CheckFolderPermission(c *gin.Context, folderID string, userID string, permission string) (bool, error) {
-	// Check if the user has a specific shared permission
-	sharedUser, err := fc.FolderService.GetFolderSharedUser(c, folderID, userID)
-	if err == nil {
-		if permission == "edit" {
-			return sharedUser.Permission, nil
+	// A trashed folder is not accessible, even to the users it is shared with
+	folder, err := fc.FolderService.GetFolderByID(c, folderID)
+	if err != nil {
+		return false, err
+	}
+
+	// Check if a role shared with the user on the folder or one of its ancestors gives the permission
+	sharedUsers, err := fc.FolderService.GetInheritedFolderShares(c, folder, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, sharedUser := range sharedUsers {
+		if sharedUser.Role.Allows(permission) {
+			return true, nil
 		}
-		return true, nil // View permission
 	}
 
-	// Get tge folder owner ID and check if the user is the owner
-	folder, err := fc.FolderService.GetFolderByID(c, folderID)
-	if err == nil {
-		if folder.OwnerID.Hex() == userID {
-			return true, nil // Owner has all permissions
+	// Check the roles shared with the groups of the user, the memberships are read on every check
+	sharedGroups, err := fc.FolderService.GetInheritedFolderGroupShares(c, folder, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, sharedGroup := range sharedGroups {
+		if sharedGroup.Role.Allows(permission) {
+			return true, nil
+		}
+	}
+
+	// In a shared drive the role of the user in the organization applies, the owner of an item has no other right
+	// and loses the access when removed from the organization
+	if folder.OrganizationID != nil {
+		role, err := fc.FolderService.GetOrganizationFolderRole(c, folder, userID)
+		if err != nil {
+			return false, err
+		}
+		if role.Allows(permission) {
+			return true, nil
 		}
+	} else if folder.OwnerID.Hex() == userID {
+		return true, nil // Owner has all permissions
 	}
 
-	if permission == "edit" {
-		return false, nil // No edit permission
+	if permission != models.PermissionView {
+		return false, nil // A public folder can only be viewed
 	}
 
 	// If no shared permission, check if the folder is public
@@ -444,6 +494,46 @@ func (fc *FolderController) CheckFolderPermission(c *gin.Context, folderID strin
 	return isPublic, nil
 }
 
+// CheckFilePermission checks the permission of a user on a file
+// The grants on the file itself are consulted first, the permissions on its parent folder apply otherwise
+func (fc *FolderController) CheckFilePermission(c *gin.Context, file *models.File, userID string, permission string) (bool, error) {
+	if file.OrganizationID == nil && file.OwnerID.Hex() == userID {
+		return true, nil // Owner has all permissions
+	}
+
+	// A file grant does not give access to the other files of the folder
+	sharedUser, err := fc.FileService.GetFileSharedUser(c, file.ID.Hex(), userID)
+	if err == nil && sharedUser.Role.Allows(permission) {
+		return true, nil
+	}
+	sharedGroups, err := fc.FileService.GetFileGroupShares(c, file.ID.Hex(), userID)
+	if err != nil {
+		return false, err
+	}
+	for _, sharedGroup := range sharedGroups {
+		if sharedGroup.Role.Allows(permission) {
+			return true, nil
+		}
+	}
+
+	return fc.CheckFolderPermission(c, file.ParentFolderID.Hex(), userID, permission)
+}
+
+// CheckItemPermission checks the permission of a user on a file or a folder
+// It is used by the share links and the invitations, which record the type of their item
+func (fc *FolderController) CheckItemPermission(c *gin.Context, itemType string, itemID string, userID string, permission string) (bool, error) {
+	if itemType != models.TrashItemFile {
+		return fc.CheckFolderPermission(c, itemID, userID, permission)
+	}
+
+	file, err := fc.FileService.GetFileByID(c, itemID)
+	if err != nil {
+		return false, err
+	}
+
+	return fc.CheckFilePermission(c, file, userID, permission)
+}
+
 // UpdateFolderPublicStatusHandler updates the public status of a folder (public for everyone to view or restricted to only added members).
 // @Summary Update folder public status of a folder (public for everyone to view or restricted to only added members)
 // @Description Updates the public status of a folder by its ID.
@@ -561,17 +651,18 @@ func (fc *FolderController) GetFolderPublicStatusHandler(c *gin.Context) {
 	})
 }
 
-// ShareFolderHandler shares a folder with a user (share edit permission by specifying permission = true, view permission by specifying permission = false.
+// ShareFolderHandler shares a folder with a user with a role: viewer, commenter, uploader, editor or co-owner.
+// The clients sending the boolean permission instead of a role share as editor (true) or viewer (false).
 // @Summary Share a folder
-// @Description Shares a folder with a user by providing the user ID and permission.
+// @Description Shares a folder with a user by providing the user ID and role. Only the owner and the co-owners can share the folder.
 // @Tags Folders
 // @Accept json
 // @Produce json
 // @Param folderId path string true "Folder ID"
 // @Param request body models.ShareFolderRequest true "Share Folder Request"
-// @Success 200 {string} string "Folder shared successfully"
+// @Success 200 {array} models.SharedUserResponse "Folder shared successfully"
 // @Failure 400 {string} string "Invalid request"
-// @Failure 403 {string} string "Only the owner can share this folder"
+// @Failure 403 {string} string "Only the owner or a co-owner can share this folder"
 // @Failure 500 {string} string "Internal server error"
 // @Security Bearer
 // @Router /api/v1/folders/{folderId}/share [post]
@@ -582,45 +673,40 @@ func (fc *FolderController) ShareFolderHandler(c *gin.Context) {
 		return
 	}
 
-	var request struct {
-		UserID     string `json:"user_id"`
-		Permission bool   `json:"permission"`
-	}
+	var request models.ShareFolderRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
 		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request.", nil)
 		return
 	}
 
-	ownerID := c.MustGet("x-user-id-hex").(primitive.ObjectID).Hex()
-	folder, err := fc.FolderService.GetFolderByID(c, folderID)
-	if err != nil || folder.OwnerID.Hex() != ownerID {
-		shared.RespondJson(c, http.StatusForbidden, "error", "Only the owner can share this folder.", nil)
-		return
-	}
-
-	err = fc.FolderService.ShareFolder(c, folderID, request.UserID, request.Permission)
+	// The share permission is checked by the middleware, the owner and the co-owners have it
+	err := fc.FolderService.ShareFolder(c, folderID, request.UserID, request.GetRole())
 	if err != nil {
-		shared.RespondJson(c, http.StatusInternalServerError, "error", "Failed to share folder.", nil)
+		c.Error(err)
 		return
 	}
 
 	// the data return should be the list of shared users
 	sharedUsers, err := fc.FolderService.GetFolderSharedUsers(c, folderID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
 	shared.RespondJson(c, http.StatusOK, "success", "Folder shared successfully.", sharedUsers)
 }
 
 // RemoveFolderShareHandler removes sharing permissions for a folder.
 // @Summary Remove folder share
-// @Description Removes sharing permissions for a folder by its ID.
+// @Description Removes sharing permissions for a folder by its ID. The subfolders inherit the removal, the grants on the ancestors still apply.
 // @Tags Folders
 // @Accept json
 // @Produce json
 // @Param folderId path string true "Folder ID"
 // @Param request body models.RemoveFolderShareRequest true "Remove Folder Share Request"
-// @Success 200 {string} string "Folder share removed successfully"
+// @Success 200 {array} models.SharedUserResponse "Folder share removed successfully"
 // @Failure 400 {string} string "Invalid request"
-// @Failure 403 {string} string "Only the owner can remove share permissions"
+// @Failure 403 {string} string "Only the owner or a co-owner can remove share permissions"
 // @Failure 500 {string} string "Internal server error"
 // @Security Bearer
 // @Router /api/v1/folders/{folderId}/share [delete]
@@ -639,17 +725,15 @@ func (fc *FolderController) RemoveFolderShareHandler(c *gin.Context) {
 		return
 	}
 
-	ownerID := c.MustGet("x-user-id-hex").(primitive.ObjectID).Hex()
-	folder, err := fc.FolderService.GetFolderByID(c, folderID)
-	if err != nil || folder.OwnerID.Hex() != ownerID {
-		shared.RespondJson(c, http.StatusForbidden, "error", "Only the owner can remove share permissions.", nil)
+	err := fc.FolderService.RemoveFolderShare(c, folderID, request.UserID)
+	if err != nil {
+		shared.RespondJson(c, http.StatusInternalServerError, "error", "Failed to remove folder share.", nil)
 		return
 	}
-	sharedUsers, err := fc.FolderService.GetFolderSharedUsers(c, folderID)
 
-	err = fc.FolderService.RemoveFolderShare(c, folderID, request.UserID)
+	sharedUsers, err := fc.FolderService.GetFolderSharedUsers(c, folderID)
 	if err != nil {
-		shared.RespondJson(c, http.StatusInternalServerError, "error", "Failed to remove folder share.", nil)
+		c.Error(err)
 		return
 	}
 
@@ -658,12 +742,12 @@ func (fc *FolderController) RemoveFolderShareHandler(c *gin.Context) {
 
 // GetFolderSharedUsersHandler retrieves the list of users a folder is shared with.
 // @Summary Get folder shared users
-// @Description Retrieves the list of users a folder is shared with by its ID.
+// @Description Retrieves the list of users a folder is shared with by its ID, with their role and the permissions given by the role. The grants inherited from the ancestors have the ancestor folder ID.
 // @Tags Folders
 // @Accept json
 // @Produce json
 // @Param folderId path string true "Folder ID"
-// @Success 200 {array} models.FolderSharedUser "List of shared users"
+// @Success 200 {array} models.SharedUserResponse "List of shared users"
 // @Failure 400 {string} string "Invalid request"
 // @Failure 404 {string} string "Folder not found"
 // @Failure 500 {string} string "Internal server error"
@@ -682,24 +766,20 @@ func (fc *FolderController) GetFolderSharedUsersHandler(c *gin.Context) {
 		return
 	}
 
-	if sharedUsers == nil {
-		sharedUsers = []*models.FolderSharedUser{}
-	}
-
 	shared.RespondJson(c, http.StatusOK, "success", "Shared users retrieved successfully.", sharedUsers)
 }
 
 // ShareFolderAndSubfoldersHandler shares a folder and its subfolders with a user.
 // @Summary Share folder and subfolders
-// @Description Shares a folder and all its subfolders with a user by providing the user ID and permission.
+// @Description Shares a folder and all its subfolders with a user by providing the user ID and role. The subfolders inherit the grant. The grants of the user on the subfolders are removed, unless they give more permissions than the role.
 // @Tags Folders
 // @Accept json
 // @Produce json
 // @Param folderId path string true "Folder ID"
 // @Param request body models.ShareFolderRequest true "Share Folder Request"
-// @Success 200 {string} string "Folder and subfolders shared successfully"
+// @Success 200 {array} models.SharedUserResponse "Folder and subfolders shared successfully"
 // @Failure 400 {string} string "Invalid request"
-// @Failure 403 {string} string "Only the owner can share this folder"
+// @Failure 403 {string} string "Only the owner or a co-owner can share this folder"
 // @Failure 500 {string} string "Internal server error"
 // @Security Bearer
 // @Router /api/v1/folders/{folderId}/share/all [post]
@@ -710,29 +790,24 @@ func (fc *FolderController) ShareFolderAndSubfoldersHandler(c *gin.Context) {
 		return
 	}
 
-	var request struct {
-		UserID     string `json:"user_id"`
-		Permission bool   `json:"permission"`
-	}
+	var request models.ShareFolderRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
 		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request.", nil)
 		return
 	}
 
-	ownerID := c.MustGet("x-user-id-hex").(primitive.ObjectID).Hex()
-	folder, err := fc.FolderService.GetFolderByID(c, folderID)
-	if err != nil || folder.OwnerID.Hex() != ownerID {
-		shared.RespondJson(c, http.StatusForbidden, "error", "Only the owner can share this folder.", nil)
+	err := fc.FolderService.ShareFolderAndSubfolders(c, folderID, request.UserID, request.GetRole())
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	err = fc.FolderService.ShareFolderAndSubfolders(c, folderID, request.UserID, request.Permission)
+	sharedUsers, err := fc.FolderService.GetFolderSharedUsers(c, folderID)
 	if err != nil {
-		shared.RespondJson(c, http.StatusInternalServerError, "error", "Failed to share folder and subfolders.", nil)
+		c.Error(err)
 		return
 	}
 
-	sharedUsers, err := fc.FolderService.GetFolderSharedUsers(c, folderID)
 	shared.RespondJson(c, http.StatusOK, "success", "Folder and subfolders shared successfully.", sharedUsers)
 }
 
@@ -744,9 +819,9 @@ func (fc *FolderController) ShareFolderAndSubfoldersHandler(c *gin.Context) {
 // @Produce json
 // @Param folderId path string true "Folder ID"
 // @Param request body models.RevokeFolderShareRequest true "Revoke Folder Share Request"
-// @Success 200 {string} string "Folder and subfolders share revoked successfully"
+// @Success 200 {array} models.SharedUserResponse "Folder and subfolders share revoked successfully"
 // @Failure 400 {string} string "Invalid request"
-// @Failure 403 {string} string "Only the owner can revoke share permissions"
+// @Failure 403 {string} string "Only the owner or a co-owner can revoke share permissions"
 // @Failure 500 {string} string "Internal server error"
 // @Security Bearer
 // @Router /api/v1/folders/{folderId}/share/all [delete]
@@ -765,17 +840,15 @@ func (fc *FolderController) RevokeFolderAndSubfoldersShareHandler(c *gin.Context
 		return
 	}
 
-	ownerID := c.MustGet("x-user-id-hex").(primitive.ObjectID).Hex()
-	folder, err := fc.FolderService.GetFolderByID(c, folderID)
-	if err != nil || folder.OwnerID.Hex() != ownerID {
-		shared.RespondJson(c, http.StatusForbidden, "error", "Only the owner can revoke share permissions.", nil)
+	err := fc.FolderService.RevokeFolderAndSubfoldersShare(c, folderID, request.UserID)
+	if err != nil {
+		shared.RespondJson(c, http.StatusInternalServerError, "error", "Failed to revoke folder and subfolders share.", nil)
 		return
 	}
-	sharedUsers, err := fc.FolderService.GetFolderSharedUsers(c, folderID)
 
-	err = fc.FolderService.RevokeFolderAndSubfoldersShare(c, folderID, request.UserID)
+	sharedUsers, err := fc.FolderService.GetFolderSharedUsers(c, folderID)
 	if err != nil {
-		shared.RespondJson(c, http.StatusInternalServerError, "error", "Failed to revoke folder and subfolders share.", nil)
+		c.Error(err)
 		return
 	}
 
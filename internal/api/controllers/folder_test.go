@@ -26,8 +26,8 @@ type MockFolderRepository struct {
 	mock.Mock
 }
 
-func (m *MockFolderRepository) CreateFolder(ctx context.Context, folder *models.Folder) (*models.Folder, error) {
-	args := m.Called(ctx, folder)
+func (m *MockFolderRepository) CreateFolder(ctx context.Context, folder *models.Folder, conflict string) (*models.Folder, error) {
+	args := m.Called(ctx, folder, conflict)
 	if folder, ok := args.Get(0).(*models.Folder); ok {
 		return folder, args.Error(1)
 	}
@@ -84,13 +84,13 @@ func (m *MockFolderRepository) DeleteFolder(ctx context.Context, id string) erro
 	return args.Error(0)
 }
 
-func (m *MockFolderRepository) RenameFolder(ctx context.Context, id string, newName string) error {
-	args := m.Called(ctx, id, newName)
+func (m *MockFolderRepository) RenameFolder(ctx context.Context, id string, newName string, conflict string) error {
+	args := m.Called(ctx, id, newName, conflict)
 	return args.Error(0)
 }
 
-func (m *MockFolderRepository) MoveFolder(ctx context.Context, id string, newParentID string) error {
-	args := m.Called(ctx, id, newParentID)
+func (m *MockFolderRepository) MoveFolder(ctx context.Context, id string, newParentID string, conflict string) error {
+	args := m.Called(ctx, id, newParentID, conflict)
 	return args.Error(0)
 }
 
@@ -99,8 +99,8 @@ type MockFileRepository struct {
 	mock.Mock
 }
 
-func (m *MockFileRepository) UploadFileMetadata(ctx context.Context, file *models.File) (*models.File, error) {
-	args := m.Called(ctx, file)
+func (m *MockFileRepository) UploadFileMetadata(ctx context.Context, file *models.File, conflict string) (*models.File, error) {
+	args := m.Called(ctx, file, conflict)
 	if file, ok := args.Get(0).(*models.File); ok {
 		return file, args.Error(1)
 	}
@@ -120,13 +120,13 @@ func (m *MockFileRepository) DeleteFile(ctx context.Context, id string) error {
 	return args.Error(0)
 }
 
-func (m *MockFileRepository) RenameFile(ctx context.Context, id string, newName string) error {
-	args := m.Called(ctx, id, newName)
+func (m *MockFileRepository) RenameFile(ctx context.Context, id string, newName string, conflict string) error {
+	args := m.Called(ctx, id, newName, conflict)
 	return args.Error(0)
 }
 
-func (m *MockFileRepository) MoveFile(ctx context.Context, id string, newParentFolderID string) error {
-	args := m.Called(ctx, id, newParentFolderID)
+func (m *MockFileRepository) MoveFile(ctx context.Context, id string, newParentFolderID string, conflict string) error {
+	args := m.Called(ctx, id, newParentFolderID, conflict)
 	return args.Error(0)
 }
 
@@ -181,9 +181,13 @@ func setupMockServices() (*FolderController, *MockFolderRepository, *MockFileRep
 	mockFolderRepo := new(MockFolderRepository)
 	mockFileRepo := new(MockFileRepository)
 	mockUSRepository := new(MockUploadSessionRepository)
+	mockUserRepo := new(MockUserRepository)
+
+	// The users have no storage used yet
+	mockUserRepo.On("GetUserByID", mock.Anything, mock.Anything).Return(&models.User{}, nil)
 
 	folderService := services.NewFolderService(mockFolderRepo)
-	fileService := services.NewFileService(mockFileRepo, mockUSRepository)
+	fileService := services.NewFileService(mockFileRepo, mockUSRepository, mockUserRepo, nil)
 
 	folderController := &FolderController{
 		FolderService: folderService,
@@ -322,7 +326,7 @@ func TestRenameFolder_Success(t *testing.T) {
 	mockNewName := "New Folder Name"
 
 	// Mock RenameFolder to succeed
-	mockFolderRepo.On("RenameFolder", mock.Anything, mockFolderID, mockNewName).Return(nil)
+	mockFolderRepo.On("RenameFolder", mock.Anything, mockFolderID, mockNewName, "").Return(nil)
 
 	// Create request
 	reqBody := map[string]string{
@@ -367,7 +371,7 @@ func TestRenameFolder_RootFolderError(t *testing.T) {
 	mockNewName := "New Root Folder Name"
 
 	// Mock RenameFolder to return an error for root folder
-	mockFolderRepo.On("RenameFolder", mock.Anything, mockRootFolderID, mockNewName).Return(fmt.Errorf("cannot rename root folder"))
+	mockFolderRepo.On("RenameFolder", mock.Anything, mockRootFolderID, mockNewName, "").Return(fmt.Errorf("cannot rename root folder"))
 
 	// Create request
 	reqBody := map[string]string{
@@ -445,7 +449,7 @@ func TestRenameFolder_OtherUserFolder(t *testing.T) {
 	mockNewName := "New Folder Name"
 
 	// Mock RenameFolder to return an error for other user's folder
-	mockFolderRepo.On("RenameFolder", mock.Anything, mockFolderID, mockNewName).Return(fmt.Errorf("folder not found"))
+	mockFolderRepo.On("RenameFolder", mock.Anything, mockFolderID, mockNewName, "").Return(fmt.Errorf("folder not found"))
 
 	// Create request
 	reqBody := map[string]string{
@@ -592,7 +596,7 @@ func TestMoveFolder_Success(t *testing.T) {
 	mockNewParentID := "new_parent_id_456"
 
 	// Mock MoveFolder to succeed
-	mockFolderRepo.On("MoveFolder", mock.Anything, mockFolderID, mockNewParentID).Return(nil)
+	mockFolderRepo.On("MoveFolder", mock.Anything, mockFolderID, mockNewParentID, "").Return(nil)
 
 	// Create request
 	reqBody := map[string]string{
@@ -637,7 +641,7 @@ func TestMoveFolder_RootFolder(t *testing.T) {
 	mockNewParentID := "new_parent_id_456"
 
 	// Mock MoveFolder to return an error for root folder
-	mockFolderRepo.On("MoveFolder", mock.Anything, mockRootFolderID, mockNewParentID).Return(fmt.Errorf("cannot move root folder"))
+	mockFolderRepo.On("MoveFolder", mock.Anything, mockRootFolderID, mockNewParentID, "").Return(fmt.Errorf("cannot move root folder"))
 
 	// Create request
 	reqBody := map[string]string{
@@ -681,7 +685,7 @@ func TestMoveFolder_OtherUserFolder(t *testing.T) {
 	mockNewParentID := "new_parent_id_456"
 
 	// Mock MoveFolder to return an error for other user's folder
-	mockFolderRepo.On("MoveFolder", mock.Anything, mockFolderID, mockNewParentID).Return(fmt.Errorf("folder not found"))
+	mockFolderRepo.On("MoveFolder", mock.Anything, mockFolderID, mockNewParentID, "").Return(fmt.Errorf("folder not found"))
 
 	// Create request
 	reqBody := map[string]string{
@@ -778,7 +782,7 @@ func TestCreateFolder_Success(t *testing.T) {
 	}
 
 	// Mock CreateFolder to succeed
-	mockFolderRepo.On("CreateFolder", mock.Anything, mock.AnythingOfType("*models.Folder")).Return(mockFolder, nil)
+	mockFolderRepo.On("CreateFolder", mock.Anything, mock.AnythingOfType("*models.Folder"), "").Return(mockFolder, nil)
 
 	// Create request
 	reqBody := map[string]string{
@@ -870,7 +874,7 @@ func TestCreateFolder_ParentFolderNotFound(t *testing.T) {
 	mockFolderName := "New Folder"
 
 	// Mock CreateFolder to return an error for parent folder not found
-	mockFolderRepo.On("CreateFolder", mock.Anything, mock.AnythingOfType("*models.Folder")).Return(nil, fmt.Errorf("parent folder not found"))
+	mockFolderRepo.On("CreateFolder", mock.Anything, mock.AnythingOfType("*models.Folder"), "").Return(nil, fmt.Errorf("parent folder not found"))
 
 	// Create request
 	reqBody := map[string]string{
@@ -939,7 +943,7 @@ func TestUploadFileMetadata_Success(t *testing.T) {
 	}
 
 	// Mock UploadFileMetadata to succeed
-	mockFileRepo.On("UploadFileMetadata", mock.Anything, mock.AnythingOfType("*models.File")).Return(mockFile, nil)
+	mockFileRepo.On("UploadFileMetadata", mock.Anything, mock.AnythingOfType("*models.File"), "").Return(mockFile, nil)
 	mockUploadSessionRepo.On("CreateSessionRecord", mock.Anything, mock.AnythingOfType("*models.UploadSession")).Return(nil, nil)
 
 	// Create request
@@ -0,0 +1,421 @@
+package controllers
+
+import (
+	"net/http"
+
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/api/services"
+	"skybox-backend/internal/shared"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GroupController handles the groups and the files and folders shared with them
+type GroupController struct {
+	GroupService     *services.GroupService
+	FolderController *FolderController // Shares the files and folders with the groups
+}
+
+// NewGroupController creates a new instance of GroupController
+func NewGroupController(groupService *services.GroupService, folderController *FolderController) *GroupController {
+	return &GroupController{
+		GroupService:     groupService,
+		FolderController: folderController,
+	}
+}
+
+// CreateGroupHandler godoc
+//
+// @Summary Create a group
+// @Description Create a group to share files and folders with several users at once. The user becomes the first admin of the group.
+// @Security Bearer
+// @Tags Groups
+// @Accept json
+// @Produce json
+// @Param request body models.CreateGroupRequest true "Create group request"
+// @Success 201 {object} models.Group "Group created successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/groups [post]
+func (gc *GroupController) CreateGroupHandler(c *gin.Context) {
+	var request models.CreateGroupRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request body", nil)
+		return
+	}
+
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	group, err := gc.GroupService.CreateGroup(c, &request, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusCreated, "success", "Group created successfully.", group)
+}
+
+// GetGroupsHandler godoc
+//
+// @Summary Get the groups of the user
+// @Description Get the groups the user is a member of with the role of the user and the number of members, ordered by name.
+// @Security Bearer
+// @Tags Groups
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.GroupResponse "Groups retrieved successfully"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/groups [get]
+func (gc *GroupController) GetGroupsHandler(c *gin.Context) {
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	groups, err := gc.GroupService.GetGroups(c, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Groups retrieved successfully.", groups)
+}
+
+// GetGroupHandler godoc
+//
+// @Summary Get a group
+// @Description Get a group the user is a member of.
+// @Security Bearer
+// @Tags Groups
+// @Accept json
+// @Produce json
+// @Param groupId path string true "Group ID" example(1234567890abcdef12345678)
+// @Success 200 {object} models.Group "Group retrieved successfully"
+// @Failure 404 {string} string "Group not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/groups/{groupId} [get]
+func (gc *GroupController) GetGroupHandler(c *gin.Context) {
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	group, err := gc.GroupService.GetGroup(c, c.Param("groupId"), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Group retrieved successfully.", group)
+}
+
+// UpdateGroupHandler godoc
+//
+// @Summary Update a group
+// @Description Change the name or the description of a group. Only the admins of the group can update it.
+// @Security Bearer
+// @Tags Groups
+// @Accept json
+// @Produce json
+// @Param groupId path string true "Group ID" example(1234567890abcdef12345678)
+// @Param request body models.UpdateGroupRequest true "Update group request"
+// @Success 200 {object} models.Group "Group updated successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Only the group admins can manage the group"
+// @Failure 404 {string} string "Group not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/groups/{groupId} [patch]
+func (gc *GroupController) UpdateGroupHandler(c *gin.Context) {
+	var request models.UpdateGroupRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request body", nil)
+		return
+	}
+
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	group, err := gc.GroupService.UpdateGroup(c, c.Param("groupId"), &request, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Group updated successfully.", group)
+}
+
+// DeleteGroupHandler godoc
+//
+// @Summary Delete a group
+// @Description Delete a group and the grants given to it, its members lose the access the group gave them. Only the admins of the group can delete it.
+// @Security Bearer
+// @Tags Groups
+// @Accept json
+// @Produce json
+// @Param groupId path string true "Group ID" example(1234567890abcdef12345678)
+// @Success 200 {string} string "Group deleted successfully"
+// @Failure 403 {string} string "Only the group admins can manage the group"
+// @Failure 404 {string} string "Group not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/groups/{groupId} [delete]
+func (gc *GroupController) DeleteGroupHandler(c *gin.Context) {
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	if err := gc.GroupService.DeleteGroup(c, c.Param("groupId"), userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Group deleted successfully.", nil)
+}
+
+// GetGroupMembersHandler godoc
+//
+// @Summary Get the members of a group
+// @Description Get the members of a group the user is a member of, in the order they were added.
+// @Security Bearer
+// @Tags Groups
+// @Accept json
+// @Produce json
+// @Param groupId path string true "Group ID" example(1234567890abcdef12345678)
+// @Success 200 {array} models.GroupMemberResponse "Group members retrieved successfully"
+// @Failure 404 {string} string "Group not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/groups/{groupId}/members [get]
+func (gc *GroupController) GetGroupMembersHandler(c *gin.Context) {
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	members, err := gc.GroupService.GetGroupMembers(c, c.Param("groupId"), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Group members retrieved successfully.", members)
+}
+
+// SetGroupMemberHandler godoc
+//
+// @Summary Add a member to a group
+// @Description Add a user to a group or change the role of a member. The admins manage the group, the members get the roles shared with it. Only the admins of the group can add members, the last admin cannot be demoted.
+// @Security Bearer
+// @Tags Groups
+// @Accept json
+// @Produce json
+// @Param groupId path string true "Group ID" example(1234567890abcdef12345678)
+// @Param request body models.SetGroupMemberRequest true "Group member request"
+// @Success 200 {array} models.GroupMemberResponse "Group member saved successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Only the group admins can manage the group"
+// @Failure 404 {string} string "Group or user not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/groups/{groupId}/members [put]
+func (gc *GroupController) SetGroupMemberHandler(c *gin.Context) {
+	var request models.SetGroupMemberRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request body", nil)
+		return
+	}
+
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	groupID := c.Param("groupId")
+	if err := gc.GroupService.SetGroupMember(c, groupID, &request, userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	members, err := gc.GroupService.GetGroupMembers(c, groupID, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Group member saved successfully.", members)
+}
+
+// RemoveGroupMemberHandler godoc
+//
+// @Summary Remove a member from a group
+// @Description Remove a member from a group, the member loses the access the group gave right away. The admins can remove any member and the members can leave the group, the last admin cannot be removed.
+// @Security Bearer
+// @Tags Groups
+// @Accept json
+// @Produce json
+// @Param groupId path string true "Group ID" example(1234567890abcdef12345678)
+// @Param userId path string true "User ID of the member" example(1234567890abcdef12345678)
+// @Success 200 {string} string "Group member removed successfully"
+// @Failure 403 {string} string "Only the group admins can manage the group"
+// @Failure 404 {string} string "Group or member not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/groups/{groupId}/members/{userId} [delete]
+func (gc *GroupController) RemoveGroupMemberHandler(c *gin.Context) {
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	if err := gc.GroupService.RemoveGroupMember(c, c.Param("groupId"), c.Param("userId"), userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Group member removed successfully.", nil)
+}
+
+// shareWithGroup shares the item with the group of the request body
+// The sharer must be a member of the group, the share permission is checked by the middleware
+func (gc *GroupController) shareWithGroup(c *gin.Context, itemType string, itemID string) {
+	var request models.ShareWithGroupRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request body", nil)
+		return
+	}
+
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	if err := gc.GroupService.CheckGroupMember(c, request.GroupID, userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	var err error
+	if itemType == models.TrashItemFile {
+		err = gc.FolderController.FileService.ShareFileWithGroup(c, itemID, request.GroupID, request.Role)
+	} else {
+		err = gc.FolderController.FolderService.ShareFolderWithGroup(c, itemID, request.GroupID, request.Role)
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	gc.respondSharedGroups(c, itemType, itemID, "Shared with the group successfully.")
+}
+
+// removeGroupShare removes the grant of the group of the path on the item
+func (gc *GroupController) removeGroupShare(c *gin.Context, itemType string, itemID string) {
+	var err error
+	if itemType == models.TrashItemFile {
+		err = gc.FolderController.FileService.RemoveFileGroupShare(c, itemID, c.Param("groupId"))
+	} else {
+		err = gc.FolderController.FolderService.RemoveFolderGroupShare(c, itemID, c.Param("groupId"))
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	gc.respondSharedGroups(c, itemType, itemID, "Group share removed successfully.")
+}
+
+// respondSharedGroups responds with the groups the item is shared with
+func (gc *GroupController) respondSharedGroups(c *gin.Context, itemType string, itemID string, message string) {
+	var sharedGroups []*models.SharedGroupResponse
+	var err error
+	if itemType == models.TrashItemFile {
+		sharedGroups, err = gc.FolderController.FileService.GetFileSharedGroups(c, itemID)
+	} else {
+		sharedGroups, err = gc.FolderController.FolderService.GetFolderSharedGroups(c, itemID)
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", message, sharedGroups)
+}
+
+// ShareFolderWithGroupHandler godoc
+//
+// @Summary Share a folder with a group
+// @Description Give a role on the folder and its subtree to every member of a group, replacing the role the group had on it. The user must be a member of the group. The members get the role while they are members.
+// @Security Bearer
+// @Tags Groups
+// @Accept json
+// @Produce json
+// @Param folderId path string true "Folder ID" example(1234567890abcdef12345678)
+// @Param request body models.ShareWithGroupRequest true "Share with group request"
+// @Success 200 {array} models.SharedGroupResponse "Shared with the group successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 404 {string} string "Group not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/folders/{folderId}/share/groups [post]
+func (gc *GroupController) ShareFolderWithGroupHandler(c *gin.Context) {
+	gc.shareWithGroup(c, models.TrashItemFolder, c.Param("folderId"))
+}
+
+// RemoveFolderGroupShareHandler godoc
+//
+// @Summary Remove the share of a folder with a group
+// @Description Remove the role given on the folder to a group. The grants of the group on the ancestors still apply.
+// @Security Bearer
+// @Tags Groups
+// @Accept json
+// @Produce json
+// @Param folderId path string true "Folder ID" example(1234567890abcdef12345678)
+// @Param groupId path string true "Group ID" example(1234567890abcdef12345678)
+// @Success 200 {array} models.SharedGroupResponse "Group share removed successfully"
+// @Failure 400 {string} string "Invalid group ID"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/folders/{folderId}/share/groups/{groupId} [delete]
+func (gc *GroupController) RemoveFolderGroupShareHandler(c *gin.Context) {
+	gc.removeGroupShare(c, models.TrashItemFolder, c.Param("folderId"))
+}
+
+// GetFolderSharedGroupsHandler godoc
+//
+// @Summary Get the groups a folder is shared with
+// @Description Get the groups a folder is shared with, the grants inherited from its ancestors included.
+// @Security Bearer
+// @Tags Groups
+// @Accept json
+// @Produce json
+// @Param folderId path string true "Folder ID" example(1234567890abcdef12345678)
+// @Success 200 {array} models.SharedGroupResponse "Shared groups retrieved successfully"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/folders/{folderId}/shared-groups [get]
+func (gc *GroupController) GetFolderSharedGroupsHandler(c *gin.Context) {
+	gc.respondSharedGroups(c, models.TrashItemFolder, c.Param("folderId"), "Shared groups retrieved successfully.")
+}
+
+// ShareFileWithGroupHandler godoc
+//
+// @Summary Share a file with a group
+// @Description Give a role on the file to every member of a group, replacing the role the group had on it. The uploader role cannot be given on a file. The user must be a member of the group.
+// @Security Bearer
+// @Tags Groups
+// @Accept json
+// @Produce json
+// @Param fileId path string true "File ID" example(1234567890abcdef12345678)
+// @Param request body models.ShareWithGroupRequest true "Share with group request"
+// @Success 200 {array} models.SharedGroupResponse "Shared with the group successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 404 {string} string "Group not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/files/{fileId}/share/groups [post]
+func (gc *GroupController) ShareFileWithGroupHandler(c *gin.Context) {
+	gc.shareWithGroup(c, models.TrashItemFile, c.Param("fileId"))
+}
+
+// RemoveFileGroupShareHandler godoc
+//
+// @Summary Remove the share of a file with a group
+// @Description Remove the role given on the file to a group. The grants of the group on the folders of the file still apply.
+// @Security Bearer
+// @Tags Groups
+// @Accept json
+// @Produce json
+// @Param fileId path string true "File ID" example(1234567890abcdef12345678)
+// @Param groupId path string true "Group ID" example(1234567890abcdef12345678)
+// @Success 200 {array} models.SharedGroupResponse "Group share removed successfully"
+// @Failure 400 {string} string "Invalid group ID"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/files/{fileId}/share/groups/{groupId} [delete]
+func (gc *GroupController) RemoveFileGroupShareHandler(c *gin.Context) {
+	gc.removeGroupShare(c, models.TrashItemFile, c.Param("fileId"))
+}
+
+// GetFileSharedGroupsHandler godoc
+//
+// @Summary Get the groups a file is shared with
+// @Description Get the groups the file itself is shared with.
+// @Security Bearer
+// @Tags Groups
+// @Accept json
+// @Produce json
+// @Param fileId path string true "File ID" example(1234567890abcdef12345678)
+// @Success 200 {array} models.SharedGroupResponse "Shared groups retrieved successfully"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/files/{fileId}/shared-groups [get]
+func (gc *GroupController) GetFileSharedGroupsHandler(c *gin.Context) {
+	gc.respondSharedGroups(c, models.TrashItemFile, c.Param("fileId"), "Shared groups retrieved successfully.")
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"net/http"
+
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/api/services"
+	"skybox-backend/internal/shared"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InvitationController handles the sharing by email and the pending invitations
+type InvitationController struct {
+	InvitationService *services.InvitationService
+	FolderController  *FolderController // Checks the share permission on the invited items
+}
+
+// NewInvitationController creates a new instance of InvitationController
+func NewInvitationController(invitationService *services.InvitationService, folderController *FolderController) *InvitationController {
+	return &InvitationController{
+		InvitationService: invitationService,
+		FolderController:  folderController,
+	}
+}
+
+// shareByEmail shares the item with the email of the request body
+func (ic *InvitationController) shareByEmail(c *gin.Context, itemType string, itemID string) {
+	var request models.ShareByEmailRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request body", nil)
+		return
+	}
+
+	// The share permission is checked by the middleware
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	response, err := ic.InvitationService.ShareByEmail(c, itemType, itemID, &request, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if response.Status == "invited" {
+		shared.RespondJson(c, http.StatusCreated, "success", "Invitation sent successfully.", response)
+		return
+	}
+	shared.RespondJson(c, http.StatusOK, "success", "Shared successfully.", response)
+}
+
+// getInvitations lists the pending invitations of the item
+func (ic *InvitationController) getInvitations(c *gin.Context, itemType string, itemID string) {
+	invitations, err := ic.InvitationService.GetInvitations(c, itemType, itemID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Invitations retrieved successfully.", invitations)
+}
+
+// ShareFileByEmailHandler godoc
+//
+// @Summary Share a file by email
+// @Description Share a file with the user of an email. The file is shared right away when the email has an account, otherwise an invitation is emailed and the role is granted when the email registers from the emailed link.
+// @Security Bearer
+// @Tags Invitations
+// @Accept json
+// @Produce json
+// @Param fileId path string true "File ID" example(1234567890abcdef12345678)
+// @Param request body models.ShareByEmailRequest true "Share by email request"
+// @Success 200 {object} models.ShareByEmailResponse "Shared successfully"
+// @Success 201 {object} models.ShareByEmailResponse "Invitation sent successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/files/{fileId}/invitations [post]
+func (ic *InvitationController) ShareFileByEmailHandler(c *gin.Context) {
+	ic.shareByEmail(c, models.TrashItemFile, c.Param("fileId"))
+}
+
+// GetFileInvitationsHandler godoc
+//
+// @Summary Get the invitations of a file
+// @Description Get the pending invitations of the file, newest first. The expired invitations are listed too.
+// @Security Bearer
+// @Tags Invitations
+// @Accept json
+// @Produce json
+// @Param fileId path string true "File ID" example(1234567890abcdef12345678)
+// @Success 200 {array} models.ShareInvitation "Invitations retrieved successfully"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/files/{fileId}/invitations [get]
+func (ic *InvitationController) GetFileInvitationsHandler(c *gin.Context) {
+	ic.getInvitations(c, models.TrashItemFile, c.Param("fileId"))
+}
+
+// ShareFolderByEmailHandler godoc
+//
+// @Summary Share a folder by email
+// @Description Share a folder with the user of an email. The folder is shared right away when the email has an account, otherwise an invitation is emailed and the role is granted when the email registers from the emailed link.
+// @Security Bearer
+// @Tags Invitations
+// @Accept json
+// @Produce json
+// @Param folderId path string true "Folder ID" example(1234567890abcdef12345678)
+// @Param request body models.ShareByEmailRequest true "Share by email request"
+// @Success 200 {object} models.ShareByEmailResponse "Shared successfully"
+// @Success 201 {object} models.ShareByEmailResponse "Invitation sent successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/folders/{folderId}/invitations [post]
+func (ic *InvitationController) ShareFolderByEmailHandler(c *gin.Context) {
+	ic.shareByEmail(c, models.TrashItemFolder, c.Param("folderId"))
+}
+
+// GetFolderInvitationsHandler godoc
+//
+// @Summary Get the invitations of a folder
+// @Description Get the pending invitations of the folder, newest first. The invitations of the subfolders are not listed.
+// @Security Bearer
+// @Tags Invitations
+// @Accept json
+// @Produce json
+// @Param folderId path string true "Folder ID" example(1234567890abcdef12345678)
+// @Success 200 {array} models.ShareInvitation "Invitations retrieved successfully"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/folders/{folderId}/invitations [get]
+func (ic *InvitationController) GetFolderInvitationsHandler(c *gin.Context) {
+	ic.getInvitations(c, models.TrashItemFolder, c.Param("folderId"))
+}
+
+// DeleteInvitationHandler godoc
+//
+// @Summary Delete an invitation
+// @Description Delete a pending invitation, the email no longer gets the role from its link.
+// @Security Bearer
+// @Tags Invitations
+// @Accept json
+// @Produce json
+// @Param invitationId path string true "Invitation ID" example(1234567890abcdef12345678)
+// @Success 200 {string} string "Invitation deleted successfully"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 404 {string} string "Invitation not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/invitations/{invitationId} [delete]
+func (ic *InvitationController) DeleteInvitationHandler(c *gin.Context) {
+	invitation, err := ic.InvitationService.GetInvitationByID(c, c.Param("invitationId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID).Hex()
+	hasPermission, err := ic.FolderController.CheckItemPermission(c, invitation.ItemType, invitation.ItemID.Hex(), userID, models.PermissionShare)
+	if err != nil || !hasPermission {
+		shared.RespondJson(c, http.StatusForbidden, "error", "You do not have the required permission for this invitation.", nil)
+		return
+	}
+
+	if err := ic.InvitationService.DeleteInvitation(c, invitation.ID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Invitation deleted successfully.", nil)
+}
+
+// AcceptInvitationsHandler godoc
+//
+// @Summary Accept the invitations
+// @Description Accept the pending invitations sent to the email of the current user, with the token of one of their links. The invited items are shared with the user.
+// @Security Bearer
+// @Tags Invitations
+// @Accept json
+// @Produce json
+// @Param request body models.AcceptInvitationRequest true "Accept invitation request"
+// @Success 200 {array} models.ShareInvitation "Invitations accepted successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 404 {string} string "Invitation not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/invitations/accept [post]
+func (ic *InvitationController) AcceptInvitationsHandler(c *gin.Context) {
+	var request models.AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request body", nil)
+		return
+	}
+
+	// The invitations must be sent to the email of the current user
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	invitations, err := ic.InvitationService.AcceptInvitations(c, userID, request.Token)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Invitations accepted successfully.", invitations)
+}
@@ -0,0 +1,278 @@
+package controllers
+
+import (
+	"net/http"
+
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/api/services"
+	"skybox-backend/internal/shared"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OrganizationController handles the organizations, their members and their shared drives
+type OrganizationController struct {
+	OrganizationService *services.OrganizationService
+}
+
+// NewOrganizationController creates a new instance of OrganizationController
+func NewOrganizationController(organizationService *services.OrganizationService) *OrganizationController {
+	return &OrganizationController{
+		OrganizationService: organizationService,
+	}
+}
+
+// CreateOrganizationHandler godoc
+//
+// @Summary Create an organization
+// @Description Create an organization, a workspace with shared drives and a storage quota of its own. The user becomes the first admin of the organization.
+// @Security Bearer
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param request body models.CreateOrganizationRequest true "Create organization request"
+// @Success 201 {object} models.OrganizationResponse "Organization created successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/organizations [post]
+func (oc *OrganizationController) CreateOrganizationHandler(c *gin.Context) {
+	var request models.CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request body", nil)
+		return
+	}
+
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	organization, err := oc.OrganizationService.CreateOrganization(c, &request, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusCreated, "success", "Organization created successfully.", organization)
+}
+
+// GetOrganizationsHandler godoc
+//
+// @Summary Get the organizations of the user
+// @Description Get the organizations the user is a member of with the role of the user, the number of members and the storage usage, ordered by name.
+// @Security Bearer
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.OrganizationResponse "Organizations retrieved successfully"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/organizations [get]
+func (oc *OrganizationController) GetOrganizationsHandler(c *gin.Context) {
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	organizations, err := oc.OrganizationService.GetOrganizations(c, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Organizations retrieved successfully.", organizations)
+}
+
+// GetOrganizationHandler godoc
+//
+// @Summary Get an organization
+// @Description Get an organization the user is a member of with its storage quota and usage.
+// @Security Bearer
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param organizationId path string true "Organization ID" example(1234567890abcdef12345678)
+// @Success 200 {object} models.OrganizationResponse "Organization retrieved successfully"
+// @Failure 404 {string} string "Organization not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/organizations/{organizationId} [get]
+func (oc *OrganizationController) GetOrganizationHandler(c *gin.Context) {
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	organization, err := oc.OrganizationService.GetOrganization(c, c.Param("organizationId"), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Organization retrieved successfully.", organization)
+}
+
+// UpdateOrganizationHandler godoc
+//
+// @Summary Rename an organization
+// @Description Change the name of an organization. Only the admins of the organization can update it.
+// @Security Bearer
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param organizationId path string true "Organization ID" example(1234567890abcdef12345678)
+// @Param request body models.UpdateOrganizationRequest true "Update organization request"
+// @Success 200 {object} models.Organization "Organization updated successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Only the organization admins can manage the organization"
+// @Failure 404 {string} string "Organization not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/organizations/{organizationId} [patch]
+func (oc *OrganizationController) UpdateOrganizationHandler(c *gin.Context) {
+	var request models.UpdateOrganizationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request body", nil)
+		return
+	}
+
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	organization, err := oc.OrganizationService.UpdateOrganization(c, c.Param("organizationId"), &request, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Organization updated successfully.", organization)
+}
+
+// GetOrganizationMembersHandler godoc
+//
+// @Summary Get the members of an organization
+// @Description Get the members of an organization the user is a member of with their role, in the order they were added.
+// @Security Bearer
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param organizationId path string true "Organization ID" example(1234567890abcdef12345678)
+// @Success 200 {array} models.OrganizationMemberResponse "Organization members retrieved successfully"
+// @Failure 404 {string} string "Organization not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/organizations/{organizationId}/members [get]
+func (oc *OrganizationController) GetOrganizationMembersHandler(c *gin.Context) {
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	members, err := oc.OrganizationService.GetOrganizationMembers(c, c.Param("organizationId"), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Organization members retrieved successfully.", members)
+}
+
+// SetOrganizationMemberHandler godoc
+//
+// @Summary Add a member to an organization
+// @Description Add a user to an organization or change the role of a member. The admins are co-owners of the shared drives, the members are editors and the viewers can only view them. Only the admins can add members, the last admin cannot be demoted.
+// @Security Bearer
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param organizationId path string true "Organization ID" example(1234567890abcdef12345678)
+// @Param request body models.SetOrganizationMemberRequest true "Organization member request"
+// @Success 200 {array} models.OrganizationMemberResponse "Organization member saved successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Only the organization admins can manage the organization"
+// @Failure 404 {string} string "Organization or user not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/organizations/{organizationId}/members [put]
+func (oc *OrganizationController) SetOrganizationMemberHandler(c *gin.Context) {
+	var request models.SetOrganizationMemberRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request body", nil)
+		return
+	}
+
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	organizationID := c.Param("organizationId")
+	if err := oc.OrganizationService.SetOrganizationMember(c, organizationID, &request, userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	members, err := oc.OrganizationService.GetOrganizationMembers(c, organizationID, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Organization member saved successfully.", members)
+}
+
+// RemoveOrganizationMemberHandler godoc
+//
+// @Summary Remove a member from an organization
+// @Description Remove a member from an organization, the member loses the access to the shared drives right away. The items of the member stay in the organization. The admins can remove any member and the members can leave, the last admin cannot be removed.
+// @Security Bearer
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param organizationId path string true "Organization ID" example(1234567890abcdef12345678)
+// @Param userId path string true "User ID of the member" example(1234567890abcdef12345678)
+// @Success 200 {string} string "Organization member removed successfully"
+// @Failure 403 {string} string "Only the organization admins can manage the organization"
+// @Failure 404 {string} string "Organization or member not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/organizations/{organizationId}/members/{userId} [delete]
+func (oc *OrganizationController) RemoveOrganizationMemberHandler(c *gin.Context) {
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	if err := oc.OrganizationService.RemoveOrganizationMember(c, c.Param("organizationId"), c.Param("userId"), userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Organization member removed successfully.", nil)
+}
+
+// CreateSharedDriveHandler godoc
+//
+// @Summary Create a shared drive
+// @Description Create a shared drive in an organization. The drive is a root folder whose content belongs to the organization and uses its storage quota. Only the admins of the organization can create drives.
+// @Security Bearer
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param organizationId path string true "Organization ID" example(1234567890abcdef12345678)
+// @Param request body models.CreateSharedDriveRequest true "Create shared drive request"
+// @Success 201 {object} models.Folder "Shared drive created successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Only the organization admins can manage the organization"
+// @Failure 404 {string} string "Organization not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/organizations/{organizationId}/drives [post]
+func (oc *OrganizationController) CreateSharedDriveHandler(c *gin.Context) {
+	var request models.CreateSharedDriveRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request body", nil)
+		return
+	}
+
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	drive, err := oc.OrganizationService.CreateSharedDrive(c, c.Param("organizationId"), &request, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusCreated, "success", "Shared drive created successfully.", drive)
+}
+
+// GetSharedDrivesHandler godoc
+//
+// @Summary Get the shared drives of an organization
+// @Description Get the root folders of the shared drives of an organization the user is a member of, ordered by name. Their content is browsed with the folder routes.
+// @Security Bearer
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Param organizationId path string true "Organization ID" example(1234567890abcdef12345678)
+// @Success 200 {array} models.Folder "Shared drives retrieved successfully"
+// @Failure 404 {string} string "Organization not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/organizations/{organizationId}/drives [get]
+func (oc *OrganizationController) GetSharedDrivesHandler(c *gin.Context) {
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	drives, err := oc.OrganizationService.GetSharedDrives(c, c.Param("organizationId"), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Shared drives retrieved successfully.", drives)
+}
@@ -47,7 +47,7 @@ func (sc *SearchController) SearchFilesAndFoldersHandler(c *gin.Context) {
 		return
 	}
 
-	results, err := sc.SearchService.SearchFilesAndFolders(c.Request.Context(), ownerId, query)
+	results, err := sc.SearchService.SearchFilesAndFolders(c, ownerId, query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -0,0 +1,331 @@
+package controllers
+
+import (
+	"net/http"
+
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/api/services"
+	"skybox-backend/internal/shared"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShareLinkController handles the share links and the anonymous /s/:token routes
+type ShareLinkController struct {
+	ShareLinkService *services.ShareLinkService
+	FolderController *FolderController // Checks the share permission on the linked items
+}
+
+// NewShareLinkController creates a new instance of ShareLinkController
+func NewShareLinkController(shareLinkService *services.ShareLinkService, folderController *FolderController) *ShareLinkController {
+	return &ShareLinkController{
+		ShareLinkService: shareLinkService,
+		FolderController: folderController,
+	}
+}
+
+// createShareLink creates a share link on the item from the request body
+func (slc *ShareLinkController) createShareLink(c *gin.Context, itemType string, itemID string) {
+	var request models.CreateShareLinkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request body", nil)
+		return
+	}
+
+	// The share permission is checked by the middleware, the owner and the co-owners have it
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	link, err := slc.ShareLinkService.CreateShareLink(c, itemType, itemID, userID, &request)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusCreated, "success", "Share link created successfully.", link)
+}
+
+// getShareLinks lists the share links of the item
+func (slc *ShareLinkController) getShareLinks(c *gin.Context, itemType string, itemID string) {
+	links, err := slc.ShareLinkService.GetShareLinks(c, itemType, itemID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Share links retrieved successfully.", links)
+}
+
+// CreateFileShareLinkHandler godoc
+//
+// @Summary Create a share link on a file
+// @Description Create a link giving access to the file without an account. The link can have a password, an expiry and a download limit, and downloads can be disabled. Only the owner and the co-owners can create it.
+// @Security Bearer
+// @Tags Share Links
+// @Accept json
+// @Produce json
+// @Param fileId path string true "File ID" example(1234567890abcdef12345678)
+// @Param request body models.CreateShareLinkRequest true "Create share link request"
+// @Success 201 {object} models.ShareLink "Share link created successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/files/{fileId}/links [post]
+func (slc *ShareLinkController) CreateFileShareLinkHandler(c *gin.Context) {
+	slc.createShareLink(c, models.TrashItemFile, c.Param("fileId"))
+}
+
+// GetFileShareLinksHandler godoc
+//
+// @Summary Get the share links of a file
+// @Description Get the share links created on the file, newest first.
+// @Security Bearer
+// @Tags Share Links
+// @Accept json
+// @Produce json
+// @Param fileId path string true "File ID" example(1234567890abcdef12345678)
+// @Success 200 {array} models.ShareLink "Share links retrieved successfully"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/files/{fileId}/links [get]
+func (slc *ShareLinkController) GetFileShareLinksHandler(c *gin.Context) {
+	slc.getShareLinks(c, models.TrashItemFile, c.Param("fileId"))
+}
+
+// CreateFolderShareLinkHandler godoc
+//
+// @Summary Create a share link on a folder
+// @Description Create a link giving access to the folder, its subfolders and their files without an account. The link can have a password, an expiry and a download limit, and downloads can be disabled. Only the owner and the co-owners can create it.
+// @Security Bearer
+// @Tags Share Links
+// @Accept json
+// @Produce json
+// @Param folderId path string true "Folder ID" example(1234567890abcdef12345678)
+// @Param request body models.CreateShareLinkRequest true "Create share link request"
+// @Success 201 {object} models.ShareLink "Share link created successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/folders/{folderId}/links [post]
+func (slc *ShareLinkController) CreateFolderShareLinkHandler(c *gin.Context) {
+	slc.createShareLink(c, models.TrashItemFolder, c.Param("folderId"))
+}
+
+// GetFolderShareLinksHandler godoc
+//
+// @Summary Get the share links of a folder
+// @Description Get the share links created on the folder, newest first. The links of the subfolders are not listed.
+// @Security Bearer
+// @Tags Share Links
+// @Accept json
+// @Produce json
+// @Param folderId path string true "Folder ID" example(1234567890abcdef12345678)
+// @Success 200 {array} models.ShareLink "Share links retrieved successfully"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/folders/{folderId}/links [get]
+func (slc *ShareLinkController) GetFolderShareLinksHandler(c *gin.Context) {
+	slc.getShareLinks(c, models.TrashItemFolder, c.Param("folderId"))
+}
+
+// getManagedShareLink retrieves the share link of the request and checks the user can share its item
+func (slc *ShareLinkController) getManagedShareLink(c *gin.Context) (*models.ShareLink, bool) {
+	link, err := slc.ShareLinkService.GetShareLinkByID(c, c.Param("linkId"))
+	if err != nil {
+		c.Error(err)
+		return nil, false
+	}
+
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID).Hex()
+	hasPermission, err := slc.FolderController.CheckItemPermission(c, link.ItemType, link.ItemID.Hex(), userID, models.PermissionShare)
+	if err != nil || !hasPermission {
+		shared.RespondJson(c, http.StatusForbidden, "error", "You do not have the required permission for this share link.", nil)
+		return nil, false
+	}
+
+	return link, true
+}
+
+// UpdateShareLinkHandler godoc
+//
+// @Summary Update a share link
+// @Description Change the password, the expiry, the download limit or the download toggle of a share link. The missing fields are kept, an empty password removes the password. The token does not change.
+// @Security Bearer
+// @Tags Share Links
+// @Accept json
+// @Produce json
+// @Param linkId path string true "Share link ID" example(1234567890abcdef12345678)
+// @Param request body models.UpdateShareLinkRequest true "Update share link request"
+// @Success 200 {object} models.ShareLink "Share link updated successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 404 {string} string "Share link not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/links/{linkId} [patch]
+func (slc *ShareLinkController) UpdateShareLinkHandler(c *gin.Context) {
+	var request models.UpdateShareLinkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request body", nil)
+		return
+	}
+
+	link, ok := slc.getManagedShareLink(c)
+	if !ok {
+		return
+	}
+
+	link, err := slc.ShareLinkService.UpdateShareLink(c, link, &request)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Share link updated successfully.", link)
+}
+
+// DeleteShareLinkHandler godoc
+//
+// @Summary Delete a share link
+// @Description Delete a share link, its URL stops working immediately. The download URLs already given stay valid until they expire.
+// @Security Bearer
+// @Tags Share Links
+// @Accept json
+// @Produce json
+// @Param linkId path string true "Share link ID" example(1234567890abcdef12345678)
+// @Success 200 {string} string "Share link deleted successfully"
+// @Failure 403 {string} string "Permission denied"
+// @Failure 404 {string} string "Share link not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/links/{linkId} [delete]
+func (slc *ShareLinkController) DeleteShareLinkHandler(c *gin.Context) {
+	link, ok := slc.getManagedShareLink(c)
+	if !ok {
+		return
+	}
+
+	if err := slc.ShareLinkService.DeleteShareLink(c, link.ID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Share link deleted successfully.", nil)
+}
+
+// openShareLink retrieves the share link of the token with the password of the X-Share-Password header
+// The link stops working when its creator can no longer share the item, e.g. after losing the grant or leaving the organization
+func (slc *ShareLinkController) openShareLink(c *gin.Context) (*models.ShareLink, bool) {
+	link, err := slc.ShareLinkService.OpenShareLink(c, c.Param("token"), c.GetHeader("X-Share-Password"))
+	if err != nil {
+		c.Error(err)
+		return nil, false
+	}
+
+	// The visitor is anonymous, the permission is checked on a copy of the request acting as the creator
+	creatorContext := c.Copy()
+	creatorContext.Set("x-user-id-hex", link.CreatedBy)
+	hasPermission, err := slc.FolderController.CheckItemPermission(creatorContext, link.ItemType, link.ItemID.Hex(), link.CreatedBy.Hex(), models.PermissionShare)
+	if err != nil || !hasPermission {
+		c.Error(models.ErrShareLinkNotFound)
+		return nil, false
+	}
+
+	return link, true
+}
+
+// GetShareLinkContentHandler godoc
+//
+// @Summary Open a share link
+// @Description Get the file of a share link, or the folder with its subfolders and files. No account is needed, the password of the link is sent in the X-Share-Password header.
+// @Tags Share Links
+// @Accept json
+// @Produce json
+// @Param token path string true "Share link token"
+// @Param X-Share-Password header string false "Password of the share link"
+// @Success 200 {object} models.ShareLinkResponse "Share link opened successfully"
+// @Failure 401 {string} string "Missing or wrong password"
+// @Failure 404 {string} string "Share link not found"
+// @Failure 410 {string} string "Share link expired"
+// @Failure 500 {string} string "Internal server error"
+// @Router /s/{token} [get]
+func (slc *ShareLinkController) GetShareLinkContentHandler(c *gin.Context) {
+	link, ok := slc.openShareLink(c)
+	if !ok {
+		return
+	}
+
+	response, err := slc.ShareLinkService.GetShareLinkContent(c, link, "")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Share link opened successfully.", response)
+}
+
+// GetShareLinkFolderHandler godoc
+//
+// @Summary List a subfolder of a share link
+// @Description Get a subfolder of the folder of a share link with its subfolders and files. No account is needed, the password of the link is sent in the X-Share-Password header.
+// @Tags Share Links
+// @Accept json
+// @Produce json
+// @Param token path string true "Share link token"
+// @Param folderId path string true "Folder ID" example(1234567890abcdef12345678)
+// @Param X-Share-Password header string false "Password of the share link"
+// @Success 200 {object} models.ShareLinkResponse "Folder retrieved successfully"
+// @Failure 401 {string} string "Missing or wrong password"
+// @Failure 404 {string} string "Folder not found in the share link"
+// @Failure 410 {string} string "Share link expired"
+// @Failure 500 {string} string "Internal server error"
+// @Router /s/{token}/folders/{folderId} [get]
+func (slc *ShareLinkController) GetShareLinkFolderHandler(c *gin.Context) {
+	link, ok := slc.openShareLink(c)
+	if !ok {
+		return
+	}
+
+	response, err := slc.ShareLinkService.GetShareLinkContent(c, link, c.Param("folderId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Folder retrieved successfully.", response)
+}
+
+// DownloadShareLinkFileHandler godoc
+//
+// @Summary Download the file of a share link
+// @Description Get the block server URL downloading the file of a file share link, or a file of a folder share link. Every URL counts as a download against the limit of the link. No account is needed, the password of the link is sent in the X-Share-Password header.
+// @Tags Share Links
+// @Accept json
+// @Produce json
+// @Param token path string true "Share link token"
+// @Param fileId path string false "File ID, required for a folder share link" example(1234567890abcdef12345678)
+// @Param X-Share-Password header string false "Password of the share link"
+// @Success 200 {object} models.ShareLinkDownloadResponse "Download URL generated successfully"
+// @Failure 400 {string} string "File ID is required"
+// @Failure 401 {string} string "Missing or wrong password"
+// @Failure 403 {string} string "Downloads disabled or download limit reached"
+// @Failure 404 {string} string "File not found in the share link"
+// @Failure 410 {string} string "Share link expired"
+// @Failure 500 {string} string "Internal server error"
+// @Router /s/{token}/download [get]
+// @Router /s/{token}/files/{fileId}/download [get]
+func (slc *ShareLinkController) DownloadShareLinkFileHandler(c *gin.Context) {
+	link, ok := slc.openShareLink(c)
+	if !ok {
+		return
+	}
+
+	downloadURL, err := slc.ShareLinkService.GetShareLinkDownloadURL(c, link, c.Param("fileId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response := models.ShareLinkDownloadResponse{
+		DownloadURL: downloadURL,
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Download URL generated successfully.", response)
+}
@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/api/services"
+	"skybox-backend/internal/shared"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SharedItemController handles the shared with me and shared by me listings
+type SharedItemController struct {
+	SharedItemService *services.SharedItemService
+}
+
+// NewSharedItemController creates a new instance of SharedItemController
+func NewSharedItemController(sharedItemService *services.SharedItemService) *SharedItemController {
+	return &SharedItemController{
+		SharedItemService: sharedItemService,
+	}
+}
+
+// getPagination reads the page and page_size query parameters, the missing ones use the defaults
+func getPagination(c *gin.Context) (models.Pagination, bool) {
+	pagination := models.Pagination{}
+	for query, value := range map[string]*int{"page": &pagination.Page, "page_size": &pagination.PageSize} {
+		if c.Query(query) == "" {
+			continue
+		}
+
+		number, err := strconv.Atoi(c.Query(query))
+		if err != nil || number < 1 {
+			shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid "+query+", it must be a positive number", nil)
+			return pagination, false
+		}
+		*value = number
+	}
+
+	return pagination, true
+}
+
+// GetSharedWithMeHandler godoc
+//
+// @Summary Get the items shared with the user
+// @Description Get a page of the folders and files shared with the user, the most recently updated first. The subfolders of a shared folder are not listed, they are in the contents of that folder. The hidden shares are only listed with include_hidden.
+// @Security Bearer
+// @Tags Shared
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number, starting at 1" default(1)
+// @Param page_size query int false "Number of items per page, at most 200" default(50)
+// @Param include_hidden query bool false "List the hidden shares too" default(false)
+// @Success 200 {object} models.SharedItemsResponse "Shared items retrieved successfully"
+// @Failure 400 {string} string "Invalid pagination"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/shared/with-me [get]
+func (sic *SharedItemController) GetSharedWithMeHandler(c *gin.Context) {
+	pagination, ok := getPagination(c)
+	if !ok {
+		return
+	}
+	includeHidden := c.Query("include_hidden") == "true"
+
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	response, err := sic.SharedItemService.GetItemsSharedWithUser(c, userID, includeHidden, pagination)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Shared items retrieved successfully.", response)
+}
+
+// GetSharedByMeHandler godoc
+//
+// @Summary Get the items the user shares
+// @Description Get a page of the folders and files owned by the user that are shared with other users, the most recently updated first. Every item lists the users it is shared with and their roles.
+// @Security Bearer
+// @Tags Shared
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number, starting at 1" default(1)
+// @Param page_size query int false "Number of items per page, at most 200" default(50)
+// @Success 200 {object} models.SharedItemsResponse "Shared items retrieved successfully"
+// @Failure 400 {string} string "Invalid pagination"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/shared/by-me [get]
+func (sic *SharedItemController) GetSharedByMeHandler(c *gin.Context) {
+	pagination, ok := getPagination(c)
+	if !ok {
+		return
+	}
+
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	response, err := sic.SharedItemService.GetItemsSharedByUser(c, userID, pagination)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Shared items retrieved successfully.", response)
+}
+
+// HideShareHandler godoc
+//
+// @Summary Hide a share from the shared with me listing
+// @Description Hide a folder or a file shared with the user from the shared with me listing, or show it again. The access given by the share is not changed.
+// @Security Bearer
+// @Tags Shared
+// @Accept json
+// @Produce json
+// @Param itemType path string true "Item type" Enums(file, folder)
+// @Param itemId path string true "Item ID" example(1234567890abcdef12345678)
+// @Param request body models.HideShareRequest true "Hide share request"
+// @Success 200 {string} string "Share updated successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 404 {string} string "Share not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/shared/with-me/{itemType}/{itemId}/hidden [put]
+func (sic *SharedItemController) HideShareHandler(c *gin.Context) {
+	var request models.HideShareRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request body", nil)
+		return
+	}
+
+	// Only the grants of the user are changed, no permission is needed
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	if err := sic.SharedItemService.SetShareHidden(c, userID, c.Param("itemType"), c.Param("itemId"), request.Hidden); err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Share updated successfully.", nil)
+}
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"net/http"
+
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/api/services"
+	"skybox-backend/internal/shared"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OwnershipTransferController handles the ownership transfers of the files and folders
+type OwnershipTransferController struct {
+	OwnershipTransferService *services.OwnershipTransferService
+}
+
+// NewOwnershipTransferController creates a new instance of OwnershipTransferController
+func NewOwnershipTransferController(ownershipTransferService *services.OwnershipTransferService) *OwnershipTransferController {
+	return &OwnershipTransferController{
+		OwnershipTransferService: ownershipTransferService,
+	}
+}
+
+// RequestTransferHandler godoc
+//
+// @Summary Transfer the ownership of a file or a folder
+// @Description Ask another user to become the owner of a file or a folder. Only the owner can ask, the co-owners cannot. The item changes owner when the user accepts, an item has a single pending transfer and asking again replaces it.
+// @Security Bearer
+// @Tags Ownership Transfers
+// @Accept json
+// @Produce json
+// @Param request body models.TransferOwnershipRequest true "Transfer ownership request"
+// @Success 201 {object} models.OwnershipTransfer "Ownership transfer requested successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 403 {string} string "Only the owner can transfer the ownership"
+// @Failure 404 {string} string "Item or user not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/transfers [post]
+func (otc *OwnershipTransferController) RequestTransferHandler(c *gin.Context) {
+	var request models.TransferOwnershipRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.RespondJson(c, http.StatusBadRequest, "error", "Invalid request body", nil)
+		return
+	}
+
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	transfer, err := otc.OwnershipTransferService.RequestTransfer(c, &request, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusCreated, "success", "Ownership transfer requested successfully.", transfer)
+}
+
+// GetTransfersHandler godoc
+//
+// @Summary Get the pending ownership transfers
+// @Description Get the pending ownership transfers sent or received by the user, newest first.
+// @Security Bearer
+// @Tags Ownership Transfers
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.OwnershipTransfer "Ownership transfers retrieved successfully"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/transfers [get]
+func (otc *OwnershipTransferController) GetTransfersHandler(c *gin.Context) {
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	transfers, err := otc.OwnershipTransferService.GetPendingTransfers(c, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Ownership transfers retrieved successfully.", transfers)
+}
+
+// AcceptTransferHandler godoc
+//
+// @Summary Accept an ownership transfer
+// @Description Become the owner of the item of a received transfer. The item is moved to the root folder of the user with its subfolders and files, trashed ones included, and their storage counts in the quota of the user. The previous owner keeps an editor access.
+// @Security Bearer
+// @Tags Ownership Transfers
+// @Accept json
+// @Produce json
+// @Param transferId path string true "Ownership transfer ID" example(1234567890abcdef12345678)
+// @Success 200 {object} models.OwnershipTransfer "Ownership transfer accepted successfully"
+// @Failure 403 {string} string "The item is no longer owned by the sender"
+// @Failure 404 {string} string "Ownership transfer not found"
+// @Failure 413 {string} string "Storage quota exceeded"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/transfers/{transferId}/accept [post]
+func (otc *OwnershipTransferController) AcceptTransferHandler(c *gin.Context) {
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	transfer, err := otc.OwnershipTransferService.AcceptTransfer(c, c.Param("transferId"), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Ownership transfer accepted successfully.", transfer)
+}
+
+// DeclineTransferHandler godoc
+//
+// @Summary Decline an ownership transfer
+// @Description Decline a received transfer, the item keeps its owner.
+// @Security Bearer
+// @Tags Ownership Transfers
+// @Accept json
+// @Produce json
+// @Param transferId path string true "Ownership transfer ID" example(1234567890abcdef12345678)
+// @Success 200 {string} string "Ownership transfer declined successfully"
+// @Failure 404 {string} string "Ownership transfer not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/transfers/{transferId}/decline [post]
+func (otc *OwnershipTransferController) DeclineTransferHandler(c *gin.Context) {
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	if err := otc.OwnershipTransferService.DeclineTransfer(c, c.Param("transferId"), userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Ownership transfer declined successfully.", nil)
+}
+
+// CancelTransferHandler godoc
+//
+// @Summary Cancel an ownership transfer
+// @Description Cancel a transfer sent by the user before it is accepted.
+// @Security Bearer
+// @Tags Ownership Transfers
+// @Accept json
+// @Produce json
+// @Param transferId path string true "Ownership transfer ID" example(1234567890abcdef12345678)
+// @Success 200 {string} string "Ownership transfer cancelled successfully"
+// @Failure 404 {string} string "Ownership transfer not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/transfers/{transferId} [delete]
+func (otc *OwnershipTransferController) CancelTransferHandler(c *gin.Context) {
+	userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+	if err := otc.OwnershipTransferService.CancelTransfer(c, c.Param("transferId"), userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.RespondJson(c, http.StatusOK, "success", "Ownership transfer cancelled successfully.", nil)
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"net/http"
+	"skybox-backend/internal/api/services"
+	"skybox-backend/internal/shared"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TrashController struct {
+	TrashService *services.TrashService
+}
+
+func NewTrashController(ts *services.TrashService) *TrashController {
+	return &TrashController{
+		TrashService: ts,
+	}
+}
+
+// GetTrashHandler godoc
+//
+//	@Summary		List the trash
+//	@Description	List the trashed files and folders of the user, the most recently deleted first.
+//	@Security		Bearer
+//	@Tags			Trash
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	models.TrashListResponse	"Trash retrieved successfully"
+//	@Failure		401	{string}	string	"Unauthorized"
+//	@Failure		500	{string}	string	"Internal Server Error"
+//	@Router			/api/v1/trash [get]
+//
+// GetTrashHandler handles the request to list the trash of the user
+func (tc *TrashController) GetTrashHandler(c *gin.Context) {
+	response, err := tc.TrashService.GetTrashItems(c, c.GetString("x-user-id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.SuccessJSON(c, http.StatusOK, "Trash retrieved successfully", response)
+}
+
+// RestoreTrashItemHandler godoc
+//
+//	@Summary		Restore a trashed item
+//	@Description	Restore a trashed file or folder. The trashed parent folders are restored with it, and the item is restored in the root folder when its parent was purged.
+//	@Security		Bearer
+//	@Tags			Trash
+//	@Accept			json
+//	@Produce		json
+//	@Param			itemType	path		string	true	"Item type (file or folder)"
+//	@Param			itemId		path		string	true	"Item ID"
+//	@Success		200			{object}	models.TrashItem	"Item restored successfully"
+//	@Failure		400			{string}	string	"Bad Request: Invalid item type or ID"
+//	@Failure		404			{string}	string	"Not Found: The item is not in the trash"
+//	@Failure		500			{string}	string	"Internal Server Error"
+//	@Router			/api/v1/trash/{itemType}/{itemId}/restore [post]
+//
+// RestoreTrashItemHandler handles the request to restore a trashed item
+func (tc *TrashController) RestoreTrashItemHandler(c *gin.Context) {
+	item, err := tc.TrashService.RestoreItem(c, c.GetString("x-user-id"), c.Param("itemType"), c.Param("itemId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.SuccessJSON(c, http.StatusOK, "Item restored successfully", item)
+}
+
+// PurgeTrashItemHandler godoc
+//
+//	@Summary		Permanently delete a trashed item
+//	@Description	Permanently delete a trashed file or folder with its stored data. A folder is deleted with everything it contains.
+//	@Security		Bearer
+//	@Tags			Trash
+//	@Accept			json
+//	@Produce		json
+//	@Param			itemType	path		string	true	"Item type (file or folder)"
+//	@Param			itemId		path		string	true	"Item ID"
+//	@Success		200			{object}	models.PurgeTrashResponse	"Item deleted permanently"
+//	@Failure		400			{string}	string	"Bad Request: Invalid item type or ID"
+//	@Failure		404			{string}	string	"Not Found: The item is not in the trash"
+//	@Failure		500			{string}	string	"Internal Server Error"
+//	@Router			/api/v1/trash/{itemType}/{itemId} [delete]
+//
+// PurgeTrashItemHandler handles the request to permanently delete a trashed item
+func (tc *TrashController) PurgeTrashItemHandler(c *gin.Context) {
+	response, err := tc.TrashService.PurgeItem(c, c.GetString("x-user-id"), c.Param("itemType"), c.Param("itemId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.SuccessJSON(c, http.StatusOK, "Item deleted permanently", response)
+}
+
+// EmptyTrashHandler godoc
+//
+//	@Summary		Empty the trash
+//	@Description	Permanently delete every trashed file and folder of the user with their stored data.
+//	@Security		Bearer
+//	@Tags			Trash
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	models.PurgeTrashResponse	"Trash emptied successfully"
+//	@Failure		401	{string}	string	"Unauthorized"
+//	@Failure		500	{string}	string	"Internal Server Error"
+//	@Router			/api/v1/trash [delete]
+//
+// EmptyTrashHandler handles the request to empty the trash of the user
+func (tc *TrashController) EmptyTrashHandler(c *gin.Context) {
+	response, err := tc.TrashService.EmptyTrash(c, c.GetString("x-user-id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.SuccessJSON(c, http.StatusOK, "Trash emptied successfully", response)
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"skybox-backend/internal/api/models"
 	"skybox-backend/internal/api/services"
@@ -157,7 +158,7 @@ func (usc *UploadSessionController) GetSessionRecordByUserIDHandler(c *gin.Conte
 // AddChunkHandler godoc
 //
 //	@Summary		Add a chunk to an upload session
-//	@Description	Add a chunk to an existing upload session using its session token.
+//	@Description	Add a chunk to an upload session of the user using its session token. Only the block server can add a chunk: the request must carry its claim that the chunk data is stored.
 //	@Tags			UploadSession
 //	@Accept			json
 //	@Produce		json
@@ -165,6 +166,8 @@ func (usc *UploadSessionController) GetSessionRecordByUserIDHandler(c *gin.Conte
 //	@Param			body			body		models.AddChunkRequest	true	"Chunk data"
 //	@Success		200				{string}	string	"Chunk added successfully"
 //	@Failure		400				{string}	string	"Bad Request: Invalid request body or session token"
+//	@Failure		403				{string}	string	"Forbidden: The session belongs to another user or the chunk claim is invalid"
+//	@Failure		413				{string}	string	"Request Entity Too Large: Storage quota exceeded"
 //	@Failure		500				{string}	string	"Internal Server Error"
 //	@Router			/upload/{sessionToken} [put]
 //
@@ -184,7 +187,7 @@ func (usc *UploadSessionController) AddChunkHandler(c *gin.Context) {
 		return
 	}
 
-	err := usc.UploadSessionService.AddChunkSessionRecord(c, sessionToken, requestBody.ChunkNumber, requestBody.ChunkSize, requestBody.ChunkHash)
+	err := usc.UploadSessionService.AddChunkSessionRecord(c, sessionToken, c.GetString("x-user-id"), requestBody.ChunkNumber, requestBody.ChunkSize, requestBody.ChunkHash, requestBody.ChunkClaim)
 	if err != nil {
 		c.Error(err)
 		return
@@ -196,7 +199,7 @@ func (usc *UploadSessionController) AddChunkHandler(c *gin.Context) {
 // AddChunkViaFileIDHandler godoc
 //
 //	@Summary		Add a chunk to an upload session using file ID
-//	@Description	Add a chunk to an existing upload session using its associated file ID.
+//	@Description	Add a chunk to an upload session of the user using its associated file ID. Only the block server can add a chunk: the request must carry its claim that the chunk data is stored.
 //	@Tags			UploadSession
 //	@Accept			json
 //	@Produce		json
@@ -204,6 +207,8 @@ func (usc *UploadSessionController) AddChunkHandler(c *gin.Context) {
 //	@Param			body	body		models.AddChunkViaFileIDRequest	true	"Chunk data"
 //	@Success		200		{string}	string	"Chunk added successfully"
 //	@Failure		400		{string}	string	"Bad Request: Invalid request body or file ID"
+//	@Failure		403		{string}	string	"Forbidden: The session belongs to another user or the chunk claim is invalid"
+//	@Failure		413		{string}	string	"Request Entity Too Large: Storage quota exceeded"
 //	@Failure		500		{string}	string	"Internal Server Error"
 //	@Router			/upload/file/{fileID} [put]
 //
@@ -223,7 +228,7 @@ func (usc *UploadSessionController) AddChunkViaFileIDHandler(c *gin.Context) {
 		return
 	}
 
-	err := usc.UploadSessionService.AddChunkSessionRecordByFileID(c, fileID, requestBody.ChunkNumber, requestBody.ChunkSize, requestBody.ChunkHash)
+	err := usc.UploadSessionService.AddChunkSessionRecordByFileID(c, fileID, c.GetString("x-user-id"), requestBody.ChunkNumber, requestBody.ChunkSize, requestBody.ChunkHash, requestBody.ChunkClaim)
 	if err != nil {
 		c.Error(err)
 		return
@@ -231,3 +236,178 @@ func (usc *UploadSessionController) AddChunkViaFileIDHandler(c *gin.Context) {
 
 	shared.SuccessJSON(c, http.StatusOK, "Chunk added successfully", nil)
 }
+
+// GetSessionChunksHandler godoc
+//
+//	@Summary		Get the chunks of an upload session
+//	@Description	Retrieve the uploaded chunks of an upload session, ordered by chunk index.
+//	@Security		Bearer
+//	@Tags			UploadSession
+//	@Accept			json
+//	@Produce		json
+//	@Param			sessionToken	path		string	true	"Session Token"
+//	@Success		200				{array}		models.Chunk	"Chunks retrieved successfully"
+//	@Failure		400				{string}	string	"Bad Request: Missing session token"
+//	@Failure		403				{string}	string	"Forbidden: The session belongs to another user"
+//	@Failure		404				{string}	string	"Not Found: Session not found"
+//	@Failure		500				{string}	string	"Internal Server Error"
+//	@Router			/api/v1/upload/{sessionToken}/chunks [get]
+//
+// GetSessionChunksHandler handles the request to get the chunks of an upload session
+func (usc *UploadSessionController) GetSessionChunksHandler(c *gin.Context) {
+	sessionToken := c.Param("sessionToken")
+	if sessionToken == "" {
+		shared.ErrorJSON(c, http.StatusBadRequest, "Session Token is required")
+		return
+	}
+
+	chunks, err := usc.UploadSessionService.GetSessionChunks(c, sessionToken, c.GetString("x-user-id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.SuccessJSON(c, http.StatusOK, "Chunks retrieved successfully", chunks)
+}
+
+// CompleteUploadSessionHandler godoc
+//
+//	@Summary		Complete an upload session
+//	@Description	Complete an upload session after checking every chunk has been uploaded. The file is then marked as uploaded. Only the block server can complete a session: the request must carry its claim that the chunk hashes are verified.
+//	@Security		Bearer
+//	@Tags			UploadSession
+//	@Accept			json
+//	@Produce		json
+//	@Param			sessionToken	path		string	true	"Session Token"
+//	@Param			body			body		models.CompleteUploadSessionRequest	true	"Completion claim"
+//	@Success		200				{object}	models.UploadSession	"Session completed successfully"
+//	@Failure		400				{string}	string	"Bad Request: Missing chunks or size mismatch"
+//	@Failure		403				{string}	string	"Forbidden: The session belongs to another user or the completion claim is invalid"
+//	@Failure		404				{string}	string	"Not Found: Session not found"
+//	@Failure		500				{string}	string	"Internal Server Error"
+//	@Router			/api/v1/upload/{sessionToken}/complete [post]
+//
+// CompleteUploadSessionHandler handles the request to complete an upload session
+func (usc *UploadSessionController) CompleteUploadSessionHandler(c *gin.Context) {
+	sessionToken := c.Param("sessionToken")
+	if sessionToken == "" {
+		shared.ErrorJSON(c, http.StatusBadRequest, "Session Token is required")
+		return
+	}
+
+	var requestBody models.CompleteUploadSessionRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		shared.ErrorJSON(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	session, err := usc.UploadSessionService.CompleteSessionRecord(c, sessionToken, c.GetString("x-user-id"), requestBody.CompletionClaim)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.SuccessJSON(c, http.StatusOK, "Session completed successfully", session)
+}
+
+// CancelUploadSessionHandler godoc
+//
+//	@Summary		Cancel an upload session
+//	@Description	Cancel an upload session. The chunk records are deleted and the session is marked as cancelled. The response lists the blocks that are no longer referenced, they are deleted from the storage by the block collector after the grace period.
+//	@Security		Bearer
+//	@Tags			UploadSession
+//	@Accept			json
+//	@Produce		json
+//	@Param			sessionToken	path		string	true	"Session Token"
+//	@Success		200				{object}	models.CancelUploadSessionResponse	"Session cancelled successfully"
+//	@Failure		400				{string}	string	"Bad Request: The session is already completed"
+//	@Failure		403				{string}	string	"Forbidden: The session belongs to another user"
+//	@Failure		404				{string}	string	"Not Found: Session not found"
+//	@Failure		500				{string}	string	"Internal Server Error"
+//	@Router			/api/v1/upload/{sessionToken} [delete]
+//
+// CancelUploadSessionHandler handles the request to cancel an upload session
+func (usc *UploadSessionController) CancelUploadSessionHandler(c *gin.Context) {
+	sessionToken := c.Param("sessionToken")
+	if sessionToken == "" {
+		shared.ErrorJSON(c, http.StatusBadRequest, "Session Token is required")
+		return
+	}
+
+	response, err := usc.UploadSessionService.CancelSessionRecord(c, sessionToken, c.GetString("x-user-id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.SuccessJSON(c, http.StatusOK, "Session cancelled successfully", response)
+}
+
+// ExtendUploadSessionHandler godoc
+//
+//	@Summary		Extend an upload session
+//	@Description	Extend a pending upload session so it is not reaped. The new expiry is the configured session TTL from now.
+//	@Security		Bearer
+//	@Tags			UploadSession
+//	@Accept			json
+//	@Produce		json
+//	@Param			sessionToken	path		string	true	"Session Token"
+//	@Success		200				{object}	models.UploadSession	"Session extended successfully"
+//	@Failure		400				{string}	string	"Bad Request: The session is not pending"
+//	@Failure		403				{string}	string	"Forbidden: The session belongs to another user"
+//	@Failure		404				{string}	string	"Not Found: Session not found"
+//	@Failure		500				{string}	string	"Internal Server Error"
+//	@Router			/api/v1/upload/{sessionToken}/extend [put]
+//
+// ExtendUploadSessionHandler handles the request to extend an upload session
+func (usc *UploadSessionController) ExtendUploadSessionHandler(c *gin.Context) {
+	sessionToken := c.Param("sessionToken")
+	if sessionToken == "" {
+		shared.ErrorJSON(c, http.StatusBadRequest, "Session Token is required")
+		return
+	}
+
+	session, err := usc.UploadSessionService.ExtendSessionRecord(c, sessionToken, c.GetString("x-user-id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.SuccessJSON(c, http.StatusOK, "Session extended successfully", session)
+}
+
+// AcquireBlockHandler godoc
+//
+//	@Summary		Acquire a block before saving a chunk
+//	@Description	Called by the block server before it saves a chunk. The block is kept during the grace period so the chunk can be recorded safely. The response tells whether the block is already stored, then its upload can be skipped, and whether a file of the user already references it.
+//	@Security		Bearer
+//	@Tags			UploadSession
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		models.AcquireBlockRequest	true	"Block hash and size"
+//	@Success		200		{object}	models.AcquireBlockResponse	"Block acquired successfully"
+//	@Failure		400		{string}	string	"Bad Request: Invalid block hash"
+//	@Failure		503		{string}	string	"Service Unavailable: The block is being deleted"
+//	@Failure		500		{string}	string	"Internal Server Error"
+//	@Router			/api/v1/upload/blocks [post]
+//
+// AcquireBlockHandler handles the request to acquire a block before a chunk is saved
+func (usc *UploadSessionController) AcquireBlockHandler(c *gin.Context) {
+	var requestBody models.AcquireBlockRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		shared.ErrorJSON(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	response, err := usc.UploadSessionService.AcquireBlock(c, c.GetString("x-user-id"), requestBody.Hash, requestBody.Size)
+	if errors.Is(err, models.ErrBlockBusy) {
+		shared.ErrorJSON(c, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.SuccessJSON(c, http.StatusOK, "Block acquired successfully", response)
+}
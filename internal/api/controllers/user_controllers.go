@@ -48,6 +48,30 @@ func (uc *UserController) GetUserInformationHandler(c *gin.Context) {
 	shared.SuccessJSON(c, http.StatusOK, "User information retrieved successfully", user)
 }
 
+// GetStorageUsageHandler godoc
+// @Summary Get the storage usage of the user
+// @Description Returns the storage quota and usage of the user with the usage of each mime type. Every version of the files counts, trashed files included.
+// @Security		Bearer
+// @Tags User
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.StorageUsageResponse
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/v1/user/usage [get]
+func (uc *UserController) GetStorageUsageHandler(c *gin.Context) {
+	// Get the user ID from the request context
+	userID := c.GetString("x-user-id")
+
+	usage, err := uc.UserService.GetStorageUsage(c, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	shared.SuccessJSON(c, http.StatusOK, "Storage usage retrieved successfully", usage)
+}
+
 // GetUserByIDHandler godoc
 // @Summary Get user by ID
 // @Description Get user by ID
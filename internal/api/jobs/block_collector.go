@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"skybox-backend/configs"
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/shared/storage"
+)
+
+// BlockCollector deletes the blocks that are no longer referenced from the block store
+// A released block is kept during the grace period, so a chunk uploaded meanwhile can reference it again.
+// Each block is marked as being deleted only if its reference count is still zero, the uploads of a marked
+// block are rejected until its object and its record are deleted
+type BlockCollector struct {
+	blockRepository models.BlockRepository
+	store           storage.BlockStore
+	interval        time.Duration
+	gracePeriod     time.Duration
+}
+
+// NewBlockCollector creates a collector using the configured interval and grace period
+func NewBlockCollector(br models.BlockRepository, store storage.BlockStore) *BlockCollector {
+	return &BlockCollector{
+		blockRepository: br,
+		store:           store,
+		interval:        configs.Config.BlockCollectInterval,
+		gracePeriod:     configs.Config.BlockReleaseGracePeriod,
+	}
+}
+
+// Start runs the collector every interval until the context is cancelled
+func (bc *BlockCollector) Start(ctx context.Context) {
+	ticker := time.NewTicker(bc.interval)
+	defer ticker.Stop()
+
+	for {
+		if count, err := bc.RunOnce(ctx); err != nil {
+			log.Printf("Block collector failed: %v", err)
+		} else if count > 0 {
+			log.Printf("Block collector deleted %d blocks", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce deletes every block released before the grace period and returns the number of deleted blocks
+func (bc *BlockCollector) RunOnce(ctx context.Context) (int, error) {
+	releasedBefore := time.Now().Add(-bc.gracePeriod)
+	count := 0
+
+	for {
+		block, err := bc.blockRepository.ClaimReleasedBlock(ctx, releasedBefore)
+		if err != nil {
+			return count, err
+		}
+		if block == nil {
+			return count, nil
+		}
+
+		// A block whose object cannot be deleted stays marked, it is claimed again after another grace period
+		if storage.IsValidBlockHash(block.Hash) {
+			if err := bc.store.Delete(ctx, storage.BlockKey(block.Hash)); err != nil {
+				return count, fmt.Errorf("failed to delete block %s: %w", block.Hash, err)
+			}
+		}
+		if err := bc.blockRepository.DeleteBlockRecord(ctx, block.Hash); err != nil {
+			return count, err
+		}
+		count++
+	}
+}
@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/shared/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBlockRepository keeps the blocks in memory, only the methods used by the collector are implemented
+type fakeBlockRepository struct {
+	models.BlockRepository
+	blocks map[string]*models.Block
+}
+
+func (f *fakeBlockRepository) ClaimReleasedBlock(ctx context.Context, releasedBefore time.Time) (*models.Block, error) {
+	for _, block := range f.blocks {
+		if block.RefCount <= 0 && block.UpdatedAt.Before(releasedBefore) {
+			block.Deleting = true
+			block.UpdatedAt = time.Now()
+			return block, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeBlockRepository) DeleteBlockRecord(ctx context.Context, hash string) error {
+	if f.blocks[hash].Deleting {
+		delete(f.blocks, hash)
+	}
+	return nil
+}
+
+func TestBlockCollector_RunOnce(t *testing.T) {
+	store := storage.NewMemoryStore()
+	releasedHash := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	recentHash := "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+	referencedHash := "fcde2b2edba56bf408601fb721fe9b5c338d10ee429ea04fae5511b68fbf8fb9"
+
+	repository := &fakeBlockRepository{
+		blocks: map[string]*models.Block{
+			releasedHash:   {Hash: releasedHash, RefCount: 0, UpdatedAt: time.Now().Add(-48 * time.Hour)},
+			recentHash:     {Hash: recentHash, RefCount: 0, UpdatedAt: time.Now().Add(-time.Hour)}, // Still in its grace period
+			referencedHash: {Hash: referencedHash, RefCount: 1, UpdatedAt: time.Now().Add(-48 * time.Hour)},
+		},
+	}
+	for hash := range repository.blocks {
+		putObject(t, store, storage.BlockKey(hash))
+	}
+
+	collector := NewBlockCollector(repository, store)
+	collector.gracePeriod = 24 * time.Hour
+	count, err := collector.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.NotContains(t, repository.blocks, releasedHash)
+	assert.Len(t, repository.blocks, 2)
+
+	objects, err := store.List(context.Background(), "")
+	require.NoError(t, err)
+	var keys []string
+	for _, object := range objects {
+		keys = append(keys, object.Key)
+	}
+	assert.ElementsMatch(t, []string{storage.BlockKey(recentHash), storage.BlockKey(referencedHash)}, keys)
+}
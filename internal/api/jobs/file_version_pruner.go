@@ -0,0 +1,120 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"skybox-backend/configs"
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/api/services"
+	"skybox-backend/internal/shared/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// prunerBatchSize is the number of files checked by one query
+const prunerBatchSize = 100
+
+// FileVersionPruner deletes the old versions of the files according to the version policy
+// A file keeps at most maxVersions versions and the versions older than the retention are deleted.
+// The current version is always kept.
+type FileVersionPruner struct {
+	fileVersionRepository models.FileVersionRepository
+	store                 storage.BlockStore
+	interval              time.Duration
+	maxVersions           int
+	retention             time.Duration
+}
+
+// NewFileVersionPruner creates a pruner using the configured interval and version policy
+func NewFileVersionPruner(fvr models.FileVersionRepository, store storage.BlockStore) *FileVersionPruner {
+	return &FileVersionPruner{
+		fileVersionRepository: fvr,
+		store:                 store,
+		interval:              configs.Config.FileVersionPruneInterval,
+		maxVersions:           configs.Config.FileMaxVersions,
+		retention:             configs.Config.FileVersionRetention,
+	}
+}
+
+// Start runs the pruner every interval until the context is cancelled
+func (p *FileVersionPruner) Start(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if count, err := p.RunOnce(ctx); err != nil {
+			log.Printf("File version pruner failed: %v", err)
+		} else if count > 0 {
+			log.Printf("File version pruner deleted %d versions", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce applies the version policy to every versioned file and returns the number of deleted versions
+func (p *FileVersionPruner) RunOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+	count := 0
+
+	afterID := primitive.NilObjectID
+	for {
+		files, err := p.fileVersionRepository.GetVersionedFiles(ctx, afterID, prunerBatchSize)
+		if err != nil {
+			return count, fmt.Errorf("failed to get versioned files: %w", err)
+		}
+		if len(files) == 0 {
+			return count, nil
+		}
+		afterID = files[len(files)-1].ID
+
+		for _, file := range files {
+			versions := p.prunableVersions(file, now)
+			if len(versions) == 0 {
+				continue
+			}
+
+			released, err := p.fileVersionRepository.PruneVersions(ctx, file.ID, versions)
+			if err != nil {
+				return count, fmt.Errorf("failed to prune the versions of file %s: %w", file.ID.Hex(), err)
+			}
+			count += len(released)
+
+			// The records are already deleted, a failed deletion only leaves an orphan object behind
+			if err := services.DeleteReleasedChunks(ctx, p.store, released); err != nil {
+				log.Printf("Failed to delete the chunks of file %s: %v", file.ID.Hex(), err)
+			}
+		}
+	}
+}
+
+// prunableVersions returns the versions of the file that the policy deletes
+// The oldest versions above maxVersions are deleted first, then the versions older than the retention
+func (p *FileVersionPruner) prunableVersions(file *models.File, now time.Time) []int {
+	versions := []int{}
+
+	// The versions are ordered from the oldest
+	excess := 0
+	if p.maxVersions > 0 {
+		excess = len(file.Versions) - p.maxVersions
+	}
+	for _, fileVersion := range file.Versions {
+		if fileVersion.Version == file.CurrentVersion {
+			continue
+		}
+
+		if excess > 0 || (p.retention > 0 && fileVersion.CreatedAt.Before(now.Add(-p.retention))) {
+			versions = append(versions, fileVersion.Version)
+			excess--
+		}
+	}
+
+	return versions
+}
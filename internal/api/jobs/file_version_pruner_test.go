@@ -0,0 +1,114 @@
+package jobs
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/shared/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeFileVersionRepository keeps the files in memory, only the methods used by the pruner are implemented
+type fakeFileVersionRepository struct {
+	models.FileVersionRepository
+	files    []*models.File
+	released map[int]*models.ReleasedChunks
+}
+
+func (f *fakeFileVersionRepository) GetVersionedFiles(ctx context.Context, afterID primitive.ObjectID, limit int64) ([]*models.File, error) {
+	var files []*models.File
+	for _, file := range f.files {
+		if file.ID.Hex() > afterID.Hex() && len(file.Versions) > 1 && int64(len(files)) < limit {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+func (f *fakeFileVersionRepository) PruneVersions(ctx context.Context, fileID primitive.ObjectID, versions []int) ([]*models.ReleasedChunks, error) {
+	var released []*models.ReleasedChunks
+	for _, file := range f.files {
+		if file.ID != fileID {
+			continue
+		}
+		file.Versions = slices.DeleteFunc(file.Versions, func(fileVersion models.FileVersion) bool {
+			if slices.Contains(versions, fileVersion.Version) {
+				released = append(released, f.released[fileVersion.Version])
+				return true
+			}
+			return false
+		})
+	}
+	return released, nil
+}
+
+func versionNumbers(file *models.File) []int {
+	var versions []int
+	for _, fileVersion := range file.Versions {
+		versions = append(versions, fileVersion.Version)
+	}
+	return versions
+}
+
+func TestFileVersionPruner_PrunableVersions(t *testing.T) {
+	now := time.Now()
+	file := &models.File{CurrentVersion: 2}
+	for version := 1; version <= 5; version++ {
+		file.Versions = append(file.Versions, models.FileVersion{Version: version, CreatedAt: now.Add(time.Duration(version-6) * 24 * time.Hour)})
+	}
+
+	// The current version is kept even when it is the oldest one above the limit
+	pruner := &FileVersionPruner{maxVersions: 3}
+	assert.Equal(t, []int{1, 3}, pruner.prunableVersions(file, now))
+
+	// Every old version past the retention is deleted
+	pruner = &FileVersionPruner{retention: 2*24*time.Hour + time.Hour}
+	assert.Equal(t, []int{1, 3}, pruner.prunableVersions(file, now))
+
+	pruner = &FileVersionPruner{}
+	assert.Empty(t, pruner.prunableVersions(file, now))
+}
+
+func TestFileVersionPruner_RunOnce(t *testing.T) {
+	store := storage.NewMemoryStore()
+	hash := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	sharedHash := "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+
+	ownerID := primitive.NewObjectID()
+	file := &models.File{
+		ID:             primitive.NewObjectID(),
+		OwnerID:        ownerID,
+		CurrentVersion: 3,
+		Versions:       []models.FileVersion{{Version: 1}, {Version: 2}, {Version: 3}},
+	}
+	repository := &fakeFileVersionRepository{
+		files: []*models.File{file},
+		released: map[int]*models.ReleasedChunks{
+			1: {OwnerID: ownerID.Hex(), FileID: file.ID.Hex(), ChunkIndexes: []int{0}, ReleasedBlocks: []string{hash}},
+		},
+	}
+
+	putObject(t, store, storage.BlockKey(hash))       // Released, left to the block collector
+	putObject(t, store, storage.BlockKey(sharedHash)) // Still referenced by version 2
+	putObject(t, store, storage.ChunkKey(ownerID.Hex(), file.ID.Hex(), 0))
+
+	pruner := NewFileVersionPruner(repository, store)
+	pruner.maxVersions = 2
+	pruner.retention = 0
+	count, err := pruner.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []int{2, 3}, versionNumbers(file))
+
+	objects, err := store.List(context.Background(), "")
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	assert.Equal(t, storage.BlockKey(sharedHash), objects[0].Key)
+	assert.Equal(t, storage.BlockKey(hash), objects[1].Key)
+}
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// repairerBatchSize is the number of folder trees read by one query
+const repairerBatchSize = 100
+
+// FolderStatsRepairer recomputes the statistics of every folder tree from its files and folders
+// The statistics are maintained on every change, the repair is needed for the databases created before that
+// or after a manual change of the data.
+// The repair overwrites the statistics, so it must not run while the folders are modified.
+type FolderStatsRepairer struct {
+	folderStatsRepository models.FolderStatsRepository
+}
+
+// NewFolderStatsRepairer creates a repairer of the folder statistics
+func NewFolderStatsRepairer(fsr models.FolderStatsRepository) *FolderStatsRepairer {
+	return &FolderStatsRepairer{
+		folderStatsRepository: fsr,
+	}
+}
+
+// Start runs the repair once and returns when it is done
+func (r *FolderStatsRepairer) Start(ctx context.Context) {
+	if count, err := r.RunOnce(ctx); err != nil {
+		log.Printf("Folder stats repairer failed: %v", err)
+	} else {
+		log.Printf("Folder stats repairer recomputed %d folders", count)
+	}
+}
+
+// RunOnce recomputes the statistics of every folder tree and returns the number of updated folders
+func (r *FolderStatsRepairer) RunOnce(ctx context.Context) (int, error) {
+	count := 0
+
+	afterID := primitive.NilObjectID
+	for {
+		rootFolderIDs, err := r.folderStatsRepository.GetRootFolderIDs(ctx, afterID, repairerBatchSize)
+		if err != nil {
+			return count, fmt.Errorf("failed to get root folders: %w", err)
+		}
+		if len(rootFolderIDs) == 0 {
+			return count, nil
+		}
+		afterID = rootFolderIDs[len(rootFolderIDs)-1]
+
+		for _, rootFolderID := range rootFolderIDs {
+			if err := ctx.Err(); err != nil {
+				return count, err
+			}
+
+			updated, err := r.folderStatsRepository.RecomputeFolderStats(ctx, rootFolderID)
+			if err != nil {
+				return count, fmt.Errorf("failed to recompute the statistics of the folder %s: %w", rootFolderID.Hex(), err)
+			}
+			count += updated
+		}
+	}
+}
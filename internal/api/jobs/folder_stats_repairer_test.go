@@ -0,0 +1,46 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeFolderStatsRepository returns the root folders in ID order and records the recomputed trees
+type fakeFolderStatsRepository struct {
+	rootFolderIDs []primitive.ObjectID
+	treeSizes     map[primitive.ObjectID]int
+	recomputed    []primitive.ObjectID
+}
+
+func (f *fakeFolderStatsRepository) GetRootFolderIDs(ctx context.Context, afterID primitive.ObjectID, limit int64) ([]primitive.ObjectID, error) {
+	var folderIDs []primitive.ObjectID
+	for _, folderID := range f.rootFolderIDs {
+		if folderID.Hex() > afterID.Hex() && int64(len(folderIDs)) < limit {
+			folderIDs = append(folderIDs, folderID)
+		}
+	}
+	return folderIDs, nil
+}
+
+func (f *fakeFolderStatsRepository) RecomputeFolderStats(ctx context.Context, rootFolderID primitive.ObjectID) (int, error) {
+	f.recomputed = append(f.recomputed, rootFolderID)
+	return f.treeSizes[rootFolderID], nil
+}
+
+func TestFolderStatsRepairer_RunOnce(t *testing.T) {
+	repository := &fakeFolderStatsRepository{treeSizes: map[primitive.ObjectID]int{}}
+	for i := 0; i < repairerBatchSize+5; i++ {
+		folderID := primitive.NewObjectID()
+		repository.rootFolderIDs = append(repository.rootFolderIDs, folderID)
+		repository.treeSizes[folderID] = 3
+	}
+
+	count, err := NewFolderStatsRepairer(repository).RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3*(repairerBatchSize+5), count)
+	assert.Equal(t, repository.rootFolderIDs, repository.recomputed)
+}
@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"skybox-backend/configs"
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/api/services"
+	"skybox-backend/internal/shared/storage"
+)
+
+// purgerBatchSize is the number of trash items purged by one query
+const purgerBatchSize = 100
+
+// TrashPurger permanently deletes the items that stayed in the trash longer than the retention period
+// The file, folder and chunk records are deleted and the legacy chunk objects are deleted from the block store,
+// the blocks that are no longer referenced are left to the block collector
+type TrashPurger struct {
+	trashRepository models.TrashRepository
+	store           storage.BlockStore
+	interval        time.Duration
+	retention       time.Duration
+}
+
+// NewTrashPurger creates a purger using the configured interval and retention period
+func NewTrashPurger(tr models.TrashRepository, store storage.BlockStore) *TrashPurger {
+	return &TrashPurger{
+		trashRepository: tr,
+		store:           store,
+		interval:        configs.Config.TrashPurgeInterval,
+		retention:       configs.Config.TrashRetention,
+	}
+}
+
+// Start runs the purger every interval until the context is cancelled
+func (p *TrashPurger) Start(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if count, err := p.RunOnce(ctx); err != nil {
+			log.Printf("Trash purger failed: %v", err)
+		} else if count > 0 {
+			log.Printf("Trash purger deleted %d items", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce purges every item trashed before the retention period and returns the number of purged items
+func (p *TrashPurger) RunOnce(ctx context.Context) (int, error) {
+	deletedBefore := time.Now().Add(-p.retention)
+	count := 0
+
+	for {
+		items, err := p.trashRepository.GetExpiredTrashItems(ctx, deletedBefore, purgerBatchSize)
+		if err != nil {
+			return count, fmt.Errorf("failed to get expired trash items: %w", err)
+		}
+		if len(items) == 0 {
+			return count, nil
+		}
+
+		for _, item := range items {
+			released, err := p.trashRepository.PurgeItem(ctx, item.OwnerID, item.Type, item.ID)
+			if errors.Is(err, models.ErrTrashItemNotFound) {
+				continue // Purged with its parent folder or restored meanwhile
+			}
+			if err != nil {
+				return count, fmt.Errorf("failed to purge %s %s: %w", item.Type, item.ID.Hex(), err)
+			}
+			count++
+
+			// The records are already deleted, a failed deletion only leaves an orphan object behind
+			if err := services.DeleteReleasedChunks(ctx, p.store, released); err != nil {
+				log.Printf("Failed to delete the chunks of %s %s: %v", item.Type, item.ID.Hex(), err)
+			}
+		}
+	}
+}
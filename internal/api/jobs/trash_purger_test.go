@@ -0,0 +1,91 @@
+package jobs
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/shared/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeTrashRepository keeps the trash items in memory, only the methods used by the purger are implemented
+type fakeTrashRepository struct {
+	models.TrashRepository
+	items    map[primitive.ObjectID]*models.TrashItem
+	released map[primitive.ObjectID][]*models.ReleasedChunks
+}
+
+func (f *fakeTrashRepository) GetExpiredTrashItems(ctx context.Context, deletedBefore time.Time, limit int64) ([]*models.TrashItem, error) {
+	var items []*models.TrashItem
+	for _, item := range f.items {
+		if item.DeletedAt.Before(deletedBefore) && int64(len(items)) < limit {
+			items = append(items, item)
+		}
+	}
+
+	// Folders first, like the repository
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Type == models.TrashItemFolder && items[j].Type != models.TrashItemFolder
+	})
+	return items, nil
+}
+
+func (f *fakeTrashRepository) PurgeItem(ctx context.Context, ownerID primitive.ObjectID, itemType string, itemID primitive.ObjectID) ([]*models.ReleasedChunks, error) {
+	if _, ok := f.items[itemID]; !ok {
+		return nil, models.ErrTrashItemNotFound
+	}
+
+	// Purging a folder also purges the items it contains
+	delete(f.items, itemID)
+	for id, item := range f.items {
+		if item.ParentFolderID == itemID {
+			delete(f.items, id)
+		}
+	}
+	return f.released[itemID], nil
+}
+
+func TestTrashPurger_RunOnce(t *testing.T) {
+	store := storage.NewMemoryStore()
+	hash := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	sharedHash := "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+
+	ownerID := primitive.NewObjectID()
+	expiredAt := time.Now().Add(-31 * 24 * time.Hour)
+	recentAt := time.Now().Add(-time.Hour)
+
+	folder := &models.TrashItem{ID: primitive.NewObjectID(), Type: models.TrashItemFolder, OwnerID: ownerID, DeletedAt: &expiredAt}
+	nestedFile := &models.TrashItem{ID: primitive.NewObjectID(), Type: models.TrashItemFile, OwnerID: ownerID, ParentFolderID: folder.ID, DeletedAt: &expiredAt}
+	recentFile := &models.TrashItem{ID: primitive.NewObjectID(), Type: models.TrashItemFile, OwnerID: ownerID, DeletedAt: &recentAt}
+
+	repository := &fakeTrashRepository{
+		items: map[primitive.ObjectID]*models.TrashItem{folder.ID: folder, nestedFile.ID: nestedFile, recentFile.ID: recentFile},
+		released: map[primitive.ObjectID][]*models.ReleasedChunks{
+			folder.ID: {{OwnerID: ownerID.Hex(), FileID: nestedFile.ID.Hex(), ChunkIndexes: []int{0}, ReleasedBlocks: []string{hash}}},
+		},
+	}
+
+	putObject(t, store, storage.BlockKey(hash))       // Released, left to the block collector
+	putObject(t, store, storage.BlockKey(sharedHash)) // Still referenced by another file
+	putObject(t, store, storage.ChunkKey(ownerID.Hex(), nestedFile.ID.Hex(), 0))
+
+	purger := NewTrashPurger(repository, store)
+	purger.retention = 30 * 24 * time.Hour
+	count, err := purger.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Contains(t, repository.items, recentFile.ID)
+	assert.Len(t, repository.items, 1)
+
+	objects, err := store.List(context.Background(), "")
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	assert.Equal(t, storage.BlockKey(sharedHash), objects[0].Key)
+	assert.Equal(t, storage.BlockKey(hash), objects[1].Key)
+}
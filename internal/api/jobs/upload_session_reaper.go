@@ -0,0 +1,92 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"skybox-backend/configs"
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/shared/storage"
+)
+
+// reaperBatchSize is the number of sessions expired by one query
+const reaperBatchSize = 100
+
+// UploadSessionReaper expires the abandoned upload sessions
+// A pending session past its expiry is marked as expired, its chunk records are deleted,
+// the legacy chunk objects are deleted from the block store and the pending file record is deleted.
+// The blocks that are no longer referenced are left to the block collector
+type UploadSessionReaper struct {
+	uploadSessionRepository models.UploadSessionRepository
+	store                   storage.BlockStore
+	interval                time.Duration
+	ttl                     time.Duration
+}
+
+// NewUploadSessionReaper creates a reaper using the configured interval and session TTL
+func NewUploadSessionReaper(usr models.UploadSessionRepository, store storage.BlockStore) *UploadSessionReaper {
+	return &UploadSessionReaper{
+		uploadSessionRepository: usr,
+		store:                   store,
+		interval:                configs.Config.UploadReaperInterval,
+		ttl:                     configs.Config.UploadSessionTTL,
+	}
+}
+
+// Start runs the reaper every interval until the context is cancelled
+func (r *UploadSessionReaper) Start(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if count, err := r.RunOnce(ctx); err != nil {
+			log.Printf("Upload session reaper failed: %v", err)
+		} else if count > 0 {
+			log.Printf("Upload session reaper expired %d sessions", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce expires every pending session past its expiry and returns the number of expired sessions
+func (r *UploadSessionReaper) RunOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+	count := 0
+
+	for {
+		sessions, err := r.uploadSessionRepository.GetExpiredSessionRecords(ctx, now, now.Add(-r.ttl), reaperBatchSize)
+		if err != nil {
+			return count, fmt.Errorf("failed to get expired sessions: %w", err)
+		}
+		if len(sessions) == 0 {
+			return count, nil
+		}
+
+		for _, session := range sessions {
+			released, err := r.uploadSessionRepository.ExpireSessionRecord(ctx, session.SessionToken, now)
+			if err != nil {
+				return count, fmt.Errorf("failed to expire session %s: %w", session.SessionToken, err)
+			}
+			if released == nil {
+				continue // Completed or extended meanwhile
+			}
+			count++
+
+			// The records are already deleted, a failed deletion only leaves an orphan object behind
+			err = storage.DeleteChunks(ctx, r.store, session.UserID.Hex(), session.FileID.Hex(), released.ChunkIndexes)
+			if err != nil {
+				log.Printf("Failed to delete the chunks of file %s: %v", session.FileID.Hex(), err)
+			}
+			if err := r.store.Delete(ctx, storage.PartialUploadKey(session.SessionToken)); err != nil {
+				log.Printf("Failed to delete the partial upload of session %s: %v", session.SessionToken, err)
+			}
+		}
+	}
+}
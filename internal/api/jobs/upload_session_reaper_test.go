@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/shared/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeUploadSessionRepository keeps the sessions in memory, only the methods used by the reaper are implemented
+type fakeUploadSessionRepository struct {
+	models.UploadSessionRepository
+	sessions map[string]*models.UploadSession
+	released map[string]*models.CancelUploadSessionResponse
+}
+
+func (f *fakeUploadSessionRepository) GetExpiredSessionRecords(ctx context.Context, now time.Time, legacyCreatedBefore time.Time, limit int64) ([]models.UploadSession, error) {
+	var sessions []models.UploadSession
+	for _, session := range f.sessions {
+		if session.Status == "pending" && session.IsExpired(now) {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions, nil
+}
+
+func (f *fakeUploadSessionRepository) ExpireSessionRecord(ctx context.Context, sessionToken string, now time.Time) (*models.CancelUploadSessionResponse, error) {
+	f.sessions[sessionToken].Status = "expired"
+	return f.released[sessionToken], nil
+}
+
+func putObject(t *testing.T, store storage.BlockStore, key string) {
+	require.NoError(t, store.Put(context.Background(), key, bytes.NewReader([]byte("data")), 4))
+}
+
+func TestUploadSessionReaper_RunOnce(t *testing.T) {
+	store := storage.NewMemoryStore()
+	hash := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	sharedHash := "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+
+	expired := &models.UploadSession{
+		UserID:       primitive.NewObjectID(),
+		FileID:       primitive.NewObjectID(),
+		SessionToken: "expired",
+		Status:       "pending",
+		ExpiresAt:    time.Now().Add(-time.Hour),
+	}
+	active := &models.UploadSession{
+		UserID:       primitive.NewObjectID(),
+		FileID:       primitive.NewObjectID(),
+		SessionToken: "active",
+		Status:       "pending",
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+	repository := &fakeUploadSessionRepository{
+		sessions: map[string]*models.UploadSession{"expired": expired, "active": active},
+		released: map[string]*models.CancelUploadSessionResponse{
+			"expired": {FileID: expired.FileID.Hex(), ChunkIndexes: []int{0, 1}, ReleasedBlocks: []string{hash}},
+		},
+	}
+
+	putObject(t, store, storage.BlockKey(hash))       // Released, left to the block collector
+	putObject(t, store, storage.BlockKey(sharedHash)) // Still referenced by another file
+	putObject(t, store, storage.ChunkKey(expired.UserID.Hex(), expired.FileID.Hex(), 1))
+	putObject(t, store, storage.PartialUploadKey("expired"))
+	putObject(t, store, storage.PartialUploadKey("active"))
+
+	reaper := NewUploadSessionReaper(repository, store)
+	count, err := reaper.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "expired", expired.Status)
+	assert.Equal(t, "pending", active.Status)
+
+	objects, err := store.List(context.Background(), "")
+	require.NoError(t, err)
+	var keys []string
+	for _, object := range objects {
+		keys = append(keys, object.Key)
+	}
+	assert.ElementsMatch(t, []string{storage.BlockKey(hash), storage.BlockKey(sharedHash), storage.PartialUploadKey("active")}, keys)
+}
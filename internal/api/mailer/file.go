@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileMailer writes every email to a .eml file instead of sending it
+// It stands in for a mail server during development, the files can be opened by any mail client
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer creates a FileMailer writing to the directory
+func NewFileMailer(dir string, from string) *FileMailer {
+	return &FileMailer{
+		dir:  dir,
+		from: from,
+	}
+}
+
+func (fm *FileMailer) Send(ctx context.Context, message *Message) error {
+	if err := validateHeaders(message); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(fm.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create the mail directory: %w", err)
+	}
+
+	// The recipient is kept in the name so the emails of a user are easy to find
+	now := time.Now()
+	recipient := strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(message.To)
+	name := fmt.Sprintf("%d_%s.eml", now.UnixNano(), recipient)
+
+	if err := os.WriteFile(filepath.Join(fm.dir, name), formatMessage(fm.from, message, now), 0o644); err != nil {
+		return fmt.Errorf("failed to write the email: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"skybox-backend/configs"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer is the interface implemented by every email backend.
+// The services only send emails through this interface, so they can be tested without a mail server.
+type Mailer interface {
+	Send(ctx context.Context, message *Message) error
+}
+
+// NewMailer creates the Mailer selected by configs.Config.MailerBackend
+func NewMailer() (Mailer, error) {
+	switch configs.Config.MailerBackend {
+	case "smtp":
+		return NewSMTPMailer(configs.Config.SMTPHost, configs.Config.SMTPPort, configs.Config.SMTPUsername, configs.Config.SMTPPassword, configs.Config.MailFrom), nil
+	case "file", "":
+		return NewFileMailer(configs.Config.MailDropPath, configs.Config.MailFrom), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer backend: %s", configs.Config.MailerBackend)
+	}
+}
+
+// formatMessage formats the message with its headers, as sent over SMTP
+func formatMessage(from string, message *Message, date time.Time) []byte {
+	var builder strings.Builder
+	builder.WriteString("From: " + from + "\r\n")
+	builder.WriteString("To: " + message.To + "\r\n")
+	builder.WriteString("Subject: " + message.Subject + "\r\n")
+	builder.WriteString("Date: " + date.Format(time.RFC1123Z) + "\r\n")
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+
+	return []byte(builder.String())
+}
+
+// validateHeaders rejects the line breaks that would add headers to the message
+func validateHeaders(message *Message) error {
+	if strings.ContainsAny(message.To, "\r\n") || strings.ContainsAny(message.Subject, "\r\n") {
+		return fmt.Errorf("invalid email: the recipient and the subject cannot contain line breaks")
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer_WritesTheEmail(t *testing.T) {
+	dir := t.TempDir()
+	fileMailer := NewFileMailer(dir, "SkyBox <no-reply@skybox.local>")
+
+	err := fileMailer.Send(context.Background(), &Message{To: "invitee@example.com", Subject: "Invitation", Body: "Hello\nWorld"})
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*_invitee@example.com.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "From: SkyBox <no-reply@skybox.local>\r\nTo: invitee@example.com\r\nSubject: Invitation\r\n"))
+	assert.True(t, strings.HasSuffix(string(content), "\r\n\r\nHello\r\nWorld"))
+}
+
+func TestFileMailer_RejectsHeaderInjection(t *testing.T) {
+	fileMailer := NewFileMailer(t.TempDir(), "no-reply@skybox.local")
+
+	err := fileMailer.Send(context.Background(), &Message{To: "invitee@example.com\r\nBcc: other@example.com", Subject: "Invitation"})
+	assert.ErrorContains(t, err, "invalid email")
+}
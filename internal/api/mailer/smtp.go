@@ -0,0 +1,54 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer sends the emails through an SMTP server
+// The credentials are optional, a local relay usually accepts the emails without them
+type SMTPMailer struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+// NewSMTPMailer creates a new instance of the SMTPMailer
+func NewSMTPMailer(host string, port string, username string, password string, from string) *SMTPMailer {
+	return &SMTPMailer{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+func (sm *SMTPMailer) Send(ctx context.Context, message *Message) error {
+	if err := validateHeaders(message); err != nil {
+		return err
+	}
+
+	sender, err := mail.ParseAddress(sm.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+
+	var auth smtp.Auth
+	if sm.username != "" {
+		auth = smtp.PlainAuth("", sm.username, sm.password, sm.host)
+	}
+
+	err = smtp.SendMail(net.JoinHostPort(sm.host, sm.port), auth, sender.Address, []string{message.To}, formatMessage(sm.from, message, time.Now()))
+	if err != nil {
+		return fmt.Errorf("failed to send the email: %w", err)
+	}
+
+	return nil
+}
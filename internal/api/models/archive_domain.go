@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// ArchiveEntry is a folder or a file of a zip archive streamed by the block server
+type ArchiveEntry struct {
+	Path       string    `json:"path"`               // Path in the archive, folder paths end with a slash
+	FileID     string    `json:"file_id,omitempty"`  // Empty for folders
+	OwnerID    string    `json:"owner_id,omitempty"` // Owner of the file, part of the legacy chunk keys
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modified_at"`
+	Chunks     []Chunk   `json:"chunks,omitempty"` // Chunks of the current version, ordered by chunk index
+}
+
+type ArchiveResponse struct {
+	Name    string         `json:"name"` // File name of the archive
+	Entries []ArchiveEntry `json:"entries"`
+}
+
+type CreateArchiveRequest struct {
+	FileIDs   []string `json:"file_ids"`
+	FolderIDs []string `json:"folder_ids"`
+}
+
+type CreateArchiveResponse struct {
+	DownloadURL string `json:"download_url"` // Block server URL streaming the archive
+}
@@ -4,6 +4,8 @@ type RegisterRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Username string `json:"username" binding:"required,min=3,max=20"`
 	Password string `json:"password" binding:"required,min=6,max=20"`
+
+	InvitationToken string `json:"invitation_token,omitempty"` // The token of an invitation link sent to the email, accepts its invitations
 }
 
 type LoginRequest struct {
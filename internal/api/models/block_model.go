@@ -0,0 +1,56 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	CollectionBlocks = "blocks"
+)
+
+// ErrBlockBusy is returned when a block is being deleted from the block store, the upload must be retried later
+var ErrBlockBusy = errors.New("block is being deleted, retry the upload later")
+
+// Block is a content-addressed chunk object saved once in the block store
+// The ID is the SHA-256 hash of the chunk data, and RefCount is the number of chunk records pointing to it.
+// A block that is no longer referenced is kept as a tombstone: the block collector deletes it from the block store
+// once UpdatedAt is older than the grace period, unless a chunk references it again meanwhile
+type Block struct {
+	Hash      string    `bson:"_id" json:"hash"`
+	Size      int64     `bson:"size" json:"size"`                             // Size of the block in bytes
+	RefCount  int64     `bson:"ref_count" json:"ref_count"`                   // Number of chunks referencing the block
+	Deleting  bool      `bson:"deleting,omitempty" json:"deleting,omitempty"` // Set by the block collector while the object is deleted
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"` // Last reference change or upload, starts the grace period
+}
+
+type BlockRepository interface {
+	GetBlockByHash(ctx context.Context, hash string) (*Block, error)                                   // Get a block by its hash
+	GetBlocksByHashes(ctx context.Context, hashes []string) ([]*Block, error)                          // Get the known blocks among the hashes
+	IncrementRefCount(ctx context.Context, hash string, size int64) error                              // Add a reference to a block, creating it if needed
+	DecrementRefCount(ctx context.Context, hash string) (*Block, error)                                // Remove a reference from a block
+	AcquireBlock(ctx context.Context, hash string, size int64) (*Block, error)                         // Keep a block for an upload, creating it if needed
+	IsBlockReferencedByUser(ctx context.Context, hash string, userID primitive.ObjectID) (bool, error) // Check if a file of the user references the block
+	ClaimReleasedBlock(ctx context.Context, releasedBefore time.Time) (*Block, error)                  // Mark a block released before the time as being deleted
+	DeleteBlockRecord(ctx context.Context, hash string) error                                          // Delete the record of a block marked as being deleted
+}
+
+// AcquireBlockRequest is sent by the block server before it saves a chunk
+type AcquireBlockRequest struct {
+	Hash string `json:"hash" binding:"required"` // SHA-256 hash of the chunk data, hex encoded
+	Size int64  `json:"size" binding:"min=0"`    // Size of the chunk data in bytes
+}
+
+// AcquireBlockResponse tells the block server whether the block must be saved
+// The block cannot be deleted from the block store during the grace period, the chunk must be recorded before it ends.
+// The blocks of other users are answered as new blocks, so the response does not tell whether they exist
+type AcquireBlockResponse struct {
+	Hash       string `json:"hash"`
+	Size       int64  `json:"size"`       // Size of the stored block, the size of the request for any other block
+	Stored     bool   `json:"stored"`     // A file of the user references the block, so it is saved in the block store
+	Referenced bool   `json:"referenced"` // A file of the user already references the block
+}
@@ -14,9 +14,10 @@ var (
 type Chunk struct {
 	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	FileID     primitive.ObjectID `bson:"file_id" json:"file_id"`
+	SessionID  primitive.ObjectID `bson:"session_id,omitempty" json:"session_id,omitempty"` // The upload session that added the chunk, one per file version
 	ChunkIndex int                `bson:"chunk_index" json:"chunk_index"`
 	ChunkSize  int64              `bson:"chunk_size" json:"chunk_size"` // Size of the chunk in bytes
-	ChunkHash  string             `bson:"chunk_hash" json:"chunk_hash"` // Hash of the chunk data, could use MD5, SHA-1, etc.
+	ChunkHash  string             `bson:"chunk_hash" json:"chunk_hash"` // SHA-256 hash of the chunk data, identifies its block
 	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
 }
 
@@ -24,5 +25,7 @@ type ChunkRepository interface {
 	UploadChunkMetadata(ctx context.Context, fileId string, chunk *Chunk) (*Chunk, error)      // Upload chunk metadata
 	UpdateChunkStatus(ctx context.Context, fileId string, chunkIndex int, status string) error // Update chunk status
 	GetChunksByFileID(ctx context.Context, fileId string) ([]Chunk, error)                     // Get all chunks for a file
+	GetChunksByFileVersion(ctx context.Context, fileId string, version int) ([]Chunk, error)   // Get the chunks of a file version, 0 is the current version
+	GetChunksBySession(ctx context.Context, session *UploadSession) ([]Chunk, error)           // Get the chunks uploaded by a session
 	GetChunkByID(ctx context.Context, id string) (*Chunk, error)                               // Get chunk by ID
 }
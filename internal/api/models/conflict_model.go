@@ -0,0 +1,28 @@
+package models
+
+import "errors"
+
+// Conflict options of the operations adding an item to a folder (create, upload, rename, move and copy)
+// The items of a folder have unique names, the files and the folders are checked separately
+const (
+	ConflictFail    = "fail"    // Reject the operation, this is the default
+	ConflictRename  = "rename"  // Add " (1)", " (2)"... to the name of the new item
+	ConflictReplace = "replace" // An uploaded file gets a new version, any other existing file is moved to the trash
+)
+
+var (
+	// ErrNameConflict is returned when the folder already has an item with the same name
+	ErrNameConflict = errors.New("an item with the same name already exists in the folder")
+	// ErrFolderReplace is returned when the replace option would trash a folder, its subtree can hold items the user cannot edit
+	ErrFolderReplace = errors.New("invalid conflict option: a folder cannot be replaced, rename it or delete the existing folder first")
+)
+
+// IsValidConflict checks if the conflict option is known, an empty option is the default one
+func IsValidConflict(conflict string) bool {
+	switch conflict {
+	case "", ConflictFail, ConflictRename, ConflictReplace:
+		return true
+	default:
+		return false
+	}
+}
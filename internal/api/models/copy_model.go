@@ -0,0 +1,17 @@
+package models
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CopyRepository copies files and folder trees
+// The copies reference the blocks of the copied files, no data is copied in the block store
+type CopyRepository interface {
+	GetFolderTree(ctx context.Context, folderID primitive.ObjectID) ([]*Folder, error)                                                        // Get the folder and its descendants, parents first
+	GetDestinationFolder(ctx context.Context, folderID primitive.ObjectID) (*Folder, error)                                                   // Get the folder receiving a copy
+	GetCopySize(ctx context.Context, fileIDs []primitive.ObjectID, folderIDs []primitive.ObjectID) (int64, error)                             // Get the size of the copies of the files and of the files in the folders
+	CopyFile(ctx context.Context, fileID primitive.ObjectID, destFolderID primitive.ObjectID, newName string, conflict string) (*File, error) // Copy the current version of a file
+	CopyFolder(ctx context.Context, folders []*Folder, destFolderID primitive.ObjectID, newName string, conflict string) (*Folder, error)     // Copy the folders of a tree and their files
+}
@@ -1,7 +1,8 @@
 package models
 
 type RenameFileRequest struct {
-	NewName string `json:"new_name" binding:"required"`
+	NewName  string `json:"new_name" binding:"required"`
+	Conflict string `json:"conflict"` // optional, "fail" (default), "rename" or "replace" when the name is already used
 }
 
 type RenameFileResponse struct {
@@ -9,7 +10,56 @@ type RenameFileResponse struct {
 
 type MoveFileRequest struct {
 	NewParentID string `json:"new_parent_id" binding:"required"`
+	Conflict    string `json:"conflict"` // optional, "fail" (default), "rename" or "replace" when the name is already used
 }
 
 type MoveFileResponse struct {
 }
+
+type FileChunksResponse struct {
+	FileID      string  `json:"file_id"`
+	TotalChunks int     `json:"total_chunks"`
+	Chunks      []Chunk `json:"chunks"` // Ordered by chunk index
+}
+
+type FileVersionsResponse struct {
+	FileID         string        `json:"file_id"`
+	CurrentVersion int           `json:"current_version"`
+	Versions       []FileVersion `json:"versions"` // Oldest first
+}
+
+type CreateFileVersionRequest struct {
+	FileSize int64 `json:"file_size" binding:"required"`
+}
+
+type CreateFileVersionResponse struct {
+	FileID       string `json:"file_id"`
+	Version      int    `json:"version"`
+	UploadURL    string `json:"upload_url"`
+	SessionToken string `json:"session_token"` // Token of the upload session, also part of the upload URL
+}
+
+type CopyFileRequest struct {
+	NewParentID string `json:"new_parent_id" binding:"required"`
+	NewName     string `json:"new_name"` // optional, the copy keeps the name of the file by default
+	Conflict    string `json:"conflict"` // optional, "fail" (default), "rename" or "replace" when the name is already used
+}
+
+type ShareFileRequest struct {
+	UserID     string    `json:"user_id" binding:"required"`
+	Role       ShareRole `json:"role" enums:"viewer,commenter,editor,co-owner"`
+	Permission bool      `json:"permission"` // Deprecated: used when no role is given, true for editor and false for viewer
+}
+
+// GetRole returns the requested role, the boolean permission is used by the clients not sending a role
+func (r *ShareFileRequest) GetRole() ShareRole {
+	if r.Role != "" {
+		return r.Role
+	}
+
+	return RoleFromPermission(r.Permission)
+}
+
+type RemoveFileShareRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+}
@@ -8,25 +8,35 @@ import (
 )
 
 const (
-	CollectionFiles = "files"
+	CollectionFiles            = "files"
+	CollectionFileSharedUsers  = "file_shared_users"
+	CollectionFileSharedGroups = "file_shared_groups"
 )
 
 // File struct encapsulates the file model
 type File struct {
-	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	OwnerID        primitive.ObjectID `bson:"owner_id" json:"owner_id"`                                     // The owner of the file
-	ParentFolderID primitive.ObjectID `bson:"parent_folder_id,omitempty" json:"parent_folder_id,omitempty"` // The parent folder ID, if any
-
-	FileName  string     `bson:"file_name" json:"file_name"`
-	MimeType  string     `bson:"mime_type" json:"mime_type"`
-	Extension string     `bson:"extension" json:"extension"`
-	Size      int64      `bson:"size" json:"size"`
-	IsDeleted bool       `bson:"is_deleted" json:"is_deleted"`
-	DeletedAt *time.Time `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // Nullable field for soft delete
-	Status    string     `bson:"status" json:"status"`                             // Status of the file (e.g., "uploaded", "processing", "failed")
+	ID             primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	OwnerID        primitive.ObjectID  `bson:"owner_id" json:"owner_id"`                                     // The owner of the file
+	OrganizationID *primitive.ObjectID `bson:"organization_id,omitempty" json:"organization_id,omitempty"`   // The organization of the shared drive the file is in, nil in a personal drive
+	ParentFolderID primitive.ObjectID  `bson:"parent_folder_id,omitempty" json:"parent_folder_id,omitempty"` // The parent folder ID, if any
+
+	FileName    string             `bson:"file_name" json:"file_name"`
+	MimeType    string             `bson:"mime_type" json:"mime_type"`
+	Extension   string             `bson:"extension" json:"extension"`
+	Size        int64              `bson:"size" json:"size"`
+	IsDeleted   bool               `bson:"is_deleted" json:"is_deleted"`
+	DeletedAt   *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`     // Nullable field for soft delete
+	TrashedWith primitive.ObjectID `bson:"trashed_with,omitempty" json:"trashed_with,omitempty"` // The deleted folder this file was trashed with
+	DeletedBy   primitive.ObjectID `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`     // The user who moved the file to the trash
+	TrashReason string             `bson:"trash_reason,omitempty" json:"trash_reason,omitempty"` // Why the file was moved to the trash
+	Status      string             `bson:"status" json:"status"`                                 // Status of the file (e.g., "uploaded", "processing", "failed")
 
 	TotalChunks int `bson:"total_chunks" json:"total_chunks"`
 
+	CurrentVersion int           `bson:"current_version,omitempty" json:"current_version,omitempty"` // The version returned by downloads, 0 before versioning
+	LatestVersion  int           `bson:"latest_version,omitempty" json:"latest_version,omitempty"`   // The last version number given to an upload session
+	Versions       []FileVersion `bson:"versions,omitempty" json:"versions,omitempty"`               // The uploaded versions, oldest first
+
 	CreatedAt time.Time `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
 
@@ -34,11 +44,87 @@ type File struct {
 	OwnerUsername string `bson:"owner_username,omitempty" json:"owner_username,omitempty"`
 }
 
+// FileVersion is an uploaded revision of a file
+// Its chunk records have the session_id of the upload session that created the version
+type FileVersion struct {
+	Version     int                `bson:"version" json:"version"`
+	SessionID   primitive.ObjectID `bson:"session_id,omitempty" json:"session_id,omitempty"`
+	Size        int64              `bson:"size" json:"size"`
+	TotalChunks int                `bson:"total_chunks" json:"total_chunks"`
+	UploadedBy  primitive.ObjectID `bson:"uploaded_by" json:"uploaded_by"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// GetVersions returns the uploaded versions of the file
+// A file uploaded before versioning has a single version built from the file record
+func (f *File) GetVersions() []FileVersion {
+	if len(f.Versions) > 0 {
+		return f.Versions
+	}
+	if f.Status != "uploaded" {
+		return []FileVersion{}
+	}
+
+	return []FileVersion{{
+		Version:     1,
+		Size:        f.Size,
+		TotalChunks: f.TotalChunks,
+		UploadedBy:  f.OwnerID,
+		CreatedAt:   f.CreatedAt,
+	}}
+}
+
+// GetVersion returns the given version of the file, 0 is the current version
+func (f *File) GetVersion(version int) *FileVersion {
+	if version == 0 {
+		version = max(f.CurrentVersion, 1)
+	}
+
+	for _, fileVersion := range f.GetVersions() {
+		if fileVersion.Version == version {
+			return &fileVersion
+		}
+	}
+
+	return nil
+}
+
+// FileSharedUser is a grant on a single file, it does not give access to the other files of the folder
+type FileSharedUser struct {
+	FileID primitive.ObjectID `bson:"file_id" json:"file_id"`
+	UserID primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Role   ShareRole          `bson:"role" json:"role"`                         // Any role but uploader
+	Hidden bool               `bson:"hidden,omitempty" json:"hidden,omitempty"` // Hidden from the shared with me listing of the user, the access is kept
+}
+
+// FileSharedGroup is a role given on a file to every member of a group
+type FileSharedGroup struct {
+	FileID  primitive.ObjectID `bson:"file_id" json:"file_id"`
+	GroupID primitive.ObjectID `bson:"group_id" json:"group_id"`
+	Role    ShareRole          `bson:"role" json:"role"` // Any role but uploader
+}
+
 type FileRepository interface {
-	UploadFileMetadata(ctx context.Context, file *File) (*File, error) // Upload file metadata
-	GetFileByID(ctx context.Context, id string) (*File, error)         // Get FilenewParentID metadata
+	UploadFileMetadata(ctx context.Context, file *File, conflict string) (*File, error) // Upload file metadata
+	GetFileByID(ctx context.Context, id string) (*File, error)                          // Get FilenewParentID metadata
 	DeleteFile(ctx context.Context, id string) error
-	RenameFile(ctx context.Context, id string, newName string) error
-	MoveFile(ctx context.Context, id string, newParentFolderID string) error
+	RenameFile(ctx context.Context, id string, newName string, conflict string) error
+	MoveFile(ctx context.Context, id string, newParentFolderID string, conflict string) error
 	SearchFiles(ctx context.Context, ownerId primitive.ObjectID, query string) ([]*File, error) // Search files by name or folder name
+	GetFileSharedUsers(ctx context.Context, fileID string) ([]*SharedUserResponse, error)
+	GetFileSharedUser(ctx context.Context, fileID string, userID string) (*FileSharedUser, error)
+	ShareFile(ctx context.Context, fileID, userID string, role ShareRole) error
+	RemoveFileShare(ctx context.Context, fileID, userID string) error
+	GetFileSharedGroups(ctx context.Context, fileID string) ([]*SharedGroupResponse, error)
+	GetFileGroupShares(ctx context.Context, fileID string, userID string) ([]*FileSharedGroup, error) // Get the grants of the groups of the user on the file
+	ShareFileWithGroup(ctx context.Context, fileID, groupID string, role ShareRole) error
+	RemoveFileGroupShare(ctx context.Context, fileID, groupID string) error
+}
+
+// FileVersionRepository manages the versions of the files
+type FileVersionRepository interface {
+	NextVersion(ctx context.Context, fileID primitive.ObjectID) (int, error)                                 // Reserve the number of a new version
+	RestoreVersion(ctx context.Context, fileID primitive.ObjectID, version int) (*File, error)               // Make a version the current one
+	GetVersionedFiles(ctx context.Context, afterID primitive.ObjectID, limit int64) ([]*File, error)         // Get the files with more than one version, ordered by ID
+	PruneVersions(ctx context.Context, fileID primitive.ObjectID, versions []int) ([]*ReleasedChunks, error) // Delete versions that are not current
 }
@@ -3,7 +3,8 @@ package models
 import "time"
 
 type CreateFolderRequest struct {
-	Name string `json:"name" binding:"required"`
+	Name     string `json:"name" binding:"required"`
+	Conflict string `json:"conflict"` // optional, "fail" (default) or "rename" when the name is already used, a folder is never replaced
 }
 
 type CreateFolderResponse struct {
@@ -21,7 +22,8 @@ type GetFolderContentsResponse struct {
 }
 
 type RenameFolderRequest struct {
-	NewName string `json:"new_name" binding:"required"`
+	NewName  string `json:"new_name" binding:"required"`
+	Conflict string `json:"conflict"` // optional, "fail" (default) or "rename" when the name is already used, a folder is never replaced
 }
 
 type RenameFolderResponse struct {
@@ -29,6 +31,7 @@ type RenameFolderResponse struct {
 
 type MoveFolderRequest struct {
 	NewParentID string `json:"new_parent_id" binding:"required"`
+	Conflict    string `json:"conflict"` // optional, "fail" (default) or "rename" when the name is already used, a folder is never replaced
 }
 
 type MoveFolderResponse struct {
@@ -42,6 +45,7 @@ type FileResponse struct {
 	OwnerEmail     string    `json:"owner_email" bson:"owner_email"`
 	Name           string    `json:"name" bson:"name"`
 	MimeType       string    `json:"mime_type" bson:"mime_type"`
+	OrganizationID string    `json:"organization_id,omitempty" bson:"organization_id,omitempty"` // The organization of the shared drive the file is in, empty in a personal drive
 	Size           int64     `json:"size" bson:"size"`
 	Status         string    `json:"status" bson:"status"`
 	CreatedAt      time.Time `json:"created_at" bson:"created_at"`
@@ -62,13 +66,15 @@ type FolderResponse struct {
 
 type UploadFileMetadataRequest struct {
 	FileName string `json:"file_name" binding:"required"`
-	FileSize int64  `json:"file_size" binding:"required"`
-	MimeType string `json:"mime_type"` // optional
+	FileSize int64  `json:"file_size" binding:"min=0"` // 0 for an empty file
+	MimeType string `json:"mime_type"`                 // optional
+	Conflict string `json:"conflict"`                  // optional, "fail" (default), "rename" or "replace" (new version of an uploaded file) when the name is already used
 }
 
 type UploadFileMetadataResponse struct {
-	File      FileResponse `json:"file"`
-	UploadURL string       `json:"upload_url" binding:"required"`
+	File         FileResponse `json:"file"`
+	UploadURL    string       `json:"upload_url" binding:"required"`
+	SessionToken string       `json:"session_token"` // Token of the upload session, also part of the upload URL
 }
 
 type UpdateFolderPublicRequest struct {
@@ -76,8 +82,18 @@ type UpdateFolderPublicRequest struct {
 }
 
 type ShareFolderRequest struct {
-	UserID     string `json:"user_id" binding:"required"`
-	Permission bool   `json:"permission"`
+	UserID     string    `json:"user_id" binding:"required"`
+	Role       ShareRole `json:"role" enums:"viewer,commenter,uploader,editor,co-owner"`
+	Permission bool      `json:"permission"` // Deprecated: used when no role is given, true for editor and false for viewer
+}
+
+// GetRole returns the requested role, the boolean permission is used by the clients not sending a role
+func (r *ShareFolderRequest) GetRole() ShareRole {
+	if r.Role != "" {
+		return r.Role
+	}
+
+	return RoleFromPermission(r.Permission)
 }
 
 type RevokeFolderShareRequest struct {
@@ -87,3 +103,9 @@ type RevokeFolderShareRequest struct {
 type RemoveFolderShareRequest struct {
 	UserID string `json:"user_id" binding:"required"`
 }
+
+type CopyFolderRequest struct {
+	NewParentID string `json:"new_parent_id" binding:"required"`
+	NewName     string `json:"new_name"` // optional, the copy keeps the name of the folder by default
+	Conflict    string `json:"conflict"` // optional, "fail" (default) or "rename" when the name is already used, a folder is never replaced
+}
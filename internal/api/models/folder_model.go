@@ -8,10 +8,14 @@ import (
 )
 
 const (
-	CollectionFolders           = "folders"
-	CollectionFolderSharedUsers = "folder_shared_users"
+	CollectionFolders            = "folders"
+	CollectionFolderSharedUsers  = "folder_shared_users"
+	CollectionFolderSharedGroups = "folder_shared_groups"
 )
 
+// FolderStat holds the totals of the subtree of a folder: its files, subfolders and the size of the files
+// Only the uploaded files are counted, with the size of their current version. A folder moved to the trash
+// keeps the content trashed with it in its statistics, but no longer counts in the statistics of its parent.
 type FolderStat struct {
 	TotalFiles   int   `bson:"total_files" json:"total_files"`
 	TotalFolders int   `bson:"total_folders" json:"total_folders"`
@@ -20,31 +24,44 @@ type FolderStat struct {
 
 // Folder struct encapsulates the folder model
 type Folder struct {
-	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	OwnerID        primitive.ObjectID `bson:"owner_id" json:"owner_id"`                                     // The owner of the folder
-	ParentFolderID primitive.ObjectID `bson:"parent_folder_id,omitempty" json:"parent_folder_id,omitempty"` // The parent folder ID, if any
-	Name           string             `bson:"name" json:"name"`
-	IsDeleted      bool               `bson:"is_deleted" json:"is_deleted"`
-	Stats          FolderStat         `bson:"stats" json:"stats"`
-	IsRoot         bool               `bson:"is_root" json:"is_root"` // Indicates if this is a root folder
-	IsPublic       bool               `bson:"is_public" json:"is_public"`
+	ID             primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	OwnerID        primitive.ObjectID   `bson:"owner_id" json:"owner_id"`                                     // The owner of the folder
+	OrganizationID *primitive.ObjectID  `bson:"organization_id,omitempty" json:"organization_id,omitempty"`   // The organization of the shared drive the folder is in, nil in a personal drive
+	ParentFolderID primitive.ObjectID   `bson:"parent_folder_id,omitempty" json:"parent_folder_id,omitempty"` // The parent folder ID, if any
+	AncestorIDs    []primitive.ObjectID `bson:"ancestor_ids" json:"ancestor_ids"`                             // The folders above this one, from the root folder to the parent
+	Name           string               `bson:"name" json:"name"`
+	IsDeleted      bool                 `bson:"is_deleted" json:"is_deleted"`
+	Stats          FolderStat           `bson:"stats" json:"stats"`
+	IsRoot         bool                 `bson:"is_root" json:"is_root"` // Indicates if this is a root folder
+	IsPublic       bool                 `bson:"is_public" json:"is_public"`
 
-	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time  `bson:"updated_at" json:"updated_at"`
-	DeletedAt *time.Time `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // Nullable field for soft delete
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	DeletedAt   *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`     // Nullable field for soft delete
+	TrashedWith primitive.ObjectID `bson:"trashed_with,omitempty" json:"trashed_with,omitempty"` // The deleted folder this folder was trashed with
+	DeletedBy   primitive.ObjectID `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`     // The user who moved the folder to the trash
+	TrashReason string             `bson:"trash_reason,omitempty" json:"trash_reason,omitempty"` // Why the folder was moved to the trash
 
 	OwnerEmail    string `bson:"owner_email,omitempty" json:"owner_email,omitempty"`
 	OwnerUsername string `bson:"owner_username,omitempty" json:"owner_username,omitempty"`
 }
 
 type FolderSharedUser struct {
-	FolderID   primitive.ObjectID `bson:"folder_id" json:"folder_id"`
-	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
-	Permission bool               `bson:"permission" json:"permission"` // "view" or "edit"
+	FolderID primitive.ObjectID `bson:"folder_id" json:"folder_id"`
+	UserID   primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Role     ShareRole          `bson:"role" json:"role"`
+	Hidden   bool               `bson:"hidden,omitempty" json:"hidden,omitempty"` // Hidden from the shared with me listing of the user, the access is kept
+}
+
+// FolderSharedGroup is a role given on a folder to every member of a group
+type FolderSharedGroup struct {
+	FolderID primitive.ObjectID `bson:"folder_id" json:"folder_id"`
+	GroupID  primitive.ObjectID `bson:"group_id" json:"group_id"`
+	Role     ShareRole          `bson:"role" json:"role"`
 }
 
 type FolderRepository interface {
-	CreateFolder(ctx context.Context, folder *Folder) (*Folder, error)
+	CreateFolder(ctx context.Context, folder *Folder, conflict string) (*Folder, error)
 	GetFolderByID(ctx context.Context, id string) (*Folder, error)
 	GetFolderParentIDByFolderID(ctx context.Context, folderID string) (string, error)
 	GetFolderListInFolder(ctx context.Context, folderID string) ([]*Folder, error)
@@ -52,16 +69,29 @@ type FolderRepository interface {
 	GetFileListInFolder(ctx context.Context, folderID string) ([]*File, error)
 	GetFileResponseListInFolder(ctx context.Context, folderID string) ([]*FileResponse, error)
 	DeleteFolder(ctx context.Context, id string) error
-	RenameFolder(ctx context.Context, id string, newName string) error
-	MoveFolder(ctx context.Context, id string, newParentID string) error
+	RenameFolder(ctx context.Context, id string, newName string, conflict string) error
+	MoveFolder(ctx context.Context, id string, newParentID string, conflict string) error
 	SearchFolders(ctx context.Context, ownerId primitive.ObjectID, query string) ([]*Folder, error)
 	UpdateFolderPublicStatus(ctx context.Context, folderID string, isPublic bool) error
 	UpdateFolderAndAllSubfoldersPublicStatus(ctx context.Context, folderID string, isPublic bool) error
 	GetFolderShareInfo(ctx context.Context, folderID string) (bool, error)
-	GetFolderSharedUsers(ctx context.Context, folderID string) ([]*FolderSharedUser, error)
+	GetFolderSharedUsers(ctx context.Context, folderID string) ([]*SharedUserResponse, error)
 	GetFolderSharedUser(ctx context.Context, folderID string, userID string) (*FolderSharedUser, error)
-	ShareFolder(ctx context.Context, folderID, userID string, permission bool) error
+	GetInheritedFolderShares(ctx context.Context, folder *Folder, userID string) ([]*FolderSharedUser, error) // Get the grants of the user on the folder and its ancestors
+	ShareFolder(ctx context.Context, folderID, userID string, role ShareRole) error
 	RemoveFolderShare(ctx context.Context, folderID, userID string) error
-	ShareFolderAndAllSubfolders(ctx context.Context, folderID, userID string, permission bool) error
+	ShareFolderAndAllSubfolders(ctx context.Context, folderID, userID string, role ShareRole) error
 	RevokeFolderAndAllSubfoldersShare(ctx context.Context, folderID, userID string) error
+	GetFolderSharedGroups(ctx context.Context, folderID string) ([]*SharedGroupResponse, error)
+	GetInheritedFolderGroupShares(ctx context.Context, folder *Folder, userID string) ([]*FolderSharedGroup, error) // Get the grants of the groups of the user on the folder and its ancestors
+	ShareFolderWithGroup(ctx context.Context, folderID, groupID string, role ShareRole) error
+	RemoveFolderGroupShare(ctx context.Context, folderID, groupID string) error
+	GetOrganizationFolderRole(ctx context.Context, folder *Folder, userID string) (ShareRole, error) // Get the role the organization of the folder's shared drive gives the user, empty for a non-member
+}
+
+// FolderStatsRepository recomputes the folder statistics from the files and folders
+// The statistics are maintained by the repositories changing the files and folders, the recomputation repairs them
+type FolderStatsRepository interface {
+	GetRootFolderIDs(ctx context.Context, afterID primitive.ObjectID, limit int64) ([]primitive.ObjectID, error) // Get the root folders, ordered by ID
+	RecomputeFolderStats(ctx context.Context, rootFolderID primitive.ObjectID) (int, error)                      // Recompute the statistics of a folder tree, returns the number of folders
 }
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CreateGroupRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+type UpdateGroupRequest struct {
+	Name        *string `json:"name"`        // optional
+	Description *string `json:"description"` // optional
+}
+
+type SetGroupMemberRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Role   string `json:"role" enums:"admin,member"` // optional, member by default
+}
+
+type ShareWithGroupRequest struct {
+	GroupID string    `json:"group_id" binding:"required"`
+	Role    ShareRole `json:"role" binding:"required" enums:"viewer,commenter,uploader,editor,co-owner"`
+}
+
+// GroupResponse is a group of the user with the role of the user in it
+type GroupResponse struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	Name        string             `bson:"name" json:"name"`
+	Description string             `bson:"description" json:"description"`
+	Role        string             `bson:"role" json:"role"` // The role of the user in the group
+	MemberCount int                `bson:"member_count" json:"member_count"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type GroupMemberResponse struct {
+	UserID   primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Username string             `bson:"username" json:"username"`
+	Email    string             `bson:"email" json:"email"`
+	Role     string             `bson:"role" json:"role"`
+	AddedAt  time.Time          `bson:"added_at" json:"added_at"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	CollectionGroups       = "groups"
+	CollectionGroupMembers = "group_members"
+)
+
+const (
+	GroupRoleAdmin  = "admin"  // Manages the group and its members
+	GroupRoleMember = "member" // Gets the roles shared with the group
+)
+
+var (
+	// ErrGroupNotFound is returned when the group does not exist or the user is not a member of it
+	ErrGroupNotFound = errors.New("group not found")
+	// ErrGroupMemberNotFound is returned when the user is not a member of the group
+	ErrGroupMemberNotFound = errors.New("group member not found")
+	// ErrGroupNotAdmin is returned when a member who is not an admin manages the group
+	ErrGroupNotAdmin = errors.New("permission denied: only the group admins can manage the group")
+	// ErrGroupLastAdmin is returned when the last admin of a group would be removed or demoted
+	ErrGroupLastAdmin = errors.New("cannot remove the last admin of the group")
+	// ErrInvalidGroupRole is returned when a member is given an unknown group role
+	ErrInvalidGroupRole = errors.New("invalid group role")
+)
+
+// IsValidGroupRole checks if the group role is known
+func IsValidGroupRole(role string) bool {
+	return role == GroupRoleAdmin || role == GroupRoleMember
+}
+
+// Group is a set of users the files and folders can be shared with at once
+// The members get the roles shared with the group while they are members
+type Group struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name        string             `bson:"name" json:"name"`
+	Description string             `bson:"description" json:"description"`
+	CreatedBy   primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// GroupMember is the membership of a user in a group
+type GroupMember struct {
+	GroupID primitive.ObjectID `bson:"group_id" json:"group_id"`
+	UserID  primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Role    string             `bson:"role" json:"role"` // GroupRoleAdmin or GroupRoleMember
+	AddedAt time.Time          `bson:"added_at" json:"added_at"`
+}
+
+// GroupRepository manages the groups and their members
+type GroupRepository interface {
+	CreateGroup(ctx context.Context, group *Group) (*Group, error) // The creator is the first admin
+	GetGroupByID(ctx context.Context, id primitive.ObjectID) (*Group, error)
+	GetGroupsByUser(ctx context.Context, userID primitive.ObjectID) ([]*GroupResponse, error) // Get the groups the user is a member of
+	UpdateGroup(ctx context.Context, group *Group) error
+	DeleteGroup(ctx context.Context, id primitive.ObjectID) error // Delete the group, its members and its grants
+	GetGroupMember(ctx context.Context, groupID primitive.ObjectID, userID primitive.ObjectID) (*GroupMember, error)
+	GetGroupMembers(ctx context.Context, groupID primitive.ObjectID) ([]*GroupMemberResponse, error)
+	SetGroupMember(ctx context.Context, member *GroupMember) error                                      // Add a member or change the role of a member
+	RemoveGroupMember(ctx context.Context, groupID primitive.ObjectID, userID primitive.ObjectID) error // The grants of the group no longer apply to the user
+}
@@ -0,0 +1,16 @@
+package models
+
+type ShareByEmailRequest struct {
+	Email string    `json:"email" binding:"required"`
+	Role  ShareRole `json:"role" binding:"required"` // "viewer", "commenter", "uploader" (folders only), "editor" or "co-owner"
+}
+
+type ShareByEmailResponse struct {
+	Status     string           `json:"status"`               // "shared" when the email has an account, "invited" otherwise
+	UserID     string           `json:"user_id,omitempty"`    // The user the item is shared with
+	Invitation *ShareInvitation `json:"invitation,omitempty"` // The invitation sent to the email
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"` // The token of the invitation link sent to the email of the user
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	CollectionShareInvitations = "share_invitations"
+)
+
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+)
+
+// ErrInvitationNotFound is returned when the invitation does not exist or is no longer pending
+var ErrInvitationNotFound = errors.New("invitation not found")
+
+// ShareInvitation shares a file or a folder with an email that has no account yet
+// It becomes a grant of the user of the email who gives the secret token of the emailed link, while it is not expired
+type ShareInvitation struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Email     string             `bson:"email" json:"email"`         // Lowercase
+	ItemType  string             `bson:"item_type" json:"item_type"` // TrashItemFile or TrashItemFolder
+	ItemID    primitive.ObjectID `bson:"item_id" json:"item_id"`
+	Role      ShareRole          `bson:"role" json:"role"`
+	InvitedBy primitive.ObjectID `bson:"invited_by" json:"invited_by"`
+	Status    string             `bson:"status" json:"status"` // InvitationPending or InvitationAccepted
+	TokenHash string             `bson:"token_hash" json:"-"`  // SHA-256 hash of the token sent in the email, hex encoded
+
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt  time.Time          `bson:"expires_at" json:"expires_at"`
+	AcceptedAt *time.Time         `bson:"accepted_at,omitempty" json:"accepted_at,omitempty"`
+	AcceptedBy primitive.ObjectID `bson:"accepted_by,omitempty" json:"accepted_by,omitempty"` // The registered user
+}
+
+// InvitationRepository manages the share invitations
+type InvitationRepository interface {
+	CreateInvitation(ctx context.Context, invitation *ShareInvitation) (*ShareInvitation, error) // Replaces the pending invitation of the email on the item, if any
+	GetInvitationByID(ctx context.Context, id primitive.ObjectID) (*ShareInvitation, error)
+	GetPendingInvitationsByItem(ctx context.Context, itemType string, itemID primitive.ObjectID) ([]*ShareInvitation, error)
+	DeleteInvitation(ctx context.Context, id primitive.ObjectID) error
+	AcceptInvitations(ctx context.Context, email string, tokenHash string, userID primitive.ObjectID, now time.Time) ([]*ShareInvitation, error) // Turn the pending invitations of the email into grants of the user
+}
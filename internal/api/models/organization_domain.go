@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type UpdateOrganizationRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type SetOrganizationMemberRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Role   string `json:"role" enums:"admin,member,viewer"` // optional, member by default
+}
+
+type CreateSharedDriveRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// OrganizationResponse is an organization of the user with the role of the user in it
+type OrganizationResponse struct {
+	ID           primitive.ObjectID `bson:"_id" json:"id"`
+	Name         string             `bson:"name" json:"name"`
+	Role         string             `bson:"role" json:"role"` // The role of the user in the organization
+	MemberCount  int                `bson:"member_count" json:"member_count"`
+	StorageQuota int64              `bson:"storage_quota" json:"storage_quota"` // The storage quota in bytes, 0 is unlimited
+	StorageUsed  int64              `bson:"storage_used" json:"storage_used"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type OrganizationMemberResponse struct {
+	UserID   primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Username string             `bson:"username" json:"username"`
+	Email    string             `bson:"email" json:"email"`
+	Role     string             `bson:"role" json:"role"`
+	AddedAt  time.Time          `bson:"added_at" json:"added_at"`
+}
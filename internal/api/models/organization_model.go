@@ -0,0 +1,108 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	CollectionOrganizations       = "organizations"
+	CollectionOrganizationMembers = "organization_members"
+)
+
+const (
+	OrganizationRoleAdmin  = "admin"  // Manages the organization, its members and its shared drives
+	OrganizationRoleMember = "member" // Changes the content of the shared drives
+	OrganizationRoleViewer = "viewer" // Views the content of the shared drives
+)
+
+// organizationShareRoles holds the role every organization role gives on the shared drives
+var organizationShareRoles = map[string]ShareRole{
+	OrganizationRoleAdmin:  RoleCoOwner,
+	OrganizationRoleMember: RoleEditor,
+	OrganizationRoleViewer: RoleViewer,
+}
+
+var (
+	// ErrOrganizationNotFound is returned when the organization does not exist or the user is not a member of it
+	ErrOrganizationNotFound = errors.New("organization not found")
+	// ErrOrganizationMemberNotFound is returned when the user is not a member of the organization
+	ErrOrganizationMemberNotFound = errors.New("organization member not found")
+	// ErrOrganizationNotAdmin is returned when a member who is not an admin manages the organization
+	ErrOrganizationNotAdmin = errors.New("permission denied: only the organization admins can manage the organization")
+	// ErrOrganizationLastAdmin is returned when the last admin of an organization would be removed or demoted
+	ErrOrganizationLastAdmin = errors.New("cannot remove the last admin of the organization")
+	// ErrInvalidOrganizationRole is returned when a member is given an unknown organization role
+	ErrInvalidOrganizationRole = errors.New("invalid organization role")
+	// ErrOrganizationStorageQuotaExceeded is returned when an upload to a shared drive does not fit in the storage quota of the organization
+	ErrOrganizationStorageQuotaExceeded = errors.New("organization storage quota exceeded")
+	// ErrCrossTenantMove is returned when an item would be moved between a shared drive and a personal folder or another organization
+	ErrCrossTenantMove = errors.New("cannot move an item to another organization")
+)
+
+// IsValidOrganizationRole checks if the organization role is known
+func IsValidOrganizationRole(role string) bool {
+	_, ok := organizationShareRoles[role]
+	return ok
+}
+
+// Organization is a workspace shared by its members
+// Its shared drives are root folders of their own, their files use the storage quota of the organization
+type Organization struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name         string             `bson:"name" json:"name"`
+	StorageQuota int64              `bson:"storage_quota,omitempty" json:"storage_quota,omitempty"` // The storage quota in bytes, 0 uses the default organization quota and a negative quota is unlimited
+	StorageUsed  int64              `bson:"storage_used" json:"storage_used"`                       // The bytes used by every version of the files of the shared drives, trashed files included
+	CreatedBy    primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// GetStorageQuota returns the storage quota of the organization in bytes, 0 means unlimited
+// Organizations without a quota of their own get the default organization quota
+func (o *Organization) GetStorageQuota(defaultQuota int64) int64 {
+	if o.StorageQuota < 0 {
+		return 0
+	}
+	if o.StorageQuota == 0 {
+		return max(defaultQuota, 0)
+	}
+
+	return o.StorageQuota
+}
+
+// HasStorageFor checks if the organization can store size more bytes without exceeding the quota
+func (o *Organization) HasStorageFor(size int64, defaultQuota int64) bool {
+	quota := o.GetStorageQuota(defaultQuota)
+	return quota == 0 || o.StorageUsed+size <= quota
+}
+
+// OrganizationMember is the membership of a user in an organization
+type OrganizationMember struct {
+	OrganizationID primitive.ObjectID `bson:"organization_id" json:"organization_id"`
+	UserID         primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Role           string             `bson:"role" json:"role"` // OrganizationRoleAdmin, OrganizationRoleMember or OrganizationRoleViewer
+	AddedAt        time.Time          `bson:"added_at" json:"added_at"`
+}
+
+// ShareRole returns the role the membership gives on the shared drives of the organization
+func (m *OrganizationMember) ShareRole() ShareRole {
+	return organizationShareRoles[m.Role]
+}
+
+// OrganizationRepository manages the organizations, their members and their shared drives
+type OrganizationRepository interface {
+	CreateOrganization(ctx context.Context, organization *Organization) (*Organization, error) // The creator is the first admin
+	GetOrganizationByID(ctx context.Context, id primitive.ObjectID) (*Organization, error)
+	GetOrganizationsByUser(ctx context.Context, userID primitive.ObjectID) ([]*OrganizationResponse, error) // Get the organizations the user is a member of
+	UpdateOrganization(ctx context.Context, organization *Organization) error
+	GetOrganizationMember(ctx context.Context, organizationID primitive.ObjectID, userID primitive.ObjectID) (*OrganizationMember, error)
+	GetOrganizationMembers(ctx context.Context, organizationID primitive.ObjectID) ([]*OrganizationMemberResponse, error)
+	SetOrganizationMember(ctx context.Context, member *OrganizationMember) error                                      // Add a member or change the role of a member
+	RemoveOrganizationMember(ctx context.Context, organizationID primitive.ObjectID, userID primitive.ObjectID) error // The member loses the access to the shared drives
+	CreateSharedDrive(ctx context.Context, drive *Folder) (*Folder, error)                                            // Create the root folder of a shared drive
+	GetSharedDrives(ctx context.Context, organizationID primitive.ObjectID) ([]*Folder, error)
+}
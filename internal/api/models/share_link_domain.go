@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type CreateShareLinkRequest struct {
+	Password      string     `json:"password"`       // optional, the visitors must send it in the X-Share-Password header
+	ExpiresAt     *time.Time `json:"expires_at"`     // optional, the link never expires without it
+	MaxDownloads  int        `json:"max_downloads"`  // optional, 0 (default) for unlimited downloads
+	AllowDownload *bool      `json:"allow_download"` // optional, true by default
+}
+
+type UpdateShareLinkRequest struct {
+	Password      *string    `json:"password"`       // optional, an empty password removes it
+	ExpiresAt     *time.Time `json:"expires_at"`     // optional
+	RemoveExpiry  bool       `json:"remove_expiry"`  // optional, the link no longer expires
+	MaxDownloads  *int       `json:"max_downloads"`  // optional, 0 for unlimited downloads
+	AllowDownload *bool      `json:"allow_download"` // optional
+}
+
+type ShareLinkResponse struct {
+	ItemType      string     `json:"item_type"`
+	AllowDownload bool       `json:"allow_download"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+
+	File     *FileResponse              `json:"file,omitempty"`     // The linked file
+	Folder   *FolderResponse            `json:"folder,omitempty"`   // The linked folder or the listed subfolder
+	Contents *GetFolderContentsResponse `json:"contents,omitempty"` // The content of the folder
+}
+
+type ShareLinkDownloadResponse struct {
+	DownloadURL string `json:"download_url"` // Block server URL, valid for one hour
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	CollectionShareLinks = "share_links"
+)
+
+// The messages of the share link errors are mapped to the status codes by the global error middleware
+var (
+	ErrShareLinkNotFound       = errors.New("share link not found")
+	ErrShareLinkExpired        = errors.New("share link expired")
+	ErrShareLinkPassword       = errors.New("unauthorized: the share link password is missing or wrong")
+	ErrShareLinkNoDownload     = errors.New("permission denied: the share link does not allow downloads")
+	ErrShareLinkDownloadsLimit = errors.New("permission denied: the share link download limit is reached")
+)
+
+// ShareLink gives access to a file or a folder to anyone knowing its token, without an account
+// The link of a folder gives access to its subfolders and their files
+type ShareLink struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Token     string             `bson:"token" json:"token"`         // The unguessable part of the /s/:token URL
+	ItemType  string             `bson:"item_type" json:"item_type"` // TrashItemFile or TrashItemFolder
+	ItemID    primitive.ObjectID `bson:"item_id" json:"item_id"`
+	CreatedBy primitive.ObjectID `bson:"created_by" json:"created_by"` // The owner or a user allowed to share the item
+
+	PasswordHash  string     `bson:"password_hash,omitempty" json:"-"`
+	HasPassword   bool       `bson:"has_password" json:"has_password"`
+	ExpiresAt     *time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"` // Nullable, the link never expires without it
+	MaxDownloads  int        `bson:"max_downloads" json:"max_downloads"`               // 0 for unlimited downloads
+	DownloadCount int        `bson:"download_count" json:"download_count"`
+	AllowDownload bool       `bson:"allow_download" json:"allow_download"` // The content can only be listed without it
+
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// IsExpired checks if the link is expired at the given time
+func (l *ShareLink) IsExpired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+// ShareLinkRepository manages the share links and reads the items they give access to
+// The items are read without the user of the request, the links are used anonymously
+type ShareLinkRepository interface {
+	CreateShareLink(ctx context.Context, link *ShareLink) (*ShareLink, error)
+	GetShareLinkByID(ctx context.Context, id primitive.ObjectID) (*ShareLink, error)
+	GetShareLinkByToken(ctx context.Context, token string) (*ShareLink, error)
+	GetShareLinksByItem(ctx context.Context, itemType string, itemID primitive.ObjectID) ([]*ShareLink, error)
+	UpdateShareLink(ctx context.Context, link *ShareLink) error
+	DeleteShareLink(ctx context.Context, id primitive.ObjectID) error
+	RecordDownload(ctx context.Context, id primitive.ObjectID) error // Counts a download, fails once the limit is reached
+
+	GetLinkedFolder(ctx context.Context, link *ShareLink, folderID primitive.ObjectID) (*Folder, error) // The linked folder or one of its subfolders
+	GetLinkedFile(ctx context.Context, link *ShareLink, fileID primitive.ObjectID) (*File, error)       // The linked file or a file under the linked folder
+	GetLinkedFolderContents(ctx context.Context, folderID primitive.ObjectID) (*GetFolderContentsResponse, error)
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"errors"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShareRole is the role given to a user by a folder or file grant
+type ShareRole string
+
+const (
+	RoleViewer    ShareRole = "viewer"    // View the content
+	RoleCommenter ShareRole = "commenter" // View and comment the content
+	RoleUploader  ShareRole = "uploader"  // Add files without seeing the other files, like a drop box
+	RoleEditor    ShareRole = "editor"    // Change the content
+	RoleCoOwner   ShareRole = "co-owner"  // Everything the owner can do, including managing the shares
+)
+
+// Permissions checked by the permission middlewares
+const (
+	PermissionView    = "view"
+	PermissionComment = "comment"
+	PermissionUpload  = "upload"
+	PermissionEdit    = "edit"
+	PermissionShare   = "share"
+)
+
+// rolePermissions holds the permissions given by every role
+var rolePermissions = map[ShareRole][]string{
+	RoleViewer:    {PermissionView},
+	RoleCommenter: {PermissionView, PermissionComment},
+	RoleUploader:  {PermissionUpload},
+	RoleEditor:    {PermissionView, PermissionComment, PermissionUpload, PermissionEdit},
+	RoleCoOwner:   {PermissionView, PermissionComment, PermissionUpload, PermissionEdit, PermissionShare},
+}
+
+// ErrInvalidShareRole is returned when a grant is given an unknown role
+var ErrInvalidShareRole = errors.New("invalid share role")
+
+// IsValid checks if the role is known
+func (r ShareRole) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// IsValidForFile checks if the role can be given on a single file, a file cannot be used as a drop box
+func (r ShareRole) IsValidForFile() bool {
+	return r.IsValid() && r != RoleUploader
+}
+
+// Permissions returns the permissions given by the role
+func (r ShareRole) Permissions() []string {
+	permissions := rolePermissions[r]
+	if permissions == nil {
+		return []string{}
+	}
+
+	return permissions
+}
+
+// IncludedRoles returns the roles whose permissions are all given by the role, the role included
+// A grant of an included role adds nothing under a grant of the role
+func (r ShareRole) IncludedRoles() []ShareRole {
+	included := []ShareRole{}
+	for role, permissions := range rolePermissions {
+		if slices.ContainsFunc(permissions, func(permission string) bool { return !r.Allows(permission) }) {
+			continue
+		}
+		included = append(included, role)
+	}
+
+	return included
+}
+
+// Allows checks if the role gives the permission
+func (r ShareRole) Allows(permission string) bool {
+	for _, allowed := range rolePermissions[r] {
+		if allowed == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RoleFromPermission returns the role matching the boolean permission used before the roles
+func RoleFromPermission(edit bool) ShareRole {
+	if edit {
+		return RoleEditor
+	}
+
+	return RoleViewer
+}
+
+// SharedUserResponse is a grant of a folder or a file with the user it is given to
+type SharedUserResponse struct {
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Username    string             `bson:"username" json:"username"`
+	Email       string             `bson:"email" json:"email"`
+	Role        ShareRole          `bson:"role" json:"role"`
+	Permissions []string           `bson:"-" json:"permissions"` // The permissions given by the role
+
+	InheritedFrom *primitive.ObjectID `bson:"inherited_from,omitempty" json:"inherited_from,omitempty"` // The ancestor folder the grant is given on, if not the folder itself
+}
+
+// SharedGroupResponse is a grant of a folder or a file with the group it is given to
+type SharedGroupResponse struct {
+	GroupID     primitive.ObjectID `bson:"group_id" json:"group_id"`
+	Name        string             `bson:"name" json:"name"`
+	Role        ShareRole          `bson:"role" json:"role"`
+	Permissions []string           `bson:"-" json:"permissions"` // The permissions given by the role
+
+	InheritedFrom *primitive.ObjectID `bson:"inherited_from,omitempty" json:"inherited_from,omitempty"` // The ancestor folder the grant is given on, if not the folder itself
+}
@@ -0,0 +1,12 @@
+package models
+
+type SharedItemsResponse struct {
+	Items    []*SharedItem `json:"items"`
+	Page     int           `json:"page"`
+	PageSize int           `json:"page_size"`
+	Total    int64         `json:"total"` // The number of items on all the pages
+}
+
+type HideShareRequest struct {
+	Hidden bool `json:"hidden"` // false shows the item again
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// ErrShareNotFound is returned when the user has no grant on the item
+var ErrShareNotFound = errors.New("share not found")
+
+// Pagination selects a page of a listing, the first page is 1
+type Pagination struct {
+	Page     int `json:"page"`
+	PageSize int `json:"page_size"`
+}
+
+// Normalize returns the pagination with the first page and the default size when they are missing,
+// the size is capped at MaxPageSize
+func (p Pagination) Normalize() Pagination {
+	if p.Page < 1 {
+		p.Page = 1
+	}
+	if p.PageSize < 1 {
+		p.PageSize = DefaultPageSize
+	}
+
+	p.PageSize = min(p.PageSize, MaxPageSize)
+	return p
+}
+
+// Skip returns the number of items before the page
+func (p Pagination) Skip() int64 {
+	return int64(p.Page-1) * int64(p.PageSize)
+}
+
+// SharedItem is a folder or a file of the shared with me and shared by me listings
+type SharedItem struct {
+	ItemType string `bson:"item_type" json:"item_type"` // TrashItemFile or TrashItemFolder
+
+	Role   ShareRole `bson:"role,omitempty" json:"role,omitempty"` // The role given to the user, in the shared with me listing
+	Hidden bool      `bson:"hidden" json:"hidden"`                 // Hidden from the shared with me listing
+
+	SharedWith []*SharedUserResponse `bson:"shared_with,omitempty" json:"shared_with,omitempty"` // The users the item is shared with, in the shared by me listing
+
+	Folder *FolderResponse `bson:"folder,omitempty" json:"folder,omitempty"`
+	File   *FileResponse   `bson:"file,omitempty" json:"file,omitempty"`
+}
+
+// SharedItemRepository lists the items shared between the users from the folder and file grants
+type SharedItemRepository interface {
+	GetItemsSharedWithUser(ctx context.Context, userID primitive.ObjectID, includeHidden bool, pagination Pagination) ([]*SharedItem, int64, error) // Get a page of the items shared with the user and the total
+	GetItemsSharedByUser(ctx context.Context, ownerID primitive.ObjectID, pagination Pagination) ([]*SharedItem, int64, error)                      // Get a page of the items of the user shared with others and the total
+	SetShareHidden(ctx context.Context, userID primitive.ObjectID, itemType string, itemID primitive.ObjectID, hidden bool) error
+}
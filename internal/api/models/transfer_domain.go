@@ -0,0 +1,7 @@
+package models
+
+type TransferOwnershipRequest struct {
+	ItemType string `json:"item_type" binding:"required" enums:"file,folder"`
+	ItemID   string `json:"item_id" binding:"required"`
+	UserID   string `json:"user_id" binding:"required"` // The new owner
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	CollectionOwnershipTransfers = "ownership_transfers"
+)
+
+const (
+	TransferPending   = "pending"
+	TransferAccepted  = "accepted"
+	TransferDeclined  = "declined"
+	TransferCancelled = "cancelled"
+)
+
+var (
+	// ErrTransferNotFound is returned when the transfer does not exist, is no longer pending or is not sent to the user
+	ErrTransferNotFound = errors.New("ownership transfer not found")
+	// ErrTransferNotOwner is returned when a user who is not the owner asks to transfer an item, the co-owners included
+	ErrTransferNotOwner = errors.New("permission denied: only the owner can transfer the ownership")
+	// ErrTransferOrganizationItem is returned when the item is in a shared drive, it belongs to the organization
+	ErrTransferOrganizationItem = errors.New("cannot transfer an item of a shared drive")
+)
+
+// OwnershipTransfer is a request to give a file or a folder to another user
+// The item, its subfolders and their files change owner when the recipient accepts it
+type OwnershipTransfer struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ItemType   string             `bson:"item_type" json:"item_type"` // TrashItemFile or TrashItemFolder
+	ItemID     primitive.ObjectID `bson:"item_id" json:"item_id"`
+	ItemName   string             `bson:"item_name" json:"item_name"` // The name of the item when the transfer was requested
+	FromUserID primitive.ObjectID `bson:"from_user_id" json:"from_user_id"`
+	ToUserID   primitive.ObjectID `bson:"to_user_id" json:"to_user_id"`
+	Status     string             `bson:"status" json:"status"`
+
+	CreatedAt   time.Time  `bson:"created_at" json:"created_at"`
+	RespondedAt *time.Time `bson:"responded_at,omitempty" json:"responded_at,omitempty"` // When the transfer was accepted, declined or cancelled
+}
+
+// LegacyChunks lists the chunk objects of a file stored under the key prefix of its owner
+// The chunks uploaded before the content-addressed blocks are keyed by owner and must follow the file
+type LegacyChunks struct {
+	FileID       string `json:"file_id"`
+	ChunkIndexes []int  `json:"chunk_indexes"`
+}
+
+// TransferContent describes what an ownership transfer moves to the recipient
+type TransferContent struct {
+	StorageSize  int64           `json:"storage_size"` // The storage used by the files, every version and the trashed files included
+	LegacyChunks []*LegacyChunks `json:"legacy_chunks"`
+}
+
+// OwnershipTransferRepository manages the ownership transfers
+type OwnershipTransferRepository interface {
+	CreateTransfer(ctx context.Context, transfer *OwnershipTransfer) (*OwnershipTransfer, error) // Replaces the pending transfer of the item, if any
+	GetTransferByID(ctx context.Context, id primitive.ObjectID) (*OwnershipTransfer, error)
+	GetPendingTransfers(ctx context.Context, userID primitive.ObjectID) ([]*OwnershipTransfer, error) // Get the pending transfers sent or received by the user
+	CloseTransfer(ctx context.Context, id primitive.ObjectID, status string, now time.Time) error     // Decline or cancel a pending transfer
+	GetTransferContent(ctx context.Context, transfer *OwnershipTransfer) (*TransferContent, error)
+	AcceptTransfer(ctx context.Context, transfer *OwnershipTransfer, now time.Time) (*OwnershipTransfer, error) // Give the item to the recipient
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TrashItem is a trashed file or folder
+type TrashItem struct {
+	ID             primitive.ObjectID `json:"id"`
+	Type           string             `json:"type"` // "file" or "folder"
+	Name           string             `json:"name"`
+	OwnerID        primitive.ObjectID `json:"owner_id"`
+	ParentFolderID primitive.ObjectID `json:"parent_folder_id"`
+	MimeType       string             `json:"mime_type,omitempty"`
+	Size           int64              `json:"size"`
+	DeletedAt      *time.Time         `json:"deleted_at,omitempty"`
+	DeletedBy      primitive.ObjectID `json:"deleted_by,omitempty"`
+	TrashReason    string             `json:"trash_reason,omitempty"` // "deleted" or "replaced"
+}
+
+// TrashListResponse is the content of the trash of the user
+type TrashListResponse struct {
+	Items []*TrashItem `json:"items"`
+	Total int          `json:"total"`
+}
+
+// PurgeTrashResponse summarizes a permanent deletion
+type PurgeTrashResponse struct {
+	PurgedFiles    int `json:"purged_files"`
+	ReleasedBlocks int `json:"released_blocks"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	TrashItemFile   = "file"
+	TrashItemFolder = "folder"
+)
+
+// Reasons recorded with the trashed items
+const (
+	TrashReasonDeleted  = "deleted"  // Deleted by a user
+	TrashReasonReplaced = "replaced" // Replaced by an item with the same name
+)
+
+// ErrTrashItemNotFound is returned when the item is not in the trash of the user
+var ErrTrashItemNotFound = errors.New("trash item not found")
+
+// ReleasedChunks lists the storage objects of a purged file
+// The legacy chunk objects must be deleted from the block store, the released blocks are deleted later by the block collector
+type ReleasedChunks struct {
+	OwnerID        string   `json:"owner_id"`
+	FileID         string   `json:"file_id"`
+	ChunkIndexes   []int    `json:"chunk_indexes"`   // Indexes of the legacy chunk objects
+	ReleasedBlocks []string `json:"released_blocks"` // Hashes of the blocks that are no longer referenced
+}
+
+// TrashRepository manages the soft-deleted files and folders
+// The trash is not a collection, the items are the file and folder records with is_deleted set
+type TrashRepository interface {
+	GetTrashItems(ctx context.Context, ownerID primitive.ObjectID) ([]*TrashItem, error)
+	RestoreItem(ctx context.Context, ownerID primitive.ObjectID, itemType string, itemID primitive.ObjectID) (*TrashItem, error)
+	PurgeItem(ctx context.Context, ownerID primitive.ObjectID, itemType string, itemID primitive.ObjectID) ([]*ReleasedChunks, error)
+	EmptyTrash(ctx context.Context, ownerID primitive.ObjectID) ([]*ReleasedChunks, error)
+	GetExpiredTrashItems(ctx context.Context, deletedBefore time.Time, limit int64) ([]*TrashItem, error)
+}
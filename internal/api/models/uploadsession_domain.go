@@ -1,9 +1,35 @@
 package models
 
+import "strconv"
+
+// ChunkClaim returns the values of the claim signed by the block server once it stored or verified the data of a chunk
+// The API server only records the chunks of such a claim, so a client cannot reference a block it does not have
+func ChunkClaim(userID string, fileID string, chunkNumber int, chunkSize int, chunkHash string) map[string]string {
+	return map[string]string{
+		"action":      "add-chunk",
+		"userId":      userID,
+		"fileId":      fileID,
+		"chunkNumber": strconv.Itoa(chunkNumber),
+		"chunkSize":   strconv.Itoa(chunkSize),
+		"chunkHash":   chunkHash,
+	}
+}
+
+// CompletionClaim returns the values of the claim signed by the block server once it verified the chunk data of a session
+// The API server only completes the sessions of such a claim, so a client cannot skip the verification of the hashes
+func CompletionClaim(userID string, sessionToken string) map[string]string {
+	return map[string]string{
+		"action":       "complete-session",
+		"userId":       userID,
+		"sessionToken": sessionToken,
+	}
+}
+
 type AddChunkRequest struct {
 	ChunkNumber int    `json:"chunk_number"` // The number of the chunk being uploaded
 	ChunkSize   int    `json:"chunk_size"`   // The size of the chunk being uploaded
 	ChunkHash   string `json:"chunk_hash"`   // The hash of the chunk being uploaded
+	ChunkClaim  string `json:"chunk_claim"`  // Signed by the block server once the chunk data is stored, see ChunkClaim
 }
 
 type AddChunkResponse struct {
@@ -13,7 +39,18 @@ type AddChunkViaFileIDRequest struct {
 	ChunkNumber int    `json:"chunk_number"` // The number of the chunk being uploaded
 	ChunkSize   int    `json:"chunk_size"`   // The size of the chunk being uploaded
 	ChunkHash   string `json:"chunk_hash"`   // The hash of the chunk being uploaded
+	ChunkClaim  string `json:"chunk_claim"`  // Signed by the block server once the chunk data is stored, see ChunkClaim
 }
 
 type AddChunkViaFileIDResponse struct {
 }
+
+type CompleteUploadSessionRequest struct {
+	CompletionClaim string `json:"completion_claim"` // Signed by the block server once the chunk hashes are verified, see CompletionClaim
+}
+
+type CancelUploadSessionResponse struct {
+	FileID         string   `json:"file_id"`         // The ID of the file of the cancelled session
+	ChunkIndexes   []int    `json:"chunk_indexes"`   // The indexes of the chunks removed from the session
+	ReleasedBlocks []string `json:"released_blocks"` // The hashes of the blocks that are no longer referenced, deleted later by the block collector
+}
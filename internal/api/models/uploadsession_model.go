@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -18,7 +19,30 @@ type UploadSession struct {
 	TotalSize    int64              `bson:"total_size" json:"total_size"`       // Total size of the file to be uploaded
 	ActualSize   int64              `bson:"actual_size" json:"actual_size"`     // Actual size of the uploaded file
 	ChunkList    []int              `bson:"chunk_list" json:"chunk_list"`       // List of chunks that have been uploaded
-	Status       string             `bson:"status" json:"status"`               // Status of the upload session (e.g., "pending", "completed", "failed")
+	Status       string             `bson:"status" json:"status"`               // Status of the upload session (e.g., "pending", "completed", "cancelled", "expired")
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+	ExpiresAt    time.Time          `bson:"expires_at" json:"expires_at"`               // The session is reaped when still pending after this time
+	Version      int                `bson:"version,omitempty" json:"version,omitempty"` // The file version uploaded by the session, 0 for sessions created before versioning
+
+	OrganizationID *primitive.ObjectID `bson:"organization_id,omitempty" json:"organization_id,omitempty"` // The organization whose storage quota the upload uses, nil for the quota of the user
+	OwnerID        primitive.ObjectID  `bson:"owner_id,omitempty" json:"owner_id,omitempty"`               // The owner of the file, whose storage quota the upload uses outside of a shared drive
+}
+
+// QuotaUserID returns the user whose storage quota the upload uses outside of a shared drive
+// Sessions created before the owner was recorded use the quota of the uploader
+func (s *UploadSession) QuotaUserID() primitive.ObjectID {
+	if s.OwnerID.IsZero() {
+		return s.UserID
+	}
+
+	return s.OwnerID
+}
+
+// IsExpired checks if the session can no longer receive chunks
+// Sessions created before the expiry was introduced have no ExpiresAt and are left to the reaper
+func (s *UploadSession) IsExpired(now time.Time) bool {
+	return s.Status == "expired" || (!s.ExpiresAt.IsZero() && now.After(s.ExpiresAt))
 }
 
 type UploadSessionRepository interface {
@@ -28,4 +52,10 @@ type UploadSessionRepository interface {
 	GetSessionRecordByUserID(ctx context.Context, userID string) (*[]UploadSession, error)
 	AddChunkSessionRecord(ctx context.Context, sessionToken string, chunkNumber int, chunkSize int, chunkHash string) error
 	AddChunkSessionRecordByFileID(ctx context.Context, fileID string, chunkNumber int, chunkSize int, chunkHash string) error
+	CompleteSessionRecord(ctx context.Context, sessionToken string) (*UploadSession, error)
+	CancelSessionRecord(ctx context.Context, sessionToken string) (*CancelUploadSessionResponse, error)
+	ExtendSessionRecord(ctx context.Context, sessionToken string, expiresAt time.Time) (*UploadSession, error)
+	GetExpiredSessionRecords(ctx context.Context, now time.Time, legacyCreatedBefore time.Time, limit int64) ([]UploadSession, error)
+	ExpireSessionRecord(ctx context.Context, sessionToken string, now time.Time) (*CancelUploadSessionResponse, error)
+	GetReservedStorage(ctx context.Context, userID primitive.ObjectID, organizationID *primitive.ObjectID, excludeSessionID primitive.ObjectID, now time.Time) (int64, error)
 }
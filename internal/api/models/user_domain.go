@@ -18,3 +18,16 @@ type UserIDListRequest struct {
 type UserEmailListRequest struct {
 	Emails []string `json:"emails" binding:"required"`
 }
+
+// MimeTypeUsage is the storage used by the files of a mime type
+type MimeTypeUsage struct {
+	MimeType   string `bson:"_id" json:"mime_type"`
+	TotalFiles int64  `bson:"total_files" json:"total_files"`
+	TotalSize  int64  `bson:"total_size" json:"total_size"` // Every version of the files is counted
+}
+
+type StorageUsageResponse struct {
+	Quota     int64           `json:"quota"` // 0 when the storage is unlimited
+	Used      int64           `json:"used"`
+	Breakdown []MimeTypeUsage `json:"breakdown"` // Ordered by size, largest first
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -19,11 +20,35 @@ type User struct {
 	PasswordHash         string             `bson:"password_hash" json:"-"`
 	LastLoginAt          time.Time          `bson:"last_login_at" json:"last_login_at"`
 	LastPasswordChangeAt time.Time          `bson:"last_password_change_at" json:"last_password_change_at"`
-	RootFolderID         primitive.ObjectID `bson:"root_folder_id" json:"root_folder_id"` // The root folder ID for the user
+	RootFolderID         primitive.ObjectID `bson:"root_folder_id" json:"root_folder_id"`                   // The root folder ID for the user
+	StorageQuota         int64              `bson:"storage_quota,omitempty" json:"storage_quota,omitempty"` // The storage quota in bytes, 0 uses the default plan and a negative quota is unlimited
+	StorageUsed          int64              `bson:"storage_used" json:"storage_used"`                       // The bytes used by every version of the files of the user, trashed files included
 	CreatedAt            time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt            time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
+// ErrStorageQuotaExceeded is returned when an upload would use more storage than the quota of the user
+var ErrStorageQuotaExceeded = errors.New("storage quota exceeded")
+
+// GetStorageQuota returns the storage quota of the user in bytes, 0 means unlimited
+// Users without a quota of their own get the quota of the default plan
+func (u *User) GetStorageQuota(defaultQuota int64) int64 {
+	if u.StorageQuota < 0 {
+		return 0
+	}
+	if u.StorageQuota == 0 {
+		return max(defaultQuota, 0)
+	}
+
+	return u.StorageQuota
+}
+
+// HasStorageFor checks if the user can store size more bytes without exceeding the quota
+func (u *User) HasStorageFor(size int64, defaultQuota int64) bool {
+	quota := u.GetStorageQuota(defaultQuota)
+	return quota == 0 || u.StorageUsed+size <= quota
+}
+
 type UserRepository interface {
 	CreateUser(ctx context.Context, user *User) error
 	GetUserByEmail(ctx context.Context, email string) (*User, error)
@@ -32,4 +57,5 @@ type UserRepository interface {
 	GetUsersByIDs(ctx context.Context, ids []string) ([]*User, error)
 	GetUserByUsername(ctx context.Context, username string) (*User, error)
 	UpdateUserLastLogin(ctx context.Context, id string) error
+	GetStorageBreakdown(ctx context.Context, id string) ([]MimeTypeUsage, error)
 }
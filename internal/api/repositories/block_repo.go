@@ -0,0 +1,220 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type BlockRepository struct {
+	database   *mongo.Database
+	collection string
+}
+
+// NewBlockRepository creates a new instance of the BlockRepository
+func NewBlockRepository(db *mongo.Database, collection string) *BlockRepository {
+	return &BlockRepository{
+		database:   db,
+		collection: collection,
+	}
+}
+
+// GetBlockByHash retrieves a block by its hash
+func (br *BlockRepository) GetBlockByHash(ctx context.Context, hash string) (*models.Block, error) {
+	collection := br.database.Collection(br.collection)
+
+	block := &models.Block{}
+	err := collection.FindOne(ctx, bson.M{"_id": hash}).Decode(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// GetBlocksByHashes retrieves the blocks already known among the given hashes
+func (br *BlockRepository) GetBlocksByHashes(ctx context.Context, hashes []string) ([]*models.Block, error) {
+	collection := br.database.Collection(br.collection)
+
+	cursor, err := collection.Find(ctx, bson.M{
+		"_id":       bson.M{"$in": hashes},
+		"ref_count": bson.M{"$gt": 0},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var blocks []*models.Block
+	if err := cursor.All(ctx, &blocks); err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// IncrementRefCount adds a reference to a block, creating the block record if it does not exist
+func (br *BlockRepository) IncrementRefCount(ctx context.Context, hash string, size int64) error {
+	return incrementBlockRefCount(ctx, br.database, hash, size)
+}
+
+// DecrementRefCount removes a reference from a block and returns the updated block
+func (br *BlockRepository) DecrementRefCount(ctx context.Context, hash string) (*models.Block, error) {
+	return decrementBlockRefCount(ctx, br.database, hash)
+}
+
+// AcquireBlock keeps a block for an upload and returns its record, creating a record without reference if needed
+// Touching the block starts a new grace period, so the block collector cannot delete it before the chunk is recorded.
+// It returns models.ErrBlockBusy when the block is being deleted
+func (br *BlockRepository) AcquireBlock(ctx context.Context, hash string, size int64) (*models.Block, error) {
+	now := time.Now()
+	block := &models.Block{}
+	err := br.database.Collection(br.collection).FindOneAndUpdate(ctx, bson.M{"_id": hash, "deleting": bson.M{"$ne": true}}, bson.M{
+		"$set":         bson.M{"updated_at": now},
+		"$setOnInsert": bson.M{"size": size, "ref_count": 0, "created_at": now},
+	}, options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(block)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, models.ErrBlockBusy
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire block: %v", err)
+	}
+
+	return block, nil
+}
+
+// IsBlockReferencedByUser checks if a chunk of a file owned by the user references the block
+func (br *BlockRepository) IsBlockReferencedByUser(ctx context.Context, hash string, userID primitive.ObjectID) (bool, error) {
+	fileIDs, err := br.database.Collection(models.CollectionChunks).Distinct(ctx, "file_id", bson.M{"chunk_hash": hash})
+	if err != nil {
+		return false, err
+	}
+	if len(fileIDs) == 0 {
+		return false, nil
+	}
+
+	count, err := br.database.Collection(models.CollectionFiles).CountDocuments(ctx, bson.M{
+		"_id":      bson.M{"$in": fileIDs},
+		"owner_id": userID,
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// ClaimReleasedBlock marks a block without reference whose grace period ended before releasedBefore as being deleted
+// The reference count is checked again by the update itself, and a marked block cannot be referenced or acquired.
+// A block marked by a collector that stopped before deleting it can be claimed again after another grace period.
+// It returns nil when there is no block to delete
+func (br *BlockRepository) ClaimReleasedBlock(ctx context.Context, releasedBefore time.Time) (*models.Block, error) {
+	block := &models.Block{}
+	err := br.database.Collection(br.collection).FindOneAndUpdate(ctx, bson.M{
+		"ref_count":  bson.M{"$lte": 0},
+		"updated_at": bson.M{"$lt": releasedBefore},
+	}, bson.M{
+		"$set": bson.M{"deleting": true, "updated_at": time.Now()},
+	}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(block)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim released block: %v", err)
+	}
+
+	return block, nil
+}
+
+// DeleteBlockRecord deletes the record of a block marked as being deleted, once its object is deleted
+func (br *BlockRepository) DeleteBlockRecord(ctx context.Context, hash string) error {
+	_, err := br.database.Collection(br.collection).DeleteOne(ctx, bson.M{"_id": hash, "deleting": true})
+	if err != nil {
+		return fmt.Errorf("failed to delete block record: %v", err)
+	}
+
+	return nil
+}
+
+// incrementBlockRefCount is shared with the other repositories so the reference count
+// can be updated inside their transactions
+// It returns models.ErrBlockBusy when the block is being deleted
+func incrementBlockRefCount(ctx context.Context, db *mongo.Database, hash string, size int64) error {
+	if hash == "" {
+		return fmt.Errorf("block hash is required")
+	}
+
+	now := time.Now()
+	_, err := db.Collection(models.CollectionBlocks).UpdateOne(ctx, bson.M{"_id": hash, "deleting": bson.M{"$ne": true}}, bson.M{
+		"$inc":         bson.M{"ref_count": 1},
+		"$set":         bson.M{"updated_at": now},
+		"$setOnInsert": bson.M{"size": size, "created_at": now},
+	}, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return models.ErrBlockBusy
+	}
+	if err != nil {
+		return fmt.Errorf("failed to increment block reference count: %v", err)
+	}
+
+	return nil
+}
+
+// addBlockRef adds a reference to a block that is already saved in the block store
+// It returns false when the block has no record: the chunks saved before the blocks were introduced
+// are only stored under their legacy key and cannot be shared
+// It must run inside the caller's transaction
+func addBlockRef(ctx context.Context, db *mongo.Database, hash string) (bool, error) {
+	if hash == "" {
+		return false, nil
+	}
+
+	result, err := db.Collection(models.CollectionBlocks).UpdateOne(ctx, bson.M{"_id": hash, "ref_count": bson.M{"$gt": 0}}, bson.M{
+		"$inc": bson.M{"ref_count": 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to increment block reference count: %v", err)
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+// releaseBlockRef removes a reference from a block inside the caller's transaction
+// When the block is no longer referenced true is returned, its record is kept as a tombstone and
+// the block collector deletes it from the block store after the grace period. The caller must not delete it
+// Chunks saved before the blocks were introduced have no block record and are ignored
+func releaseBlockRef(ctx context.Context, db *mongo.Database, hash string) (bool, error) {
+	block, err := decrementBlockRefCount(ctx, db, hash)
+	if err != nil {
+		return false, err
+	}
+
+	return block != nil && block.RefCount <= 0, nil
+}
+
+// decrementBlockRefCount is shared with the other repositories so the reference count
+// can be updated inside their transactions
+func decrementBlockRefCount(ctx context.Context, db *mongo.Database, hash string) (*models.Block, error) {
+	block := &models.Block{}
+	err := db.Collection(models.CollectionBlocks).FindOneAndUpdate(ctx, bson.M{"_id": hash}, bson.M{
+		"$inc": bson.M{"ref_count": -1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(block)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrement block reference count: %v", err)
+	}
+
+	return block, nil
+}
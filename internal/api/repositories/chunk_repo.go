@@ -2,11 +2,13 @@ package repositories
 
 import (
 	"context"
+	"fmt"
 	"skybox-backend/internal/api/models"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type ChunkRepository struct {
@@ -65,8 +67,46 @@ func (cr *ChunkRepository) GetChunksByFileID(ctx context.Context, fileId string)
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	// Retrieve all chunks for the specified file ID
-	cursor, err := cr.database.Collection(cr.collection).Find(ctx, bson.M{"file_id": fileId})
+	// Convert the file ID string to an ObjectID
+	fileIdHex, err := primitive.ObjectIDFromHex(fileId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Retrieve all chunks for the specified file ID, ordered by chunk index
+	return cr.findChunks(ctx, bson.M{"file_id": fileIdHex})
+}
+
+// GetChunksByFileVersion retrieves the chunks of a file version ordered by chunk index, 0 is the current version
+func (cr *ChunkRepository) GetChunksByFileVersion(ctx context.Context, fileId string, version int) ([]models.Chunk, error) {
+	fileIdHex, err := primitive.ObjectIDFromHex(fileId)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &models.File{}
+	err = cr.database.Collection(models.CollectionFiles).FindOne(ctx, bson.M{"_id": fileIdHex, "is_deleted": false}).Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("file not found or deleted")
+	}
+
+	fileVersion := file.GetVersion(version)
+	if fileVersion == nil {
+		return nil, fmt.Errorf("file version %d not found", version)
+	}
+
+	return cr.findChunks(ctx, versionChunksFilter(fileIdHex, fileVersion.Version, fileVersion.SessionID))
+}
+
+// GetChunksBySession retrieves the chunks uploaded by a session ordered by chunk index
+func (cr *ChunkRepository) GetChunksBySession(ctx context.Context, session *models.UploadSession) ([]models.Chunk, error) {
+	return cr.findChunks(ctx, sessionChunksFilter(session))
+}
+
+// findChunks retrieves the chunks matching the filter ordered by chunk index
+func (cr *ChunkRepository) findChunks(ctx context.Context, filter bson.M) ([]models.Chunk, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "chunk_index", Value: 1}})
+	cursor, err := cr.database.Collection(cr.collection).Find(ctx, filter, findOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -80,6 +120,21 @@ func (cr *ChunkRepository) GetChunksByFileID(ctx context.Context, fileId string)
 	return chunks, nil
 }
 
+// versionChunksFilter matches the chunk records of a file version uploaded by the given session
+// The chunks uploaded before versioning have no session_id, they belong to the first version
+func versionChunksFilter(fileID primitive.ObjectID, version int, sessionID primitive.ObjectID) bson.M {
+	if version <= 1 {
+		return bson.M{"file_id": fileID, "session_id": bson.M{"$in": bson.A{sessionID, nil}}}
+	}
+
+	return bson.M{"file_id": fileID, "session_id": sessionID}
+}
+
+// sessionChunksFilter matches the chunk records uploaded by a session
+func sessionChunksFilter(session *models.UploadSession) bson.M {
+	return versionChunksFilter(session.FileID, session.Version, session.ID)
+}
+
 // GetChunkByID retrieves a chunk by its ID from the database
 func (cr *ChunkRepository) GetChunkByID(ctx context.Context, id string) (*models.Chunk, error) {
 	ctx, cancel := context.WithCancel(ctx)
@@ -100,3 +155,47 @@ func (cr *ChunkRepository) GetChunkByID(ctx context.Context, id string) (*models
 
 	return &chunk, nil
 }
+
+// releaseFileChunks deletes the chunk records of a file and releases their blocks
+// It must run inside the caller's transaction
+func releaseFileChunks(ctx context.Context, db *mongo.Database, fileID primitive.ObjectID) (*models.ReleasedChunks, error) {
+	return releaseChunks(ctx, db, fileID, bson.M{"file_id": fileID})
+}
+
+// releaseChunks deletes the chunk records of a file matching the filter and releases their blocks
+// It must run inside the caller's transaction
+func releaseChunks(ctx context.Context, db *mongo.Database, fileID primitive.ObjectID, filter bson.M) (*models.ReleasedChunks, error) {
+	chunkCollection := db.Collection(models.CollectionChunks)
+
+	released := &models.ReleasedChunks{
+		FileID:         fileID.Hex(),
+		ChunkIndexes:   []int{},
+		ReleasedBlocks: []string{},
+	}
+
+	cursor, err := chunkCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var chunks []models.Chunk
+	if err := cursor.All(ctx, &chunks); err != nil {
+		return nil, err
+	}
+	for _, chunk := range chunks {
+		released.ChunkIndexes = append(released.ChunkIndexes, chunk.ChunkIndex)
+
+		blockReleased, err := releaseBlockRef(ctx, db, chunk.ChunkHash)
+		if err != nil {
+			return nil, err
+		}
+		if blockReleased {
+			released.ReleasedBlocks = append(released.ReleasedBlocks, chunk.ChunkHash)
+		}
+	}
+
+	if _, err := chunkCollection.DeleteMany(ctx, filter); err != nil {
+		return nil, err
+	}
+
+	return released, nil
+}
@@ -0,0 +1,219 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// nameField returns the field holding the name of the items of the collection
+func nameField(collectionName string) string {
+	if collectionName == models.CollectionFiles {
+		return "file_name"
+	}
+	return "name"
+}
+
+// findSibling returns the ID of the item of the folder with the given name, the item excludeID is skipped
+// The ID is zero when the name is free
+func findSibling(ctx context.Context, db *mongo.Database, collectionName string, parentID primitive.ObjectID, name string, excludeID primitive.ObjectID) (primitive.ObjectID, error) {
+	filter := bson.M{
+		"parent_folder_id":        parentID,
+		nameField(collectionName): name,
+		"is_deleted":              false,
+	}
+	if !excludeID.IsZero() {
+		filter["_id"] = bson.M{"$ne": excludeID}
+	}
+
+	var item struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	err := db.Collection(collectionName).FindOne(ctx, filter, options.FindOne().SetProjection(bson.M{"_id": 1})).Decode(&item)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return primitive.NilObjectID, nil
+	}
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return item.ID, nil
+}
+
+// resolveNameConflict returns the name an item gets in the folder according to the conflict option
+// With the replace option, the existing file is moved to the trash, a folder is never replaced. It must run inside the caller's transaction.
+func resolveNameConflict(ctx context.Context, db *mongo.Database, collectionName string, parentID primitive.ObjectID, name string, excludeID primitive.ObjectID, conflict string) (string, error) {
+	if !models.IsValidConflict(conflict) {
+		return "", fmt.Errorf("invalid conflict option: %s", conflict)
+	}
+
+	siblingID, err := findSibling(ctx, db, collectionName, parentID, name, excludeID)
+	if err != nil || siblingID.IsZero() {
+		return name, err
+	}
+
+	switch conflict {
+	case models.ConflictRename:
+		return uniqueSiblingName(ctx, db, collectionName, parentID, name)
+	case models.ConflictReplace:
+		if collectionName != models.CollectionFiles {
+			return "", models.ErrFolderReplace
+		}
+		return name, trashFile(ctx, db, siblingID, models.TrashReasonReplaced, time.Now())
+	default:
+		return "", models.ErrNameConflict
+	}
+}
+
+// uniqueSiblingName returns the first name of the "name (n)" series that is free in the folder
+func uniqueSiblingName(ctx context.Context, db *mongo.Database, collectionName string, parentID primitive.ObjectID, name string) (string, error) {
+	base, ext := splitName(name, collectionName == models.CollectionFiles)
+	field := nameField(collectionName)
+
+	// Only the names of the series can be taken
+	cursor, err := db.Collection(collectionName).Find(ctx, bson.M{
+		"parent_folder_id": parentID,
+		"is_deleted":       false,
+		field:              bson.M{"$regex": "^" + regexp.QuoteMeta(base) + `( \(\d+\))?` + regexp.QuoteMeta(ext) + "$"},
+	}, options.Find().SetProjection(bson.M{field: 1}))
+	if err != nil {
+		return "", err
+	}
+	var items []bson.M
+	if err := cursor.All(ctx, &items); err != nil {
+		return "", err
+	}
+
+	used := map[string]bool{}
+	for _, item := range items {
+		if itemName, ok := item[field].(string); ok {
+			used[itemName] = true
+		}
+	}
+
+	return nextFreeName(base, ext, used), nil
+}
+
+// splitName splits a name into its base and its extension
+// Only the files have an extension, a dot file like ".env" has none
+func splitName(name string, isFile bool) (string, string) {
+	ext := ""
+	if isFile {
+		ext = filepath.Ext(name)
+	}
+	if ext == name {
+		ext = ""
+	}
+
+	return strings.TrimSuffix(name, ext), ext
+}
+
+// nextFreeName returns the first "base (n)ext" name that is not used
+func nextFreeName(base string, ext string, used map[string]bool) string {
+	for n := 1; ; n++ {
+		name := fmt.Sprintf("%s (%d)%s", base, n, ext)
+		if !used[name] {
+			return name
+		}
+	}
+}
+
+// nameConflictError converts the duplicate key errors of the sibling name indexes
+// Two requests can pass the sibling check at the same time, the unique index rejects the second one
+func nameConflictError(err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return models.ErrNameConflict
+	}
+	return err
+}
+
+// trashedFields returns the fields set on an item moved to the trash, with the user of the request and the reason
+func trashedFields(ctx context.Context, reason string, now time.Time) bson.M {
+	fields := bson.M{
+		"is_deleted":   true,
+		"deleted_at":   now,
+		"trash_reason": reason,
+	}
+	if userID, ok := ctx.Value("x-user-id-hex").(primitive.ObjectID); ok {
+		fields["deleted_by"] = userID
+	}
+
+	return fields
+}
+
+// trashFile moves a file to the trash and removes it from the statistics of its folders
+// It must run inside the caller's transaction
+func trashFile(ctx context.Context, db *mongo.Database, fileID primitive.ObjectID, reason string, now time.Time) error {
+	file := &models.File{}
+	err := db.Collection(models.CollectionFiles).FindOneAndUpdate(ctx, bson.M{"_id": fileID, "is_deleted": false}, bson.M{
+		"$set": trashedFields(ctx, reason, now),
+	}).Decode(file)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// The file is already in the trash
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete file: %v", err)
+	}
+
+	return addFolderStats(ctx, db, file.ParentFolderID, negateStats(fileStats(file)))
+}
+
+// RenameDuplicateNames renames the items sharing their name with an older item of the same folder
+// The data saved before the names were checked can have duplicates, they would prevent the creation of the unique name indexes
+func RenameDuplicateNames(ctx context.Context, db *mongo.Database) error {
+	for _, collectionName := range []string{models.CollectionFiles, models.CollectionFolders} {
+		collection := db.Collection(collectionName)
+		field := nameField(collectionName)
+
+		cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"is_deleted": false, "parent_folder_id": bson.M{"$exists": true}}}},
+			{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}}},
+			{{Key: "$group", Value: bson.M{
+				"_id":   bson.M{"parent_folder_id": "$parent_folder_id", "name": "$" + field},
+				"ids":   bson.M{"$push": "$_id"},
+				"count": bson.M{"$sum": 1},
+			}}},
+			{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+		})
+		if err != nil {
+			return err
+		}
+		var duplicates []struct {
+			Key struct {
+				ParentFolderID primitive.ObjectID `bson:"parent_folder_id"`
+				Name           string             `bson:"name"`
+			} `bson:"_id"`
+			IDs []primitive.ObjectID `bson:"ids"`
+		}
+		if err := cursor.All(ctx, &duplicates); err != nil {
+			return err
+		}
+
+		// The oldest item keeps its name
+		for _, duplicate := range duplicates {
+			for _, id := range duplicate.IDs[1:] {
+				name, err := uniqueSiblingName(ctx, db, collectionName, duplicate.Key.ParentFolderID, duplicate.Key.Name)
+				if err != nil {
+					return err
+				}
+				if _, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{field: name}}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitName(t *testing.T) {
+	names := []string{"report.pdf", "archive.tar.gz", ".env", "notes", "v1.2"}
+	expected := [][2]string{{"report", ".pdf"}, {"archive.tar", ".gz"}, {".env", ""}, {"notes", ""}, {"v1", ".2"}}
+	for i, name := range names {
+		base, ext := splitName(name, true)
+		assert.Equal(t, expected[i], [2]string{base, ext})
+	}
+
+	// Folder names have no extension
+	base, ext := splitName("v1.2", false)
+	assert.Equal(t, "v1.2", base)
+	assert.Empty(t, ext)
+}
+
+func TestNextFreeName(t *testing.T) {
+	assert.Equal(t, "report (1).pdf", nextFreeName("report", ".pdf", map[string]bool{"report.pdf": true}))
+	assert.Equal(t, "report (3).pdf", nextFreeName("report", ".pdf", map[string]bool{
+		"report.pdf":     true,
+		"report (1).pdf": true,
+		"report (2).pdf": true,
+	}))
+	assert.Equal(t, "photos (1)", nextFreeName("photos", "", map[string]bool{"photos": true, "photos (2)": true}))
+}
@@ -0,0 +1,381 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type CopyRepository struct {
+	database *mongo.Database
+}
+
+func NewCopyRepository(db *mongo.Database) *CopyRepository {
+	return &CopyRepository{
+		database: db,
+	}
+}
+
+// GetFolderTree returns the folder followed by its descendants that are not deleted
+// The parents are always before their children, the folder must be in the organization selected by the request
+func (cr *CopyRepository) GetFolderTree(ctx context.Context, folderID primitive.ObjectID) ([]*models.Folder, error) {
+	folderIDs, err := getFolderTreeIDs(ctx, cr.database, folderID, bson.M{"is_deleted": false})
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := cr.database.Collection(models.CollectionFolders).Find(ctx, tenantItemFilter(ctx, bson.M{"_id": bson.M{"$in": folderIDs}, "is_deleted": false}))
+	if err != nil {
+		return nil, err
+	}
+	var found []*models.Folder
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+
+	// Keep the order of the tree, level by level
+	byID := map[primitive.ObjectID]*models.Folder{}
+	for _, folder := range found {
+		byID[folder.ID] = folder
+	}
+	folders := []*models.Folder{}
+	for _, id := range folderIDs {
+		if folder, ok := byID[id]; ok {
+			folders = append(folders, folder)
+		}
+	}
+	if len(folders) == 0 || folders[0].ID != folderID {
+		return nil, fmt.Errorf("folder not found or deleted")
+	}
+
+	return folders, nil
+}
+
+// GetDestinationFolder retrieves the folder receiving a copy, it must not be deleted
+func (cr *CopyRepository) GetDestinationFolder(ctx context.Context, folderID primitive.ObjectID) (*models.Folder, error) {
+	return getDestinationFolder(ctx, cr.database, folderID)
+}
+
+// GetCopySize returns the storage used by the copies of the uploaded files and of the uploaded files of the folders
+// A copy only has the current version of its file
+func (cr *CopyRepository) GetCopySize(ctx context.Context, fileIDs []primitive.ObjectID, folderIDs []primitive.ObjectID) (int64, error) {
+	if len(fileIDs) == 0 && len(folderIDs) == 0 {
+		return 0, nil
+	}
+
+	cursor, err := cr.database.Collection(models.CollectionFiles).Find(ctx, bson.M{
+		"$or": bson.A{
+			bson.M{"_id": bson.M{"$in": append([]primitive.ObjectID{}, fileIDs...)}},
+			bson.M{"parent_folder_id": bson.M{"$in": append([]primitive.ObjectID{}, folderIDs...)}},
+		},
+		"is_deleted": false,
+		"status":     "uploaded",
+	})
+	if err != nil {
+		return 0, err
+	}
+	var files []*models.File
+	if err := cursor.All(ctx, &files); err != nil {
+		return 0, err
+	}
+
+	var size int64
+	for _, file := range files {
+		if version := file.GetVersion(0); version != nil {
+			size += version.Size
+		}
+	}
+
+	return size, nil
+}
+
+// getDestinationFolder retrieves the folder receiving a copy, it must not be deleted
+// It must be in the organization selected by the request
+func getDestinationFolder(ctx context.Context, db *mongo.Database, folderID primitive.ObjectID) (*models.Folder, error) {
+	folder := &models.Folder{}
+	err := db.Collection(models.CollectionFolders).FindOne(ctx, tenantItemFilter(ctx, bson.M{"_id": folderID, "is_deleted": false})).Decode(folder)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("destination folder not found or deleted")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// CopyFile copies the current version of an uploaded file into the destination folder
+// The copy is owned by the user of the context, it has a single version.
+// A name already used in the destination folder is handled according to the conflict option.
+func (cr *CopyRepository) CopyFile(ctx context.Context, fileID primitive.ObjectID, destFolderID primitive.ObjectID, newName string, conflict string) (*models.File, error) {
+	userID, ok := ctx.Value("x-user-id-hex").(primitive.ObjectID)
+	if !ok {
+		return nil, fmt.Errorf("user ID not found in context or invalid type")
+	}
+
+	session, err := cr.database.Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		file := &models.File{}
+		err := cr.database.Collection(models.CollectionFiles).FindOne(sessCtx, tenantItemFilter(ctx, bson.M{"_id": fileID, "is_deleted": false})).Decode(file)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("file not found or deleted")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if file.Status != "uploaded" {
+			return nil, fmt.Errorf("invalid file: the file is not uploaded yet")
+		}
+
+		destFolder, err := getDestinationFolder(sessCtx, cr.database, destFolderID)
+		if err != nil {
+			return nil, err
+		}
+
+		if newName == "" {
+			newName = file.FileName
+		}
+		name, err := resolveNameConflict(sessCtx, cr.database, models.CollectionFiles, destFolder.ID, newName, primitive.NilObjectID, conflict)
+		if err != nil {
+			return nil, err
+		}
+		fileCopy, err := copyFile(sessCtx, cr.database, file, destFolder, name, userID, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if err := addFolderStats(sessCtx, cr.database, destFolder.ID, fileStats(fileCopy)); err != nil {
+			return nil, err
+		}
+
+		return fileCopy, nil
+	}
+
+	result, err := session.WithTransaction(ctx, callback)
+	if err != nil {
+		return nil, nameConflictError(err)
+	}
+
+	return result.(*models.File), nil
+}
+
+// CopyFolder copies the folders of a tree and their uploaded files into the destination folder
+// The folders must be ordered with the parents first, the first one is the copied folder.
+// A folder missing from the list is not copied, neither is its content.
+// The copies are owned by the user of the context.
+// A name already used in the destination folder is handled according to the conflict option.
+func (cr *CopyRepository) CopyFolder(ctx context.Context, folders []*models.Folder, destFolderID primitive.ObjectID, newName string, conflict string) (*models.Folder, error) {
+	userID, ok := ctx.Value("x-user-id-hex").(primitive.ObjectID)
+	if !ok {
+		return nil, fmt.Errorf("user ID not found in context or invalid type")
+	}
+	if len(folders) == 0 {
+		return nil, fmt.Errorf("folder not found or deleted")
+	}
+
+	session, err := cr.database.Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		destFolder, err := getDestinationFolder(sessCtx, cr.database, destFolderID)
+		if err != nil {
+			return nil, err
+		}
+
+		// The copy would be copied again while walking the tree
+		treeIDs, err := getFolderTreeIDs(sessCtx, cr.database, folders[0].ID, bson.M{})
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(treeIDs, destFolder.ID) {
+			return nil, fmt.Errorf("cannot copy a folder into itself or one of its subfolders")
+		}
+
+		now := time.Now()
+		copyIDs := map[primitive.ObjectID]primitive.ObjectID{}
+		copies := map[primitive.ObjectID]*models.Folder{}
+		copyOrder := []primitive.ObjectID{}
+		fileTotals := map[primitive.ObjectID]models.FolderStat{}
+		var root *models.Folder
+		for i, folder := range folders {
+			parentID, name := destFolder.ID, folder.Name
+			if i == 0 {
+				if newName != "" {
+					name = newName
+				}
+				if name, err = resolveNameConflict(sessCtx, cr.database, models.CollectionFolders, parentID, name, primitive.NilObjectID, conflict); err != nil {
+					return nil, err
+				}
+			} else if parentID, ok = copyIDs[folder.ParentFolderID]; !ok {
+				continue // The parent was left out
+			}
+
+			parent := destFolder
+			if i > 0 {
+				parent = copies[parentID]
+			}
+			folderCopy := &models.Folder{
+				ID:             primitive.NewObjectID(),
+				OwnerID:        userID,
+				OrganizationID: destFolder.OrganizationID,
+				ParentFolderID: parentID,
+				AncestorIDs:    childAncestors(parent),
+				Name:           name,
+				IsDeleted:      false,
+				IsRoot:         false,
+				CreatedAt:      now,
+				UpdatedAt:      now,
+			}
+			if _, err := cr.database.Collection(models.CollectionFolders).InsertOne(sessCtx, folderCopy); err != nil {
+				return nil, err
+			}
+			copyIDs[folder.ID] = folderCopy.ID
+			copies[folderCopy.ID] = folderCopy
+			copyOrder = append(copyOrder, folderCopy.ID)
+			if root == nil {
+				root = folderCopy
+			}
+
+			// Copy the uploaded files of the folder
+			cursor, err := cr.database.Collection(models.CollectionFiles).Find(sessCtx, bson.M{
+				"parent_folder_id": folder.ID,
+				"is_deleted":       false,
+				"status":           "uploaded",
+			})
+			if err != nil {
+				return nil, err
+			}
+			var files []*models.File
+			if err := cursor.All(sessCtx, &files); err != nil {
+				return nil, err
+			}
+			totals := models.FolderStat{}
+			for _, file := range files {
+				fileCopy, err := copyFile(sessCtx, cr.database, file, folderCopy, file.FileName, userID, now)
+				if err != nil {
+					return nil, err
+				}
+				totals.TotalFiles++
+				totals.TotalSize += fileCopy.Size
+			}
+			fileTotals[folderCopy.ID] = totals
+		}
+
+		// Save the statistics of the copies, then add the copied tree to the destination folder
+		stats := sumFolderStats(copyOrder, copies, fileTotals)
+		for _, copyID := range copyOrder {
+			copies[copyID].Stats = stats[copyID]
+			if _, err := cr.database.Collection(models.CollectionFolders).UpdateOne(sessCtx, bson.M{"_id": copyID}, bson.M{
+				"$set": bson.M{"stats": stats[copyID]},
+			}); err != nil {
+				return nil, err
+			}
+		}
+		if err := addFolderStats(sessCtx, cr.database, destFolder.ID, folderStats(root)); err != nil {
+			return nil, err
+		}
+
+		return root, nil
+	}
+
+	result, err := session.WithTransaction(ctx, callback)
+	if err != nil {
+		return nil, nameConflictError(err)
+	}
+
+	return result.(*models.Folder), nil
+}
+
+// copyFile copies the current version of a file into the folder, the copy references the blocks of the file
+// The copy uses the storage of its owner, or of the organization of the folder's shared drive.
+// It must run inside the caller's transaction.
+func copyFile(ctx context.Context, db *mongo.Database, file *models.File, parent *models.Folder, name string, ownerID primitive.ObjectID, now time.Time) (*models.File, error) {
+	version := file.GetVersion(0)
+	if version == nil {
+		return nil, fmt.Errorf("invalid file: the file %s has no uploaded version", file.FileName)
+	}
+
+	cursor, err := db.Collection(models.CollectionChunks).Find(ctx, versionChunksFilter(file.ID, version.Version, version.SessionID))
+	if err != nil {
+		return nil, err
+	}
+	var chunks []models.Chunk
+	if err := cursor.All(ctx, &chunks); err != nil {
+		return nil, err
+	}
+
+	fileCopy := &models.File{
+		ID:             primitive.NewObjectID(),
+		OwnerID:        ownerID,
+		OrganizationID: parent.OrganizationID,
+		ParentFolderID: parent.ID,
+		FileName:       name,
+		MimeType:       file.MimeType,
+		Extension:      filepath.Ext(name),
+		Size:           version.Size,
+		IsDeleted:      false,
+		Status:         "uploaded",
+		TotalChunks:    version.TotalChunks,
+		CurrentVersion: 1,
+		LatestVersion:  1,
+		Versions: []models.FileVersion{{
+			Version:     1,
+			Size:        version.Size,
+			TotalChunks: version.TotalChunks,
+			UploadedBy:  ownerID,
+			CreatedAt:   now,
+		}},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	// The chunk records of the copy point to the same blocks
+	chunkCopies := []interface{}{}
+	for _, chunk := range chunks {
+		shared, err := addBlockRef(ctx, db, chunk.ChunkHash)
+		if err != nil {
+			return nil, err
+		}
+		if !shared {
+			return nil, fmt.Errorf("cannot copy the file %s, it was uploaded before the chunks were stored as shared blocks", file.FileName)
+		}
+
+		chunkCopies = append(chunkCopies, models.Chunk{
+			FileID:     fileCopy.ID,
+			ChunkIndex: chunk.ChunkIndex,
+			ChunkSize:  chunk.ChunkSize,
+			ChunkHash:  chunk.ChunkHash,
+			CreatedAt:  now,
+		})
+	}
+
+	if _, err := db.Collection(models.CollectionFiles).InsertOne(ctx, fileCopy); err != nil {
+		return nil, err
+	}
+	if len(chunkCopies) > 0 {
+		if _, err := db.Collection(models.CollectionChunks).InsertMany(ctx, chunkCopies); err != nil {
+			return nil, err
+		}
+	}
+	if err := addFileStorageUsed(ctx, db, fileCopy, fileStorageSize(fileCopy)); err != nil {
+		return nil, err
+	}
+
+	return fileCopy, nil
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type FileRepository struct {
@@ -25,35 +27,94 @@ func NewFileRepository(db *mongo.Database, collection string) *FileRepository {
 	}
 }
 
-func (fr *FileRepository) UploadFileMetadata(ctx context.Context, file *models.File) (*models.File, error) {
+// UploadFileMetadata saves the metadata of a new file
+// A name already used in the folder is handled according to the conflict option. When an uploaded file
+// is replaced, the upload becomes a new version of that file: the file is returned with the reserved
+// version as its latest version.
+// The folder may belong to another user, the caller checks the upload permission of the uploader.
+func (fr *FileRepository) UploadFileMetadata(ctx context.Context, file *models.File, conflict string) (*models.File, error) {
 	collection := fr.database.Collection(fr.collection)
 	folderCollection := fr.database.Collection(models.CollectionFolders)
-	userIDValue := ctx.Value("x-user-id-hex")
-	userID, ok := userIDValue.(primitive.ObjectID)
-	if !ok {
-		return nil, fmt.Errorf("user ID not found in context or invalid type")
-	}
 
-	// Get the folder
+	// Get the folder, the files of a shared drive belong to its organization
 	var folder models.Folder
-	err := folderCollection.FindOne(ctx, bson.M{"_id": file.ParentFolderID}).Decode(&folder)
+	err := folderCollection.FindOne(ctx, bson.M{"_id": file.ParentFolderID, "is_deleted": false}).Decode(&folder)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("folder not found or deleted")
+	}
+	if err != nil {
+		return nil, err
+	}
+	file.OrganizationID = folder.OrganizationID
+
+	session, err := fr.database.Client().StartSession()
 	if err != nil {
 		return nil, err
 	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		if conflict == models.ConflictReplace {
+			existing := &models.File{}
+			err := collection.FindOne(sessCtx, bson.M{
+				"parent_folder_id": file.ParentFolderID,
+				"file_name":        file.FileName,
+				"is_deleted":       false,
+			}).Decode(existing)
+			if err == nil && existing.Status == "uploaded" {
+				return replaceFileContent(sessCtx, fr.database, existing)
+			}
+			if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, err
+			}
+		}
 
-	// Check if the folder belongs to the user
-	if folder.OwnerID != userID {
-		return nil, mongo.ErrNoDocuments
+		name, err := resolveNameConflict(sessCtx, fr.database, fr.collection, file.ParentFolderID, file.FileName, primitive.NilObjectID, conflict)
+		if err != nil {
+			return nil, err
+		}
+		file.FileName = name
+
+		// Insert the file metadata into the database
+		result, err := collection.InsertOne(sessCtx, file)
+		if err != nil {
+			return nil, err
+		}
+
+		// Set the ID of the file to the inserted ID
+		file.ID = result.InsertedID.(primitive.ObjectID)
+		return file, nil
 	}
 
-	// Insert the file metadata into the database
-	result, err := collection.InsertOne(ctx, file)
+	result, err := session.WithTransaction(ctx, callback)
+	if err != nil {
+		return nil, nameConflictError(err)
+	}
+
+	return result.(*models.File), nil
+}
+
+// replaceFileContent reserves a new version of an uploaded file replaced by an upload
+// It must run inside the caller's transaction
+func replaceFileContent(ctx context.Context, db *mongo.Database, file *models.File) (*models.File, error) {
+	// One upload at a time, the pending session must be completed or cancelled first
+	pending, err := db.Collection(models.CollectionUploadSessions).CountDocuments(ctx, bson.M{
+		"file_id":    file.ID,
+		"status":     "pending",
+		"expires_at": bson.M{"$gt": time.Now()},
+	})
 	if err != nil {
 		return nil, err
 	}
+	if pending > 0 {
+		return nil, fmt.Errorf("cannot upload a new version while another upload of the file is pending")
+	}
 
-	// Set the ID of the file to the inserted ID
-	file.ID = result.InsertedID.(primitive.ObjectID)
+	version, err := reserveFileVersion(ctx, db, file)
+	if err != nil {
+		return nil, err
+	}
+	file.LatestVersion = version
 
 	return file, nil
 }
@@ -73,40 +134,40 @@ func (fr *FileRepository) GetFileByID(ctx context.Context, id string) (*models.F
 		return nil, fmt.Errorf("invalid file ID: %v", err)
 	}
 
-	// Find the file by ID and isDeleted := false
-	err = collection.FindOne(ctx, bson.M{"_id": idHex, "is_deleted": false}).Decode(file)
-	if err == nil {
-		return file, nil
+	// Find the file by ID and isDeleted := false, in the organization selected by the request
+	// The access of the user is checked by the permission middleware, with the file and folder grants
+	err = collection.FindOne(ctx, tenantItemFilter(ctx, bson.M{"_id": idHex, "is_deleted": false})).Decode(file)
+	if err != nil {
+		return nil, err
 	}
-	// TODO: Implement sharing functionality later
-	// If the file is not found, check if it is shared with the user
 
-	return nil, err
+	return file, nil
 }
 
 func (fr *FileRepository) DeleteFile(ctx context.Context, id string) error {
-	collection := fr.database.Collection(fr.collection)
-
 	idHex, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
 	}
 
-	// Soft delete the file by setting is_deleted to true and deleted_at to current time
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": idHex}, bson.M{
-		"$set": bson.M{
-			"is_deleted": true,
-			"deleted_at": time.Now(),
-		},
-	})
+	session, err := fr.database.Client().StartSession()
 	if err != nil {
-		return fmt.Errorf("failed to delete file: %v", err)
+		return err
 	}
+	defer session.EndSession(ctx)
 
-	return nil
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		// Soft delete the file by setting is_deleted to true and deleted_at to current time
+		return nil, trashFile(sessCtx, fr.database, idHex, models.TrashReasonDeleted, time.Now())
+	}
+
+	_, err = session.WithTransaction(ctx, callback)
+	return err
 }
 
-func (fr *FileRepository) RenameFile(ctx context.Context, id string, newName string) error {
+// RenameFile renames a file
+// A name already used in the folder is handled according to the conflict option
+func (fr *FileRepository) RenameFile(ctx context.Context, id string, newName string, conflict string) error {
 	collection := fr.database.Collection(fr.collection)
 
 	idHex, err := primitive.ObjectIDFromHex(id)
@@ -115,27 +176,50 @@ func (fr *FileRepository) RenameFile(ctx context.Context, id string, newName str
 	}
 
 	// Check if the file related to the user (via owner or sharing)
-	_, err = fr.GetFileByID(ctx, id)
+	file, err := fr.GetFileByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	// Rename the file by updating the file_name field
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": idHex}, bson.M{
-		"$set": bson.M{
-			"file_name": newName,
-		},
-	})
+	session, err := fr.database.Client().StartSession()
 	if err != nil {
-		return fmt.Errorf("failed to rename file: %v", err)
+		return err
 	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		name, err := resolveNameConflict(sessCtx, fr.database, fr.collection, file.ParentFolderID, newName, idHex, conflict)
+		if err != nil {
+			return nil, err
+		}
+
+		// Rename the file by updating the file_name field
+		_, err = collection.UpdateOne(sessCtx, bson.M{"_id": idHex}, bson.M{
+			"$set": bson.M{
+				"file_name": name,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to rename file: %w", err)
+		}
 
-	return nil
+		return nil, nil
+	}
+
+	_, err = session.WithTransaction(ctx, callback)
+	return nameConflictError(err)
 }
 
-func (fr *FileRepository) MoveFile(ctx context.Context, id string, newParentFolderID string) error {
+// MoveFile moves a file to a new parent folder
+// A name already used in the new parent folder is handled according to the conflict option
+func (fr *FileRepository) MoveFile(ctx context.Context, id string, newParentFolderID string, conflict string) error {
 	collection := fr.database.Collection(fr.collection)
 	folderCollection := fr.database.Collection(models.CollectionFolders)
+	userIDValue := ctx.Value("x-user-id-hex")
+	userID, ok := userIDValue.(primitive.ObjectID)
+	if !ok {
+		return fmt.Errorf("user ID not found in context or invalid type")
+	}
 
 	idHex, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -143,7 +227,7 @@ func (fr *FileRepository) MoveFile(ctx context.Context, id string, newParentFold
 	}
 
 	// Check if the file related to the user (via owner or sharing)
-	_, err = fr.GetFileByID(ctx, id)
+	file, err := fr.GetFileByID(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -153,26 +237,63 @@ func (fr *FileRepository) MoveFile(ctx context.Context, id string, newParentFold
 		return fmt.Errorf("invalid new parent folder ID: %v", err)
 	}
 
-	// Check if the new parent folder ID is exist
+	// Check if the new parent folder exists in the workspace selected by the request
 	var folder models.Folder
-	err = folderCollection.FindOne(ctx, bson.M{"_id": newParentIDHex}).Decode(&folder)
+	err = folderCollection.FindOne(ctx, tenantItemFilter(ctx, bson.M{"_id": newParentIDHex, "is_deleted": false})).Decode(&folder)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("folder not found or deleted")
+	}
 	if err != nil {
 		return err
 	}
+	if !sameOrganization(file.OrganizationID, folder.OrganizationID) {
+		return models.ErrCrossTenantMove
+	}
+	if folder.OrganizationID == nil && folder.OwnerID != userID {
+		return fmt.Errorf("user does not have permission to move this file to the new parent folder")
+	}
+	if folder.OrganizationID != nil {
+		allowed, err := hasOrganizationPermission(ctx, fr.database, *folder.OrganizationID, userID, models.PermissionEdit)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return fmt.Errorf("user does not have permission to move this file to the new parent folder")
+		}
+	}
 
-	// Move the file by updating the parent_folder_id field
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": idHex}, bson.M{
-		"$set": bson.M{
-			"parent_folder_id": newParentIDHex,
-		},
-	})
+	session, err := fr.database.Client().StartSession()
 	if err != nil {
-		return fmt.Errorf("failed to move file: %v", err)
+		return err
 	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		name, err := resolveNameConflict(sessCtx, fr.database, fr.collection, newParentIDHex, file.FileName, idHex, conflict)
+		if err != nil {
+			return nil, err
+		}
 
-	return nil
+		// Move the file by updating the parent_folder_id field
+		moved := &models.File{}
+		err = collection.FindOneAndUpdate(sessCtx, bson.M{"_id": idHex}, bson.M{
+			"$set": bson.M{
+				"parent_folder_id": newParentIDHex,
+				"file_name":        name,
+			},
+		}).Decode(moved)
+		if err != nil {
+			return nil, fmt.Errorf("failed to move file: %w", err)
+		}
+
+		return nil, moveFolderStats(sessCtx, fr.database, moved.ParentFolderID, newParentIDHex, fileStats(moved))
+	}
+
+	_, err = session.WithTransaction(ctx, callback)
+	return nameConflictError(err)
 }
 
+// SearchFiles searches the uploaded files of the workspace selected by the request by name
 func (fr *FileRepository) SearchFiles(ctx context.Context, ownerId primitive.ObjectID, query string) ([]*models.File, error) {
 	collection := fr.database.Collection(fr.collection)
 	ctx, cancel := context.WithCancel(ctx)
@@ -181,9 +302,9 @@ func (fr *FileRepository) SearchFiles(ctx context.Context, ownerId primitive.Obj
 	// Define the filter for searching files
 	filter := bson.M{
 		"$and": []bson.M{
-			{"owner_id": ownerId},
+			workspaceFilter(ctx, ownerId),                           // The items of another organization are never matched
 			{"file_name": bson.M{"$regex": query, "$options": "i"}}, // Case-insensitive regex match
-			{"is_deleted": false}, // Exclude deleted files
+			{"is_deleted": false},                                   // Exclude deleted files
 			{"status": "uploaded"},
 		},
 	}
@@ -211,3 +332,144 @@ func (fr *FileRepository) SearchFiles(ctx context.Context, ownerId primitive.Obj
 
 	return files, nil
 }
+
+func (fr *FileRepository) GetFileSharedUsers(ctx context.Context, fileID string) ([]*models.SharedUserResponse, error) {
+	collection := fr.database.Collection(models.CollectionFileSharedUsers)
+	fileIDHex, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file ID")
+	}
+
+	return getSharedUsers(ctx, collection, bson.M{"file_id": fileIDHex}, nil)
+}
+
+func (fr *FileRepository) GetFileSharedUser(ctx context.Context, fileID string, userID string) (*models.FileSharedUser, error) {
+	collection := fr.database.Collection(models.CollectionFileSharedUsers)
+
+	fileIDHex, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file ID")
+	}
+	userIDHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID")
+	}
+
+	sharedUser := &models.FileSharedUser{}
+	err = collection.FindOne(ctx, bson.M{"file_id": fileIDHex, "user_id": userIDHex}).Decode(sharedUser)
+	if err != nil {
+		return nil, err
+	}
+
+	return sharedUser, nil
+}
+
+func (fr *FileRepository) ShareFile(ctx context.Context, fileID, userID string, role models.ShareRole) error {
+	collection := fr.database.Collection(models.CollectionFileSharedUsers)
+
+	fileIDHex, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return fmt.Errorf("invalid file ID")
+	}
+	userIDHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID")
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"file_id": fileIDHex, "user_id": userIDHex}, bson.M{
+		"$set": bson.M{"role": role},
+	}, options.Update().SetUpsert(true))
+	return err
+}
+
+func (fr *FileRepository) RemoveFileShare(ctx context.Context, fileID, userID string) error {
+	collection := fr.database.Collection(models.CollectionFileSharedUsers)
+
+	fileIDHex, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return fmt.Errorf("invalid file ID")
+	}
+	userIDHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID")
+	}
+
+	_, err = collection.DeleteOne(ctx, bson.M{"file_id": fileIDHex, "user_id": userIDHex})
+	return err
+}
+
+func (fr *FileRepository) GetFileSharedGroups(ctx context.Context, fileID string) ([]*models.SharedGroupResponse, error) {
+	collection := fr.database.Collection(models.CollectionFileSharedGroups)
+	fileIDHex, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file ID")
+	}
+
+	return getSharedGroups(ctx, collection, bson.M{"file_id": fileIDHex}, nil)
+}
+
+// GetFileGroupShares retrieves the grants of the groups of a user on a file
+func (fr *FileRepository) GetFileGroupShares(ctx context.Context, fileID string, userID string) ([]*models.FileSharedGroup, error) {
+	collection := fr.database.Collection(models.CollectionFileSharedGroups)
+
+	fileIDHex, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file ID")
+	}
+	userIDHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID")
+	}
+
+	sharedGroups := []*models.FileSharedGroup{}
+	groupIDs, err := getUserGroupIDs(ctx, fr.database, userIDHex)
+	if err != nil || len(groupIDs) == 0 {
+		return sharedGroups, err
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{"file_id": fileIDHex, "group_id": bson.M{"$in": groupIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &sharedGroups); err != nil {
+		return nil, err
+	}
+
+	return sharedGroups, nil
+}
+
+func (fr *FileRepository) ShareFileWithGroup(ctx context.Context, fileID, groupID string, role models.ShareRole) error {
+	collection := fr.database.Collection(models.CollectionFileSharedGroups)
+
+	fileIDHex, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return fmt.Errorf("invalid file ID")
+	}
+	groupIDHex, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return fmt.Errorf("invalid group ID")
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"file_id": fileIDHex, "group_id": groupIDHex}, bson.M{
+		"$set": bson.M{"role": role},
+	}, options.Update().SetUpsert(true))
+	return err
+}
+
+func (fr *FileRepository) RemoveFileGroupShare(ctx context.Context, fileID, groupID string) error {
+	collection := fr.database.Collection(models.CollectionFileSharedGroups)
+
+	fileIDHex, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return fmt.Errorf("invalid file ID")
+	}
+	groupIDHex, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return fmt.Errorf("invalid group ID")
+	}
+
+	_, err = collection.DeleteOne(ctx, bson.M{"file_id": fileIDHex, "group_id": groupIDHex})
+	return err
+}
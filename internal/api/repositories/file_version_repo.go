@@ -0,0 +1,227 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type FileVersionRepository struct {
+	database *mongo.Database
+}
+
+func NewFileVersionRepository(db *mongo.Database) *FileVersionRepository {
+	return &FileVersionRepository{
+		database: db,
+	}
+}
+
+// getFile retrieves a file that is not deleted, in the organization selected by the request
+func (fvr *FileVersionRepository) getFile(ctx context.Context, fileID primitive.ObjectID) (*models.File, error) {
+	file := &models.File{}
+	err := fvr.database.Collection(models.CollectionFiles).FindOne(ctx, tenantItemFilter(ctx, bson.M{"_id": fileID, "is_deleted": false})).Decode(file)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("file not found or deleted")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// NextVersion reserves the number of a new version of an uploaded file
+// The version of a file uploaded before versioning is saved first, so it is kept in the version list
+func (fvr *FileVersionRepository) NextVersion(ctx context.Context, fileID primitive.ObjectID) (int, error) {
+	session, err := fvr.database.Client().StartSession()
+	if err != nil {
+		return 0, err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		file, err := fvr.getFile(sessCtx, fileID)
+		if err != nil {
+			return nil, err
+		}
+
+		return reserveFileVersion(sessCtx, fvr.database, file)
+	}
+
+	result, err := session.WithTransaction(ctx, callback)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.(int), nil
+}
+
+// reserveFileVersion reserves the number of a new version of an uploaded file
+// It must run inside the caller's transaction
+func reserveFileVersion(ctx context.Context, db *mongo.Database, file *models.File) (int, error) {
+	if file.Status != "uploaded" {
+		return 0, fmt.Errorf("invalid file: the file is not uploaded yet")
+	}
+
+	version := max(file.LatestVersion, 1) + 1
+	update := bson.M{"latest_version": version}
+	if len(file.Versions) == 0 {
+		update["versions"] = file.GetVersions()
+		update["current_version"] = 1
+	}
+
+	if _, err := db.Collection(models.CollectionFiles).UpdateOne(ctx, bson.M{"_id": file.ID}, bson.M{
+		"$set": update,
+	}); err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// RestoreVersion makes an uploaded version the current version of the file
+// The statistics of the folders are updated with the size of the restored version
+func (fvr *FileVersionRepository) RestoreVersion(ctx context.Context, fileID primitive.ObjectID, version int) (*models.File, error) {
+	session, err := fvr.database.Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		file, err := fvr.getFile(sessCtx, fileID)
+		if err != nil {
+			return nil, err
+		}
+
+		fileVersion := file.GetVersion(version)
+		if fileVersion == nil {
+			return nil, fmt.Errorf("file version %d not found", version)
+		}
+
+		previousSize := file.Size
+		err = fvr.database.Collection(models.CollectionFiles).FindOneAndUpdate(sessCtx, bson.M{
+			"_id":              fileID,
+			"versions.version": version,
+		}, bson.M{
+			"$set": bson.M{
+				"size":            fileVersion.Size,
+				"total_chunks":    fileVersion.TotalChunks,
+				"current_version": version,
+				"updated_at":      time.Now(),
+			},
+		}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(file)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// A file uploaded before versioning only has its current version
+			return file, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := addFolderStats(sessCtx, fvr.database, file.ParentFolderID, models.FolderStat{TotalSize: file.Size - previousSize}); err != nil {
+			return nil, err
+		}
+
+		return file, nil
+	}
+
+	result, err := session.WithTransaction(ctx, callback)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*models.File), nil
+}
+
+// GetVersionedFiles retrieves up to limit files with more than one version, ordered by ID after afterID
+func (fvr *FileVersionRepository) GetVersionedFiles(ctx context.Context, afterID primitive.ObjectID, limit int64) ([]*models.File, error) {
+	findOptions := options.Find().SetSort(bson.M{"_id": 1}).SetLimit(limit)
+	cursor, err := fvr.database.Collection(models.CollectionFiles).Find(ctx, bson.M{
+		"_id":        bson.M{"$gt": afterID},
+		"versions.1": bson.M{"$exists": true},
+	}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var files []*models.File
+	if err := cursor.All(ctx, &files); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// PruneVersions deletes versions of a file with their chunk records, the current version is never deleted
+// It returns the released chunks so they can be deleted from the block store
+// The storage used by the deleted versions is released
+func (fvr *FileVersionRepository) PruneVersions(ctx context.Context, fileID primitive.ObjectID, versions []int) ([]*models.ReleasedChunks, error) {
+	session, err := fvr.database.Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		file := &models.File{}
+		if err := fvr.database.Collection(models.CollectionFiles).FindOne(sessCtx, bson.M{"_id": fileID}).Decode(file); err != nil {
+			return nil, err
+		}
+
+		released := []*models.ReleasedChunks{}
+		pruned := []int{}
+		var prunedSize int64
+		for _, fileVersion := range file.Versions {
+			if fileVersion.Version == file.CurrentVersion || !slices.Contains(versions, fileVersion.Version) {
+				continue
+			}
+
+			versionReleased, err := releaseChunks(sessCtx, fvr.database, fileID, versionChunksFilter(fileID, fileVersion.Version, fileVersion.SessionID))
+			if err != nil {
+				return nil, err
+			}
+			versionReleased.OwnerID = file.OwnerID.Hex()
+
+			// The legacy chunk objects belong to the first version
+			if fileVersion.Version > 1 {
+				versionReleased.ChunkIndexes = []int{}
+			}
+
+			released = append(released, versionReleased)
+			pruned = append(pruned, fileVersion.Version)
+			prunedSize += fileVersion.Size
+		}
+		if len(pruned) == 0 {
+			return released, nil
+		}
+
+		if _, err := fvr.database.Collection(models.CollectionFiles).UpdateOne(sessCtx, bson.M{"_id": fileID}, bson.M{
+			"$pull": bson.M{"versions": bson.M{"version": bson.M{"$in": pruned}}},
+		}); err != nil {
+			return nil, err
+		}
+		if err := addFileStorageUsed(sessCtx, fvr.database, file, -prunedSize); err != nil {
+			return nil, err
+		}
+
+		return released, nil
+	}
+
+	result, err := session.WithTransaction(ctx, callback)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]*models.ReleasedChunks), nil
+}
@@ -0,0 +1,205 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// childAncestors returns the ancestors of the children of a folder: the ancestors of the folder and the folder itself
+func childAncestors(parent *models.Folder) []primitive.ObjectID {
+	ancestors := make([]primitive.ObjectID, 0, len(parent.AncestorIDs)+1)
+	ancestors = append(ancestors, parent.AncestorIDs...)
+
+	return append(ancestors, parent.ID)
+}
+
+// setFolderAncestors sets the ancestors of a folder placed under a new parent and rewrites the ancestors of its subtree
+// It must run inside the caller's transaction
+func setFolderAncestors(ctx context.Context, db *mongo.Database, folderID primitive.ObjectID, parentID primitive.ObjectID) error {
+	collection := db.Collection(models.CollectionFolders)
+
+	parent := &models.Folder{}
+	err := collection.FindOne(ctx, bson.M{"_id": parentID}, options.FindOne().SetProjection(bson.M{"ancestor_ids": 1})).Decode(parent)
+	if err != nil {
+		return err
+	}
+	ancestors := childAncestors(parent)
+
+	if _, err := collection.UpdateOne(ctx, bson.M{"_id": folderID}, bson.M{
+		"$set": bson.M{"ancestor_ids": ancestors},
+	}); err != nil {
+		return err
+	}
+
+	// The descendants keep their ancestors below the folder, the ones above are replaced
+	_, err = collection.UpdateMany(ctx, bson.M{"ancestor_ids": folderID}, bson.A{
+		bson.M{"$set": bson.M{"ancestor_ids": bson.M{"$concatArrays": bson.A{
+			childAncestors(&models.Folder{ID: folderID, AncestorIDs: ancestors}),
+			bson.M{"$slice": bson.A{
+				"$ancestor_ids",
+				bson.M{"$add": bson.A{bson.M{"$indexOfArray": bson.A{"$ancestor_ids", folderID}}, 1}},
+				bson.M{"$size": "$ancestor_ids"},
+			}},
+		}}}},
+	})
+	return err
+}
+
+// getDescendantFolderIDs retrieves the IDs of the folders under a folder, trashed or not
+func getDescendantFolderIDs(ctx context.Context, db *mongo.Database, folderID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	cursor, err := db.Collection(models.CollectionFolders).Find(ctx, bson.M{"ancestor_ids": folderID}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var folders []*models.Folder
+	if err := cursor.All(ctx, &folders); err != nil {
+		return nil, err
+	}
+
+	folderIDs := []primitive.ObjectID{}
+	for _, folder := range folders {
+		folderIDs = append(folderIDs, folder.ID)
+	}
+
+	return folderIDs, nil
+}
+
+// BackfillFolderAncestors computes the ancestors of the folders saved before the ancestors were recorded
+// Only the folders without an ancestor_ids field are updated, it does nothing once every folder has one.
+// The grants copied on every subfolder before the grants were inherited are removed once the ancestors are known.
+func BackfillFolderAncestors(ctx context.Context, db *mongo.Database) error {
+	collection := db.Collection(models.CollectionFolders)
+
+	cursor, err := collection.Find(ctx, bson.M{"ancestor_ids": bson.M{"$exists": false}}, options.Find().SetProjection(bson.M{
+		"parent_folder_id": 1,
+		"is_root":          1,
+	}))
+	if err != nil {
+		return err
+	}
+	var missing []*models.Folder
+	if err := cursor.All(ctx, &missing); err != nil {
+		return err
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	folders := map[primitive.ObjectID]*models.Folder{}
+	for _, folder := range missing {
+		folders[folder.ID] = folder
+	}
+
+	// The ancestors are resolved from the parent, the folders already saved with their ancestors are read once
+	ancestors := map[primitive.ObjectID][]primitive.ObjectID{}
+	var resolve func(folderID primitive.ObjectID, visited map[primitive.ObjectID]bool) ([]primitive.ObjectID, error)
+	resolve = func(folderID primitive.ObjectID, visited map[primitive.ObjectID]bool) ([]primitive.ObjectID, error) {
+		if found, ok := ancestors[folderID]; ok {
+			return found, nil
+		}
+
+		folder, ok := folders[folderID]
+		if !ok {
+			folder = &models.Folder{}
+			err := collection.FindOne(ctx, bson.M{"_id": folderID}, options.FindOne().SetProjection(bson.M{"ancestor_ids": 1})).Decode(folder)
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return []primitive.ObjectID{}, nil // The parent was purged
+			}
+			if err != nil {
+				return nil, err
+			}
+			ancestors[folderID] = folder.AncestorIDs
+			return folder.AncestorIDs, nil
+		}
+
+		result := []primitive.ObjectID{}
+		if !folder.IsRoot && !folder.ParentFolderID.IsZero() && !visited[folder.ParentFolderID] {
+			visited[folderID] = true
+			parentAncestors, err := resolve(folder.ParentFolderID, visited)
+			if err != nil {
+				return nil, err
+			}
+			result = childAncestors(&models.Folder{ID: folder.ParentFolderID, AncestorIDs: parentAncestors})
+		}
+		ancestors[folderID] = result
+
+		return result, nil
+	}
+
+	writes := []mongo.WriteModel{}
+	for _, folder := range missing {
+		folderAncestors, err := resolve(folder.ID, map[primitive.ObjectID]bool{})
+		if err != nil {
+			return err
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": folder.ID, "ancestor_ids": bson.M{"$exists": false}}).
+			SetUpdate(bson.M{"$set": bson.M{"ancestor_ids": folderAncestors}}))
+	}
+	if _, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return err
+	}
+
+	return removeInheritedShares(ctx, db)
+}
+
+// removeInheritedShares removes the grants giving a user the role the user already inherits from an ancestor
+func removeInheritedShares(ctx context.Context, db *mongo.Database) error {
+	collection := db.Collection(models.CollectionFolderSharedUsers)
+
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.M{
+			"from":         models.CollectionFolders,
+			"localField":   "folder_id",
+			"foreignField": "_id",
+			"as":           "folder",
+		}}},
+		{{Key: "$unwind", Value: "$folder"}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": models.CollectionFolderSharedUsers,
+			"let": bson.M{
+				"user_id":      "$user_id",
+				"role":         "$role",
+				"ancestor_ids": bson.M{"$ifNull": bson.A{"$folder.ancestor_ids", bson.A{}}},
+			},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+					bson.M{"$eq": bson.A{"$user_id", "$$user_id"}},
+					bson.M{"$eq": bson.A{"$role", "$$role"}},
+					bson.M{"$in": bson.A{"$folder_id", "$$ancestor_ids"}},
+				}}}},
+				bson.M{"$limit": 1},
+			},
+			"as": "inherited",
+		}}},
+		{{Key: "$match", Value: bson.M{"inherited": bson.M{"$ne": bson.A{}}}}},
+		{{Key: "$project", Value: bson.M{"_id": 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	var grants []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &grants); err != nil {
+		return err
+	}
+	if len(grants) == 0 {
+		return nil
+	}
+
+	grantIDs := []primitive.ObjectID{}
+	for _, grant := range grants {
+		grantIDs = append(grantIDs, grant.ID)
+	}
+	_, err = collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": grantIDs}})
+	return err
+}
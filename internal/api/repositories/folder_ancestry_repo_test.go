@@ -0,0 +1,28 @@
+package repositories
+
+import (
+	"testing"
+
+	"skybox-backend/internal/api/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestChildAncestors(t *testing.T) {
+	root := &models.Folder{ID: primitive.NewObjectID(), AncestorIDs: []primitive.ObjectID{}}
+	assert.Equal(t, []primitive.ObjectID{root.ID}, childAncestors(root))
+
+	// A root folder saved without ancestors has none
+	assert.Equal(t, []primitive.ObjectID{root.ID}, childAncestors(&models.Folder{ID: root.ID}))
+
+	parent := &models.Folder{ID: primitive.NewObjectID(), AncestorIDs: childAncestors(root)}
+	first := childAncestors(parent)
+	second := childAncestors(parent)
+	assert.Equal(t, []primitive.ObjectID{root.ID, parent.ID}, first)
+
+	// The children do not share the ancestors of their parent
+	first[0] = primitive.NilObjectID
+	assert.Equal(t, root.ID, second[0])
+	assert.Equal(t, root.ID, parent.AncestorIDs[0])
+}
@@ -2,7 +2,9 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -28,7 +30,8 @@ func NewFolderRepository(db *mongo.Database, collection string) *FolderRepositor
 }
 
 // CreateFolder creates a new folder
-func (fr *FolderRepository) CreateFolder(ctx context.Context, folder *models.Folder) (*models.Folder, error) {
+// A name already used in the parent folder is handled according to the conflict option
+func (fr *FolderRepository) CreateFolder(ctx context.Context, folder *models.Folder, conflict string) (*models.Folder, error) {
 	collection := fr.database.Collection(fr.collection)
 	userIDValue := ctx.Value("x-user-id-hex") // Get userID from context x-user-id-hex saved before
 	userID, ok := userIDValue.(primitive.ObjectID)
@@ -37,6 +40,7 @@ func (fr *FolderRepository) CreateFolder(ctx context.Context, folder *models.Fol
 	}
 
 	// Get the folder ID from the parent folder if it exists
+	folder.AncestorIDs = []primitive.ObjectID{}
 	if folder.ParentFolderID != primitive.NilObjectID {
 		parentFolder, err := fr.GetFolderByID(ctx, folder.ParentFolderID.Hex())
 		if err != nil {
@@ -44,19 +48,49 @@ func (fr *FolderRepository) CreateFolder(ctx context.Context, folder *models.Fol
 		}
 
 		// TODO: Implement sharing functionality later
-		if parentFolder.OwnerID != userID {
+		// In a shared drive the role of the user in the organization was checked by the permission middleware
+		if parentFolder.OrganizationID == nil && parentFolder.OwnerID != userID {
 			return nil, fmt.Errorf("user does not have permission to create a folder in this parent folder")
 		}
+		folder.AncestorIDs = childAncestors(parentFolder)
+		folder.OrganizationID = parentFolder.OrganizationID
 	}
 
-	// Create folder in database
-	result, err := collection.InsertOne(ctx, folder)
+	session, err := fr.database.Client().StartSession()
 	if err != nil {
 		return nil, err
 	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		// The root folder has no siblings
+		if folder.ParentFolderID != primitive.NilObjectID {
+			name, err := resolveNameConflict(sessCtx, fr.database, fr.collection, folder.ParentFolderID, folder.Name, primitive.NilObjectID, conflict)
+			if err != nil {
+				return nil, err
+			}
+			folder.Name = name
+		}
+
+		// Create folder in database
+		result, err := collection.InsertOne(sessCtx, folder)
+		if err != nil {
+			return nil, err
+		}
+		if err := addFolderStats(sessCtx, fr.database, folder.ParentFolderID, models.FolderStat{TotalFolders: 1}); err != nil {
+			return nil, err
+		}
+
+		return result.InsertedID, nil
+	}
+
+	insertedID, err := session.WithTransaction(ctx, callback)
+	if err != nil {
+		return nil, nameConflictError(err)
+	}
 
 	// Assign the ID to the folder object
-	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+	if oid, ok := insertedID.(primitive.ObjectID); ok {
 		folder.ID = oid
 	}
 
@@ -78,9 +112,9 @@ func (fr *FolderRepository) GetFolderByID(ctx context.Context, id string) (*mode
 		return nil, fmt.Errorf("invalid folder ID")
 	}
 
-	// Find the folder by ID and isDeleted := false
-	// Owner priority
-	err = collection.FindOne(ctx, bson.M{"_id": idHex, "is_deleted": false}).Decode(folder)
+	// Find the folder by ID and isDeleted := false, in the organization selected by the request
+	// The listings of the folder content look the folder up first, so they are restricted too
+	err = collection.FindOne(ctx, tenantItemFilter(ctx, bson.M{"_id": idHex, "is_deleted": false})).Decode(folder)
 	if err == nil {
 		// If the folder is found, return it
 		return folder, nil
@@ -102,7 +136,7 @@ func (fr *FolderRepository) GetFolderParentIDByFolderID(ctx context.Context, fol
 
 	// Find the folder by ID and isDeleted := false
 	var folder models.Folder
-	err = collection.FindOne(ctx, bson.M{"_id": folderIDHex, "is_deleted": false}).Decode(&folder)
+	err = collection.FindOne(ctx, tenantItemFilter(ctx, bson.M{"_id": folderIDHex, "is_deleted": false})).Decode(&folder)
 	if err != nil {
 		return "", fmt.Errorf("folder not found or deleted")
 	}
@@ -149,36 +183,49 @@ func (fr *FolderRepository) GetFolderListInFolder(ctx context.Context, folderID
 	return contents, nil
 }
 
-// GetFolderResponseListInFolder retrieves the folder responses in a folder by ID
-// For the ownerID, it will get the Username and Email from the token
-// SELECT * FROM folders f
-// JOIN users u ON f.owner_id = u.id
-// WHERE f.parent_folder_id = folderID AND f.is_deleted = false
-func (fr *FolderRepository) GetFolderResponseListInFolder(ctx context.Context, folderID string) ([]*models.FolderResponse, error) {
-	collection := fr.database.Collection(fr.collection)
-
-	// Decode the results into a slice of FileResponse
-	var folderResponse []*models.FolderResponse
-
-	// Get the current folder and check if the user has permission to access the folder
-	_, err := fr.GetFolderByID(ctx, folderID)
-	if err != nil {
-		return nil, err
-	}
-
-	// Check if folderID is a valid ObjectID
-	folderIDHex, err := primitive.ObjectIDFromHex(folderID)
-	if err != nil {
-		return nil, fmt.Errorf("invalid folder ID")
+// folderResponsePipeline builds the aggregation pipeline of the folder responses matching the filter
+// The owner of every folder is joined for the username and the email
+func folderResponsePipeline(match bson.M) []bson.M {
+	return []bson.M{
+		{
+			"$match": match,
+		},
+		{
+			"$lookup": bson.M{
+				"from":         models.CollectionUsers, // The users collection
+				"localField":   "owner_id",             // The field in the folders collection
+				"foreignField": "_id",                  // The field in the users collection
+				"as":           "owner_details",        // The field to store the joined data
+			},
+		},
+		{
+			"$unwind": bson.M{
+				"path":                       "$owner_details",
+				"preserveNullAndEmptyArrays": true, // Optional: Keep folders without matching users
+			},
+		},
+		{
+			"$project": bson.M{
+				"id":               "$_id",
+				"name":             "$name",
+				"owner_id":         "$owner_id",
+				"owner_user_name":  "$owner_details.username",
+				"owner_email":      "$owner_details.email",
+				"parent_folder_id": "$parent_folder_id",
+				"stats":            "$stats",
+				"created_at":       "$created_at",
+				"updated_at":       "$updated_at",
+			},
+		},
 	}
+}
 
-	// Define the aggregation pipeline
-	pipeline := []bson.M{
+// fileResponsePipeline builds the aggregation pipeline of the file responses matching the filter
+// The owner of every file is joined for the username and the email
+func fileResponsePipeline(match bson.M) []bson.M {
+	return []bson.M{
 		{
-			"$match": bson.M{
-				"parent_folder_id": folderIDHex,
-				"is_deleted":       false,
-			},
+			"$match": match,
 		},
 		{
 			"$lookup": bson.M{
@@ -197,17 +244,48 @@ func (fr *FolderRepository) GetFolderResponseListInFolder(ctx context.Context, f
 		{
 			"$project": bson.M{
 				"id":               "$_id",
-				"name":             "$name",
+				"name":             "$file_name",
 				"owner_id":         "$owner_id",
 				"owner_user_name":  "$owner_details.username",
 				"owner_email":      "$owner_details.email",
 				"parent_folder_id": "$parent_folder_id",
-				"stats":            "$stats",
+				"size":             "$size",
+				"mime_type":        "$mime_type",
 				"created_at":       "$created_at",
 				"updated_at":       "$updated_at",
 			},
 		},
 	}
+}
+
+// GetFolderResponseListInFolder retrieves the folder responses in a folder by ID
+// For the ownerID, it will get the Username and Email from the token
+// SELECT * FROM folders f
+// JOIN users u ON f.owner_id = u.id
+// WHERE f.parent_folder_id = folderID AND f.is_deleted = false
+func (fr *FolderRepository) GetFolderResponseListInFolder(ctx context.Context, folderID string) ([]*models.FolderResponse, error) {
+	collection := fr.database.Collection(fr.collection)
+
+	// Decode the results into a slice of FileResponse
+	var folderResponse []*models.FolderResponse
+
+	// Get the current folder and check if the user has permission to access the folder
+	_, err := fr.GetFolderByID(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if folderID is a valid ObjectID
+	folderIDHex, err := primitive.ObjectIDFromHex(folderID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid folder ID")
+	}
+
+	// Define the aggregation pipeline
+	pipeline := folderResponsePipeline(bson.M{
+		"parent_folder_id": folderIDHex,
+		"is_deleted":       false,
+	})
 
 	// TODO: Implement sharing functionality later
 	// Get all folder contents where parent_folder_id matches the folderID
@@ -287,43 +365,11 @@ func (fr *FolderRepository) GetFileResponseListInFolder(ctx context.Context, fol
 	}
 
 	// Define the aggregation pipeline
-	pipeline := []bson.M{
-		{
-			"$match": bson.M{
-				"parent_folder_id": folderIDHex,
-				"is_deleted":       false,
-				"status":           "uploaded", // The file must be uploaded
-			},
-		},
-		{
-			"$lookup": bson.M{
-				"from":         models.CollectionUsers, // The users collection
-				"localField":   "owner_id",             // The field in the files collection
-				"foreignField": "_id",                  // The field in the users collection
-				"as":           "owner_details",        // The field to store the joined data
-			},
-		},
-		{
-			"$unwind": bson.M{
-				"path":                       "$owner_details",
-				"preserveNullAndEmptyArrays": true, // Optional: Keep files without matching users
-			},
-		},
-		{
-			"$project": bson.M{
-				"id":               "$_id",
-				"name":             "$file_name",
-				"owner_id":         "$owner_id",
-				"owner_user_name":  "$owner_details.username",
-				"owner_email":      "$owner_details.email",
-				"parent_folder_id": "$parent_folder_id",
-				"size":             "$size",
-				"mime_type":        "$mime_type",
-				"created_at":       "$created_at",
-				"updated_at":       "$updated_at",
-			},
-		},
-	}
+	pipeline := fileResponsePipeline(bson.M{
+		"parent_folder_id": folderIDHex,
+		"is_deleted":       false,
+		"status":           "uploaded", // The file must be uploaded
+	})
 
 	cursor, err := folderCollection.Aggregate(ctx, pipeline)
 	if err != nil {
@@ -339,9 +385,10 @@ func (fr *FolderRepository) GetFileResponseListInFolder(ctx context.Context, fol
 	return fileResponses, nil
 }
 
+// DeleteFolder moves a folder and its content to the trash
+// The subfolders and files are marked as trashed with the folder, so the trash lists
+// the folder as a single item and restoring it brings back the whole subtree
 func (fr *FolderRepository) DeleteFolder(ctx context.Context, id string) error {
-	collection := fr.database.Collection(fr.collection)
-
 	idHex, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return fmt.Errorf("invalid folder ID")
@@ -357,18 +404,104 @@ func (fr *FolderRepository) DeleteFolder(ctx context.Context, id string) error {
 		return fmt.Errorf("cannot delete root folder")
 	}
 
+	session, err := fr.database.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, trashFolder(sessCtx, fr.database, idHex, models.TrashReasonDeleted, time.Now())
+	}
+
+	_, err = session.WithTransaction(ctx, callback)
+	return err
+}
+
+// trashFolder moves a folder to the trash with its subfolders and files and removes it from the statistics of its parents
+// It must run inside the caller's transaction
+func trashFolder(ctx context.Context, db *mongo.Database, folderID primitive.ObjectID, reason string, now time.Time) error {
+	collection := db.Collection(models.CollectionFolders)
+
+	// Items deleted before keep their own trash entry
+	folderIDs, err := getFolderTreeIDs(ctx, db, folderID, bson.M{"is_deleted": false})
+	if err != nil {
+		return err
+	}
+
 	// Soft delete the folder by setting IsDeleted to true and updating DeletedAt timestamp
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": idHex}, bson.M{
+	folder := &models.Folder{}
+	err = collection.FindOneAndUpdate(ctx, bson.M{"_id": folderID, "is_deleted": false}, bson.M{
+		"$set": trashedFields(ctx, reason, now),
+	}).Decode(folder)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("folder not found or deleted")
+	}
+	if err != nil {
+		return err
+	}
+
+	// The content trashed with the folder stays in its statistics
+	if err := addFolderStats(ctx, db, folder.ParentFolderID, negateStats(folderStats(folder))); err != nil {
+		return err
+	}
+
+	trashedWith := bson.M{
 		"$set": bson.M{
-			"is_deleted": true,
-			"deleted_at": time.Now(),
+			"is_deleted":   true,
+			"deleted_at":   now,
+			"trashed_with": folderID,
 		},
-	})
+	}
+	if _, err := collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": folderIDs[1:]}}, trashedWith); err != nil {
+		return err
+	}
+	if _, err := db.Collection(models.CollectionFiles).UpdateMany(ctx, bson.M{
+		"parent_folder_id": bson.M{"$in": folderIDs},
+		"is_deleted":       false,
+	}, trashedWith); err != nil {
+		return err
+	}
 
-	return err
+	return nil
+}
+
+// getFolderTreeIDs returns the ID of the folder followed by the IDs of its descendants
+// Only the subfolders matching the filter are returned and descended into
+func getFolderTreeIDs(ctx context.Context, db *mongo.Database, folderID primitive.ObjectID, filter bson.M) ([]primitive.ObjectID, error) {
+	collection := db.Collection(models.CollectionFolders)
+
+	// Collect the folder IDs of the subtree level by level
+	folderIDs := []primitive.ObjectID{folderID}
+	level := []primitive.ObjectID{folderID}
+	for len(level) > 0 {
+		levelFilter := bson.M{"parent_folder_id": bson.M{"$in": level}}
+		for key, value := range filter {
+			levelFilter[key] = value
+		}
+
+		var children []*models.Folder
+		cursor, err := collection.Find(ctx, levelFilter, options.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			return nil, err
+		}
+		if err := cursor.All(ctx, &children); err != nil {
+			return nil, err
+		}
+
+		level = []primitive.ObjectID{}
+		for _, child := range children {
+			folderIDs = append(folderIDs, child.ID)
+			level = append(level, child.ID)
+		}
+	}
+
+	return folderIDs, nil
 }
 
-func (fr *FolderRepository) RenameFolder(ctx context.Context, id string, newName string) error {
+// RenameFolder renames a folder
+// A name already used in the parent folder is handled according to the conflict option
+func (fr *FolderRepository) RenameFolder(ctx context.Context, id string, newName string, conflict string) error {
 	collection := fr.database.Collection(fr.collection)
 
 	idHex, err := primitive.ObjectIDFromHex(id)
@@ -386,17 +519,34 @@ func (fr *FolderRepository) RenameFolder(ctx context.Context, id string, newName
 		return fmt.Errorf("cannot rename root folder")
 	}
 
-	// Update the folder name
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": idHex}, bson.M{
-		"$set": bson.M{
-			"name": newName,
-		},
-	})
+	session, err := fr.database.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
 
-	return err
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		name, err := resolveNameConflict(sessCtx, fr.database, fr.collection, folder.ParentFolderID, newName, idHex, conflict)
+		if err != nil {
+			return nil, err
+		}
+
+		// Update the folder name
+		_, err = collection.UpdateOne(sessCtx, bson.M{"_id": idHex}, bson.M{
+			"$set": bson.M{
+				"name": name,
+			},
+		})
+		return nil, err
+	}
+
+	_, err = session.WithTransaction(ctx, callback)
+	return nameConflictError(err)
 }
 
-func (fr *FolderRepository) MoveFolder(ctx context.Context, id string, newParentID string) error {
+// MoveFolder moves a folder to a new parent folder
+// A name already used in the new parent folder is handled according to the conflict option
+func (fr *FolderRepository) MoveFolder(ctx context.Context, id string, newParentID string, conflict string) error {
 	collection := fr.database.Collection(fr.collection)
 	userIDValue := ctx.Value("x-user-id-hex")
 	userID, ok := userIDValue.(primitive.ObjectID)
@@ -422,7 +572,7 @@ func (fr *FolderRepository) MoveFolder(ctx context.Context, id string, newParent
 		return fmt.Errorf("cannot move root folder")
 	}
 	// TODO: Implement sharing functionality later
-	if folder.OwnerID != userID {
+	if folder.OrganizationID == nil && folder.OwnerID != userID {
 		return fmt.Errorf("user does not have permission to move this folder")
 	}
 
@@ -431,20 +581,60 @@ func (fr *FolderRepository) MoveFolder(ctx context.Context, id string, newParent
 	if err != nil {
 		return err
 	}
-	if parentFolder.OwnerID != userID {
+	if !sameOrganization(folder.OrganizationID, parentFolder.OrganizationID) {
+		return models.ErrCrossTenantMove
+	}
+	if parentFolder.OrganizationID == nil && parentFolder.OwnerID != userID {
 		return fmt.Errorf("user does not have permission to move this folder to the new parent folder")
 	}
+	if parentFolder.OrganizationID != nil {
+		allowed, err := hasOrganizationPermission(ctx, fr.database, *parentFolder.OrganizationID, userID, models.PermissionEdit)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return fmt.Errorf("user does not have permission to move this folder to the new parent folder")
+		}
+	}
+	if parentFolder.ID == idHex || slices.Contains(parentFolder.AncestorIDs, idHex) {
+		return fmt.Errorf("cannot move a folder into itself or one of its subfolders")
+	}
 
-	// Update the parent folder ID
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": idHex}, bson.M{
-		"$set": bson.M{
-			"parent_folder_id": newParentIDHex,
-		},
-	})
+	session, err := fr.database.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
 
-	return err
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		name, err := resolveNameConflict(sessCtx, fr.database, fr.collection, newParentIDHex, folder.Name, idHex, conflict)
+		if err != nil {
+			return nil, err
+		}
+
+		// Update the parent folder ID
+		moved := &models.Folder{}
+		err = collection.FindOneAndUpdate(sessCtx, bson.M{"_id": idHex}, bson.M{
+			"$set": bson.M{
+				"parent_folder_id": newParentIDHex,
+				"name":             name,
+			},
+		}).Decode(moved)
+		if err != nil {
+			return nil, err
+		}
+		if err := setFolderAncestors(sessCtx, fr.database, idHex, newParentIDHex); err != nil {
+			return nil, err
+		}
+
+		return nil, moveFolderStats(sessCtx, fr.database, moved.ParentFolderID, newParentIDHex, folderStats(moved))
+	}
+
+	_, err = session.WithTransaction(ctx, callback)
+	return nameConflictError(err)
 }
 
+// SearchFolders searches the folders of the workspace selected by the request by name
 func (fr *FolderRepository) SearchFolders(ctx context.Context, ownerId primitive.ObjectID, query string) ([]*models.Folder, error) {
 	collection := fr.database.Collection(fr.collection)
 	ctx, cancel := context.WithCancel(ctx)
@@ -453,7 +643,7 @@ func (fr *FolderRepository) SearchFolders(ctx context.Context, ownerId primitive
 	// Define the filter for searching folders
 	filter := bson.M{
 		"$and": []bson.M{
-			{"owner_id": ownerId},
+			workspaceFilter(ctx, ownerId),                      // The items of another organization are never matched
 			{"name": bson.M{"$regex": query, "$options": "i"}}, // Case-insensitive regex match
 			{"is_deleted": false},                              // Only include non-deleted folders
 		},
@@ -559,25 +749,23 @@ func (fr *FolderRepository) GetFolderShareInfo(ctx context.Context, folderID str
 	return folder.IsPublic, nil
 }
 
-func (fr *FolderRepository) GetFolderSharedUsers(ctx context.Context, folderID string) ([]*models.FolderSharedUser, error) {
+// GetFolderSharedUsers retrieves the grants of a folder and the grants inherited from its ancestors
+func (fr *FolderRepository) GetFolderSharedUsers(ctx context.Context, folderID string) ([]*models.SharedUserResponse, error) {
 	collection := fr.database.Collection(models.CollectionFolderSharedUsers)
 	folderIDHex, err := primitive.ObjectIDFromHex(folderID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid folder ID")
 	}
 
-	cursor, err := collection.Find(ctx, bson.M{"folder_id": folderIDHex})
+	folder := &models.Folder{}
+	err = fr.database.Collection(fr.collection).FindOne(ctx, bson.M{"_id": folderIDHex}, options.FindOne().SetProjection(bson.M{"ancestor_ids": 1})).Decode(folder)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
-
-	var sharedUsers []*models.FolderSharedUser
-	if err := cursor.All(ctx, &sharedUsers); err != nil {
-		return nil, err
-	}
 
-	return sharedUsers, nil
+	return getSharedUsers(ctx, collection, bson.M{"folder_id": bson.M{"$in": childAncestors(folder)}}, bson.M{
+		"inherited_from": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$folder_id", folderIDHex}}, "$$REMOVE", "$folder_id"}},
+	})
 }
 
 func (fr *FolderRepository) GetFolderSharedUser(ctx context.Context, folderID string, userID string) (*models.FolderSharedUser, error) {
@@ -601,7 +789,7 @@ func (fr *FolderRepository) GetFolderSharedUser(ctx context.Context, folderID st
 	return sharedUser, nil
 }
 
-func (fr *FolderRepository) ShareFolder(ctx context.Context, folderID, userID string, permission bool) error {
+func (fr *FolderRepository) ShareFolder(ctx context.Context, folderID, userID string, role models.ShareRole) error {
 	collection := fr.database.Collection(models.CollectionFolderSharedUsers)
 
 	folderIDHex, err := primitive.ObjectIDFromHex(folderID)
@@ -614,7 +802,7 @@ func (fr *FolderRepository) ShareFolder(ctx context.Context, folderID, userID st
 	}
 
 	_, err = collection.UpdateOne(ctx, bson.M{"folder_id": folderIDHex, "user_id": userIDHex}, bson.M{
-		"$set": bson.M{"permission": permission},
+		"$set": bson.M{"role": role},
 	}, options.Update().SetUpsert(true))
 	return err
 }
@@ -635,9 +823,11 @@ func (fr *FolderRepository) RemoveFolderShare(ctx context.Context, folderID, use
 	return err
 }
 
-func (fr *FolderRepository) ShareFolderAndAllSubfolders(ctx context.Context, folderID, userID string, permission bool) error {
+// ShareFolderAndAllSubfolders shares a folder with a user and gives the same role on the whole subtree
+// The subfolders inherit the grant of the folder. The grants of the user on the subfolders that give no more
+// permissions than the role are removed, the grants giving more permissions are kept
+func (fr *FolderRepository) ShareFolderAndAllSubfolders(ctx context.Context, folderID, userID string, role models.ShareRole) error {
 	collection := fr.database.Collection(models.CollectionFolderSharedUsers)
-	folderCollection := fr.database.Collection(fr.collection)
 
 	folderIDHex, err := primitive.ObjectIDFromHex(folderID)
 	if err != nil {
@@ -648,53 +838,33 @@ func (fr *FolderRepository) ShareFolderAndAllSubfolders(ctx context.Context, fol
 		return fmt.Errorf("invalid user ID")
 	}
 
-	// Collect all folder IDs using BFS
-	queue := []primitive.ObjectID{folderIDHex}
-	var folderIDs []primitive.ObjectID
-
-	for len(queue) > 0 {
-		currentFolderID := queue[0]
-		queue = queue[1:]
-		folderIDs = append(folderIDs, currentFolderID)
-
-		// Find all subfolders
-		cursor, err := folderCollection.Find(ctx, bson.M{"parent_folder_id": currentFolderID})
-		if err != nil {
-			return err
-		}
-		defer cursor.Close(ctx)
-
-		for cursor.Next(ctx) {
-			var subFolder models.Folder
-			if err := cursor.Decode(&subFolder); err != nil {
-				return err
-			}
-			queue = append(queue, subFolder.ID)
-		}
+	descendantIDs, err := getDescendantFolderIDs(ctx, fr.database, folderIDHex)
+	if err != nil {
+		return err
 	}
 
-	// Perform a single bulk update for all folder IDs
-	var operations []mongo.WriteModel
-	for _, folderID := range folderIDs {
-		operations = append(operations, mongo.NewUpdateOneModel().
-			SetFilter(bson.M{"folder_id": folderID, "user_id": userIDHex}).
-			SetUpdate(bson.M{"$set": bson.M{"permission": permission}}).
-			SetUpsert(true))
+	operations := []mongo.WriteModel{
+		mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"folder_id": folderIDHex, "user_id": userIDHex}).
+			SetUpdate(bson.M{"$set": bson.M{"role": role}}).
+			SetUpsert(true),
 	}
-
-	if len(operations) > 0 {
-		_, err = collection.BulkWrite(ctx, operations)
-		if err != nil {
-			return err
-		}
+	if len(descendantIDs) > 0 {
+		operations = append(operations, mongo.NewDeleteManyModel().
+			SetFilter(bson.M{
+				"folder_id": bson.M{"$in": descendantIDs},
+				"user_id":   userIDHex,
+				"role":      bson.M{"$in": role.IncludedRoles()},
+			}))
 	}
 
-	return nil
+	_, err = collection.BulkWrite(ctx, operations)
+	return err
 }
 
+// RevokeFolderAndAllSubfoldersShare removes the grants of a user on a folder and on its subfolders
 func (fr *FolderRepository) RevokeFolderAndAllSubfoldersShare(ctx context.Context, folderID, userID string) error {
 	collection := fr.database.Collection(models.CollectionFolderSharedUsers)
-	folderCollection := fr.database.Collection(fr.collection)
 
 	folderIDHex, err := primitive.ObjectIDFromHex(folderID)
 	if err != nil {
@@ -705,36 +875,148 @@ func (fr *FolderRepository) RevokeFolderAndAllSubfoldersShare(ctx context.Contex
 		return fmt.Errorf("invalid user ID")
 	}
 
-	// Collect all folder IDs using BFS
-	queue := []primitive.ObjectID{folderIDHex}
-	var folderIDs []primitive.ObjectID
+	descendantIDs, err := getDescendantFolderIDs(ctx, fr.database, folderIDHex)
+	if err != nil {
+		return err
+	}
 
-	for len(queue) > 0 {
-		currentFolderID := queue[0]
-		queue = queue[1:]
-		folderIDs = append(folderIDs, currentFolderID)
+	// Perform a single delete operation for all folder IDs
+	folderIDs := append(descendantIDs, folderIDHex)
+	_, err = collection.DeleteMany(ctx, bson.M{"folder_id": bson.M{"$in": folderIDs}, "user_id": userIDHex})
+	return err
+}
 
-		// Find all subfolders
-		cursor, err := folderCollection.Find(ctx, bson.M{"parent_folder_id": currentFolderID})
-		if err != nil {
-			return err
-		}
-		defer cursor.Close(ctx)
+// GetInheritedFolderShares retrieves the grants of a user on a folder and on its ancestors
+// The grants of the ancestors apply to the whole subtree, including the folders created after the share
+func (fr *FolderRepository) GetInheritedFolderShares(ctx context.Context, folder *models.Folder, userID string) ([]*models.FolderSharedUser, error) {
+	collection := fr.database.Collection(models.CollectionFolderSharedUsers)
 
-		for cursor.Next(ctx) {
-			var subFolder models.Folder
-			if err := cursor.Decode(&subFolder); err != nil {
-				return err
-			}
-			queue = append(queue, subFolder.ID)
-		}
+	userIDHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID")
 	}
 
-	// Perform a single delete operation for all folder IDs
-	_, err = collection.DeleteMany(ctx, bson.M{"folder_id": bson.M{"$in": folderIDs}, "user_id": userIDHex})
+	cursor, err := collection.Find(ctx, bson.M{
+		"folder_id": bson.M{"$in": childAncestors(folder)},
+		"user_id":   userIDHex,
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer cursor.Close(ctx)
 
-	return nil
+	sharedUsers := []*models.FolderSharedUser{}
+	if err := cursor.All(ctx, &sharedUsers); err != nil {
+		return nil, err
+	}
+
+	return sharedUsers, nil
+}
+
+// GetFolderSharedGroups retrieves the group grants of a folder and the group grants inherited from its ancestors
+func (fr *FolderRepository) GetFolderSharedGroups(ctx context.Context, folderID string) ([]*models.SharedGroupResponse, error) {
+	collection := fr.database.Collection(models.CollectionFolderSharedGroups)
+	folderIDHex, err := primitive.ObjectIDFromHex(folderID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid folder ID")
+	}
+
+	folder := &models.Folder{}
+	err = fr.database.Collection(fr.collection).FindOne(ctx, bson.M{"_id": folderIDHex}, options.FindOne().SetProjection(bson.M{"ancestor_ids": 1})).Decode(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	return getSharedGroups(ctx, collection, bson.M{"folder_id": bson.M{"$in": childAncestors(folder)}}, bson.M{
+		"inherited_from": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$folder_id", folderIDHex}}, "$$REMOVE", "$folder_id"}},
+	})
+}
+
+// GetInheritedFolderGroupShares retrieves the grants of the groups of a user on a folder and on its ancestors
+func (fr *FolderRepository) GetInheritedFolderGroupShares(ctx context.Context, folder *models.Folder, userID string) ([]*models.FolderSharedGroup, error) {
+	collection := fr.database.Collection(models.CollectionFolderSharedGroups)
+
+	userIDHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID")
+	}
+
+	sharedGroups := []*models.FolderSharedGroup{}
+	groupIDs, err := getUserGroupIDs(ctx, fr.database, userIDHex)
+	if err != nil || len(groupIDs) == 0 {
+		return sharedGroups, err
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{
+		"folder_id": bson.M{"$in": childAncestors(folder)},
+		"group_id":  bson.M{"$in": groupIDs},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &sharedGroups); err != nil {
+		return nil, err
+	}
+
+	return sharedGroups, nil
+}
+
+// ShareFolderWithGroup gives a role on a folder to the members of a group, replacing the role the group had on it
+func (fr *FolderRepository) ShareFolderWithGroup(ctx context.Context, folderID, groupID string, role models.ShareRole) error {
+	collection := fr.database.Collection(models.CollectionFolderSharedGroups)
+
+	folderIDHex, err := primitive.ObjectIDFromHex(folderID)
+	if err != nil {
+		return fmt.Errorf("invalid folder ID")
+	}
+	groupIDHex, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return fmt.Errorf("invalid group ID")
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"folder_id": folderIDHex, "group_id": groupIDHex}, bson.M{
+		"$set": bson.M{"role": role},
+	}, options.Update().SetUpsert(true))
+	return err
+}
+
+func (fr *FolderRepository) RemoveFolderGroupShare(ctx context.Context, folderID, groupID string) error {
+	collection := fr.database.Collection(models.CollectionFolderSharedGroups)
+
+	folderIDHex, err := primitive.ObjectIDFromHex(folderID)
+	if err != nil {
+		return fmt.Errorf("invalid folder ID")
+	}
+	groupIDHex, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return fmt.Errorf("invalid group ID")
+	}
+
+	_, err = collection.DeleteOne(ctx, bson.M{"folder_id": folderIDHex, "group_id": groupIDHex})
+	return err
+}
+
+// GetOrganizationFolderRole retrieves the role the membership of a user gives on a folder of a shared drive
+// The role is empty when the folder is in a personal drive or the user is not a member of the organization
+func (fr *FolderRepository) GetOrganizationFolderRole(ctx context.Context, folder *models.Folder, userID string) (models.ShareRole, error) {
+	if folder.OrganizationID == nil {
+		return "", nil
+	}
+
+	userIDHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return "", fmt.Errorf("invalid user ID")
+	}
+
+	member, err := getOrganizationMember(ctx, fr.database, *folder.OrganizationID, userIDHex)
+	if errors.Is(err, models.ErrOrganizationMemberNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return member.ShareRole(), nil
 }
@@ -0,0 +1,249 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type FolderStatsRepository struct {
+	database *mongo.Database
+}
+
+func NewFolderStatsRepository(db *mongo.Database) *FolderStatsRepository {
+	return &FolderStatsRepository{
+		database: db,
+	}
+}
+
+// fileStats returns the statistics a file adds to its folder, the files not uploaded yet are not counted
+func fileStats(file *models.File) models.FolderStat {
+	if file.Status != "uploaded" {
+		return models.FolderStat{}
+	}
+
+	return models.FolderStat{TotalFiles: 1, TotalSize: file.Size}
+}
+
+// folderStats returns the statistics a folder adds to its parent: its content and itself
+func folderStats(folder *models.Folder) models.FolderStat {
+	return models.FolderStat{
+		TotalFiles:   folder.Stats.TotalFiles,
+		TotalFolders: folder.Stats.TotalFolders + 1,
+		TotalSize:    folder.Stats.TotalSize,
+	}
+}
+
+// negateStats returns the statistics to add to remove the given ones
+func negateStats(stats models.FolderStat) models.FolderStat {
+	return models.FolderStat{
+		TotalFiles:   -stats.TotalFiles,
+		TotalFolders: -stats.TotalFolders,
+		TotalSize:    -stats.TotalSize,
+	}
+}
+
+// countsInParent checks if an item counts in the statistics of its parent folder
+// An item trashed on its own does not, the items trashed with a folder still count in that folder
+func countsInParent(isDeleted bool, trashedWith primitive.ObjectID) bool {
+	return !isDeleted || !trashedWith.IsZero()
+}
+
+// addFolderStats adds the delta to the statistics of the folder and of the ancestors counting it
+// It must run inside the caller's transaction
+func addFolderStats(ctx context.Context, db *mongo.Database, folderID primitive.ObjectID, delta models.FolderStat) error {
+	if delta == (models.FolderStat{}) {
+		return nil
+	}
+
+	collection := db.Collection(models.CollectionFolders)
+
+	folderIDs := []primitive.ObjectID{}
+	visited := map[primitive.ObjectID]bool{}
+	for id := folderID; !id.IsZero() && !visited[id]; {
+		visited[id] = true
+
+		folder := &models.Folder{}
+		err := collection.FindOne(ctx, bson.M{"_id": id}, options.FindOne().SetProjection(bson.M{
+			"parent_folder_id": 1,
+			"is_deleted":       1,
+			"trashed_with":     1,
+		})).Decode(folder)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		folderIDs = append(folderIDs, id)
+		if !countsInParent(folder.IsDeleted, folder.TrashedWith) {
+			break
+		}
+		id = folder.ParentFolderID
+	}
+	if len(folderIDs) == 0 {
+		return nil
+	}
+
+	_, err := collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": folderIDs}}, bson.M{
+		"$inc": bson.M{
+			"stats.total_files":   delta.TotalFiles,
+			"stats.total_folders": delta.TotalFolders,
+			"stats.total_size":    delta.TotalSize,
+		},
+	})
+	return err
+}
+
+// moveFolderStats moves the statistics of an item from its old folder to its new folder
+// It must run inside the caller's transaction
+func moveFolderStats(ctx context.Context, db *mongo.Database, oldFolderID primitive.ObjectID, newFolderID primitive.ObjectID, stats models.FolderStat) error {
+	if oldFolderID == newFolderID {
+		return nil
+	}
+	if err := addFolderStats(ctx, db, oldFolderID, negateStats(stats)); err != nil {
+		return err
+	}
+
+	return addFolderStats(ctx, db, newFolderID, stats)
+}
+
+// GetRootFolderIDs retrieves up to limit root folder IDs, ordered by ID after afterID
+func (fsr *FolderStatsRepository) GetRootFolderIDs(ctx context.Context, afterID primitive.ObjectID, limit int64) ([]primitive.ObjectID, error) {
+	findOptions := options.Find().SetSort(bson.M{"_id": 1}).SetLimit(limit).SetProjection(bson.M{"_id": 1})
+	cursor, err := fsr.database.Collection(models.CollectionFolders).Find(ctx, bson.M{
+		"is_root": true,
+		"_id":     bson.M{"$gt": afterID},
+	}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var folders []*models.Folder
+	if err := cursor.All(ctx, &folders); err != nil {
+		return nil, err
+	}
+
+	folderIDs := []primitive.ObjectID{}
+	for _, folder := range folders {
+		folderIDs = append(folderIDs, folder.ID)
+	}
+
+	return folderIDs, nil
+}
+
+// RecomputeFolderStats recomputes the statistics of every folder of a tree from its files and folders
+// The trashed folders are recomputed too. The update is not atomic, the repair is meant to run while the tree is not changed.
+func (fsr *FolderStatsRepository) RecomputeFolderStats(ctx context.Context, rootFolderID primitive.ObjectID) (int, error) {
+	folderCollection := fsr.database.Collection(models.CollectionFolders)
+
+	folderIDs, err := getFolderTreeIDs(ctx, fsr.database, rootFolderID, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+
+	cursor, err := folderCollection.Find(ctx, bson.M{"_id": bson.M{"$in": folderIDs}}, options.Find().SetProjection(bson.M{
+		"parent_folder_id": 1,
+		"is_deleted":       1,
+		"trashed_with":     1,
+	}))
+	if err != nil {
+		return 0, err
+	}
+	var found []*models.Folder
+	if err := cursor.All(ctx, &found); err != nil {
+		return 0, err
+	}
+	folders := map[primitive.ObjectID]*models.Folder{}
+	for _, folder := range found {
+		folders[folder.ID] = folder
+	}
+
+	// Sum the uploaded files counting in each folder
+	cursor, err = fsr.database.Collection(models.CollectionFiles).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"parent_folder_id": bson.M{"$in": folderIDs},
+			"status":           "uploaded",
+			"$or": bson.A{
+				bson.M{"is_deleted": false},
+				bson.M{"trashed_with": bson.M{"$exists": true}},
+			},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         "$parent_folder_id",
+			"total_files": bson.M{"$sum": 1},
+			"total_size":  bson.M{"$sum": "$size"},
+		}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	var sums []struct {
+		FolderID   primitive.ObjectID `bson:"_id"`
+		TotalFiles int                `bson:"total_files"`
+		TotalSize  int64              `bson:"total_size"`
+	}
+	if err := cursor.All(ctx, &sums); err != nil {
+		return 0, err
+	}
+	fileTotals := map[primitive.ObjectID]models.FolderStat{}
+	for _, sum := range sums {
+		fileTotals[sum.FolderID] = models.FolderStat{TotalFiles: sum.TotalFiles, TotalSize: sum.TotalSize}
+	}
+
+	stats := sumFolderStats(folderIDs, folders, fileTotals)
+
+	writes := []mongo.WriteModel{}
+	for _, folderID := range folderIDs {
+		if _, ok := folders[folderID]; !ok {
+			continue
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": folderID}).
+			SetUpdate(bson.M{"$set": bson.M{"stats": stats[folderID]}}))
+	}
+	if len(writes) == 0 {
+		return 0, nil
+	}
+	if _, err := folderCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return 0, err
+	}
+
+	return len(writes), nil
+}
+
+// sumFolderStats adds up the statistics of a folder tree
+// The folder IDs must be ordered with the parents first, fileTotals holds the files counting in each folder
+func sumFolderStats(folderIDs []primitive.ObjectID, folders map[primitive.ObjectID]*models.Folder, fileTotals map[primitive.ObjectID]models.FolderStat) map[primitive.ObjectID]models.FolderStat {
+	stats := map[primitive.ObjectID]models.FolderStat{}
+	for _, folderID := range folderIDs {
+		stats[folderID] = fileTotals[folderID]
+	}
+
+	// The children are added to their parent before the parent is added to its own parent
+	for i := len(folderIDs) - 1; i > 0; i-- {
+		folder, ok := folders[folderIDs[i]]
+		if !ok || !countsInParent(folder.IsDeleted, folder.TrashedWith) {
+			continue
+		}
+		parentStats, ok := stats[folder.ParentFolderID]
+		if !ok {
+			continue
+		}
+
+		childStats := folderStats(&models.Folder{Stats: stats[folder.ID]})
+		parentStats.TotalFiles += childStats.TotalFiles
+		parentStats.TotalFolders += childStats.TotalFolders
+		parentStats.TotalSize += childStats.TotalSize
+		stats[folder.ParentFolderID] = parentStats
+	}
+
+	return stats
+}
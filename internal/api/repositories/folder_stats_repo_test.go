@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"testing"
+
+	"skybox-backend/internal/api/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSumFolderStats(t *testing.T) {
+	root := &models.Folder{ID: primitive.NewObjectID()}
+	child := &models.Folder{ID: primitive.NewObjectID(), ParentFolderID: root.ID}
+	nested := &models.Folder{ID: primitive.NewObjectID(), ParentFolderID: child.ID}
+	trashed := &models.Folder{ID: primitive.NewObjectID(), ParentFolderID: root.ID, IsDeleted: true}
+	trashedWith := &models.Folder{ID: primitive.NewObjectID(), ParentFolderID: trashed.ID, IsDeleted: true, TrashedWith: trashed.ID}
+
+	folderIDs := []primitive.ObjectID{root.ID, child.ID, trashed.ID, nested.ID, trashedWith.ID}
+	folders := map[primitive.ObjectID]*models.Folder{}
+	for _, folder := range []*models.Folder{root, child, nested, trashed, trashedWith} {
+		folders[folder.ID] = folder
+	}
+	fileTotals := map[primitive.ObjectID]models.FolderStat{
+		root.ID:        {TotalFiles: 1, TotalSize: 10},
+		nested.ID:      {TotalFiles: 2, TotalSize: 20},
+		trashedWith.ID: {TotalFiles: 1, TotalSize: 5},
+	}
+
+	stats := sumFolderStats(folderIDs, folders, fileTotals)
+	assert.Equal(t, models.FolderStat{TotalFiles: 2, TotalFolders: 1, TotalSize: 20}, stats[child.ID])
+	assert.Equal(t, models.FolderStat{TotalFiles: 3, TotalFolders: 2, TotalSize: 30}, stats[root.ID])
+
+	// The trashed folder keeps the content trashed with it, but no longer counts in the root folder
+	assert.Equal(t, models.FolderStat{TotalFiles: 1, TotalFolders: 1, TotalSize: 5}, stats[trashed.ID])
+}
@@ -0,0 +1,348 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type GroupRepository struct {
+	database   *mongo.Database
+	collection string
+}
+
+// NewGroupRepository creates a new instance of the GroupRepository
+func NewGroupRepository(db *mongo.Database, collection string) *GroupRepository {
+	return &GroupRepository{
+		database:   db,
+		collection: collection,
+	}
+}
+
+// CreateGroup saves a group with its creator as the first admin
+func (gr *GroupRepository) CreateGroup(ctx context.Context, group *models.Group) (*models.Group, error) {
+	session, err := gr.database.Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		result, err := gr.database.Collection(gr.collection).InsertOne(sessCtx, group)
+		if err != nil {
+			return nil, err
+		}
+		group.ID = result.InsertedID.(primitive.ObjectID)
+
+		_, err = gr.database.Collection(models.CollectionGroupMembers).InsertOne(sessCtx, &models.GroupMember{
+			GroupID: group.ID,
+			UserID:  group.CreatedBy,
+			Role:    models.GroupRoleAdmin,
+			AddedAt: group.CreatedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return group, nil
+	}
+
+	result, err := session.WithTransaction(ctx, callback)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*models.Group), nil
+}
+
+// GetGroupByID retrieves a group by ID
+func (gr *GroupRepository) GetGroupByID(ctx context.Context, id primitive.ObjectID) (*models.Group, error) {
+	group := &models.Group{}
+	err := gr.database.Collection(gr.collection).FindOne(ctx, bson.M{"_id": id}).Decode(group)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// GetGroupsByUser retrieves the groups the user is a member of with the role of the user, ordered by name
+func (gr *GroupRepository) GetGroupsByUser(ctx context.Context, userID primitive.ObjectID) ([]*models.GroupResponse, error) {
+	pipeline := []bson.M{
+		{
+			"$match": bson.M{"user_id": userID},
+		},
+		{
+			"$lookup": bson.M{
+				"from":         gr.collection,
+				"localField":   "group_id",
+				"foreignField": "_id",
+				"as":           "group",
+			},
+		},
+		{
+			"$unwind": "$group",
+		},
+		{
+			"$lookup": bson.M{
+				"from": models.CollectionGroupMembers,
+				"let":  bson.M{"group_id": "$group_id"},
+				"pipeline": bson.A{
+					bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$group_id", "$$group_id"}}}},
+					bson.M{"$count": "count"},
+				},
+				"as": "members",
+			},
+		},
+		{
+			"$project": bson.M{
+				"_id":          "$group._id",
+				"name":         "$group.name",
+				"description":  "$group.description",
+				"role":         "$role",
+				"member_count": bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$members.count", 0}}, 0}},
+				"created_at":   "$group.created_at",
+				"updated_at":   "$group.updated_at",
+			},
+		},
+		{
+			"$sort": bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}},
+		},
+	}
+
+	cursor, err := gr.database.Collection(models.CollectionGroupMembers).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	groups := []*models.GroupResponse{}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// UpdateGroup saves the name and the description of a group
+func (gr *GroupRepository) UpdateGroup(ctx context.Context, group *models.Group) error {
+	result, err := gr.database.Collection(gr.collection).UpdateOne(ctx, bson.M{"_id": group.ID}, bson.M{
+		"$set": bson.M{
+			"name":        group.Name,
+			"description": group.Description,
+			"updated_at":  group.UpdatedAt,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return models.ErrGroupNotFound
+	}
+
+	return nil
+}
+
+// DeleteGroup deletes a group, its members and the grants given to it
+// The members lose the access the group gave them
+func (gr *GroupRepository) DeleteGroup(ctx context.Context, id primitive.ObjectID) error {
+	session, err := gr.database.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		result, err := gr.database.Collection(gr.collection).DeleteOne(sessCtx, bson.M{"_id": id})
+		if err != nil {
+			return nil, err
+		}
+		if result.DeletedCount == 0 {
+			return nil, models.ErrGroupNotFound
+		}
+
+		for _, collection := range []string{models.CollectionGroupMembers, models.CollectionFolderSharedGroups, models.CollectionFileSharedGroups} {
+			if _, err := gr.database.Collection(collection).DeleteMany(sessCtx, bson.M{"group_id": id}); err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, nil
+	}
+
+	_, err = session.WithTransaction(ctx, callback)
+	return err
+}
+
+// GetGroupMember retrieves the membership of a user in a group
+func (gr *GroupRepository) GetGroupMember(ctx context.Context, groupID primitive.ObjectID, userID primitive.ObjectID) (*models.GroupMember, error) {
+	member := &models.GroupMember{}
+	err := gr.database.Collection(models.CollectionGroupMembers).FindOne(ctx, bson.M{"group_id": groupID, "user_id": userID}).Decode(member)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrGroupMemberNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// GetGroupMembers retrieves the members of a group with their username and email, in the order they were added
+func (gr *GroupRepository) GetGroupMembers(ctx context.Context, groupID primitive.ObjectID) ([]*models.GroupMemberResponse, error) {
+	pipeline := []bson.M{
+		{
+			"$match": bson.M{"group_id": groupID},
+		},
+		{
+			"$sort": bson.D{{Key: "added_at", Value: 1}, {Key: "_id", Value: 1}},
+		},
+		{
+			"$lookup": bson.M{
+				"from":         models.CollectionUsers,
+				"localField":   "user_id",
+				"foreignField": "_id",
+				"as":           "user_details",
+			},
+		},
+		{
+			"$unwind": bson.M{
+				"path":                       "$user_details",
+				"preserveNullAndEmptyArrays": true,
+			},
+		},
+		{
+			"$project": bson.M{
+				"user_id":  "$user_id",
+				"username": "$user_details.username",
+				"email":    "$user_details.email",
+				"role":     "$role",
+				"added_at": "$added_at",
+			},
+		},
+	}
+
+	cursor, err := gr.database.Collection(models.CollectionGroupMembers).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	members := []*models.GroupMemberResponse{}
+	if err := cursor.All(ctx, &members); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// SetGroupMember adds a user to a group or changes the role of a member
+// The last admin of a group cannot be demoted
+func (gr *GroupRepository) SetGroupMember(ctx context.Context, member *models.GroupMember) error {
+	session, err := gr.database.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		if member.Role != models.GroupRoleAdmin {
+			if err := checkLastGroupAdmin(sessCtx, gr.database, member.GroupID, member.UserID); err != nil {
+				return nil, err
+			}
+		}
+
+		_, err := gr.database.Collection(models.CollectionGroupMembers).UpdateOne(sessCtx,
+			bson.M{"group_id": member.GroupID, "user_id": member.UserID},
+			bson.M{
+				"$set":         bson.M{"role": member.Role},
+				"$setOnInsert": bson.M{"added_at": member.AddedAt},
+			},
+			options.Update().SetUpsert(true),
+		)
+		return nil, err
+	}
+
+	_, err = session.WithTransaction(ctx, callback)
+	return err
+}
+
+// RemoveGroupMember removes a user from a group, the grants of the group no longer apply to the user
+// The last admin of a group cannot be removed, the group must be deleted instead
+func (gr *GroupRepository) RemoveGroupMember(ctx context.Context, groupID primitive.ObjectID, userID primitive.ObjectID) error {
+	session, err := gr.database.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		if err := checkLastGroupAdmin(sessCtx, gr.database, groupID, userID); err != nil {
+			return nil, err
+		}
+
+		result, err := gr.database.Collection(models.CollectionGroupMembers).DeleteOne(sessCtx, bson.M{"group_id": groupID, "user_id": userID})
+		if err != nil {
+			return nil, err
+		}
+		if result.DeletedCount == 0 {
+			return nil, models.ErrGroupMemberNotFound
+		}
+
+		return nil, nil
+	}
+
+	_, err = session.WithTransaction(ctx, callback)
+	return err
+}
+
+// checkLastGroupAdmin fails when the user is the only admin of the group
+// It must run inside the caller's transaction
+func checkLastGroupAdmin(ctx context.Context, db *mongo.Database, groupID primitive.ObjectID, userID primitive.ObjectID) error {
+	collection := db.Collection(models.CollectionGroupMembers)
+
+	isAdmin, err := collection.CountDocuments(ctx, bson.M{"group_id": groupID, "user_id": userID, "role": models.GroupRoleAdmin})
+	if err != nil {
+		return err
+	}
+	if isAdmin == 0 {
+		return nil
+	}
+
+	admins, err := collection.CountDocuments(ctx, bson.M{"group_id": groupID, "role": models.GroupRoleAdmin})
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return models.ErrGroupLastAdmin
+	}
+
+	return nil
+}
+
+// getUserGroupIDs retrieves the IDs of the groups the user is a member of
+// The memberships are read on every permission check, a user who leaves a group loses its grants at once
+func getUserGroupIDs(ctx context.Context, db *mongo.Database, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	values, err := db.Collection(models.CollectionGroupMembers).Distinct(ctx, "group_id", bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+
+	groupIDs := make([]primitive.ObjectID, 0, len(values))
+	for _, value := range values {
+		if groupID, ok := value.(primitive.ObjectID); ok {
+			groupIDs = append(groupIDs, groupID)
+		}
+	}
+
+	return groupIDs, nil
+}
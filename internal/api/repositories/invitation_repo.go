@@ -0,0 +1,189 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type InvitationRepository struct {
+	database   *mongo.Database
+	collection string
+}
+
+// NewInvitationRepository creates a new instance of the InvitationRepository
+func NewInvitationRepository(db *mongo.Database, collection string) *InvitationRepository {
+	return &InvitationRepository{
+		database:   db,
+		collection: collection,
+	}
+}
+
+// CreateInvitation saves a pending invitation
+// Inviting an email again to the same item replaces the role, the token and the expiry of its pending invitation
+func (ir *InvitationRepository) CreateInvitation(ctx context.Context, invitation *models.ShareInvitation) (*models.ShareInvitation, error) {
+	saved := &models.ShareInvitation{}
+	err := ir.database.Collection(ir.collection).FindOneAndUpdate(ctx,
+		bson.M{
+			"email":     invitation.Email,
+			"item_type": invitation.ItemType,
+			"item_id":   invitation.ItemID,
+			"status":    models.InvitationPending,
+		},
+		bson.M{
+			"$set": bson.M{
+				"role":       invitation.Role,
+				"token_hash": invitation.TokenHash,
+				"invited_by": invitation.InvitedBy,
+				"created_at": invitation.CreatedAt,
+				"expires_at": invitation.ExpiresAt,
+			},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(saved)
+	if err != nil {
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+// GetInvitationByID retrieves a pending invitation by ID
+func (ir *InvitationRepository) GetInvitationByID(ctx context.Context, id primitive.ObjectID) (*models.ShareInvitation, error) {
+	invitation := &models.ShareInvitation{}
+	err := ir.database.Collection(ir.collection).FindOne(ctx, bson.M{"_id": id, "status": models.InvitationPending}).Decode(invitation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrInvitationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// GetPendingInvitationsByItem retrieves the pending invitations of a file or a folder, newest first
+// The expired invitations are listed too, so they can be sent again or deleted
+func (ir *InvitationRepository) GetPendingInvitationsByItem(ctx context.Context, itemType string, itemID primitive.ObjectID) ([]*models.ShareInvitation, error) {
+	cursor, err := ir.database.Collection(ir.collection).Find(ctx,
+		bson.M{"item_type": itemType, "item_id": itemID, "status": models.InvitationPending},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations := []*models.ShareInvitation{}
+	if err := cursor.All(ctx, &invitations); err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+// DeleteInvitation deletes a pending invitation, the email can no longer accept it
+func (ir *InvitationRepository) DeleteInvitation(ctx context.Context, id primitive.ObjectID) error {
+	result, err := ir.database.Collection(ir.collection).DeleteOne(ctx, bson.M{"_id": id, "status": models.InvitationPending})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return models.ErrInvitationNotFound
+	}
+
+	return nil
+}
+
+// AcceptInvitations turns the pending invitations of an email into grants of the user of the email
+// The token hash must match a pending invitation of the email: its token was only sent to the address, so the user
+// receives the emails of the address and all its invitations are accepted. A used token cannot be given again.
+// The expired invitations are left pending, they are not accepted. It returns models.ErrInvitationNotFound
+// when the token does not match
+func (ir *InvitationRepository) AcceptInvitations(ctx context.Context, email string, tokenHash string, userID primitive.ObjectID, now time.Time) ([]*models.ShareInvitation, error) {
+	session, err := ir.database.Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		collection := ir.database.Collection(ir.collection)
+
+		count, err := collection.CountDocuments(sessCtx, bson.M{
+			"email":      email,
+			"token_hash": tokenHash,
+			"status":     models.InvitationPending,
+			"expires_at": bson.M{"$gt": now},
+		}, options.Count().SetLimit(1))
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, models.ErrInvitationNotFound
+		}
+
+		cursor, err := collection.Find(sessCtx, bson.M{
+			"email":      email,
+			"status":     models.InvitationPending,
+			"expires_at": bson.M{"$gt": now},
+		})
+		if err != nil {
+			return nil, err
+		}
+		invitations := []*models.ShareInvitation{}
+		if err := cursor.All(sessCtx, &invitations); err != nil {
+			return nil, err
+		}
+
+		invitationIDs := []primitive.ObjectID{}
+		for _, invitation := range invitations {
+			if err := upsertGrant(sessCtx, ir.database, invitation.ItemType, invitation.ItemID, userID, invitation.Role); err != nil {
+				return nil, err
+			}
+			invitation.Status = models.InvitationAccepted
+			invitation.AcceptedAt = &now
+			invitation.AcceptedBy = userID
+			invitationIDs = append(invitationIDs, invitation.ID)
+		}
+		if len(invitationIDs) == 0 {
+			return invitations, nil
+		}
+
+		_, err = collection.UpdateMany(sessCtx, bson.M{"_id": bson.M{"$in": invitationIDs}}, bson.M{
+			"$set": bson.M{
+				"status":      models.InvitationAccepted,
+				"accepted_at": now,
+				"accepted_by": userID,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return invitations, nil
+	}
+
+	result, err := session.WithTransaction(ctx, callback)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]*models.ShareInvitation), nil
+}
+
+// deleteShareInvitations deletes the invitations of purged files or folders
+// It must run inside the caller's transaction
+func deleteShareInvitations(ctx context.Context, db *mongo.Database, itemType string, itemIDs []primitive.ObjectID) error {
+	_, err := db.Collection(models.CollectionShareInvitations).DeleteMany(ctx, bson.M{
+		"item_type": itemType,
+		"item_id":   bson.M{"$in": itemIDs},
+	})
+	return err
+}
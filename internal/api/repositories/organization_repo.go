@@ -0,0 +1,394 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OrganizationRepository struct {
+	database   *mongo.Database
+	collection string
+}
+
+// NewOrganizationRepository creates a new instance of the OrganizationRepository
+func NewOrganizationRepository(db *mongo.Database, collection string) *OrganizationRepository {
+	return &OrganizationRepository{
+		database:   db,
+		collection: collection,
+	}
+}
+
+// CreateOrganization saves an organization with its creator as the first admin
+func (or *OrganizationRepository) CreateOrganization(ctx context.Context, organization *models.Organization) (*models.Organization, error) {
+	session, err := or.database.Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		result, err := or.database.Collection(or.collection).InsertOne(sessCtx, organization)
+		if err != nil {
+			return nil, err
+		}
+		organization.ID = result.InsertedID.(primitive.ObjectID)
+
+		_, err = or.database.Collection(models.CollectionOrganizationMembers).InsertOne(sessCtx, &models.OrganizationMember{
+			OrganizationID: organization.ID,
+			UserID:         organization.CreatedBy,
+			Role:           models.OrganizationRoleAdmin,
+			AddedAt:        organization.CreatedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return organization, nil
+	}
+
+	result, err := session.WithTransaction(ctx, callback)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*models.Organization), nil
+}
+
+// GetOrganizationByID retrieves an organization by ID
+func (or *OrganizationRepository) GetOrganizationByID(ctx context.Context, id primitive.ObjectID) (*models.Organization, error) {
+	organization := &models.Organization{}
+	err := or.database.Collection(or.collection).FindOne(ctx, bson.M{"_id": id}).Decode(organization)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrOrganizationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return organization, nil
+}
+
+// GetOrganizationsByUser retrieves the organizations the user is a member of with the role of the user, ordered by name
+// The storage quota is the quota saved on the organization, the service resolves the default quota
+func (or *OrganizationRepository) GetOrganizationsByUser(ctx context.Context, userID primitive.ObjectID) ([]*models.OrganizationResponse, error) {
+	pipeline := []bson.M{
+		{
+			"$match": bson.M{"user_id": userID},
+		},
+		{
+			"$lookup": bson.M{
+				"from":         or.collection,
+				"localField":   "organization_id",
+				"foreignField": "_id",
+				"as":           "organization",
+			},
+		},
+		{
+			"$unwind": "$organization",
+		},
+		{
+			"$lookup": bson.M{
+				"from": models.CollectionOrganizationMembers,
+				"let":  bson.M{"organization_id": "$organization_id"},
+				"pipeline": bson.A{
+					bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$organization_id", "$$organization_id"}}}},
+					bson.M{"$count": "count"},
+				},
+				"as": "members",
+			},
+		},
+		{
+			"$project": bson.M{
+				"_id":           "$organization._id",
+				"name":          "$organization.name",
+				"role":          "$role",
+				"member_count":  bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$members.count", 0}}, 0}},
+				"storage_quota": bson.M{"$ifNull": bson.A{"$organization.storage_quota", 0}},
+				"storage_used":  "$organization.storage_used",
+				"created_at":    "$organization.created_at",
+				"updated_at":    "$organization.updated_at",
+			},
+		},
+		{
+			"$sort": bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}},
+		},
+	}
+
+	cursor, err := or.database.Collection(models.CollectionOrganizationMembers).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	organizations := []*models.OrganizationResponse{}
+	if err := cursor.All(ctx, &organizations); err != nil {
+		return nil, err
+	}
+
+	return organizations, nil
+}
+
+// UpdateOrganization saves the name of an organization
+func (or *OrganizationRepository) UpdateOrganization(ctx context.Context, organization *models.Organization) error {
+	result, err := or.database.Collection(or.collection).UpdateOne(ctx, bson.M{"_id": organization.ID}, bson.M{
+		"$set": bson.M{
+			"name":       organization.Name,
+			"updated_at": organization.UpdatedAt,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return models.ErrOrganizationNotFound
+	}
+
+	return nil
+}
+
+// GetOrganizationMember retrieves the membership of a user in an organization
+func (or *OrganizationRepository) GetOrganizationMember(ctx context.Context, organizationID primitive.ObjectID, userID primitive.ObjectID) (*models.OrganizationMember, error) {
+	return getOrganizationMember(ctx, or.database, organizationID, userID)
+}
+
+// getOrganizationMember retrieves the membership of a user in an organization
+func getOrganizationMember(ctx context.Context, db *mongo.Database, organizationID primitive.ObjectID, userID primitive.ObjectID) (*models.OrganizationMember, error) {
+	member := &models.OrganizationMember{}
+	err := db.Collection(models.CollectionOrganizationMembers).FindOne(ctx, bson.M{"organization_id": organizationID, "user_id": userID}).Decode(member)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrOrganizationMemberNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// GetOrganizationMembers retrieves the members of an organization with their username and email, in the order they were added
+func (or *OrganizationRepository) GetOrganizationMembers(ctx context.Context, organizationID primitive.ObjectID) ([]*models.OrganizationMemberResponse, error) {
+	pipeline := []bson.M{
+		{
+			"$match": bson.M{"organization_id": organizationID},
+		},
+		{
+			"$sort": bson.D{{Key: "added_at", Value: 1}, {Key: "_id", Value: 1}},
+		},
+		{
+			"$lookup": bson.M{
+				"from":         models.CollectionUsers,
+				"localField":   "user_id",
+				"foreignField": "_id",
+				"as":           "user_details",
+			},
+		},
+		{
+			"$unwind": bson.M{
+				"path":                       "$user_details",
+				"preserveNullAndEmptyArrays": true,
+			},
+		},
+		{
+			"$project": bson.M{
+				"user_id":  "$user_id",
+				"username": "$user_details.username",
+				"email":    "$user_details.email",
+				"role":     "$role",
+				"added_at": "$added_at",
+			},
+		},
+	}
+
+	cursor, err := or.database.Collection(models.CollectionOrganizationMembers).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	members := []*models.OrganizationMemberResponse{}
+	if err := cursor.All(ctx, &members); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// SetOrganizationMember adds a user to an organization or changes the role of a member
+// The last admin of an organization cannot be demoted
+func (or *OrganizationRepository) SetOrganizationMember(ctx context.Context, member *models.OrganizationMember) error {
+	session, err := or.database.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		if member.Role != models.OrganizationRoleAdmin {
+			if err := checkLastOrganizationAdmin(sessCtx, or.database, member.OrganizationID, member.UserID); err != nil {
+				return nil, err
+			}
+		}
+
+		_, err := or.database.Collection(models.CollectionOrganizationMembers).UpdateOne(sessCtx,
+			bson.M{"organization_id": member.OrganizationID, "user_id": member.UserID},
+			bson.M{
+				"$set":         bson.M{"role": member.Role},
+				"$setOnInsert": bson.M{"added_at": member.AddedAt},
+			},
+			options.Update().SetUpsert(true),
+		)
+		return nil, err
+	}
+
+	_, err = session.WithTransaction(ctx, callback)
+	return err
+}
+
+// RemoveOrganizationMember removes a user from an organization, the user loses the access to its shared drives
+// The items the user owns in the shared drives stay in the organization. The last admin cannot be removed.
+func (or *OrganizationRepository) RemoveOrganizationMember(ctx context.Context, organizationID primitive.ObjectID, userID primitive.ObjectID) error {
+	session, err := or.database.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		if err := checkLastOrganizationAdmin(sessCtx, or.database, organizationID, userID); err != nil {
+			return nil, err
+		}
+
+		result, err := or.database.Collection(models.CollectionOrganizationMembers).DeleteOne(sessCtx, bson.M{"organization_id": organizationID, "user_id": userID})
+		if err != nil {
+			return nil, err
+		}
+		if result.DeletedCount == 0 {
+			return nil, models.ErrOrganizationMemberNotFound
+		}
+
+		return nil, nil
+	}
+
+	_, err = session.WithTransaction(ctx, callback)
+	return err
+}
+
+// CreateSharedDrive saves the root folder of a shared drive of the organization
+func (or *OrganizationRepository) CreateSharedDrive(ctx context.Context, drive *models.Folder) (*models.Folder, error) {
+	drive.ParentFolderID = primitive.NilObjectID
+	drive.AncestorIDs = []primitive.ObjectID{}
+	drive.IsRoot = true
+
+	result, err := or.database.Collection(models.CollectionFolders).InsertOne(ctx, drive)
+	if err != nil {
+		return nil, err
+	}
+	drive.ID = result.InsertedID.(primitive.ObjectID)
+
+	return drive, nil
+}
+
+// GetSharedDrives retrieves the root folders of the shared drives of the organization, ordered by name
+func (or *OrganizationRepository) GetSharedDrives(ctx context.Context, organizationID primitive.ObjectID) ([]*models.Folder, error) {
+	cursor, err := or.database.Collection(models.CollectionFolders).Find(ctx, bson.M{
+		"organization_id": organizationID,
+		"is_root":         true,
+		"is_deleted":      false,
+	}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	drives := []*models.Folder{}
+	if err := cursor.All(ctx, &drives); err != nil {
+		return nil, err
+	}
+
+	return drives, nil
+}
+
+// checkLastOrganizationAdmin fails when the user is the only admin of the organization
+// It must run inside the caller's transaction
+func checkLastOrganizationAdmin(ctx context.Context, db *mongo.Database, organizationID primitive.ObjectID, userID primitive.ObjectID) error {
+	collection := db.Collection(models.CollectionOrganizationMembers)
+
+	isAdmin, err := collection.CountDocuments(ctx, bson.M{"organization_id": organizationID, "user_id": userID, "role": models.OrganizationRoleAdmin})
+	if err != nil {
+		return err
+	}
+	if isAdmin == 0 {
+		return nil
+	}
+
+	admins, err := collection.CountDocuments(ctx, bson.M{"organization_id": organizationID, "role": models.OrganizationRoleAdmin})
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return models.ErrOrganizationLastAdmin
+	}
+
+	return nil
+}
+
+// hasOrganizationPermission checks if the role of the user in the organization gives the permission on its shared drives
+func hasOrganizationPermission(ctx context.Context, db *mongo.Database, organizationID primitive.ObjectID, userID primitive.ObjectID, permission string) (bool, error) {
+	member, err := getOrganizationMember(ctx, db, organizationID, userID)
+	if errors.Is(err, models.ErrOrganizationMemberNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return member.ShareRole().Allows(permission), nil
+}
+
+// sameOrganization checks if two items are in the same workspace, nil being the personal drives
+func sameOrganization(a *primitive.ObjectID, b *primitive.ObjectID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	return *a == *b
+}
+
+// tenantOrganizationID returns the organization selected by the request, nil in the personal workspace of the user
+// The organization is set by the tenant middleware once the membership of the user is checked
+func tenantOrganizationID(ctx context.Context) *primitive.ObjectID {
+	organizationID, ok := ctx.Value("x-organization-id").(primitive.ObjectID)
+	if !ok {
+		return nil
+	}
+
+	return &organizationID
+}
+
+// workspaceFilter matches the folders or files of the workspace selected by the request
+// An organization matches the content of all its shared drives, the personal workspace only the items the user owns there
+func workspaceFilter(ctx context.Context, ownerID primitive.ObjectID) bson.M {
+	if organizationID := tenantOrganizationID(ctx); organizationID != nil {
+		return bson.M{"organization_id": *organizationID}
+	}
+
+	return bson.M{"owner_id": ownerID, "organization_id": nil}
+}
+
+// tenantItemFilter restricts the lookup of an item by ID to the organization selected by the request
+// The items of another organization or of a personal workspace are not found from an organization.
+// Without an organization the item is looked up in every workspace, as the block server and the share links do,
+// and the permission check decides the access with the grants and the organization membership
+func tenantItemFilter(ctx context.Context, filter bson.M) bson.M {
+	if organizationID := tenantOrganizationID(ctx); organizationID != nil {
+		filter["organization_id"] = *organizationID
+	}
+
+	return filter
+}
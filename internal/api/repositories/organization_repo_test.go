@@ -0,0 +1,24 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTenantItemFilter(t *testing.T) {
+	itemID := primitive.NewObjectID()
+
+	// Without an organization the item is looked up in every workspace, the permission check decides
+	filter := tenantItemFilter(context.Background(), bson.M{"_id": itemID, "is_deleted": false})
+	assert.Equal(t, bson.M{"_id": itemID, "is_deleted": false}, filter)
+
+	// From an organization the items of other workspaces are not found
+	organizationID := primitive.NewObjectID()
+	ctx := context.WithValue(context.Background(), "x-organization-id", organizationID)
+	filter = tenantItemFilter(ctx, bson.M{"_id": itemID, "is_deleted": false})
+	assert.Equal(t, bson.M{"_id": itemID, "is_deleted": false, "organization_id": organizationID}, filter)
+}
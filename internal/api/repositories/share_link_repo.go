@@ -0,0 +1,248 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ShareLinkRepository struct {
+	database   *mongo.Database
+	collection string
+}
+
+// NewShareLinkRepository creates a new instance of the ShareLinkRepository
+func NewShareLinkRepository(db *mongo.Database, collection string) *ShareLinkRepository {
+	return &ShareLinkRepository{
+		database:   db,
+		collection: collection,
+	}
+}
+
+// findShareLink retrieves the share link matching the filter
+func (slr *ShareLinkRepository) findShareLink(ctx context.Context, filter bson.M) (*models.ShareLink, error) {
+	link := &models.ShareLink{}
+	err := slr.database.Collection(slr.collection).FindOne(ctx, filter).Decode(link)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrShareLinkNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// CreateShareLink saves a new share link
+func (slr *ShareLinkRepository) CreateShareLink(ctx context.Context, link *models.ShareLink) (*models.ShareLink, error) {
+	result, err := slr.database.Collection(slr.collection).InsertOne(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	link.ID = result.InsertedID.(primitive.ObjectID)
+
+	return link, nil
+}
+
+// GetShareLinkByID retrieves a share link by ID
+func (slr *ShareLinkRepository) GetShareLinkByID(ctx context.Context, id primitive.ObjectID) (*models.ShareLink, error) {
+	return slr.findShareLink(ctx, bson.M{"_id": id})
+}
+
+// GetShareLinkByToken retrieves a share link by its token
+func (slr *ShareLinkRepository) GetShareLinkByToken(ctx context.Context, token string) (*models.ShareLink, error) {
+	return slr.findShareLink(ctx, bson.M{"token": token})
+}
+
+// GetShareLinksByItem retrieves the share links of a file or a folder, newest first
+func (slr *ShareLinkRepository) GetShareLinksByItem(ctx context.Context, itemType string, itemID primitive.ObjectID) ([]*models.ShareLink, error) {
+	cursor, err := slr.database.Collection(slr.collection).Find(ctx,
+		bson.M{"item_type": itemType, "item_id": itemID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	links := []*models.ShareLink{}
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// UpdateShareLink saves the settings of a share link
+// The token, the linked item and the download count are not changed
+func (slr *ShareLinkRepository) UpdateShareLink(ctx context.Context, link *models.ShareLink) error {
+	update := bson.M{
+		"$set": bson.M{
+			"password_hash":  link.PasswordHash,
+			"has_password":   link.HasPassword,
+			"max_downloads":  link.MaxDownloads,
+			"allow_download": link.AllowDownload,
+			"updated_at":     link.UpdatedAt,
+		},
+	}
+	if link.ExpiresAt != nil {
+		update["$set"].(bson.M)["expires_at"] = link.ExpiresAt
+	} else {
+		update["$unset"] = bson.M{"expires_at": ""}
+	}
+
+	result, err := slr.database.Collection(slr.collection).UpdateOne(ctx, bson.M{"_id": link.ID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return models.ErrShareLinkNotFound
+	}
+
+	return nil
+}
+
+// DeleteShareLink deletes a share link, its URL stops working
+func (slr *ShareLinkRepository) DeleteShareLink(ctx context.Context, id primitive.ObjectID) error {
+	result, err := slr.database.Collection(slr.collection).DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return models.ErrShareLinkNotFound
+	}
+
+	return nil
+}
+
+// RecordDownload counts a download of the share link
+// The limit is checked by the update filter, so concurrent downloads cannot go over it
+func (slr *ShareLinkRepository) RecordDownload(ctx context.Context, id primitive.ObjectID) error {
+	result, err := slr.database.Collection(slr.collection).UpdateOne(ctx,
+		bson.M{
+			"_id": id,
+			"$or": bson.A{
+				bson.M{"max_downloads": 0},
+				bson.M{"$expr": bson.M{"$lt": bson.A{"$download_count", "$max_downloads"}}},
+			},
+		},
+		bson.M{
+			"$inc": bson.M{"download_count": 1},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return models.ErrShareLinkDownloadsLimit
+	}
+
+	return nil
+}
+
+// GetLinkedFolder retrieves a folder the share link gives access to
+// It is the linked folder or one of its subfolders, a trashed folder is not accessible
+func (slr *ShareLinkRepository) GetLinkedFolder(ctx context.Context, link *models.ShareLink, folderID primitive.ObjectID) (*models.Folder, error) {
+	if link.ItemType != models.TrashItemFolder {
+		return nil, fmt.Errorf("folder not found in the share link")
+	}
+
+	folder := &models.Folder{}
+	err := slr.database.Collection(models.CollectionFolders).FindOne(ctx, bson.M{
+		"_id":        folderID,
+		"is_deleted": false,
+		"$or": bson.A{
+			bson.M{"_id": link.ItemID},
+			bson.M{"ancestor_ids": link.ItemID},
+		},
+	}).Decode(folder)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("folder not found in the share link")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// GetLinkedFile retrieves an uploaded file the share link gives access to
+// It is the linked file, or a file of the linked folder or of one of its subfolders
+func (slr *ShareLinkRepository) GetLinkedFile(ctx context.Context, link *models.ShareLink, fileID primitive.ObjectID) (*models.File, error) {
+	if link.ItemType == models.TrashItemFile && link.ItemID != fileID {
+		return nil, fmt.Errorf("file not found in the share link")
+	}
+
+	file := &models.File{}
+	err := slr.database.Collection(models.CollectionFiles).FindOne(ctx, bson.M{
+		"_id":        fileID,
+		"is_deleted": false,
+		"status":     "uploaded",
+	}).Decode(file)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("file not found in the share link")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if link.ItemType == models.TrashItemFolder {
+		if _, err := slr.GetLinkedFolder(ctx, link, file.ParentFolderID); err != nil {
+			return nil, fmt.Errorf("file not found in the share link")
+		}
+	}
+
+	return file, nil
+}
+
+// GetLinkedFolderContents retrieves the subfolders and the uploaded files of a folder reached through a share link
+// The folder must have been checked with GetLinkedFolder
+func (slr *ShareLinkRepository) GetLinkedFolderContents(ctx context.Context, folderID primitive.ObjectID) (*models.GetFolderContentsResponse, error) {
+	contents := &models.GetFolderContentsResponse{
+		FolderList: []*models.FolderResponse{},
+		FileList:   []*models.FileResponse{},
+	}
+
+	cursor, err := slr.database.Collection(models.CollectionFolders).Aggregate(ctx, folderResponsePipeline(bson.M{
+		"parent_folder_id": folderID,
+		"is_deleted":       false,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &contents.FolderList); err != nil {
+		return nil, err
+	}
+
+	cursor, err = slr.database.Collection(models.CollectionFiles).Aggregate(ctx, fileResponsePipeline(bson.M{
+		"parent_folder_id": folderID,
+		"is_deleted":       false,
+		"status":           "uploaded",
+	}))
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &contents.FileList); err != nil {
+		return nil, err
+	}
+
+	return contents, nil
+}
+
+// deleteShareLinks deletes the share links of purged files or folders
+// It must run inside the caller's transaction
+func deleteShareLinks(ctx context.Context, db *mongo.Database, itemType string, itemIDs []primitive.ObjectID) error {
+	_, err := db.Collection(models.CollectionShareLinks).DeleteMany(ctx, bson.M{
+		"item_type": itemType,
+		"item_id":   bson.M{"$in": itemIDs},
+	})
+	return err
+}
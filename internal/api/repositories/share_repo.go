@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// getSharedUsers retrieves the grants of the collection matching the filter with the users they are given to
+// The fields of the grant to add to the response are projected with the extra projection
+func getSharedUsers(ctx context.Context, collection *mongo.Collection, filter bson.M, extra bson.M) ([]*models.SharedUserResponse, error) {
+	projection := bson.M{
+		"user_id":  "$user_id",
+		"username": "$user_details.username",
+		"email":    "$user_details.email",
+		"role":     "$role",
+	}
+	for field, value := range extra {
+		projection[field] = value
+	}
+
+	pipeline := []bson.M{
+		{
+			"$match": filter,
+		},
+		{
+			"$lookup": bson.M{
+				"from":         models.CollectionUsers,
+				"localField":   "user_id",
+				"foreignField": "_id",
+				"as":           "user_details",
+			},
+		},
+		{
+			"$unwind": bson.M{
+				"path":                       "$user_details",
+				"preserveNullAndEmptyArrays": true,
+			},
+		},
+		{
+			"$project": projection,
+		},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sharedUsers := []*models.SharedUserResponse{}
+	if err := cursor.All(ctx, &sharedUsers); err != nil {
+		return nil, err
+	}
+
+	return sharedUsers, nil
+}
+
+// getSharedGroups retrieves the group grants of the collection matching the filter with the groups they are given to
+// The fields of the grant to add to the response are projected with the extra projection
+func getSharedGroups(ctx context.Context, collection *mongo.Collection, filter bson.M, extra bson.M) ([]*models.SharedGroupResponse, error) {
+	projection := bson.M{
+		"group_id": "$group_id",
+		"name":     "$group_details.name",
+		"role":     "$role",
+	}
+	for field, value := range extra {
+		projection[field] = value
+	}
+
+	pipeline := []bson.M{
+		{
+			"$match": filter,
+		},
+		{
+			"$lookup": bson.M{
+				"from":         models.CollectionGroups,
+				"localField":   "group_id",
+				"foreignField": "_id",
+				"as":           "group_details",
+			},
+		},
+		{
+			"$unwind": "$group_details",
+		},
+		{
+			"$project": projection,
+		},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sharedGroups := []*models.SharedGroupResponse{}
+	if err := cursor.All(ctx, &sharedGroups); err != nil {
+		return nil, err
+	}
+
+	return sharedGroups, nil
+}
+
+// upsertGrant gives the user a role on a file or a folder, replacing the role the user had on it
+// It must run inside the caller's transaction
+func upsertGrant(ctx context.Context, db *mongo.Database, itemType string, itemID primitive.ObjectID, userID primitive.ObjectID, role models.ShareRole) error {
+	var collection string
+	var filter bson.M
+	switch itemType {
+	case models.TrashItemFolder:
+		collection = models.CollectionFolderSharedUsers
+		filter = bson.M{"folder_id": itemID, "user_id": userID}
+	case models.TrashItemFile:
+		collection = models.CollectionFileSharedUsers
+		filter = bson.M{"file_id": itemID, "user_id": userID}
+	default:
+		return fmt.Errorf("invalid item type: %s", itemType)
+	}
+
+	_, err := db.Collection(collection).UpdateOne(ctx, filter, bson.M{
+		"$set": bson.M{"role": role},
+	}, options.Update().SetUpsert(true))
+	return err
+}
+
+// MigrateShareRoles gives a role to the grants saved with the boolean permission
+// An edit permission becomes the editor role and a view permission the viewer role, it does nothing once every grant has a role
+func MigrateShareRoles(ctx context.Context, db *mongo.Database) error {
+	for _, collectionName := range []string{models.CollectionFolderSharedUsers, models.CollectionFileSharedUsers} {
+		collection := db.Collection(collectionName)
+
+		// The edit grants are migrated first, the grants left are view grants
+		for _, edit := range []bool{true, false} {
+			filter := bson.M{"role": bson.M{"$exists": false}}
+			if edit {
+				filter["permission"] = true
+			}
+
+			_, err := collection.UpdateMany(ctx, filter, bson.M{
+				"$set":   bson.M{"role": models.RoleFromPermission(edit)},
+				"$unset": bson.M{"permission": ""},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,349 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type SharedItemRepository struct {
+	database *mongo.Database
+}
+
+// NewSharedItemRepository creates a new instance of the SharedItemRepository
+func NewSharedItemRepository(db *mongo.Database) *SharedItemRepository {
+	return &SharedItemRepository{
+		database: db,
+	}
+}
+
+// sharedFolderProjection projects a folder joined under "folder" with its owner joined under "owner" as a FolderResponse
+func sharedFolderProjection() bson.M {
+	return bson.M{
+		"_id":              "$folder._id",
+		"name":             "$folder.name",
+		"owner_id":         "$folder.owner_id",
+		"owner_user_name":  "$owner.username",
+		"owner_email":      "$owner.email",
+		"parent_folder_id": "$folder.parent_folder_id",
+		"stats":            "$folder.stats",
+		"created_at":       "$folder.created_at",
+		"updated_at":       "$folder.updated_at",
+	}
+}
+
+// sharedFileProjection projects a file joined under "file" with its owner joined under "owner" as a FileResponse
+func sharedFileProjection() bson.M {
+	return bson.M{
+		"_id":              "$file._id",
+		"name":             "$file.file_name",
+		"owner_id":         "$file.owner_id",
+		"owner_user_name":  "$owner.username",
+		"owner_email":      "$owner.email",
+		"parent_folder_id": "$file.parent_folder_id",
+		"size":             "$file.size",
+		"mime_type":        "$file.mime_type",
+		"status":           "$file.status",
+		"created_at":       "$file.created_at",
+		"updated_at":       "$file.updated_at",
+	}
+}
+
+// ownerLookupStages join the owner of the item joined under the given field
+func ownerLookupStages(itemField string) []bson.M {
+	return []bson.M{
+		{
+			"$lookup": bson.M{
+				"from":         models.CollectionUsers,
+				"localField":   itemField + ".owner_id",
+				"foreignField": "_id",
+				"as":           "owner",
+			},
+		},
+		{
+			"$unwind": bson.M{
+				"path":                       "$owner",
+				"preserveNullAndEmptyArrays": true,
+			},
+		},
+	}
+}
+
+// getSharedItemsPage runs a shared items pipeline and returns the requested page, the most recently updated items first
+func (sir *SharedItemRepository) getSharedItemsPage(ctx context.Context, collection string, pipeline []bson.M, pagination models.Pagination) ([]*models.SharedItem, int64, error) {
+	pipeline = append(pipeline,
+		bson.M{"$sort": bson.D{{Key: "updated_at", Value: -1}, {Key: "item_id", Value: -1}}},
+		bson.M{"$facet": bson.M{
+			"items": bson.A{
+				bson.M{"$skip": pagination.Skip()},
+				bson.M{"$limit": pagination.PageSize},
+			},
+			"total": bson.A{
+				bson.M{"$count": "count"},
+			},
+		}},
+	)
+
+	cursor, err := sir.database.Collection(collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Items []*models.SharedItem `bson:"items"`
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, 0, err
+	}
+
+	items := []*models.SharedItem{}
+	var total int64
+	if len(results) > 0 {
+		items = append(items, results[0].Items...)
+		if len(results[0].Total) > 0 {
+			total = results[0].Total[0].Count
+		}
+	}
+
+	return items, total, nil
+}
+
+// GetItemsSharedWithUser retrieves a page of the folders and files shared with the user in the workspace of the request
+// The subfolders of a folder shared with the user are in the contents of that folder, they are not listed.
+// The hidden shares are only listed with includeHidden.
+func (sir *SharedItemRepository) GetItemsSharedWithUser(ctx context.Context, userID primitive.ObjectID, includeHidden bool, pagination models.Pagination) ([]*models.SharedItem, int64, error) {
+	grantFilter := bson.M{"user_id": userID}
+	if !includeHidden {
+		grantFilter["hidden"] = bson.M{"$ne": true}
+	}
+
+	folderPipeline := []bson.M{
+		{
+			"$match": grantFilter,
+		},
+		{
+			"$lookup": bson.M{
+				"from":         models.CollectionFolders,
+				"localField":   "folder_id",
+				"foreignField": "_id",
+				"as":           "folder",
+			},
+		},
+		{
+			"$unwind": "$folder",
+		},
+		{
+			"$match": bson.M{"folder.is_deleted": false, "folder.organization_id": tenantOrganizationID(ctx)},
+		},
+		{
+			// Find a grant of the user on an ancestor, if any
+			"$lookup": bson.M{
+				"from": models.CollectionFolderSharedUsers,
+				"let":  bson.M{"ancestor_ids": bson.M{"$ifNull": bson.A{"$folder.ancestor_ids", bson.A{}}}},
+				"pipeline": []bson.M{
+					{"$match": bson.M{
+						"user_id": userID,
+						"$expr":   bson.M{"$in": bson.A{"$folder_id", "$$ancestor_ids"}},
+					}},
+					{"$limit": 1},
+				},
+				"as": "ancestor_share",
+			},
+		},
+		{
+			"$match": bson.M{"ancestor_share": bson.M{"$size": 0}},
+		},
+	}
+	folderPipeline = append(folderPipeline, ownerLookupStages("folder")...)
+	folderPipeline = append(folderPipeline, bson.M{
+		"$project": bson.M{
+			"_id":        0,
+			"item_type":  bson.M{"$literal": models.TrashItemFolder},
+			"item_id":    "$folder._id",
+			"role":       "$role",
+			"hidden":     bson.M{"$ifNull": bson.A{"$hidden", false}},
+			"updated_at": "$folder.updated_at",
+			"folder":     sharedFolderProjection(),
+		},
+	})
+
+	filePipeline := []bson.M{
+		{
+			"$match": grantFilter,
+		},
+		{
+			"$lookup": bson.M{
+				"from":         models.CollectionFiles,
+				"localField":   "file_id",
+				"foreignField": "_id",
+				"as":           "file",
+			},
+		},
+		{
+			"$unwind": "$file",
+		},
+		{
+			"$match": bson.M{
+				"file.is_deleted":      false,
+				"file.status":          "uploaded",
+				"file.organization_id": tenantOrganizationID(ctx),
+			},
+		},
+	}
+	filePipeline = append(filePipeline, ownerLookupStages("file")...)
+	filePipeline = append(filePipeline, bson.M{
+		"$project": bson.M{
+			"_id":        0,
+			"item_type":  bson.M{"$literal": models.TrashItemFile},
+			"item_id":    "$file._id",
+			"role":       "$role",
+			"hidden":     bson.M{"$ifNull": bson.A{"$hidden", false}},
+			"updated_at": "$file.updated_at",
+			"file":       sharedFileProjection(),
+		},
+	})
+
+	pipeline := append(folderPipeline, bson.M{
+		"$unionWith": bson.M{
+			"coll":     models.CollectionFileSharedUsers,
+			"pipeline": filePipeline,
+		},
+	})
+
+	return sir.getSharedItemsPage(ctx, models.CollectionFolderSharedUsers, pipeline, pagination)
+}
+
+// sharedWithLookup joins the grants of an item with the users they are given to
+func sharedWithLookup(grantCollection string, itemField string) bson.M {
+	return bson.M{
+		"$lookup": bson.M{
+			"from": grantCollection,
+			"let":  bson.M{"item_id": "$_id"},
+			"pipeline": []bson.M{
+				{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$" + itemField, "$$item_id"}}}},
+				{"$lookup": bson.M{
+					"from":         models.CollectionUsers,
+					"localField":   "user_id",
+					"foreignField": "_id",
+					"as":           "user",
+				}},
+				{"$unwind": bson.M{
+					"path":                       "$user",
+					"preserveNullAndEmptyArrays": true,
+				}},
+				{"$project": bson.M{
+					"_id":      0,
+					"user_id":  "$user_id",
+					"username": "$user.username",
+					"email":    "$user.email",
+					"role":     "$role",
+				}},
+			},
+			"as": "shared_with",
+		},
+	}
+}
+
+// GetItemsSharedByUser retrieves a page of the folders and files of the user in the workspace of the request that are shared with other users
+// Every item lists the users it is shared with. The subfolders under a shared folder are only listed with their own grants.
+func (sir *SharedItemRepository) GetItemsSharedByUser(ctx context.Context, ownerID primitive.ObjectID, pagination models.Pagination) ([]*models.SharedItem, int64, error) {
+	folderPipeline := []bson.M{
+		{
+			"$match": bson.M{"owner_id": ownerID, "organization_id": tenantOrganizationID(ctx), "is_deleted": false},
+		},
+		sharedWithLookup(models.CollectionFolderSharedUsers, "folder_id"),
+		{
+			"$match": bson.M{"shared_with": bson.M{"$ne": bson.A{}}},
+		},
+		{
+			"$replaceRoot": bson.M{"newRoot": bson.M{"folder": "$$ROOT", "shared_with": "$shared_with"}},
+		},
+	}
+	folderPipeline = append(folderPipeline, ownerLookupStages("folder")...)
+	folderPipeline = append(folderPipeline, bson.M{
+		"$project": bson.M{
+			"_id":         0,
+			"item_type":   bson.M{"$literal": models.TrashItemFolder},
+			"item_id":     "$folder._id",
+			"hidden":      bson.M{"$literal": false},
+			"updated_at":  "$folder.updated_at",
+			"folder":      sharedFolderProjection(),
+			"shared_with": "$shared_with",
+		},
+	})
+
+	filePipeline := []bson.M{
+		{
+			"$match": bson.M{"owner_id": ownerID, "organization_id": tenantOrganizationID(ctx), "is_deleted": false, "status": "uploaded"},
+		},
+		sharedWithLookup(models.CollectionFileSharedUsers, "file_id"),
+		{
+			"$match": bson.M{"shared_with": bson.M{"$ne": bson.A{}}},
+		},
+		{
+			"$replaceRoot": bson.M{"newRoot": bson.M{"file": "$$ROOT", "shared_with": "$shared_with"}},
+		},
+	}
+	filePipeline = append(filePipeline, ownerLookupStages("file")...)
+	filePipeline = append(filePipeline, bson.M{
+		"$project": bson.M{
+			"_id":         0,
+			"item_type":   bson.M{"$literal": models.TrashItemFile},
+			"item_id":     "$file._id",
+			"hidden":      bson.M{"$literal": false},
+			"updated_at":  "$file.updated_at",
+			"file":        sharedFileProjection(),
+			"shared_with": "$shared_with",
+		},
+	})
+
+	pipeline := append(folderPipeline, bson.M{
+		"$unionWith": bson.M{
+			"coll":     models.CollectionFiles,
+			"pipeline": filePipeline,
+		},
+	})
+
+	return sir.getSharedItemsPage(ctx, models.CollectionFolders, pipeline, pagination)
+}
+
+// SetShareHidden hides the grant of the user on a folder or a file from the shared with me listing, or shows it again
+// The access given by the grant is not changed
+func (sir *SharedItemRepository) SetShareHidden(ctx context.Context, userID primitive.ObjectID, itemType string, itemID primitive.ObjectID, hidden bool) error {
+	var collection string
+	var filter bson.M
+	switch itemType {
+	case models.TrashItemFolder:
+		collection = models.CollectionFolderSharedUsers
+		filter = bson.M{"folder_id": itemID, "user_id": userID}
+	case models.TrashItemFile:
+		collection = models.CollectionFileSharedUsers
+		filter = bson.M{"file_id": itemID, "user_id": userID}
+	default:
+		return fmt.Errorf("invalid item type: %s", itemType)
+	}
+
+	update := bson.M{"$unset": bson.M{"hidden": ""}}
+	if hidden {
+		update = bson.M{"$set": bson.M{"hidden": true}}
+	}
+
+	result, err := sir.database.Collection(collection).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return models.ErrShareNotFound
+	}
+
+	return nil
+}
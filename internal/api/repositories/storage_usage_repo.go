@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"context"
+
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// fileStorageSize returns the storage used by a file: the size of every uploaded version
+// Trashed files keep using their storage until they are purged
+func fileStorageSize(file *models.File) int64 {
+	var size int64
+	for _, fileVersion := range file.GetVersions() {
+		size += fileVersion.Size
+	}
+
+	return size
+}
+
+// storageSizeExpression computes the storage used by a file document in an aggregation, like fileStorageSize
+var storageSizeExpression = bson.M{"$cond": bson.A{
+	bson.M{"$gt": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{"$versions", bson.A{}}}}, 0}},
+	bson.M{"$sum": "$versions.size"},
+	bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$status", "uploaded"}}, "$size", 0}},
+}}
+
+// addStorageUsed adds the delta to the storage used by the user
+// It must run inside the caller's transaction
+func addStorageUsed(ctx context.Context, db *mongo.Database, userID primitive.ObjectID, delta int64) error {
+	if delta == 0 || userID.IsZero() {
+		return nil
+	}
+
+	_, err := db.Collection(models.CollectionUsers).UpdateOne(ctx, bson.M{"_id": userID}, bson.M{
+		"$inc": bson.M{"storage_used": delta},
+	})
+	return err
+}
+
+// addOrganizationStorageUsed adds the delta to the storage used by the organization
+// It must run inside the caller's transaction
+func addOrganizationStorageUsed(ctx context.Context, db *mongo.Database, organizationID primitive.ObjectID, delta int64) error {
+	if delta == 0 || organizationID.IsZero() {
+		return nil
+	}
+
+	_, err := db.Collection(models.CollectionOrganizations).UpdateOne(ctx, bson.M{"_id": organizationID}, bson.M{
+		"$inc": bson.M{"storage_used": delta},
+	})
+	return err
+}
+
+// addFileStorageUsed adds the delta to the storage used by the file's organization, or by its owner in a personal drive
+// It must run inside the caller's transaction
+func addFileStorageUsed(ctx context.Context, db *mongo.Database, file *models.File, delta int64) error {
+	if file.OrganizationID != nil {
+		return addOrganizationStorageUsed(ctx, db, *file.OrganizationID, delta)
+	}
+
+	return addStorageUsed(ctx, db, file.OwnerID, delta)
+}
+
+// releaseFilesStorage removes the storage used by the files from their owners or their organizations
+// It must run inside the caller's transaction
+func releaseFilesStorage(ctx context.Context, db *mongo.Database, files []*models.File) error {
+	released := map[primitive.ObjectID]int64{}
+	releasedByOrganization := map[primitive.ObjectID]int64{}
+	for _, file := range files {
+		if file.OrganizationID != nil {
+			releasedByOrganization[*file.OrganizationID] += fileStorageSize(file)
+			continue
+		}
+		released[file.OwnerID] += fileStorageSize(file)
+	}
+
+	for ownerID, size := range released {
+		if err := addStorageUsed(ctx, db, ownerID, -size); err != nil {
+			return err
+		}
+	}
+	for organizationID, size := range releasedByOrganization {
+		if err := addOrganizationStorageUsed(ctx, db, organizationID, -size); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BackfillStorageUsed computes the storage used by the users saved before the usage was recorded
+// Only the users without a storage_used field are updated, it does nothing once every user has one
+// The files of the shared drives use the storage of their organization and are not counted
+func BackfillStorageUsed(ctx context.Context, db *mongo.Database) error {
+	userCollection := db.Collection(models.CollectionUsers)
+
+	cursor, err := userCollection.Find(ctx, bson.M{"storage_used": bson.M{"$exists": false}}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return err
+	}
+	var users []*models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	userIDs := []primitive.ObjectID{}
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
+	}
+
+	cursor, err = db.Collection(models.CollectionFiles).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"owner_id": bson.M{"$in": userIDs}, "organization_id": nil, "status": "uploaded"}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":        "$owner_id",
+			"total_size": bson.M{"$sum": storageSizeExpression},
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+	var sums []struct {
+		UserID    primitive.ObjectID `bson:"_id"`
+		TotalSize int64              `bson:"total_size"`
+	}
+	if err := cursor.All(ctx, &sums); err != nil {
+		return err
+	}
+	used := map[primitive.ObjectID]int64{}
+	for _, sum := range sums {
+		used[sum.UserID] = sum.TotalSize
+	}
+
+	writes := []mongo.WriteModel{}
+	for _, userID := range userIDs {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": userID, "storage_used": bson.M{"$exists": false}}).
+			SetUpdate(bson.M{"$set": bson.M{"storage_used": used[userID]}}))
+	}
+	_, err = userCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
@@ -0,0 +1,24 @@
+package repositories
+
+import (
+	"testing"
+
+	"skybox-backend/internal/api/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStorageSize(t *testing.T) {
+	// Every version uses storage
+	versioned := &models.File{Status: "uploaded", Size: 30, Versions: []models.FileVersion{
+		{Version: 1, Size: 10},
+		{Version: 2, Size: 30},
+	}}
+	assert.Equal(t, int64(40), fileStorageSize(versioned))
+
+	// A file uploaded before versioning has a single version
+	assert.Equal(t, int64(25), fileStorageSize(&models.File{Status: "uploaded", Size: 25}))
+
+	// A file not uploaded yet uses nothing
+	assert.Equal(t, int64(0), fileStorageSize(&models.File{Status: "pending", Size: 25}))
+}
@@ -0,0 +1,383 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OwnershipTransferRepository struct {
+	database   *mongo.Database
+	collection string
+}
+
+// NewOwnershipTransferRepository creates a new instance of the OwnershipTransferRepository
+func NewOwnershipTransferRepository(db *mongo.Database, collection string) *OwnershipTransferRepository {
+	return &OwnershipTransferRepository{
+		database:   db,
+		collection: collection,
+	}
+}
+
+// CreateTransfer saves a pending transfer
+// An item has a single pending transfer, asking again replaces its recipient
+func (otr *OwnershipTransferRepository) CreateTransfer(ctx context.Context, transfer *models.OwnershipTransfer) (*models.OwnershipTransfer, error) {
+	saved := &models.OwnershipTransfer{}
+	err := otr.database.Collection(otr.collection).FindOneAndUpdate(ctx,
+		bson.M{
+			"item_type": transfer.ItemType,
+			"item_id":   transfer.ItemID,
+			"status":    models.TransferPending,
+		},
+		bson.M{
+			"$set": bson.M{
+				"item_name":    transfer.ItemName,
+				"from_user_id": transfer.FromUserID,
+				"to_user_id":   transfer.ToUserID,
+				"created_at":   transfer.CreatedAt,
+			},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(saved)
+	if err != nil {
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+// GetTransferByID retrieves a pending transfer by ID
+func (otr *OwnershipTransferRepository) GetTransferByID(ctx context.Context, id primitive.ObjectID) (*models.OwnershipTransfer, error) {
+	transfer := &models.OwnershipTransfer{}
+	err := otr.database.Collection(otr.collection).FindOne(ctx, bson.M{"_id": id, "status": models.TransferPending}).Decode(transfer)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, models.ErrTransferNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+// GetPendingTransfers retrieves the pending transfers sent or received by the user, newest first
+func (otr *OwnershipTransferRepository) GetPendingTransfers(ctx context.Context, userID primitive.ObjectID) ([]*models.OwnershipTransfer, error) {
+	cursor, err := otr.database.Collection(otr.collection).Find(ctx,
+		bson.M{
+			"status": models.TransferPending,
+			"$or": bson.A{
+				bson.M{"from_user_id": userID},
+				bson.M{"to_user_id": userID},
+			},
+		},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := []*models.OwnershipTransfer{}
+	if err := cursor.All(ctx, &transfers); err != nil {
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+// CloseTransfer declines or cancels a pending transfer, the item keeps its owner
+func (otr *OwnershipTransferRepository) CloseTransfer(ctx context.Context, id primitive.ObjectID, status string, now time.Time) error {
+	result, err := otr.database.Collection(otr.collection).UpdateOne(ctx, bson.M{"_id": id, "status": models.TransferPending}, bson.M{
+		"$set": bson.M{
+			"status":       status,
+			"responded_at": now,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return models.ErrTransferNotFound
+	}
+
+	return nil
+}
+
+// getTransferFiles retrieves the folders and the files given by a transfer, trashed or not
+// A folder is given with its subtree, the folder IDs are empty for a file.
+// Only the folders and the files of the previous owner are given, the items of other users in the subtree keep their owner
+func getTransferFiles(ctx context.Context, db *mongo.Database, transfer *models.OwnershipTransfer) ([]primitive.ObjectID, []*models.File, error) {
+	folderIDs := []primitive.ObjectID{}
+	filter := bson.M{"_id": transfer.ItemID, "owner_id": transfer.FromUserID}
+	if transfer.ItemType == models.TrashItemFolder {
+		treeIDs, err := getFolderTreeIDs(ctx, db, transfer.ItemID, bson.M{})
+		if err != nil {
+			return nil, nil, err
+		}
+		filter = bson.M{"parent_folder_id": bson.M{"$in": treeIDs}, "owner_id": transfer.FromUserID}
+
+		cursor, err := db.Collection(models.CollectionFolders).Find(ctx, bson.M{
+			"_id":      bson.M{"$in": treeIDs},
+			"owner_id": transfer.FromUserID,
+		}, options.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			return nil, nil, err
+		}
+		var folders []*models.Folder
+		if err := cursor.All(ctx, &folders); err != nil {
+			return nil, nil, err
+		}
+		for _, folder := range folders {
+			folderIDs = append(folderIDs, folder.ID)
+		}
+	}
+
+	cursor, err := db.Collection(models.CollectionFiles).Find(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	files := []*models.File{}
+	if err := cursor.All(ctx, &files); err != nil {
+		return nil, nil, err
+	}
+
+	return folderIDs, files, nil
+}
+
+// GetTransferContent computes the storage a transfer gives to the recipient and lists its legacy chunk objects
+func (otr *OwnershipTransferRepository) GetTransferContent(ctx context.Context, transfer *models.OwnershipTransfer) (*models.TransferContent, error) {
+	_, files, err := getTransferFiles(ctx, otr.database, transfer)
+	if err != nil {
+		return nil, err
+	}
+
+	content := &models.TransferContent{LegacyChunks: []*models.LegacyChunks{}}
+	fileIDs := []primitive.ObjectID{}
+	for _, file := range files {
+		content.StorageSize += fileStorageSize(file)
+		fileIDs = append(fileIDs, file.ID)
+	}
+	if len(fileIDs) == 0 {
+		return content, nil
+	}
+
+	// The chunks saved as blocks have a hash, their keys do not depend on the owner
+	cursor, err := otr.database.Collection(models.CollectionChunks).Find(ctx, bson.M{
+		"file_id":    bson.M{"$in": fileIDs},
+		"chunk_hash": bson.M{"$in": bson.A{"", nil}},
+	}, options.Find().SetSort(bson.D{{Key: "file_id", Value: 1}, {Key: "chunk_index", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var chunks []models.Chunk
+	if err := cursor.All(ctx, &chunks); err != nil {
+		return nil, err
+	}
+	byFile := map[primitive.ObjectID]*models.LegacyChunks{}
+	for _, chunk := range chunks {
+		legacy, ok := byFile[chunk.FileID]
+		if !ok {
+			legacy = &models.LegacyChunks{FileID: chunk.FileID.Hex(), ChunkIndexes: []int{}}
+			byFile[chunk.FileID] = legacy
+			content.LegacyChunks = append(content.LegacyChunks, legacy)
+		}
+		legacy.ChunkIndexes = append(legacy.ChunkIndexes, chunk.ChunkIndex)
+	}
+
+	return content, nil
+}
+
+// AcceptTransfer gives the item of a pending transfer to its recipient
+// The item is moved to the root folder of the recipient, renamed if the name is used, and the item, its subfolders
+// and their files owned by the previous owner change owner, trashed ones included. The items of other users in the
+// subtree keep their owner. The storage usage moves to the recipient and the previous owner keeps an editor grant
+// on the item. The grants of the recipient on the given items are removed, it is the owner now.
+func (otr *OwnershipTransferRepository) AcceptTransfer(ctx context.Context, transfer *models.OwnershipTransfer, now time.Time) (*models.OwnershipTransfer, error) {
+	session, err := otr.database.Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		result, err := otr.database.Collection(otr.collection).UpdateOne(sessCtx, bson.M{"_id": transfer.ID, "status": models.TransferPending}, bson.M{
+			"$set": bson.M{
+				"status":       models.TransferAccepted,
+				"responded_at": now,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount == 0 {
+			return nil, models.ErrTransferNotFound
+		}
+
+		recipient := &models.User{}
+		if err := otr.database.Collection(models.CollectionUsers).FindOne(sessCtx, bson.M{"_id": transfer.ToUserID}).Decode(recipient); err != nil {
+			return nil, fmt.Errorf("failed to get the recipient: %w", err)
+		}
+		if recipient.RootFolderID.IsZero() {
+			return nil, fmt.Errorf("invalid recipient: the user has no root folder")
+		}
+
+		if err := moveTransferItem(sessCtx, otr.database, transfer, recipient.RootFolderID); err != nil {
+			return nil, err
+		}
+
+		folderIDs, files, err := getTransferFiles(sessCtx, otr.database, transfer)
+		if err != nil {
+			return nil, err
+		}
+		fileIDs := []primitive.ObjectID{}
+		var size int64
+		for _, file := range files {
+			fileIDs = append(fileIDs, file.ID)
+			size += fileStorageSize(file)
+		}
+
+		ownerUpdate := bson.M{"$set": bson.M{"owner_id": transfer.ToUserID}}
+		if len(folderIDs) > 0 {
+			if _, err := otr.database.Collection(models.CollectionFolders).UpdateMany(sessCtx, bson.M{"_id": bson.M{"$in": folderIDs}}, ownerUpdate); err != nil {
+				return nil, err
+			}
+			if _, err := otr.database.Collection(models.CollectionFolderSharedUsers).DeleteMany(sessCtx, bson.M{
+				"folder_id": bson.M{"$in": folderIDs},
+				"user_id":   transfer.ToUserID,
+			}); err != nil {
+				return nil, err
+			}
+		}
+		if len(fileIDs) > 0 {
+			if _, err := otr.database.Collection(models.CollectionFiles).UpdateMany(sessCtx, bson.M{"_id": bson.M{"$in": fileIDs}}, ownerUpdate); err != nil {
+				return nil, err
+			}
+			if _, err := otr.database.Collection(models.CollectionFileSharedUsers).DeleteMany(sessCtx, bson.M{
+				"file_id": bson.M{"$in": fileIDs},
+				"user_id": transfer.ToUserID,
+			}); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := addStorageUsed(sessCtx, otr.database, transfer.FromUserID, -size); err != nil {
+			return nil, err
+		}
+		if err := addStorageUsed(sessCtx, otr.database, transfer.ToUserID, size); err != nil {
+			return nil, err
+		}
+
+		if err := upsertGrant(sessCtx, otr.database, transfer.ItemType, transfer.ItemID, transfer.FromUserID, models.RoleEditor); err != nil {
+			return nil, err
+		}
+
+		// The previous owner can no longer give the items of the subtree away
+		if _, err := otr.database.Collection(otr.collection).UpdateMany(sessCtx, bson.M{
+			"_id":          bson.M{"$ne": transfer.ID},
+			"status":       models.TransferPending,
+			"from_user_id": transfer.FromUserID,
+			"$or": bson.A{
+				bson.M{"item_type": models.TrashItemFolder, "item_id": bson.M{"$in": folderIDs}},
+				bson.M{"item_type": models.TrashItemFile, "item_id": bson.M{"$in": fileIDs}},
+			},
+		}, bson.M{
+			"$set": bson.M{
+				"status":       models.TransferCancelled,
+				"responded_at": now,
+			},
+		}); err != nil {
+			return nil, err
+		}
+
+		transfer.Status = models.TransferAccepted
+		transfer.RespondedAt = &now
+		return transfer, nil
+	}
+
+	result, err := session.WithTransaction(ctx, callback)
+	if err != nil {
+		return nil, nameConflictError(err)
+	}
+
+	return result.(*models.OwnershipTransfer), nil
+}
+
+// moveTransferItem moves the item of a transfer to the root folder of the recipient
+// It must run inside the caller's transaction
+func moveTransferItem(ctx context.Context, db *mongo.Database, transfer *models.OwnershipTransfer, rootFolderID primitive.ObjectID) error {
+	var collection, nameField string
+	var ownerID, parentID primitive.ObjectID
+	var name string
+	var isDeleted bool
+	var organizationID *primitive.ObjectID
+	var stats models.FolderStat
+	var err error
+	switch transfer.ItemType {
+	case models.TrashItemFolder:
+		collection, nameField = models.CollectionFolders, "name"
+		folder := &models.Folder{}
+		err = db.Collection(collection).FindOne(ctx, bson.M{"_id": transfer.ItemID}).Decode(folder)
+		if err == nil && folder.IsRoot {
+			return fmt.Errorf("cannot transfer a root folder")
+		}
+		ownerID, parentID, name, isDeleted, stats = folder.OwnerID, folder.ParentFolderID, folder.Name, folder.IsDeleted, folderStats(folder)
+		organizationID = folder.OrganizationID
+	case models.TrashItemFile:
+		collection, nameField = models.CollectionFiles, "file_name"
+		file := &models.File{}
+		err = db.Collection(collection).FindOne(ctx, bson.M{"_id": transfer.ItemID}).Decode(file)
+		ownerID, parentID, name, isDeleted, stats = file.OwnerID, file.ParentFolderID, file.FileName, file.IsDeleted, fileStats(file)
+		organizationID = file.OrganizationID
+	default:
+		return fmt.Errorf("invalid item type: %s", transfer.ItemType)
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("%s not found", transfer.ItemType)
+	}
+	if err != nil {
+		return err
+	}
+	if ownerID != transfer.FromUserID {
+		return models.ErrTransferNotOwner
+	}
+	if organizationID != nil {
+		return models.ErrTransferOrganizationItem
+	}
+	if isDeleted {
+		return fmt.Errorf("cannot transfer a %s in the trash", transfer.ItemType)
+	}
+
+	name, err = resolveNameConflict(ctx, db, collection, rootFolderID, name, transfer.ItemID, models.ConflictRename)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Collection(collection).UpdateOne(ctx, bson.M{"_id": transfer.ItemID}, bson.M{
+		"$set": bson.M{"parent_folder_id": rootFolderID, nameField: name},
+	}); err != nil {
+		return err
+	}
+	if transfer.ItemType == models.TrashItemFolder {
+		if err := setFolderAncestors(ctx, db, transfer.ItemID, rootFolderID); err != nil {
+			return err
+		}
+	}
+
+	return moveFolderStats(ctx, db, parentID, rootFolderID, stats)
+}
+
+// deleteOwnershipTransfers deletes the transfers of purged files or folders
+// It must run inside the caller's transaction
+func deleteOwnershipTransfers(ctx context.Context, db *mongo.Database, itemType string, itemIDs []primitive.ObjectID) error {
+	_, err := db.Collection(models.CollectionOwnershipTransfers).DeleteMany(ctx, bson.M{
+		"item_type": itemType,
+		"item_id":   bson.M{"$in": itemIDs},
+	})
+	return err
+}
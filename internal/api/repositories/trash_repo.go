@@ -0,0 +1,526 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TrashRepository struct {
+	database *mongo.Database
+}
+
+func NewTrashRepository(db *mongo.Database) *TrashRepository {
+	return &TrashRepository{
+		database: db,
+	}
+}
+
+// fileTrashItem converts a file record to a trash item
+func fileTrashItem(file *models.File) *models.TrashItem {
+	return &models.TrashItem{
+		ID:             file.ID,
+		Type:           models.TrashItemFile,
+		Name:           file.FileName,
+		OwnerID:        file.OwnerID,
+		ParentFolderID: file.ParentFolderID,
+		MimeType:       file.MimeType,
+		Size:           file.Size,
+		DeletedAt:      file.DeletedAt,
+		DeletedBy:      file.DeletedBy,
+		TrashReason:    file.TrashReason,
+	}
+}
+
+// folderTrashItem converts a folder record to a trash item
+func folderTrashItem(folder *models.Folder) *models.TrashItem {
+	return &models.TrashItem{
+		ID:             folder.ID,
+		Type:           models.TrashItemFolder,
+		Name:           folder.Name,
+		OwnerID:        folder.OwnerID,
+		ParentFolderID: folder.ParentFolderID,
+		DeletedAt:      folder.DeletedAt,
+		DeletedBy:      folder.DeletedBy,
+		TrashReason:    folder.TrashReason,
+	}
+}
+
+// findTrashItems finds the trashed folders and files matching the filter, folders first
+func (tr *TrashRepository) findTrashItems(ctx context.Context, filter bson.M, fileFilter bson.M, limit int64) ([]*models.TrashItem, error) {
+	items := []*models.TrashItem{}
+
+	findOptions := options.Find().SetSort(bson.M{"deleted_at": 1})
+	if limit > 0 {
+		findOptions.SetLimit(limit)
+	}
+
+	var folders []*models.Folder
+	cursor, err := tr.database.Collection(models.CollectionFolders).Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &folders); err != nil {
+		return nil, err
+	}
+	for _, folder := range folders {
+		items = append(items, folderTrashItem(folder))
+	}
+
+	if limit > 0 {
+		if int64(len(items)) >= limit {
+			return items, nil
+		}
+		findOptions.SetLimit(limit - int64(len(items)))
+	}
+
+	for key, value := range filter {
+		fileFilter[key] = value
+	}
+	var files []*models.File
+	cursor, err = tr.database.Collection(models.CollectionFiles).Find(ctx, fileFilter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &files); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		items = append(items, fileTrashItem(file))
+	}
+
+	return items, nil
+}
+
+// GetTrashItems retrieves the trashed files and folders of the user in the workspace of the request, the most recently deleted first
+// The content of a trashed folder is part of the folder item. Cancelled uploads are hidden, they are only purged.
+func (tr *TrashRepository) GetTrashItems(ctx context.Context, ownerID primitive.ObjectID) ([]*models.TrashItem, error) {
+	items, err := tr.findTrashItems(ctx, bson.M{
+		"owner_id":        ownerID,
+		"organization_id": tenantOrganizationID(ctx),
+		"is_deleted":      true,
+		"trashed_with":    bson.M{"$exists": false},
+	}, bson.M{"status": bson.M{"$ne": "cancelled"}}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].DeletedAt == nil || items[j].DeletedAt == nil {
+			return items[j].DeletedAt == nil && items[i].DeletedAt != nil
+		}
+		return items[i].DeletedAt.After(*items[j].DeletedAt)
+	})
+
+	return items, nil
+}
+
+// GetExpiredTrashItems retrieves up to limit items of every user deleted before the given time
+func (tr *TrashRepository) GetExpiredTrashItems(ctx context.Context, deletedBefore time.Time, limit int64) ([]*models.TrashItem, error) {
+	return tr.findTrashItems(ctx, bson.M{
+		"is_deleted":   true,
+		"deleted_at":   bson.M{"$lt": deletedBefore},
+		"trashed_with": bson.M{"$exists": false},
+	}, bson.M{}, limit)
+}
+
+// getTrashItem retrieves a trashed item of the user in the workspace of the request
+// Cancelled uploads are only returned with includeCancelled, they can be purged but not restored
+func (tr *TrashRepository) getTrashItem(ctx context.Context, ownerID primitive.ObjectID, itemType string, itemID primitive.ObjectID, includeCancelled bool) (*models.TrashItem, error) {
+	filter := bson.M{
+		"_id":             itemID,
+		"owner_id":        ownerID,
+		"organization_id": tenantOrganizationID(ctx),
+		"is_deleted":      true,
+		"trashed_with":    bson.M{"$exists": false},
+	}
+
+	switch itemType {
+	case models.TrashItemFile:
+		if !includeCancelled {
+			filter["status"] = bson.M{"$ne": "cancelled"}
+		}
+
+		file := &models.File{}
+		err := tr.database.Collection(models.CollectionFiles).FindOne(ctx, filter).Decode(file)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, models.ErrTrashItemNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		return fileTrashItem(file), nil
+	case models.TrashItemFolder:
+		folder := &models.Folder{}
+		err := tr.database.Collection(models.CollectionFolders).FindOne(ctx, filter).Decode(folder)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, models.ErrTrashItemNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		return folderTrashItem(folder), nil
+	default:
+		return nil, fmt.Errorf("invalid trash item type: %s", itemType)
+	}
+}
+
+// RestoreItem moves a trashed item of the user back to its folder
+// A folder is restored with the content trashed with it. The trashed ancestors of the item are restored
+// with their content too. When an ancestor was purged, the item is restored in the root folder of the user instead.
+// The item is renamed when its name was taken in the meantime.
+func (tr *TrashRepository) RestoreItem(ctx context.Context, ownerID primitive.ObjectID, itemType string, itemID primitive.ObjectID) (*models.TrashItem, error) {
+	session, err := tr.database.Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		item, err := tr.getTrashItem(sessCtx, ownerID, itemType, itemID, false)
+		if err != nil {
+			return nil, err
+		}
+
+		parentID, err := tr.restoreAncestors(sessCtx, ownerID, item.ParentFolderID)
+		if err != nil {
+			return nil, err
+		}
+
+		collectionName := models.CollectionFiles
+		if itemType == models.TrashItemFolder {
+			collectionName = models.CollectionFolders
+			if err := restoreTrashUnits(sessCtx, tr.database, []primitive.ObjectID{itemID}); err != nil {
+				return nil, err
+			}
+		}
+
+		name, err := resolveNameConflict(sessCtx, tr.database, collectionName, parentID, item.Name, itemID, models.ConflictRename)
+		if err != nil {
+			return nil, err
+		}
+		restored := tr.database.Collection(collectionName).FindOneAndUpdate(sessCtx, bson.M{"_id": itemID}, bson.M{
+			"$set": bson.M{
+				"is_deleted":              false,
+				"parent_folder_id":        parentID,
+				nameField(collectionName): name,
+				"updated_at":              time.Now(),
+			},
+			"$unset": bson.M{"deleted_at": "", "deleted_by": "", "trash_reason": ""},
+		}, options.FindOneAndUpdate().SetReturnDocument(options.After))
+
+		// The item counts again in the statistics of its folders
+		stats := models.FolderStat{}
+		if itemType == models.TrashItemFolder {
+			folder := &models.Folder{}
+			if err := restored.Decode(folder); err != nil {
+				return nil, err
+			}
+			stats = folderStats(folder)
+
+			// The folder is restored in the root folder when its original location is gone
+			if err := setFolderAncestors(sessCtx, tr.database, itemID, parentID); err != nil {
+				return nil, err
+			}
+		} else {
+			file := &models.File{}
+			if err := restored.Decode(file); err != nil {
+				return nil, err
+			}
+			stats = fileStats(file)
+		}
+		if err := addFolderStats(sessCtx, tr.database, parentID, stats); err != nil {
+			return nil, err
+		}
+
+		item.Name = name
+		item.ParentFolderID = parentID
+		item.DeletedAt = nil
+		return item, nil
+	}
+
+	result, err := session.WithTransaction(ctx, callback)
+	if err != nil {
+		return nil, nameConflictError(err)
+	}
+
+	return result.(*models.TrashItem), nil
+}
+
+// restoreAncestors restores the trashed folders between parentID and the root folder
+// It returns the folder the item must be restored in
+func (tr *TrashRepository) restoreAncestors(ctx context.Context, ownerID primitive.ObjectID, parentID primitive.ObjectID) (primitive.ObjectID, error) {
+	if parentID.IsZero() {
+		return tr.getRootFolderID(ctx, ownerID)
+	}
+
+	collection := tr.database.Collection(models.CollectionFolders)
+
+	// A folder trashed with another one is restored with it
+	units := []primitive.ObjectID{}
+	visited := map[primitive.ObjectID]bool{}
+	for folderID := parentID; !folderID.IsZero() && !visited[folderID]; {
+		visited[folderID] = true
+
+		folder := &models.Folder{}
+		err := collection.FindOne(ctx, bson.M{"_id": folderID}).Decode(folder)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// The ancestor was purged, the original location is gone
+			return tr.getRootFolderID(ctx, ownerID)
+		}
+		if err != nil {
+			return primitive.NilObjectID, err
+		}
+
+		if folder.IsDeleted {
+			if folder.TrashedWith.IsZero() {
+				units = append(units, folder.ID)
+			} else {
+				units = append(units, folder.TrashedWith)
+			}
+		}
+		if folder.IsRoot {
+			break
+		}
+		folderID = folder.ParentFolderID
+	}
+
+	if err := restoreTrashUnits(ctx, tr.database, units); err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	// Every restored folder counts again in the statistics of its parents, the folders restored
+	// inside another one were removed from its statistics when they were trashed
+	counted := map[primitive.ObjectID]bool{}
+	for _, unitID := range units {
+		if counted[unitID] {
+			continue
+		}
+		counted[unitID] = true
+
+		folder := &models.Folder{}
+		if err := collection.FindOne(ctx, bson.M{"_id": unitID}).Decode(folder); err != nil {
+			return primitive.NilObjectID, err
+		}
+		if err := addFolderStats(ctx, tr.database, folder.ParentFolderID, folderStats(folder)); err != nil {
+			return primitive.NilObjectID, err
+		}
+	}
+
+	return parentID, nil
+}
+
+// restoreTrashUnits restores the given trashed folders with the folders and files trashed with them
+// It must run inside the caller's transaction
+func restoreTrashUnits(ctx context.Context, db *mongo.Database, folderIDs []primitive.ObjectID) error {
+	if len(folderIDs) == 0 {
+		return nil
+	}
+
+	filter := bson.M{"$or": bson.A{
+		bson.M{"_id": bson.M{"$in": folderIDs}},
+		bson.M{"trashed_with": bson.M{"$in": folderIDs}},
+	}}
+	update := bson.M{
+		"$set":   bson.M{"is_deleted": false, "updated_at": time.Now()},
+		"$unset": bson.M{"deleted_at": "", "deleted_by": "", "trash_reason": "", "trashed_with": ""},
+	}
+
+	if _, err := db.Collection(models.CollectionFolders).UpdateMany(ctx, filter, update); err != nil {
+		return err
+	}
+	_, err := db.Collection(models.CollectionFiles).UpdateMany(ctx, bson.M{"trashed_with": bson.M{"$in": folderIDs}}, update)
+	return err
+}
+
+// getRootFolderID retrieves the root folder ID of the user
+// In an organization it is the oldest shared drive, an item never leaves the workspace it was trashed in
+func (tr *TrashRepository) getRootFolderID(ctx context.Context, ownerID primitive.ObjectID) (primitive.ObjectID, error) {
+	filter := bson.M{"owner_id": ownerID, "organization_id": nil, "is_root": true}
+	if organizationID := tenantOrganizationID(ctx); organizationID != nil {
+		filter = bson.M{"organization_id": *organizationID, "is_root": true, "is_deleted": false}
+	}
+
+	folder := &models.Folder{}
+	err := tr.database.Collection(models.CollectionFolders).FindOne(ctx, filter, options.FindOne().SetSort(bson.M{"_id": 1})).Decode(folder)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("root folder not found: %w", err)
+	}
+
+	return folder.ID, nil
+}
+
+// PurgeItem permanently deletes a trashed item of the user
+// A folder is deleted with everything it contains. The returned chunks must be deleted from the block store.
+func (tr *TrashRepository) PurgeItem(ctx context.Context, ownerID primitive.ObjectID, itemType string, itemID primitive.ObjectID) ([]*models.ReleasedChunks, error) {
+	session, err := tr.database.Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		if _, err := tr.getTrashItem(sessCtx, ownerID, itemType, itemID, true); err != nil {
+			return nil, err
+		}
+
+		if itemType == models.TrashItemFolder {
+			return purgeFolderTree(sessCtx, tr.database, itemID)
+		}
+		return purgeFiles(sessCtx, tr.database, bson.M{"_id": itemID})
+	}
+
+	result, err := session.WithTransaction(ctx, callback)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]*models.ReleasedChunks), nil
+}
+
+// EmptyTrash permanently deletes every trashed item of the user in the workspace of the request
+func (tr *TrashRepository) EmptyTrash(ctx context.Context, ownerID primitive.ObjectID) ([]*models.ReleasedChunks, error) {
+	session, err := tr.database.Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		trashFilter := bson.M{"owner_id": ownerID, "organization_id": tenantOrganizationID(ctx), "is_deleted": true}
+
+		var folders []*models.Folder
+		cursor, err := tr.database.Collection(models.CollectionFolders).Find(sessCtx, trashFilter)
+		if err != nil {
+			return nil, err
+		}
+		if err := cursor.All(sessCtx, &folders); err != nil {
+			return nil, err
+		}
+
+		released := []*models.ReleasedChunks{}
+		for _, folder := range folders {
+			folderReleased, err := purgeFolderTree(sessCtx, tr.database, folder.ID)
+			if err != nil {
+				return nil, err
+			}
+			released = append(released, folderReleased...)
+		}
+
+		fileReleased, err := purgeFiles(sessCtx, tr.database, trashFilter)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(released, fileReleased...), nil
+	}
+
+	result, err := session.WithTransaction(ctx, callback)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]*models.ReleasedChunks), nil
+}
+
+// purgeFolderTree deletes a folder, its subfolders and their files
+// It must run inside the caller's transaction
+func purgeFolderTree(ctx context.Context, db *mongo.Database, folderID primitive.ObjectID) ([]*models.ReleasedChunks, error) {
+	folderIDs, err := getFolderTreeIDs(ctx, db, folderID, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	released, err := purgeFiles(ctx, db, bson.M{"parent_folder_id": bson.M{"$in": folderIDs}})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Collection(models.CollectionFolderSharedUsers).DeleteMany(ctx, bson.M{"folder_id": bson.M{"$in": folderIDs}}); err != nil {
+		return nil, err
+	}
+	if _, err := db.Collection(models.CollectionFolderSharedGroups).DeleteMany(ctx, bson.M{"folder_id": bson.M{"$in": folderIDs}}); err != nil {
+		return nil, err
+	}
+	if err := deleteShareLinks(ctx, db, models.TrashItemFolder, folderIDs); err != nil {
+		return nil, err
+	}
+	if err := deleteShareInvitations(ctx, db, models.TrashItemFolder, folderIDs); err != nil {
+		return nil, err
+	}
+	if err := deleteOwnershipTransfers(ctx, db, models.TrashItemFolder, folderIDs); err != nil {
+		return nil, err
+	}
+	if _, err := db.Collection(models.CollectionFolders).DeleteMany(ctx, bson.M{"_id": bson.M{"$in": folderIDs}}); err != nil {
+		return nil, err
+	}
+
+	return released, nil
+}
+
+// purgeFiles deletes the files matching the filter with their chunk records and upload sessions
+// The storage used by the files is released. It must run inside the caller's transaction.
+func purgeFiles(ctx context.Context, db *mongo.Database, filter bson.M) ([]*models.ReleasedChunks, error) {
+	collection := db.Collection(models.CollectionFiles)
+
+	var files []*models.File
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &files); err != nil {
+		return nil, err
+	}
+
+	released := []*models.ReleasedChunks{}
+	fileIDs := []primitive.ObjectID{}
+	for _, file := range files {
+		fileReleased, err := releaseFileChunks(ctx, db, file.ID)
+		if err != nil {
+			return nil, err
+		}
+		fileReleased.OwnerID = file.OwnerID.Hex()
+
+		released = append(released, fileReleased)
+		fileIDs = append(fileIDs, file.ID)
+	}
+	if len(fileIDs) == 0 {
+		return released, nil
+	}
+
+	if err := releaseFilesStorage(ctx, db, files); err != nil {
+		return nil, err
+	}
+	if _, err := db.Collection(models.CollectionUploadSessions).DeleteMany(ctx, bson.M{"file_id": bson.M{"$in": fileIDs}}); err != nil {
+		return nil, err
+	}
+	if _, err := db.Collection(models.CollectionFileSharedUsers).DeleteMany(ctx, bson.M{"file_id": bson.M{"$in": fileIDs}}); err != nil {
+		return nil, err
+	}
+	if _, err := db.Collection(models.CollectionFileSharedGroups).DeleteMany(ctx, bson.M{"file_id": bson.M{"$in": fileIDs}}); err != nil {
+		return nil, err
+	}
+	if err := deleteShareLinks(ctx, db, models.TrashItemFile, fileIDs); err != nil {
+		return nil, err
+	}
+	if err := deleteShareInvitations(ctx, db, models.TrashItemFile, fileIDs); err != nil {
+		return nil, err
+	}
+	if err := deleteOwnershipTransfers(ctx, db, models.TrashItemFile, fileIDs); err != nil {
+		return nil, err
+	}
+	if _, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": fileIDs}}); err != nil {
+		return nil, err
+	}
+
+	return released, nil
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"fmt"
 	"skybox-backend/internal/api/models"
 	"slices"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type UploadSessionRepository struct {
@@ -50,7 +52,7 @@ func (ur *UploadSessionRepository) GetSessionRecord(ctx context.Context, session
 	return &session, nil
 }
 
-// GetSessionRecordByFileID retrieves a upload session record by file ID
+// GetSessionRecordByFileID retrieves the latest upload session record of a file ID
 func (ur *UploadSessionRepository) GetSessionRecordByFileID(ctx context.Context, fileID string) (*models.UploadSession, error) {
 	collection := ur.database.Collection(ur.collection)
 
@@ -60,7 +62,9 @@ func (ur *UploadSessionRepository) GetSessionRecordByFileID(ctx context.Context,
 		return nil, err
 	}
 
-	err = collection.FindOne(ctx, bson.M{"file_id": fileIDObj}).Decode(&session)
+	// A file has one session per version, use the most recent one
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})
+	err = collection.FindOne(ctx, bson.M{"file_id": fileIDObj}, findOptions).Decode(&session)
 	if err != nil {
 		return nil, err
 	}
@@ -94,6 +98,8 @@ func (ur *UploadSessionRepository) GetSessionRecordByUserID(ctx context.Context,
 	return &sessions, nil
 }
 
+// AddChunkSessionRecord adds a chunk to an existing upload session record
+// The session is not completed by its last chunk, only CompleteSessionRecord checks the chunks and completes it
 func (ur *UploadSessionRepository) AddChunkSessionRecord(ctx context.Context, sessionToken string, chunkNumber int, chunkSize int, chunkHash string) error {
 	session, err := ur.database.Client().StartSession()
 	if err != nil {
@@ -105,13 +111,18 @@ func (ur *UploadSessionRepository) AddChunkSessionRecord(ctx context.Context, se
 	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
 		collection := ur.database.Collection(ur.collection)
 		chunkCollection := ur.database.Collection(models.CollectionChunks)
-		fileCollection := ur.database.Collection(models.CollectionFiles)
 
 		// Re-fetch inside transaction
 		sessionRecord, err := ur.GetSessionRecord(sessCtx, sessionToken)
 		if err != nil {
 			return nil, err
 		}
+		if sessionRecord.Status == "cancelled" {
+			return nil, fmt.Errorf("invalid upload session: the session is cancelled")
+		}
+		if sessionRecord.IsExpired(time.Now()) {
+			return nil, fmt.Errorf("invalid upload session: the session is expired")
+		}
 		if slices.Contains(sessionRecord.ChunkList, chunkNumber) {
 			return nil, nil // Already added
 		}
@@ -119,6 +130,7 @@ func (ur *UploadSessionRepository) AddChunkSessionRecord(ctx context.Context, se
 		// Insert chunk
 		if _, err := chunkCollection.InsertOne(sessCtx, bson.M{
 			"file_id":     sessionRecord.FileID,
+			"session_id":  sessionRecord.ID,
 			"chunk_index": chunkNumber,
 			"chunk_size":  int64(chunkSize),
 			"chunk_hash":  chunkHash,
@@ -127,37 +139,21 @@ func (ur *UploadSessionRepository) AddChunkSessionRecord(ctx context.Context, se
 			return nil, err
 		}
 
+		// Add a reference to the content-addressed block
+		if err := incrementBlockRefCount(sessCtx, ur.database, chunkHash, int64(chunkSize)); err != nil {
+			return nil, err
+		}
+
 		// Atomically update session
 		update := bson.M{
 			"$addToSet": bson.M{"chunk_list": chunkNumber},
 			"$inc":      bson.M{"actual_size": int64(chunkSize)},
+			"$set":      bson.M{"updated_at": time.Now()},
 		}
 		if _, err := collection.UpdateOne(sessCtx, bson.M{"session_token": sessionToken}, update); err != nil {
 			return nil, err
 		}
 
-		// Re-fetch updated session to decide if it's complete
-		sessionRecord, err = ur.GetSessionRecord(sessCtx, sessionToken)
-		if err != nil {
-			return nil, err
-		}
-		if sessionRecord.TotalSize <= sessionRecord.ActualSize {
-			// Complete session and file
-			if _, err := collection.UpdateOne(sessCtx, bson.M{"session_token": sessionToken}, bson.M{
-				"$set": bson.M{"status": "completed"},
-			}); err != nil {
-				return nil, err
-			}
-			if _, err := fileCollection.UpdateOne(sessCtx, bson.M{"_id": sessionRecord.FileID}, bson.M{
-				"$set": bson.M{
-					"status":       "uploaded",
-					"total_chunks": len(sessionRecord.ChunkList) + 1, // +1 for current chunk
-				},
-			}); err != nil {
-				return nil, err
-			}
-		}
-
 		return nil, nil
 	}
 
@@ -166,6 +162,7 @@ func (ur *UploadSessionRepository) AddChunkSessionRecord(ctx context.Context, se
 }
 
 // AddChunkSessionRecordByFileID adds a chunk to an existing upload session record using file ID
+// The session is not completed by its last chunk, only CompleteSessionRecord checks the chunks and completes it
 func (ur *UploadSessionRepository) AddChunkSessionRecordByFileID(ctx context.Context, fileID string, chunkNumber int, chunkSize int, chunkHash string) error {
 	session, err := ur.database.Client().StartSession()
 	if err != nil {
@@ -176,13 +173,18 @@ func (ur *UploadSessionRepository) AddChunkSessionRecordByFileID(ctx context.Con
 	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
 		collection := ur.database.Collection(ur.collection)
 		chunkCollection := ur.database.Collection(models.CollectionChunks)
-		fileCollection := ur.database.Collection(models.CollectionFiles)
 
 		// Fetch session inside transaction
 		sessionRecord, err := ur.GetSessionRecordByFileID(sessCtx, fileID)
 		if err != nil {
 			return nil, err
 		}
+		if sessionRecord.Status == "cancelled" {
+			return nil, fmt.Errorf("invalid upload session: the session is cancelled")
+		}
+		if sessionRecord.IsExpired(time.Now()) {
+			return nil, fmt.Errorf("invalid upload session: the session is expired")
+		}
 		if slices.Contains(sessionRecord.ChunkList, chunkNumber) {
 			return nil, nil // Already uploaded
 		}
@@ -190,6 +192,7 @@ func (ur *UploadSessionRepository) AddChunkSessionRecordByFileID(ctx context.Con
 		// Insert chunk
 		if _, err := chunkCollection.InsertOne(sessCtx, bson.M{
 			"file_id":     sessionRecord.FileID,
+			"session_id":  sessionRecord.ID,
 			"chunk_index": chunkNumber,
 			"chunk_size":  int64(chunkSize),
 			"chunk_hash":  chunkHash,
@@ -198,38 +201,387 @@ func (ur *UploadSessionRepository) AddChunkSessionRecordByFileID(ctx context.Con
 			return nil, err
 		}
 
+		// Add a reference to the content-addressed block
+		if err := incrementBlockRefCount(sessCtx, ur.database, chunkHash, int64(chunkSize)); err != nil {
+			return nil, err
+		}
+
 		// Update session
-		if _, err := collection.UpdateOne(sessCtx, bson.M{"file_id": sessionRecord.FileID}, bson.M{
+		if _, err := collection.UpdateOne(sessCtx, bson.M{"_id": sessionRecord.ID}, bson.M{
 			"$addToSet": bson.M{"chunk_list": chunkNumber},
 			"$inc":      bson.M{"actual_size": int64(chunkSize)},
+			"$set":      bson.M{"updated_at": time.Now()},
 		}); err != nil {
 			return nil, err
 		}
 
-		// Re-fetch updated session
-		sessionRecord, err = ur.GetSessionRecordByFileID(sessCtx, fileID)
+		return nil, nil
+	}
+
+	_, err = session.WithTransaction(ctx, callback)
+	return err
+}
+
+// CompleteSessionRecord completes an upload session after checking every chunk has been uploaded
+// The chunks must be numbered from 0 without gaps and their sizes must add up to the total size of the session
+func (ur *UploadSessionRepository) CompleteSessionRecord(ctx context.Context, sessionToken string) (*models.UploadSession, error) {
+	session, err := ur.database.Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		collection := ur.database.Collection(ur.collection)
+		chunkCollection := ur.database.Collection(models.CollectionChunks)
+
+		sessionRecord, err := ur.GetSessionRecord(sessCtx, sessionToken)
 		if err != nil {
 			return nil, err
 		}
-		if sessionRecord.TotalSize <= sessionRecord.ActualSize {
-			if _, err := collection.UpdateOne(sessCtx, bson.M{"file_id": sessionRecord.FileID}, bson.M{
-				"$set": bson.M{"status": "completed"},
-			}); err != nil {
-				return nil, err
-			}
-			if _, err := fileCollection.UpdateOne(sessCtx, bson.M{"_id": sessionRecord.FileID}, bson.M{
-				"$set": bson.M{
-					"status":       "uploaded",
-					"total_chunks": len(sessionRecord.ChunkList) + 1, // include current
-				},
-			}); err != nil {
-				return nil, err
+		if sessionRecord.Status == "cancelled" || sessionRecord.Status == "expired" {
+			return nil, fmt.Errorf("invalid upload session: the session is %s", sessionRecord.Status)
+		}
+		if sessionRecord.Status == "completed" {
+			return sessionRecord, nil // Already completed
+		}
+
+		// Get the chunks of the session ordered by index
+		findOptions := options.Find().SetSort(bson.D{{Key: "chunk_index", Value: 1}})
+		cursor, err := chunkCollection.Find(sessCtx, sessionChunksFilter(sessionRecord), findOptions)
+		if err != nil {
+			return nil, err
+		}
+		var chunks []models.Chunk
+		if err := cursor.All(sessCtx, &chunks); err != nil {
+			return nil, err
+		}
+
+		// Every index from 0 must be present and the sizes must match the declared size
+		var totalSize int64
+		for i, chunk := range chunks {
+			if chunk.ChunkIndex != i {
+				return nil, fmt.Errorf("invalid upload session: chunk %d is missing", i)
 			}
+			totalSize += chunk.ChunkSize
+		}
+		if totalSize != sessionRecord.TotalSize {
+			return nil, fmt.Errorf("invalid upload session: uploaded %d bytes, expected %d bytes", totalSize, sessionRecord.TotalSize)
 		}
 
-		return nil, nil
+		if _, err := collection.UpdateOne(sessCtx, bson.M{"session_token": sessionToken}, bson.M{
+			"$set": bson.M{"status": "completed", "updated_at": time.Now()},
+		}); err != nil {
+			return nil, err
+		}
+		if err := completeFileVersion(sessCtx, ur.database, sessionRecord, len(chunks)); err != nil {
+			return nil, err
+		}
+
+		sessionRecord.Status = "completed"
+		return sessionRecord, nil
 	}
 
-	_, err = session.WithTransaction(ctx, callback)
-	return err
+	result, err := session.WithTransaction(ctx, callback)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*models.UploadSession), nil
+}
+
+// CancelSessionRecord cancels an upload session
+// The chunk records of the session are deleted and their blocks are released.
+// The file is marked as deleted, unless the session uploads a new version of an uploaded file.
+// It returns the released blocks and the legacy chunk indexes, only the legacy chunk objects must be deleted by the caller
+func (ur *UploadSessionRepository) CancelSessionRecord(ctx context.Context, sessionToken string) (*models.CancelUploadSessionResponse, error) {
+	session, err := ur.database.Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		collection := ur.database.Collection(ur.collection)
+		fileCollection := ur.database.Collection(models.CollectionFiles)
+
+		sessionRecord, err := ur.GetSessionRecord(sessCtx, sessionToken)
+		if err != nil {
+			return nil, err
+		}
+		if sessionRecord.Status == "completed" {
+			return nil, fmt.Errorf("invalid upload session: the session is already completed")
+		}
+
+		// Release the blocks of the uploaded chunks
+		response, err := releaseSessionChunks(sessCtx, ur.database, sessionRecord)
+		if err != nil {
+			return nil, err
+		}
+
+		// Mark the session as cancelled
+		if _, err := collection.UpdateOne(sessCtx, bson.M{"session_token": sessionToken}, bson.M{
+			"$set": bson.M{
+				"status":      "cancelled",
+				"chunk_list":  []int{},
+				"actual_size": 0,
+				"updated_at":  time.Now(),
+			},
+		}); err != nil {
+			return nil, err
+		}
+
+		// A new version leaves the file unchanged
+		if sessionRecord.Version > 1 {
+			return response, nil
+		}
+
+		// The file never finished uploading, hide it
+		now := time.Now()
+		if _, err := fileCollection.UpdateOne(sessCtx, bson.M{"_id": sessionRecord.FileID}, bson.M{
+			"$set": bson.M{
+				"status":     "cancelled",
+				"is_deleted": true,
+				"deleted_at": now,
+				"updated_at": now,
+			},
+		}); err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	result, err := session.WithTransaction(ctx, callback)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*models.CancelUploadSessionResponse), nil
+}
+
+// releaseSessionChunks deletes the chunk records of the session and releases their blocks
+// It must run inside the caller's transaction
+func releaseSessionChunks(ctx context.Context, db *mongo.Database, sessionRecord *models.UploadSession) (*models.CancelUploadSessionResponse, error) {
+	released, err := releaseChunks(ctx, db, sessionRecord.FileID, sessionChunksFilter(sessionRecord))
+	if err != nil {
+		return nil, err
+	}
+
+	// The legacy chunk objects belong to the first version
+	if sessionRecord.Version > 1 {
+		released.ChunkIndexes = []int{}
+	}
+
+	return &models.CancelUploadSessionResponse{
+		FileID:         released.FileID,
+		ChunkIndexes:   released.ChunkIndexes,
+		ReleasedBlocks: released.ReleasedBlocks,
+	}, nil
+}
+
+// completeFileVersion makes the version uploaded by the session the current version of the file
+// The version is added to the storage used by the owner, or by the organization of a shared drive, and the
+// statistics of the folders are updated with the new size of the file. It must run inside the caller's transaction.
+func completeFileVersion(ctx context.Context, db *mongo.Database, sessionRecord *models.UploadSession, totalChunks int) error {
+	now := time.Now()
+	version := max(sessionRecord.Version, 1)
+
+	previous := &models.File{}
+	err := db.Collection(models.CollectionFiles).FindOneAndUpdate(ctx, bson.M{"_id": sessionRecord.FileID}, bson.M{
+		"$set": bson.M{
+			"status":          "uploaded",
+			"size":            sessionRecord.TotalSize,
+			"total_chunks":    totalChunks,
+			"current_version": version,
+			"updated_at":      now,
+		},
+		"$push": bson.M{
+			"versions": models.FileVersion{
+				Version:     version,
+				SessionID:   sessionRecord.ID,
+				Size:        sessionRecord.TotalSize,
+				TotalChunks: totalChunks,
+				UploadedBy:  sessionRecord.UserID,
+				CreatedAt:   now,
+			},
+		},
+	}).Decode(previous)
+	if err != nil {
+		return err
+	}
+	if err := addFileStorageUsed(ctx, db, previous, sessionRecord.TotalSize); err != nil {
+		return err
+	}
+	if !countsInParent(previous.IsDeleted, previous.TrashedWith) {
+		return nil
+	}
+
+	// The first version adds the file, a new version changes its size
+	current := fileStats(&models.File{Status: "uploaded", Size: sessionRecord.TotalSize})
+	replaced := fileStats(previous)
+	return addFolderStats(ctx, db, previous.ParentFolderID, models.FolderStat{
+		TotalFiles: current.TotalFiles - replaced.TotalFiles,
+		TotalSize:  current.TotalSize - replaced.TotalSize,
+	})
+}
+
+// ExtendSessionRecord moves the expiry of a pending upload session
+func (ur *UploadSessionRepository) ExtendSessionRecord(ctx context.Context, sessionToken string, expiresAt time.Time) (*models.UploadSession, error) {
+	collection := ur.database.Collection(ur.collection)
+
+	session := &models.UploadSession{}
+	err := collection.FindOneAndUpdate(ctx, bson.M{
+		"session_token": sessionToken,
+		"status":        "pending",
+	}, bson.M{
+		"$set": bson.M{
+			"expires_at": expiresAt,
+			"updated_at": time.Now(),
+		},
+	}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(session)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("invalid upload session: only pending sessions can be extended")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// expiredSessionsFilter matches the pending sessions whose expiry has passed
+// Sessions created before the expiry was introduced have no expires_at, their creation time is read from the ObjectID
+func expiredSessionsFilter(now time.Time, legacyCreatedBefore time.Time) bson.M {
+	return bson.M{
+		"status": "pending",
+		"$or": bson.A{
+			bson.M{"expires_at": bson.M{"$lt": now, "$gt": time.Time{}}},
+			bson.M{
+				"expires_at": bson.M{"$exists": false},
+				"_id":        bson.M{"$lt": primitive.NewObjectIDFromTimestamp(legacyCreatedBefore)},
+			},
+		},
+	}
+}
+
+// GetReservedStorage returns the storage reserved by the pending uploads to the personal drive of the user,
+// or to the shared drives of the organization when organizationID is not nil
+// A session reserves its declared size until it is completed, cancelled or expired, the session excludeSessionID is left out
+func (ur *UploadSessionRepository) GetReservedStorage(ctx context.Context, userID primitive.ObjectID, organizationID *primitive.ObjectID, excludeSessionID primitive.ObjectID, now time.Time) (int64, error) {
+	collection := ur.database.Collection(ur.collection)
+
+	filter := bson.M{
+		"_id":        bson.M{"$ne": excludeSessionID},
+		"status":     "pending",
+		"expires_at": bson.M{"$gt": now},
+	}
+	if organizationID != nil {
+		filter["organization_id"] = *organizationID
+	} else {
+		filter["organization_id"] = nil
+		filter["$or"] = bson.A{
+			bson.M{"owner_id": userID},
+			bson.M{"owner_id": bson.M{"$exists": false}, "user_id": userID},
+		}
+	}
+
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      nil,
+			"reserved": bson.M{"$sum": bson.M{"$max": bson.A{"$total_size", "$actual_size"}}},
+		}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Reserved int64 `bson:"reserved"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return 0, err
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+
+	return results[0].Reserved, nil
+}
+
+// GetExpiredSessionRecords retrieves up to limit pending sessions whose expiry has passed
+func (ur *UploadSessionRepository) GetExpiredSessionRecords(ctx context.Context, now time.Time, legacyCreatedBefore time.Time, limit int64) ([]models.UploadSession, error) {
+	collection := ur.database.Collection(ur.collection)
+
+	cursor, err := collection.Find(ctx, expiredSessionsFilter(now, legacyCreatedBefore), options.Find().SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []models.UploadSession
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// ExpireSessionRecord marks a pending session as expired
+// The chunk records are deleted and their blocks released, the file record is deleted if it is still pending
+// It returns the released chunks so they can be deleted from the block store
+func (ur *UploadSessionRepository) ExpireSessionRecord(ctx context.Context, sessionToken string, now time.Time) (*models.CancelUploadSessionResponse, error) {
+	session, err := ur.database.Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
+		collection := ur.database.Collection(ur.collection)
+		fileCollection := ur.database.Collection(models.CollectionFiles)
+
+		// Re-check inside the transaction, the session may have been completed or extended meanwhile
+		sessionRecord, err := ur.GetSessionRecord(sessCtx, sessionToken)
+		if err != nil {
+			return nil, err
+		}
+		if sessionRecord.Status != "pending" || (!sessionRecord.ExpiresAt.IsZero() && !now.After(sessionRecord.ExpiresAt)) {
+			return nil, nil
+		}
+
+		response, err := releaseSessionChunks(sessCtx, ur.database, sessionRecord)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := collection.UpdateOne(sessCtx, bson.M{"session_token": sessionToken}, bson.M{
+			"$set": bson.M{
+				"status":      "expired",
+				"chunk_list":  []int{},
+				"actual_size": 0,
+				"updated_at":  now,
+			},
+		}); err != nil {
+			return nil, err
+		}
+
+		if _, err := fileCollection.DeleteOne(sessCtx, bson.M{"_id": sessionRecord.FileID, "status": "pending"}); err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	result, err := session.WithTransaction(ctx, callback)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, nil // Nothing to expire
+	}
+
+	return result.(*models.CancelUploadSessionResponse), nil
 }
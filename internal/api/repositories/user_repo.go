@@ -34,11 +34,13 @@ func (ur *UserRepository) CreateUser(ctx context.Context, user *models.User) err
 	if err != nil {
 		return err
 	}
+	user.ID = result.InsertedID.(primitive.ObjectID)
 
 	// Create the root folder for the user
 	rootFolder := &models.Folder{
 		OwnerID:        result.InsertedID.(primitive.ObjectID),
 		ParentFolderID: primitive.NilObjectID,
+		AncestorIDs:    []primitive.ObjectID{},
 		Name:           "Root",
 		IsDeleted:      false,
 		IsRoot:         true,
@@ -173,3 +175,32 @@ func (ur *UserRepository) UpdateUserLastLogin(ctx context.Context, id string) er
 
 	return nil
 }
+
+// GetStorageBreakdown retrieves the storage used by the files of a user grouped by mime type, largest first
+// The files the user owns in the shared drives of an organization use the storage of the organization and are left out
+func (ur *UserRepository) GetStorageBreakdown(ctx context.Context, id string) ([]models.MimeTypeUsage, error) {
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := ur.database.Collection(models.CollectionFiles).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"owner_id": idHex, "organization_id": nil, "status": "uploaded"}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         "$mime_type",
+			"total_files": bson.M{"$sum": 1},
+			"total_size":  bson.M{"$sum": storageSizeExpression},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "total_size", Value: -1}, {Key: "_id", Value: 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown := []models.MimeTypeUsage{}
+	if err := cursor.All(ctx, &breakdown); err != nil {
+		return nil, err
+	}
+
+	return breakdown, nil
+}
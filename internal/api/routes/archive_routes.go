@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NewArchiveRouters sets up the routes and the corresponding handlers for the selection archives
+func NewArchiveRouters(db *mongo.Database, group *gin.RouterGroup) {
+	// Initialize the application container
+	appContainer := GetApplicationContainer(db)
+	ac := appContainer.ArchiveController
+
+	archiveGroup := group.Group("/archives")
+	{
+		// The permissions of every selected item are checked by the handler
+		archiveGroup.POST("", ac.CreateArchiveHandler)
+	}
+}
+
+// NewArchiveTokenRouters sets up the archive routes authenticated by a download token instead of the JWT header
+// They are called by the block server while streaming an archive
+func NewArchiveTokenRouters(db *mongo.Database, group *gin.RouterGroup) {
+	// Initialize the application container
+	appContainer := GetApplicationContainer(db)
+	ac := appContainer.ArchiveController
+
+	archiveGroup := group.Group("/archives")
+	{
+		archiveGroup.GET("/content", ac.GetSelectionArchiveHandler)
+	}
+}
@@ -17,12 +17,17 @@ func NewFileRouters(db *mongo.Database, group *gin.RouterGroup) {
 	appContainer := GetApplicationContainer(db)
 	fr := appContainer.FileRepository
 	fc := appContainer.FileController
+	fvc := appContainer.FileVersionController
+	cc := appContainer.CopyController
+	slc := appContainer.ShareLinkController
+	ic := appContainer.InvitationController
+	gc := appContainer.GroupController
 	usr := appContainer.UploadSessionRepository
 
 	folderRepo := repositories.NewFolderRepository(db, models.CollectionFolders)
 	folderController := &controllers.FolderController{
 		FolderService: services.NewFolderService(folderRepo),
-		FileService:   services.NewFileService(fr, usr),
+		FileService:   services.NewFileService(fr, usr, appContainer.UserRepository, appContainer.OrganizationRepository),
 	}
 
 	// Create a new group for the file routes
@@ -33,7 +38,37 @@ func NewFileRouters(db *mongo.Database, group *gin.RouterGroup) {
 		fileGroup.PUT("/:fileId/rename", middlewares.FilePermissionMiddleware(folderController, "edit"), fc.RenameFileHandler)
 		fileGroup.PATCH("/:fileId/rename", middlewares.FilePermissionMiddleware(folderController, "edit"), fc.RenameFileHandler)
 		fileGroup.PUT("/:fileId/move", middlewares.FilePermissionMiddleware(folderController, "edit"), fc.MoveFileHandler)
+		fileGroup.POST("/:fileId/copy", middlewares.FilePermissionMiddleware(folderController, "view"), cc.CopyFileHandler)
 
-		fileGroup.GET("/:fileId/download", fc.FullDownloadFileHandler)
+		fileGroup.GET("/:fileId/download", middlewares.FilePermissionMiddleware(folderController, "view"), fc.FullDownloadFileHandler)
+
+		fileGroup.POST("/:fileId/share", middlewares.FilePermissionMiddleware(folderController, "share"), fc.ShareFileHandler)
+		fileGroup.DELETE("/:fileId/share", middlewares.FilePermissionMiddleware(folderController, "share"), fc.RemoveFileShareHandler)
+		fileGroup.GET("/:fileId/shared-users", middlewares.FilePermissionMiddleware(folderController, "edit"), fc.GetFileSharedUsersHandler)
+		fileGroup.POST("/:fileId/links", middlewares.FilePermissionMiddleware(folderController, "share"), slc.CreateFileShareLinkHandler)
+		fileGroup.GET("/:fileId/links", middlewares.FilePermissionMiddleware(folderController, "share"), slc.GetFileShareLinksHandler)
+		fileGroup.POST("/:fileId/invitations", middlewares.FilePermissionMiddleware(folderController, "share"), ic.ShareFileByEmailHandler)
+		fileGroup.GET("/:fileId/invitations", middlewares.FilePermissionMiddleware(folderController, "share"), ic.GetFileInvitationsHandler)
+		fileGroup.POST("/:fileId/share/groups", middlewares.FilePermissionMiddleware(folderController, "share"), gc.ShareFileWithGroupHandler)
+		fileGroup.DELETE("/:fileId/share/groups/:groupId", middlewares.FilePermissionMiddleware(folderController, "share"), gc.RemoveFileGroupShareHandler)
+		fileGroup.GET("/:fileId/shared-groups", middlewares.FilePermissionMiddleware(folderController, "edit"), gc.GetFileSharedGroupsHandler)
+
+		fileGroup.GET("/:fileId/versions", middlewares.FilePermissionMiddleware(folderController, "view"), fvc.GetFileVersionsHandler)
+		fileGroup.POST("/:fileId/versions", middlewares.FilePermissionMiddleware(folderController, "edit"), fvc.CreateFileVersionHandler)
+		fileGroup.GET("/:fileId/versions/:version/download", middlewares.FilePermissionMiddleware(folderController, "view"), fvc.DownloadFileVersionHandler)
+		fileGroup.POST("/:fileId/versions/:version/restore", middlewares.FilePermissionMiddleware(folderController, "edit"), fvc.RestoreFileVersionHandler)
+	}
+}
+
+// NewFileTokenRouters sets up the file routes authenticated by a download token instead of the JWT header
+// They are called by the block server while streaming a download
+func NewFileTokenRouters(db *mongo.Database, group *gin.RouterGroup) {
+	// Initialize the application container
+	appContainer := GetApplicationContainer(db)
+	fc := appContainer.FileController
+
+	fileGroup := group.Group("/files")
+	{
+		fileGroup.GET("/:fileId/chunks", fc.GetFileChunksHandler)
 	}
 }
@@ -12,6 +12,11 @@ func NewFolderRouters(db *mongo.Database, group *gin.RouterGroup) {
 	// Initialize the application container
 	appContainer := GetApplicationContainer(db)
 	fc := appContainer.FolderController
+	ac := appContainer.ArchiveController
+	cc := appContainer.CopyController
+	slc := appContainer.ShareLinkController
+	ic := appContainer.InvitationController
+	gc := appContainer.GroupController
 
 	// Create a new group for the folder routes
 	folderGroup := group.Group("/folders")
@@ -23,17 +28,40 @@ func NewFolderRouters(db *mongo.Database, group *gin.RouterGroup) {
 		folderGroup.PUT("/:folderId/rename", middlewares.FolderPermissionMiddleware(fc, "edit"), fc.RenameFolderHandler)
 		folderGroup.PATCH("/:folderId/rename", middlewares.FolderPermissionMiddleware(fc, "edit"), fc.RenameFolderHandler)
 		folderGroup.PUT("/:folderId/move", middlewares.FolderPermissionMiddleware(fc, "edit"), fc.MoveFolderHandler)
+		folderGroup.POST("/:folderId/copy", middlewares.FolderPermissionMiddleware(fc, "view"), cc.CopyFolderHandler)
+		folderGroup.GET("/:folderId/download", middlewares.FolderPermissionMiddleware(fc, "view"), ac.DownloadFolderHandler)
 
-		folderGroup.POST("/:folderId/upload", middlewares.FolderPermissionMiddleware(fc, "edit"), fc.UploadFileMetadataHandler) // TODO: Implement upload file metadata handler
+		// The uploaders can add files without seeing the content of the folder
+		folderGroup.POST("/:folderId/upload", middlewares.FolderPermissionMiddleware(fc, "upload"), fc.UploadFileMetadataHandler)
 
 		// Share
-		folderGroup.PUT("/:folderId/public-status", middlewares.FolderPermissionMiddleware(fc, "edit"), fc.UpdateFolderPublicStatusHandler)
-		folderGroup.PUT("/:folderId/public-status/all", middlewares.FolderPermissionMiddleware(fc, "edit"), fc.UpdateFolderAndSubfoldersPublicStatusHandler)
+		folderGroup.PUT("/:folderId/public-status", middlewares.FolderPermissionMiddleware(fc, "share"), fc.UpdateFolderPublicStatusHandler)
+		folderGroup.PUT("/:folderId/public-status/all", middlewares.FolderPermissionMiddleware(fc, "share"), fc.UpdateFolderAndSubfoldersPublicStatusHandler)
 		folderGroup.GET("/:folderId/public-status", middlewares.FolderPermissionMiddleware(fc, "view"), fc.GetFolderPublicStatusHandler)
-		folderGroup.POST("/:folderId/share", middlewares.FolderPermissionMiddleware(fc, "edit"), fc.ShareFolderHandler)
-		folderGroup.DELETE("/:folderId/share", middlewares.FolderPermissionMiddleware(fc, "edit"), fc.RemoveFolderShareHandler)
+		folderGroup.POST("/:folderId/share", middlewares.FolderPermissionMiddleware(fc, "share"), fc.ShareFolderHandler)
+		folderGroup.DELETE("/:folderId/share", middlewares.FolderPermissionMiddleware(fc, "share"), fc.RemoveFolderShareHandler)
 		folderGroup.GET("/:folderId/shared-users", middlewares.FolderPermissionMiddleware(fc, "edit"), fc.GetFolderSharedUsersHandler)
-		folderGroup.POST("/:folderId/share/all", middlewares.FolderPermissionMiddleware(fc, "edit"), fc.ShareFolderAndSubfoldersHandler)
-		folderGroup.DELETE("/:folderId/share/all", middlewares.FolderPermissionMiddleware(fc, "edit"), fc.RevokeFolderAndSubfoldersShareHandler)
+		folderGroup.POST("/:folderId/share/all", middlewares.FolderPermissionMiddleware(fc, "share"), fc.ShareFolderAndSubfoldersHandler)
+		folderGroup.DELETE("/:folderId/share/all", middlewares.FolderPermissionMiddleware(fc, "share"), fc.RevokeFolderAndSubfoldersShareHandler)
+		folderGroup.POST("/:folderId/links", middlewares.FolderPermissionMiddleware(fc, "share"), slc.CreateFolderShareLinkHandler)
+		folderGroup.GET("/:folderId/links", middlewares.FolderPermissionMiddleware(fc, "share"), slc.GetFolderShareLinksHandler)
+		folderGroup.POST("/:folderId/invitations", middlewares.FolderPermissionMiddleware(fc, "share"), ic.ShareFolderByEmailHandler)
+		folderGroup.GET("/:folderId/invitations", middlewares.FolderPermissionMiddleware(fc, "share"), ic.GetFolderInvitationsHandler)
+		folderGroup.POST("/:folderId/share/groups", middlewares.FolderPermissionMiddleware(fc, "share"), gc.ShareFolderWithGroupHandler)
+		folderGroup.DELETE("/:folderId/share/groups/:groupId", middlewares.FolderPermissionMiddleware(fc, "share"), gc.RemoveFolderGroupShareHandler)
+		folderGroup.GET("/:folderId/shared-groups", middlewares.FolderPermissionMiddleware(fc, "edit"), gc.GetFolderSharedGroupsHandler)
+	}
+}
+
+// NewFolderTokenRouters sets up the folder routes authenticated by a download token instead of the JWT header
+// They are called by the block server while streaming an archive
+func NewFolderTokenRouters(db *mongo.Database, group *gin.RouterGroup) {
+	// Initialize the application container
+	appContainer := GetApplicationContainer(db)
+	ac := appContainer.ArchiveController
+
+	folderGroup := group.Group("/folders")
+	{
+		folderGroup.GET("/:folderId/archive", ac.GetFolderArchiveHandler)
 	}
 }
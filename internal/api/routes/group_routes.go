@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NewGroupRouters sets up the routes managing the groups and their members
+// The files and folders are shared with the groups from the file and folder routes
+func NewGroupRouters(db *mongo.Database, group *gin.RouterGroup) {
+	// Initialize the application container
+	appContainer := GetApplicationContainer(db)
+	gc := appContainer.GroupController
+
+	// The membership and the admin role are checked by the service
+	groupGroup := group.Group("/groups")
+	{
+		groupGroup.POST("", gc.CreateGroupHandler)
+		groupGroup.GET("", gc.GetGroupsHandler)
+		groupGroup.GET("/:groupId", gc.GetGroupHandler)
+		groupGroup.PATCH("/:groupId", gc.UpdateGroupHandler)
+		groupGroup.DELETE("/:groupId", gc.DeleteGroupHandler)
+		groupGroup.GET("/:groupId/members", gc.GetGroupMembersHandler)
+		groupGroup.PUT("/:groupId/members", gc.SetGroupMemberHandler)
+		groupGroup.DELETE("/:groupId/members/:userId", gc.RemoveGroupMemberHandler)
+	}
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NewInvitationRouters sets up the routes managing the pending invitations
+// The invitations are sent from the file and folder routes
+func NewInvitationRouters(db *mongo.Database, group *gin.RouterGroup) {
+	// Initialize the application container
+	appContainer := GetApplicationContainer(db)
+	ic := appContainer.InvitationController
+
+	invitationGroup := group.Group("/invitations")
+	{
+		// The invitations are accepted by the user of their email, with the token of one of their links
+		invitationGroup.POST("/accept", ic.AcceptInvitationsHandler)
+
+		// The share permission on the invited item is checked by the handler
+		invitationGroup.DELETE("/:invitationId", ic.DeleteInvitationHandler)
+	}
+}
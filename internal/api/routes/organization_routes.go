@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NewOrganizationRouters sets up the routes managing the organizations, their members and their shared drives
+// The content of the shared drives is managed with the folder and file routes
+func NewOrganizationRouters(db *mongo.Database, group *gin.RouterGroup) {
+	// Initialize the application container
+	appContainer := GetApplicationContainer(db)
+	oc := appContainer.OrganizationController
+
+	// The membership and the admin role are checked by the service
+	organizationGroup := group.Group("/organizations")
+	{
+		organizationGroup.POST("", oc.CreateOrganizationHandler)
+		organizationGroup.GET("", oc.GetOrganizationsHandler)
+		organizationGroup.GET("/:organizationId", oc.GetOrganizationHandler)
+		organizationGroup.PATCH("/:organizationId", oc.UpdateOrganizationHandler)
+		organizationGroup.GET("/:organizationId/members", oc.GetOrganizationMembersHandler)
+		organizationGroup.PUT("/:organizationId/members", oc.SetOrganizationMemberHandler)
+		organizationGroup.DELETE("/:organizationId/members/:userId", oc.RemoveOrganizationMemberHandler)
+		organizationGroup.POST("/:organizationId/drives", oc.CreateSharedDriveHandler)
+		organizationGroup.GET("/:organizationId/drives", oc.GetSharedDrivesHandler)
+	}
+}
@@ -1,12 +1,16 @@
 package routes
 
 import (
+	"fmt"
+
 	"skybox-backend/configs"
 	"skybox-backend/internal/api/controllers"
+	"skybox-backend/internal/api/mailer"
 	"skybox-backend/internal/api/models"
 	"skybox-backend/internal/api/repositories"
 	"skybox-backend/internal/api/services"
 	"skybox-backend/internal/shared/middlewares"
+	"skybox-backend/internal/shared/storage"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -16,55 +20,112 @@ import (
 
 type ApplicationContainer struct {
 	// Repositories
+	BlockRepository         *repositories.BlockRepository
 	ChunkRepository         *repositories.ChunkRepository
+	CopyRepository          *repositories.CopyRepository
 	FileRepository          *repositories.FileRepository
+	FileVersionRepository   *repositories.FileVersionRepository
 	FolderRepository        *repositories.FolderRepository
+	GroupRepository         *repositories.GroupRepository
+	InvitationRepository    *repositories.InvitationRepository
+	OrganizationRepository  *repositories.OrganizationRepository
+	ShareLinkRepository     *repositories.ShareLinkRepository
+	SharedItemRepository    *repositories.SharedItemRepository
 	UserRepository          *repositories.UserRepository
 	UserTokenRepository     *repositories.UserTokenRepository
 	UploadSessionRepository *repositories.UploadSessionRepository
 
+	// Mailer
+	Mailer mailer.Mailer
+
 	// Services
+	ArchiveService       *services.ArchiveService
 	AuthService          *services.AuthService
 	ChunkService         *services.ChunkService
+	CopyService          *services.CopyService
 	FileService          *services.FileService
+	FileVersionService   *services.FileVersionService
 	FolderService        *services.FolderService
+	GroupService         *services.GroupService
+	InvitationService    *services.InvitationService
+	OrganizationService  *services.OrganizationService
+	ShareLinkService     *services.ShareLinkService
+	SharedItemService    *services.SharedItemService
 	UserService          *services.UserService
 	UserTokenService     *services.UserTokenService
 	UploadSessionService *services.UploadSessionService
 
 	// Controllers
+	ArchiveController       *controllers.ArchiveController
 	AuthController          *controllers.AuthController
+	CopyController          *controllers.CopyController
 	FileController          *controllers.FileController
+	FileVersionController   *controllers.FileVersionController
 	FolderController        *controllers.FolderController
+	GroupController         *controllers.GroupController
+	InvitationController    *controllers.InvitationController
+	OrganizationController  *controllers.OrganizationController
+	ShareLinkController     *controllers.ShareLinkController
+	SharedItemController    *controllers.SharedItemController
 	UploadSessionController *controllers.UploadSessionController
 	UserController          *controllers.UserController
 }
 
 func (app *ApplicationContainer) SetupRepositories(db *mongo.Database) {
+	app.BlockRepository = repositories.NewBlockRepository(db, models.CollectionBlocks)
 	app.ChunkRepository = repositories.NewChunkRepository(db, models.CollectionChunks)
+	app.CopyRepository = repositories.NewCopyRepository(db)
 	app.FileRepository = repositories.NewFileRepository(db, models.CollectionFiles)
+	app.FileVersionRepository = repositories.NewFileVersionRepository(db)
 	app.FolderRepository = repositories.NewFolderRepository(db, models.CollectionFolders)
+	app.GroupRepository = repositories.NewGroupRepository(db, models.CollectionGroups)
+	app.InvitationRepository = repositories.NewInvitationRepository(db, models.CollectionShareInvitations)
+	app.OrganizationRepository = repositories.NewOrganizationRepository(db, models.CollectionOrganizations)
+	app.ShareLinkRepository = repositories.NewShareLinkRepository(db, models.CollectionShareLinks)
+	app.SharedItemRepository = repositories.NewSharedItemRepository(db)
 	app.UserRepository = repositories.NewUserRepository(db, models.CollectionUsers)
 	app.UserTokenRepository = repositories.NewUserTokenRepository(db, models.CollectionUserTokens)
 	app.UploadSessionRepository = repositories.NewUploadSessionRepository(db, models.CollectionUploadSessions)
 }
 
 func (app *ApplicationContainer) SetupServices() {
+	var err error
+	app.Mailer, err = mailer.NewMailer()
+	if err != nil {
+		panic(fmt.Errorf("failed to create mailer: %w", err))
+	}
+
+	app.ArchiveService = services.NewArchiveService(app.FileRepository, app.FolderRepository, app.ChunkRepository)
 	app.AuthService = services.NewAuthService(app.UserRepository)
 	app.ChunkService = services.NewChunkService(app.ChunkRepository)
-	app.FileService = services.NewFileService(app.FileRepository, app.UploadSessionRepository)
+	app.CopyService = services.NewCopyService(app.CopyRepository, app.UserRepository, app.OrganizationRepository)
+	app.FileService = services.NewFileService(app.FileRepository, app.UploadSessionRepository, app.UserRepository, app.OrganizationRepository)
+	app.FileVersionService = services.NewFileVersionService(app.FileVersionRepository, app.FileRepository, app.UploadSessionRepository, app.UserRepository, app.OrganizationRepository)
 	app.FolderService = services.NewFolderService(app.FolderRepository)
+	app.GroupService = services.NewGroupService(app.GroupRepository, app.UserRepository)
+	app.InvitationService = services.NewInvitationService(app.InvitationRepository, app.UserRepository, app.FolderRepository, app.FileRepository, app.Mailer)
+	app.OrganizationService = services.NewOrganizationService(app.OrganizationRepository, app.UserRepository)
+	app.ShareLinkService = services.NewShareLinkService(app.ShareLinkRepository)
+	app.SharedItemService = services.NewSharedItemService(app.SharedItemRepository)
 	app.UserService = services.NewUserService(app.UserRepository)
 	app.UserTokenService = services.NewUserTokenService(app.UserTokenRepository)
-	app.UploadSessionService = services.NewUploadSessionService(app.UploadSessionRepository)
+	app.UploadSessionService = services.NewUploadSessionService(app.UploadSessionRepository, app.ChunkRepository, app.UserRepository, app.OrganizationRepository, app.BlockRepository)
 }
 
 func (app *ApplicationContainer) SetupControllers() {
-	app.AuthController = controllers.NewAuthController(app.AuthService, app.UserTokenService)
-	app.FileController = controllers.NewFileController(app.FileService)
+	app.AuthController = controllers.NewAuthController(app.AuthService, app.UserTokenService, app.InvitationService)
 	app.FolderController = controllers.NewFolderController(app.FolderService, app.FileService)
+	app.FileController = controllers.NewFileController(app.FileService, app.ChunkService, app.FolderController)
+	app.FileVersionController = controllers.NewFileVersionController(app.FileVersionService)
 	app.UploadSessionController = controllers.NewUploadSessionController(app.UploadSessionService)
 	app.UserController = controllers.NewUserController(app.UserService)
+	app.ArchiveController = controllers.NewArchiveController(app.ArchiveService, app.FolderController)
+	app.CopyController = controllers.NewCopyController(app.CopyService, app.FolderController)
+	app.GroupController = controllers.NewGroupController(app.GroupService, app.FolderController)
+	app.InvitationController = controllers.NewInvitationController(app.InvitationService, app.FolderController)
+	app.OrganizationController = controllers.NewOrganizationController(app.OrganizationService)
+	app.ShareLinkController = controllers.NewShareLinkController(app.ShareLinkService, app.FolderController)
+	app.SharedItemController = controllers.NewSharedItemController(app.SharedItemService)
 }
 
 var appContainer *ApplicationContainer
@@ -84,7 +145,7 @@ func GetApplicationContainer(db *mongo.Database) *ApplicationContainer {
 }
 
 // SetupRoutes sets up the routes and the corresponding handlers
-func SetupRouter(db *mongo.Database, gin *gin.Engine) *gin.Engine {
+func SetupRouter(db *mongo.Database, gin *gin.Engine, store storage.BlockStore) *gin.Engine {
 	// Swagger routes
 	gin.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -101,6 +162,14 @@ func SetupRouter(db *mongo.Database, gin *gin.Engine) *gin.Engine {
 		// Setup the user routes
 		NewUserRouters(db, v1)
 
+		// Setup the token-authenticated file routes
+		NewFileTokenRouters(db, v1)
+		NewFolderTokenRouters(db, v1)
+		NewArchiveTokenRouters(db, v1)
+
+		// Setup the share link routes, the visitors have no account
+		NewShareLinkPublicRouters(db, publicRouter)
+
 		// Hello World routes
 		v1.GET("/hello", controllers.HelloWorldHandler)
 	}
@@ -108,6 +177,7 @@ func SetupRouter(db *mongo.Database, gin *gin.Engine) *gin.Engine {
 	// Private routes
 	protectedRouter := gin.Group("")
 	protectedRouter.Use(middlewares.JwtAuthMiddleware(configs.Config.JWTSecret))
+	protectedRouter.Use(middlewares.TenantMiddleware(GetApplicationContainer(db).OrganizationController))
 
 	v1 = protectedRouter.Group("/api/v1")
 
@@ -123,6 +193,30 @@ func SetupRouter(db *mongo.Database, gin *gin.Engine) *gin.Engine {
 
 		// Setup the search routes
 		NewSearchRouters(db, v1)
+
+		// Setup the trash routes
+		NewTrashRouters(db, v1, store)
+
+		// Setup the archive routes
+		NewArchiveRouters(db, v1)
+
+		// Setup the shared items routes
+		NewSharedRouters(db, v1)
+
+		// Setup the share link management routes
+		NewShareLinkRouters(db, v1)
+
+		// Setup the invitation routes
+		NewInvitationRouters(db, v1)
+
+		// Setup the ownership transfer routes
+		NewTransferRouters(db, v1, store)
+
+		// Setup the group routes
+		NewGroupRouters(db, v1)
+
+		// Setup the organization routes
+		NewOrganizationRouters(db, v1)
 	}
 
 	return gin
@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NewShareLinkRouters sets up the routes managing the share links
+// The links are created from the file and folder routes
+func NewShareLinkRouters(db *mongo.Database, group *gin.RouterGroup) {
+	// Initialize the application container
+	appContainer := GetApplicationContainer(db)
+	slc := appContainer.ShareLinkController
+
+	linkGroup := group.Group("/links")
+	{
+		// The share permission on the linked item is checked by the handlers
+		linkGroup.PATCH("/:linkId", slc.UpdateShareLinkHandler)
+		linkGroup.DELETE("/:linkId", slc.DeleteShareLinkHandler)
+	}
+}
+
+// NewShareLinkPublicRouters sets up the /s/:token routes opened by the visitors of a share link
+// They are authenticated by the token of the link and its password instead of the JWT header
+func NewShareLinkPublicRouters(db *mongo.Database, group *gin.RouterGroup) {
+	// Initialize the application container
+	appContainer := GetApplicationContainer(db)
+	slc := appContainer.ShareLinkController
+
+	linkGroup := group.Group("/s")
+	{
+		linkGroup.GET("/:token", slc.GetShareLinkContentHandler)
+		linkGroup.GET("/:token/folders/:folderId", slc.GetShareLinkFolderHandler)
+		linkGroup.GET("/:token/download", slc.DownloadShareLinkFileHandler)
+		linkGroup.GET("/:token/files/:fileId/download", slc.DownloadShareLinkFileHandler)
+	}
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NewSharedRouters sets up the routes listing the items shared between users
+func NewSharedRouters(db *mongo.Database, group *gin.RouterGroup) {
+	// Initialize the application container
+	appContainer := GetApplicationContainer(db)
+	sic := appContainer.SharedItemController
+
+	sharedGroup := group.Group("/shared")
+	{
+		sharedGroup.GET("/with-me", sic.GetSharedWithMeHandler)
+		sharedGroup.GET("/by-me", sic.GetSharedByMeHandler)
+		sharedGroup.PUT("/with-me/:itemType/:itemId/hidden", sic.HideShareHandler)
+	}
+}
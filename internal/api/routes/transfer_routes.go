@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"skybox-backend/internal/api/controllers"
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/api/repositories"
+	"skybox-backend/internal/api/services"
+	"skybox-backend/internal/shared/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NewTransferRouters sets up the ownership transfer routes
+// The transfers need the block store to re-key the chunks stored under the key prefix of the previous owner
+func NewTransferRouters(db *mongo.Database, group *gin.RouterGroup, store storage.BlockStore) {
+	// Initialize the application container
+	appContainer := GetApplicationContainer(db)
+	transferRepository := repositories.NewOwnershipTransferRepository(db, models.CollectionOwnershipTransfers)
+	transferService := services.NewOwnershipTransferService(transferRepository, appContainer.UserRepository, appContainer.FolderRepository, appContainer.FileRepository, store)
+	otc := controllers.NewOwnershipTransferController(transferService)
+
+	// The owner of the item and the recipient are checked by the service
+	transferGroup := group.Group("/transfers")
+	{
+		transferGroup.POST("", otc.RequestTransferHandler)
+		transferGroup.GET("", otc.GetTransfersHandler)
+		transferGroup.POST("/:transferId/accept", otc.AcceptTransferHandler)
+		transferGroup.POST("/:transferId/decline", otc.DeclineTransferHandler)
+		transferGroup.DELETE("/:transferId", otc.CancelTransferHandler)
+	}
+}
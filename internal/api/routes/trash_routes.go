@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"skybox-backend/internal/api/controllers"
+	"skybox-backend/internal/api/repositories"
+	"skybox-backend/internal/api/services"
+	"skybox-backend/internal/shared/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NewTrashRouters sets up the routes and the corresponding handlers
+// The trash needs the block store to delete the data of the purged files
+func NewTrashRouters(db *mongo.Database, group *gin.RouterGroup, store storage.BlockStore) {
+	trashRepository := repositories.NewTrashRepository(db)
+	trashService := services.NewTrashService(trashRepository, store)
+	tc := controllers.NewTrashController(trashService)
+
+	// Create a new group for the trash routes
+	trashGroup := group.Group("/trash")
+	{
+		trashGroup.GET("", tc.GetTrashHandler)
+		trashGroup.DELETE("", tc.EmptyTrashHandler)
+		trashGroup.POST("/:itemType/:itemId/restore", tc.RestoreTrashItemHandler)
+		trashGroup.DELETE("/:itemType/:itemId", tc.PurgeTrashItemHandler)
+	}
+}
@@ -16,8 +16,13 @@ func NewUploadRouters(db *mongo.Database, group *gin.RouterGroup) {
 	{
 		folderGroup.GET("/:sessionToken", usc.GetUploadSessionHandler)
 		folderGroup.PUT("/:sessionToken", usc.AddChunkHandler)
+		folderGroup.DELETE("/:sessionToken", usc.CancelUploadSessionHandler)
+		folderGroup.GET("/:sessionToken/chunks", usc.GetSessionChunksHandler)
+		folderGroup.POST("/:sessionToken/complete", usc.CompleteUploadSessionHandler)
+		folderGroup.PUT("/:sessionToken/extend", usc.ExtendUploadSessionHandler)
 		folderGroup.GET("/file/:fileID", usc.GetSessionRecordByFileIDHandler)
 		folderGroup.PUT("/file/:fileID", usc.AddChunkViaFileIDHandler)
 		folderGroup.GET("/user/:userID", usc.GetSessionRecordByUserIDHandler)
+		folderGroup.POST("/blocks", usc.AcquireBlockHandler)
 	}
 }
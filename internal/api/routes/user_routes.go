@@ -30,5 +30,6 @@ func NewUserRouters(db *mongo.Database, group *gin.RouterGroup) {
 	// Private Routes
 	{
 		userGroup.GET("/info", uc.GetUserInformationHandler)
+		privateGroup.GET("/usage", uc.GetStorageUsageHandler)
 	}
 }
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"skybox-backend/configs"
+	"skybox-backend/internal/api/models"
+	"skybox-backend/pkg/utils"
+)
+
+// MaxArchiveSelection is the maximum number of files and folders of a selection archive
+// The selection is part of the download URL, so it must stay small
+const MaxArchiveSelection = 100
+
+// SelectionArchiveName is the file name of the selection archives
+const SelectionArchiveName = "download.zip"
+
+// ArchiveService lists the content of the zip archives streamed by the block server
+type ArchiveService struct {
+	fileRepository   models.FileRepository
+	folderRepository models.FolderRepository
+	chunkRepository  models.ChunkRepository
+}
+
+// NewArchiveService creates a new instance of the ArchiveService
+func NewArchiveService(fr models.FileRepository, fdr models.FolderRepository, cr models.ChunkRepository) *ArchiveService {
+	return &ArchiveService{
+		fileRepository:   fr,
+		folderRepository: fdr,
+		chunkRepository:  cr,
+	}
+}
+
+// FolderArchiveURL returns the block server URL streaming the zip archive of a folder
+// The download token carries the user, the block server lists the archive with the permissions of the user
+func FolderArchiveURL(folderID string, userID string) (string, error) {
+	token, err := utils.GenerateToken(
+		map[string]string{
+			"folderId": folderID,
+			"userId":   userID,
+		},
+		configs.Config.JWTSecret,
+		1,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("http://%s:%s/download/folders/%s?token=%s",
+		configs.Config.BlockServerHost,
+		configs.Config.BlockServerPort,
+		folderID,
+		token,
+	), nil
+}
+
+// SelectionArchiveURL returns the block server URL streaming the zip archive of a selection of files and folders
+// The download token carries the selection and the user, the block server lists the archive with the permissions of the user
+func SelectionArchiveURL(fileIDs []string, folderIDs []string, userID string) (string, error) {
+	token, err := utils.GenerateToken(
+		map[string]string{
+			"fileIds":   strings.Join(fileIDs, ","),
+			"folderIds": strings.Join(folderIDs, ","),
+			"userId":    userID,
+		},
+		configs.Config.JWTSecret,
+		1,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("http://%s:%s/download/archive?token=%s",
+		configs.Config.BlockServerHost,
+		configs.Config.BlockServerPort,
+		token,
+	), nil
+}
+
+// archiveName makes a file or folder name safe to use as a path element of an archive
+func archiveName(name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(strings.TrimSpace(name))
+	if name == "" || name == "." || name == ".." {
+		return "_"
+	}
+
+	return name
+}
+
+// uniqueArchiveName returns the archive name of the file or folder, numbered when the name is already used
+// Names are compared without case, so the archive extracts the same way on every file system
+func uniqueArchiveName(used map[string]bool, name string) string {
+	name = archiveName(name)
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	if base == "" {
+		base, ext = name, "" // Dot files have no extension
+	}
+
+	unique := name
+	for i := 1; used[strings.ToLower(unique)]; i++ {
+		unique = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+	used[strings.ToLower(unique)] = true
+
+	return unique
+}
+
+// archiveFileEntry returns the archive entry of the current version of an uploaded file
+func (as *ArchiveService) archiveFileEntry(ctx context.Context, file *models.File, filePath string) (models.ArchiveEntry, error) {
+	chunks, err := as.chunkRepository.GetChunksByFileVersion(ctx, file.ID.Hex(), 0)
+	if err != nil {
+		return models.ArchiveEntry{}, err
+	}
+
+	return models.ArchiveEntry{
+		Path:       filePath,
+		FileID:     file.ID.Hex(),
+		OwnerID:    file.OwnerID.Hex(),
+		Size:       file.Size,
+		ModifiedAt: file.UpdatedAt,
+		Chunks:     chunks,
+	}, nil
+}
+
+// GetFolderArchive lists the folders and the uploaded files of a folder tree, the tree is under a folder named after the folder
+// canView is called for every subfolder, the subtrees the user cannot view are left out
+func (as *ArchiveService) GetFolderArchive(ctx context.Context, folderID string, canView func(folderID string) bool) (*models.ArchiveResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	folder, err := as.folderRepository.GetFolderByID(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := archiveName(folder.Name)
+	archive := &models.ArchiveResponse{
+		Name:    name + ".zip",
+		Entries: []models.ArchiveEntry{{Path: name + "/", ModifiedAt: folder.UpdatedAt}},
+	}
+	if err := as.addFolderEntries(ctx, archive, folderID, name+"/", canView); err != nil {
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+// addFolderEntries adds the content of a folder to the archive, the subfolders are added recursively
+func (as *ArchiveService) addFolderEntries(ctx context.Context, archive *models.ArchiveResponse, folderID string, prefix string, canView func(folderID string) bool) error {
+	folders, err := as.folderRepository.GetFolderListInFolder(ctx, folderID)
+	if err != nil {
+		return err
+	}
+	files, err := as.folderRepository.GetFileListInFolder(ctx, folderID)
+	if err != nil {
+		return err
+	}
+
+	// Folders and files of the same folder share the names
+	used := map[string]bool{}
+
+	for _, file := range files {
+		if file.Status != "uploaded" {
+			continue
+		}
+
+		entry, err := as.archiveFileEntry(ctx, file, prefix+uniqueArchiveName(used, file.FileName))
+		if err != nil {
+			return err
+		}
+		archive.Entries = append(archive.Entries, entry)
+	}
+
+	for _, folder := range folders {
+		if !canView(folder.ID.Hex()) {
+			continue
+		}
+
+		folderPath := prefix + uniqueArchiveName(used, folder.Name) + "/"
+		archive.Entries = append(archive.Entries, models.ArchiveEntry{Path: folderPath, ModifiedAt: folder.UpdatedAt})
+		if err := as.addFolderEntries(ctx, archive, folder.ID.Hex(), folderPath, canView); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// uniqueIDs returns the IDs without the duplicates, in their first order
+func uniqueIDs(ids []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, id := range ids {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	return unique
+}
+
+// getSelection returns the selected files and folders
+// canViewFile is called for every file, so the files shared on their own can be selected, and canView for every folder.
+// The selection fails when an item cannot be viewed
+func (as *ArchiveService) getSelection(ctx context.Context, fileIDs []string, folderIDs []string, canView func(folderID string) bool, canViewFile func(file *models.File) bool) ([]*models.File, []*models.Folder, error) {
+	fileIDs, folderIDs = uniqueIDs(fileIDs), uniqueIDs(folderIDs)
+	if len(fileIDs)+len(folderIDs) == 0 {
+		return nil, nil, fmt.Errorf("invalid selection: no file or folder is selected")
+	}
+	if len(fileIDs)+len(folderIDs) > MaxArchiveSelection {
+		return nil, nil, fmt.Errorf("invalid selection: at most %d files and folders can be archived", MaxArchiveSelection)
+	}
+
+	files := []*models.File{}
+	for _, fileID := range fileIDs {
+		file, err := as.fileRepository.GetFileByID(ctx, fileID)
+		if err != nil || !canViewFile(file) {
+			return nil, nil, fmt.Errorf("you do not have permission to download the file %s", fileID)
+		}
+		if file.Status != "uploaded" {
+			return nil, nil, fmt.Errorf("invalid selection: the file %s is not uploaded", fileID)
+		}
+		files = append(files, file)
+	}
+
+	folders := []*models.Folder{}
+	for _, folderID := range folderIDs {
+		if !canView(folderID) {
+			return nil, nil, fmt.Errorf("you do not have permission to download the folder %s", folderID)
+		}
+		folder, err := as.folderRepository.GetFolderByID(ctx, folderID)
+		if err != nil {
+			return nil, nil, err
+		}
+		folders = append(folders, folder)
+	}
+
+	return files, folders, nil
+}
+
+// CheckSelection checks that every selected file and folder can be downloaded by the user
+func (as *ArchiveService) CheckSelection(ctx context.Context, fileIDs []string, folderIDs []string, canView func(folderID string) bool, canViewFile func(file *models.File) bool) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	_, _, err := as.getSelection(ctx, fileIDs, folderIDs, canView, canViewFile)
+	return err
+}
+
+// GetSelectionArchive lists the selected files and the trees of the selected folders
+// The selected items are at the top of the archive, their names are numbered when they collide
+func (as *ArchiveService) GetSelectionArchive(ctx context.Context, fileIDs []string, folderIDs []string, canView func(folderID string) bool, canViewFile func(file *models.File) bool) (*models.ArchiveResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	files, folders, err := as.getSelection(ctx, fileIDs, folderIDs, canView, canViewFile)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &models.ArchiveResponse{
+		Name:    SelectionArchiveName,
+		Entries: []models.ArchiveEntry{},
+	}
+	used := map[string]bool{}
+
+	for _, file := range files {
+		entry, err := as.archiveFileEntry(ctx, file, uniqueArchiveName(used, file.FileName))
+		if err != nil {
+			return nil, err
+		}
+		archive.Entries = append(archive.Entries, entry)
+	}
+
+	for _, folder := range folders {
+		folderPath := uniqueArchiveName(used, folder.Name) + "/"
+		archive.Entries = append(archive.Entries, models.ArchiveEntry{Path: folderPath, ModifiedAt: folder.UpdatedAt})
+		if err := as.addFolderEntries(ctx, archive, folder.ID.Hex(), folderPath, canView); err != nil {
+			return nil, err
+		}
+	}
+
+	return archive, nil
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"skybox-backend/internal/api/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUniqueArchiveName(t *testing.T) {
+	used := map[string]bool{}
+
+	names := []string{"report.pdf", "Report.PDF", "report.pdf", "notes", "notes", ".env", ".env", "a/b\\c", "..", ""}
+	expected := []string{"report.pdf", "Report (1).PDF", "report (2).pdf", "notes", "notes (1)", ".env", ".env (1)", "a_b_c", "_", "_ (1)"}
+	for i, name := range names {
+		assert.Equal(t, expected[i], uniqueArchiveName(used, name))
+	}
+}
+
+func TestUniqueIDs(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, uniqueIDs([]string{"a", "", "b", "a"}))
+	assert.Empty(t, uniqueIDs(nil))
+}
+
+func TestCheckSelection_FileSharedWithoutItsFolder(t *testing.T) {
+	shared := &models.File{ID: primitive.NewObjectID(), ParentFolderID: primitive.NewObjectID(), Status: "uploaded"}
+	private := &models.File{ID: primitive.NewObjectID(), ParentFolderID: shared.ParentFolderID, Status: "uploaded"}
+	files := &fakeFileRepository{files: map[string]*models.File{shared.ID.Hex(): shared, private.ID.Hex(): private}}
+	archiveService := NewArchiveService(files, nil, nil)
+
+	// The folder is not shared with the user, only the first file is
+	canView := func(folderID string) bool { return false }
+	canViewFile := func(file *models.File) bool { return file == shared }
+
+	assert.NoError(t, archiveService.CheckSelection(context.Background(), []string{shared.ID.Hex()}, nil, canView, canViewFile))
+	err := archiveService.CheckSelection(context.Background(), []string{shared.ID.Hex(), private.ID.Hex()}, nil, canView, canViewFile)
+	assert.ErrorContains(t, err, "permission")
+}
@@ -42,6 +42,14 @@ func (cs *ChunkService) GetChunksByFileID(ctx context.Context, fileId string) ([
 	return cs.chunkRepository.GetChunksByFileID(ctx, fileId)
 }
 
+// GetChunksByFileVersion retrieves the chunks of a file version from the database, 0 is the current version
+func (cs *ChunkService) GetChunksByFileVersion(ctx context.Context, fileId string, version int) ([]models.Chunk, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	return cs.chunkRepository.GetChunksByFileVersion(ctx, fileId, version)
+}
+
 // GetChunkByID retrieves a chunk by its ID from the database
 func (cs *ChunkService) GetChunkByID(ctx context.Context, id string) (*models.Chunk, error) {
 	ctx, cancel := context.WithCancel(ctx)
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CopyService is the service copying files and folders
+type CopyService struct {
+	copyRepository         models.CopyRepository
+	userRepository         models.UserRepository
+	organizationRepository models.OrganizationRepository
+}
+
+// NewCopyService creates a new instance of the CopyService
+func NewCopyService(cr models.CopyRepository, ur models.UserRepository, or models.OrganizationRepository) *CopyService {
+	return &CopyService{
+		copyRepository:         cr,
+		userRepository:         ur,
+		organizationRepository: or,
+	}
+}
+
+// parseCopyIDs converts the IDs of the copied item and of the destination folder
+func parseCopyIDs(id string, destFolderID string) (primitive.ObjectID, primitive.ObjectID, error) {
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, fmt.Errorf("invalid ID: %v", err)
+	}
+	destFolderIDHex, err := primitive.ObjectIDFromHex(destFolderID)
+	if err != nil {
+		return primitive.NilObjectID, primitive.NilObjectID, fmt.Errorf("invalid destination folder ID: %v", err)
+	}
+
+	return idHex, destFolderIDHex, nil
+}
+
+// checkCopyQuota checks that the copies fit in the storage quota of the user of the context,
+// or of the organization when the destination folder is in a shared drive
+func (cs *CopyService) checkCopyQuota(ctx context.Context, destFolderID primitive.ObjectID, fileIDs []primitive.ObjectID, folderIDs []primitive.ObjectID) error {
+	destFolder, err := cs.copyRepository.GetDestinationFolder(ctx, destFolderID)
+	if err != nil {
+		return err
+	}
+	size, err := cs.copyRepository.GetCopySize(ctx, fileIDs, folderIDs)
+	if err != nil {
+		return err
+	}
+
+	if destFolder.OrganizationID != nil {
+		return checkOrganizationStorageQuota(ctx, cs.organizationRepository, *destFolder.OrganizationID, size)
+	}
+	userID, ok := ctx.Value("x-user-id-hex").(primitive.ObjectID)
+	if !ok {
+		return fmt.Errorf("user ID not found in context or invalid type")
+	}
+
+	return checkStorageQuota(ctx, cs.userRepository, userID.Hex(), size)
+}
+
+// CopyFile copies the current version of a file into the destination folder
+// The copy must fit in the storage quota of the user, or of the organization in a shared drive
+func (cs *CopyService) CopyFile(ctx context.Context, fileID string, destFolderID string, newName string, conflict string) (*models.File, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	fileIDHex, destFolderIDHex, err := parseCopyIDs(fileID, destFolderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cs.checkCopyQuota(ctx, destFolderIDHex, []primitive.ObjectID{fileIDHex}, nil); err != nil {
+		return nil, err
+	}
+
+	return cs.copyRepository.CopyFile(ctx, fileIDHex, destFolderIDHex, newName, conflict)
+}
+
+// CopyFolder copies a folder tree into the destination folder
+// canView is called for every subfolder, the subtrees the user cannot view are not copied
+// The copied files must fit in the storage quota of the user, or of the organization in a shared drive
+func (cs *CopyService) CopyFolder(ctx context.Context, folderID string, destFolderID string, newName string, conflict string, canView func(folderID string) bool) (*models.Folder, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	folderIDHex, destFolderIDHex, err := parseCopyIDs(folderID, destFolderID)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := cs.copyRepository.GetFolderTree(ctx, folderIDHex)
+	if err != nil {
+		return nil, err
+	}
+
+	// A subfolder is copied when the user can view it and its parent is copied
+	copied := map[primitive.ObjectID]bool{folderIDHex: true}
+	folders := []*models.Folder{tree[0]}
+	for _, folder := range tree[1:] {
+		if copied[folder.ParentFolderID] && canView(folder.ID.Hex()) {
+			copied[folder.ID] = true
+			folders = append(folders, folder)
+		}
+	}
+
+	folderIDs := make([]primitive.ObjectID, 0, len(folders))
+	for _, folder := range folders {
+		folderIDs = append(folderIDs, folder.ID)
+	}
+	if err := cs.checkCopyQuota(ctx, destFolderIDHex, nil, folderIDs); err != nil {
+		return nil, err
+	}
+
+	return cs.copyRepository.CopyFolder(ctx, folders, destFolderIDHex, newName, conflict)
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"skybox-backend/configs"
+	"skybox-backend/internal/api/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeCopyRepository records the folders passed to CopyFolder
+type fakeCopyRepository struct {
+	models.CopyRepository
+	tree     []*models.Folder
+	copied   []*models.Folder
+	copySize int64
+}
+
+func (f *fakeCopyRepository) GetDestinationFolder(ctx context.Context, folderID primitive.ObjectID) (*models.Folder, error) {
+	return &models.Folder{ID: folderID}, nil
+}
+
+func (f *fakeCopyRepository) GetCopySize(ctx context.Context, fileIDs []primitive.ObjectID, folderIDs []primitive.ObjectID) (int64, error) {
+	return f.copySize, nil
+}
+
+func (f *fakeCopyRepository) GetFolderTree(ctx context.Context, folderID primitive.ObjectID) ([]*models.Folder, error) {
+	return f.tree, nil
+}
+
+func (f *fakeCopyRepository) CopyFolder(ctx context.Context, folders []*models.Folder, destFolderID primitive.ObjectID, newName string, conflict string) (*models.Folder, error) {
+	f.copied = folders
+	return folders[0], nil
+}
+
+func TestCopyFolder_SkipsSubtreesTheUserCannotView(t *testing.T) {
+	// root -> visible -> nested, root -> hidden -> below
+	root := &models.Folder{ID: primitive.NewObjectID(), Name: "root"}
+	visible := &models.Folder{ID: primitive.NewObjectID(), ParentFolderID: root.ID, Name: "visible"}
+	hidden := &models.Folder{ID: primitive.NewObjectID(), ParentFolderID: root.ID, Name: "hidden"}
+	nested := &models.Folder{ID: primitive.NewObjectID(), ParentFolderID: visible.ID, Name: "nested"}
+	below := &models.Folder{ID: primitive.NewObjectID(), ParentFolderID: hidden.ID, Name: "below"}
+
+	user := &models.User{ID: primitive.NewObjectID()}
+	repository := &fakeCopyRepository{tree: []*models.Folder{root, visible, hidden, nested, below}}
+	copyService := NewCopyService(repository, &fakeUserRepository{user: user}, nil)
+
+	canView := func(folderID string) bool { return folderID != hidden.ID.Hex() }
+	_, err := copyService.CopyFolder(context.WithValue(context.Background(), "x-user-id-hex", user.ID), root.ID.Hex(), primitive.NewObjectID().Hex(), "", "", canView)
+	require.NoError(t, err)
+	assert.Equal(t, []*models.Folder{root, visible, nested}, repository.copied)
+}
+
+func TestCopyFile_InvalidIDs(t *testing.T) {
+	copyService := NewCopyService(&fakeCopyRepository{}, nil, nil)
+
+	_, err := copyService.CopyFile(context.Background(), "not-an-id", primitive.NewObjectID().Hex(), "", "")
+	assert.ErrorContains(t, err, "invalid")
+	_, err = copyService.CopyFile(context.Background(), primitive.NewObjectID().Hex(), "not-an-id", "", "")
+	assert.ErrorContains(t, err, "invalid destination folder ID")
+}
+
+func TestCopyFolder_StorageQuota(t *testing.T) {
+	defaultQuota := configs.Config.DefaultStorageQuota
+	configs.Config.DefaultStorageQuota = 100
+	defer func() { configs.Config.DefaultStorageQuota = defaultQuota }()
+
+	root := &models.Folder{ID: primitive.NewObjectID(), Name: "root"}
+	user := &models.User{ID: primitive.NewObjectID(), StorageUsed: 60}
+	repository := &fakeCopyRepository{tree: []*models.Folder{root}, copySize: 41}
+	copyService := NewCopyService(repository, &fakeUserRepository{user: user}, nil)
+	ctx := context.WithValue(context.Background(), "x-user-id-hex", user.ID)
+	canView := func(folderID string) bool { return true }
+
+	// 60 used + 41 copied does not fit in the default quota
+	_, err := copyService.CopyFolder(ctx, root.ID.Hex(), primitive.NewObjectID().Hex(), "", "", canView)
+	assert.ErrorIs(t, err, models.ErrStorageQuotaExceeded)
+	_, err = copyService.CopyFile(ctx, primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex(), "", "")
+	assert.ErrorIs(t, err, models.ErrStorageQuotaExceeded)
+	assert.Nil(t, repository.copied)
+
+	repository.copySize = 40
+	_, err = copyService.CopyFolder(ctx, root.ID.Hex(), primitive.NewObjectID().Hex(), "", "", canView)
+	require.NoError(t, err)
+	assert.Equal(t, []*models.Folder{root}, repository.copied)
+}
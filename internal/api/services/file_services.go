@@ -3,9 +3,11 @@ package services
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"skybox-backend/configs"
 	"skybox-backend/internal/api/models"
+	"skybox-backend/pkg/utils"
 
 	"github.com/google/uuid"
 )
@@ -14,53 +16,123 @@ import (
 type FileService struct {
 	fileRepository          models.FileRepository
 	uploadSessionRepository models.UploadSessionRepository
+	userRepository          models.UserRepository
+	organizationRepository  models.OrganizationRepository
 }
 
 // NewFileService creates a new instance of the FileService
-func NewFileService(fr models.FileRepository, usr models.UploadSessionRepository) *FileService {
+func NewFileService(fr models.FileRepository, usr models.UploadSessionRepository, ur models.UserRepository, or models.OrganizationRepository) *FileService {
 	return &FileService{
 		fileRepository:          fr,
 		uploadSessionRepository: usr,
+		userRepository:          ur,
+		organizationRepository:  or,
 	}
 }
 
-// UploadFileMetadata uploads the metadata of a file and returns the saved file and upload URL
-// It also decides whether to create an upload session for chunked uploads or a single upload URL for whole file uploads
+// UploadFileMetadata uploads the metadata of a file and returns the saved file and its upload session
+// The chunks are uploaded to the block server using the session, see UploadSessionURL
+// When the upload replaces an uploaded file, the session uploads a new version of that file
+// A new file belongs to the uploader, also in a folder shared with the uploader, and uses the storage quota
+// of the uploader, or of the organization in a shared drive. A new version uses the storage of the owner of the file.
+// The declared size must fit in that quota next to the sizes declared by the other pending uploads
 // TODO: Handle concurrency and chunked uploads
-func (fr *FileService) UploadFileMetadata(ctx context.Context, file *models.File) (*models.File, string, error) {
+func (fr *FileService) UploadFileMetadata(ctx context.Context, file *models.File, conflict string) (*models.File, *models.UploadSession, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	savedFile, err := fr.fileRepository.UploadFileMetadata(ctx, file)
+	// The declared size is reserved next to the other pending uploads of the owner
+	quotaSession := &models.UploadSession{UserID: file.OwnerID, OwnerID: file.OwnerID, OrganizationID: file.OrganizationID}
+	if err := checkUploadQuota(ctx, fr.uploadSessionRepository, fr.userRepository, fr.organizationRepository, quotaSession, file.Size); err != nil {
+		return nil, nil, err
+	}
+
+	uploaderID := file.OwnerID
+	file.LatestVersion = 1
+	savedFile, err := fr.fileRepository.UploadFileMetadata(ctx, file, conflict)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
+	}
+
+	// The replaced file of another user uses the storage quota of its owner
+	if savedFile.OrganizationID == nil && savedFile.OwnerID != uploaderID {
+		quotaSession.OwnerID = savedFile.OwnerID
+		if err := checkUploadQuota(ctx, fr.uploadSessionRepository, fr.userRepository, fr.organizationRepository, quotaSession, file.Size); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	// Create a session for chunked uploads
+	now := time.Now()
 	sessionToken := uuid.New().String()
 	uploadSession := &models.UploadSession{
 		FileID:       savedFile.ID,
-		UserID:       savedFile.OwnerID,
+		UserID:       uploaderID,
 		SessionToken: sessionToken,
 		TotalSize:    file.Size,
 		ActualSize:   0,
 		ChunkList:    []int{}, // This will be updated later when chunks are uploaded
 		Status:       "pending",
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		ExpiresAt:    now.Add(configs.Config.UploadSessionTTL),
+		Version:      max(savedFile.LatestVersion, 1),
+
+		OrganizationID: savedFile.OrganizationID,
+		OwnerID:        savedFile.OwnerID,
 	}
 
 	_, err = fr.uploadSessionRepository.CreateSessionRecord(ctx, uploadSession)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to create upload session: %w", err)
+		return nil, nil, fmt.Errorf("failed to create upload session: %w", err)
 	}
 
-	// Create the uploadURL based on the uploadSession ID
-	uploadURL := fmt.Sprintf("http://%s:%s/upload/session/%s/chunk",
+	return savedFile, uploadSession, nil
+}
+
+// UploadSessionURL returns the block server URL where the chunks of the upload session are sent
+func UploadSessionURL(sessionToken string) string {
+	return fmt.Sprintf("http://%s:%s/upload/session/%s/chunk",
 		configs.Config.BlockServerHost,
 		configs.Config.BlockServerPort,
 		sessionToken,
 	)
+}
 
-	return savedFile, uploadURL, nil
+// FileDownloadURL returns the block server URL streaming a version of the file
+// The download token carries the version, so the block server reads the chunks of that version
+// The modification time is the file update time for the current version and the creation time for the older ones,
+// the block server sends it as Last-Modified.
+func FileDownloadURL(file *models.File, version *models.FileVersion) (string, error) {
+	modifiedAt := version.CreatedAt
+	if version.Version == max(file.CurrentVersion, 1) && !file.UpdatedAt.IsZero() {
+		modifiedAt = file.UpdatedAt
+	}
+
+	token, err := utils.GenerateToken(
+		map[string]string{
+			"fileId":      file.ID.Hex(),
+			"ownerId":     file.OwnerID.Hex(),
+			"totalChunks": fmt.Sprintf("%d", version.TotalChunks),
+			"fileName":    file.FileName,
+			"fileSize":    fmt.Sprintf("%d", version.Size),
+			"mimeType":    file.MimeType,
+			"version":     fmt.Sprintf("%d", version.Version),
+			"modifiedAt":  fmt.Sprintf("%d", modifiedAt.Unix()),
+		},
+		configs.Config.JWTSecret,
+		1,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("http://%s:%s/download/%s?token=%s",
+		configs.Config.BlockServerHost,
+		configs.Config.BlockServerPort,
+		file.ID.Hex(),
+		token,
+	), nil
 }
 
 func (fr *FileService) GetFileByID(ctx context.Context, id string) (*models.File, error) {
@@ -77,16 +149,92 @@ func (fr *FileService) DeleteFile(ctx context.Context, id string) error {
 	return fr.fileRepository.DeleteFile(ctx, id)
 }
 
-func (fr *FileService) RenameFile(ctx context.Context, id string, newName string) error {
+func (fr *FileService) RenameFile(ctx context.Context, id string, newName string, conflict string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	return fr.fileRepository.RenameFile(ctx, id, newName, conflict)
+}
+
+func (fr *FileService) MoveFile(ctx context.Context, id string, newParentFolderID string, conflict string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	return fr.fileRepository.MoveFile(ctx, id, newParentFolderID, conflict)
+}
+
+// GetFileSharedUsers retrieves the users a file is shared with, with the permissions given by their role
+func (fr *FileService) GetFileSharedUsers(ctx context.Context, fileID string) ([]*models.SharedUserResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sharedUsers, err := fr.fileRepository.GetFileSharedUsers(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	return withRolePermissions(sharedUsers), nil
+}
+
+func (fr *FileService) GetFileSharedUser(ctx context.Context, fileID, userID string) (*models.FileSharedUser, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	return fr.fileRepository.RenameFile(ctx, id, newName)
+	return fr.fileRepository.GetFileSharedUser(ctx, fileID, userID)
+}
+
+func (fr *FileService) ShareFile(ctx context.Context, fileID, userID string, role models.ShareRole) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if !role.IsValidForFile() {
+		return models.ErrInvalidShareRole
+	}
+
+	return fr.fileRepository.ShareFile(ctx, fileID, userID, role)
+}
+
+func (fr *FileService) RemoveFileShare(ctx context.Context, fileID, userID string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	return fr.fileRepository.RemoveFileShare(ctx, fileID, userID)
+}
+
+// GetFileSharedGroups retrieves the groups a file is shared with, with the permissions given by their role
+func (fr *FileService) GetFileSharedGroups(ctx context.Context, fileID string) ([]*models.SharedGroupResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sharedGroups, err := fr.fileRepository.GetFileSharedGroups(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	return withGroupRolePermissions(sharedGroups), nil
+}
+
+func (fr *FileService) GetFileGroupShares(ctx context.Context, fileID, userID string) ([]*models.FileSharedGroup, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	return fr.fileRepository.GetFileGroupShares(ctx, fileID, userID)
+}
+
+func (fr *FileService) ShareFileWithGroup(ctx context.Context, fileID, groupID string, role models.ShareRole) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if !role.IsValidForFile() {
+		return models.ErrInvalidShareRole
+	}
+
+	return fr.fileRepository.ShareFileWithGroup(ctx, fileID, groupID, role)
 }
 
-func (fr *FileService) MoveFile(ctx context.Context, id string, newParentFolderID string) error {
+func (fr *FileService) RemoveFileGroupShare(ctx context.Context, fileID, groupID string) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	return fr.fileRepository.MoveFile(ctx, id, newParentFolderID)
+	return fr.fileRepository.RemoveFileGroupShare(ctx, fileID, groupID)
 }
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"skybox-backend/configs"
+	"skybox-backend/internal/api/models"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FileVersionService is the service for the file versions
+type FileVersionService struct {
+	fileVersionRepository   models.FileVersionRepository
+	fileRepository          models.FileRepository
+	uploadSessionRepository models.UploadSessionRepository
+	userRepository          models.UserRepository
+	organizationRepository  models.OrganizationRepository
+}
+
+// NewFileVersionService creates a new instance of the FileVersionService
+func NewFileVersionService(fvr models.FileVersionRepository, fr models.FileRepository, usr models.UploadSessionRepository, ur models.UserRepository, or models.OrganizationRepository) *FileVersionService {
+	return &FileVersionService{
+		fileVersionRepository:   fvr,
+		fileRepository:          fr,
+		uploadSessionRepository: usr,
+		userRepository:          ur,
+		organizationRepository:  or,
+	}
+}
+
+// GetFileVersions retrieves the uploaded versions of a file
+func (fvs *FileVersionService) GetFileVersions(ctx context.Context, fileID string) (*models.FileVersionsResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	file, err := fvs.fileRepository.GetFileByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.FileVersionsResponse{
+		FileID:   file.ID.Hex(),
+		Versions: file.GetVersions(),
+	}
+	if currentVersion := file.GetVersion(0); currentVersion != nil {
+		response.CurrentVersion = currentVersion.Version
+	}
+
+	return response, nil
+}
+
+// CreateFileVersion creates the upload session of a new version of a file
+// The version becomes the current version when its upload session is completed.
+// The version uses the storage of the owner of the file, or of the organization in a shared drive
+func (fvs *FileVersionService) CreateFileVersion(ctx context.Context, fileID string, fileSize int64, userID string) (*models.CreateFileVersionResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if fileSize <= 0 {
+		return nil, fmt.Errorf("invalid file size")
+	}
+	uploaderID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID")
+	}
+
+	file, err := fvs.fileRepository.GetFileByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	// One upload at a time, the pending session must be completed or cancelled first
+	if session, err := fvs.uploadSessionRepository.GetSessionRecordByFileID(ctx, fileID); err == nil && session.Status == "pending" && !session.IsExpired(time.Now()) {
+		return nil, fmt.Errorf("cannot upload a new version while another upload of the file is pending")
+	}
+
+	quotaSession := &models.UploadSession{UserID: uploaderID, OwnerID: file.OwnerID, OrganizationID: file.OrganizationID}
+	if err := checkUploadQuota(ctx, fvs.uploadSessionRepository, fvs.userRepository, fvs.organizationRepository, quotaSession, fileSize); err != nil {
+		return nil, err
+	}
+
+	version, err := fvs.fileVersionRepository.NextVersion(ctx, file.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	uploadSession := &models.UploadSession{
+		FileID:       file.ID,
+		UserID:       uploaderID,
+		SessionToken: uuid.New().String(),
+		TotalSize:    fileSize,
+		ActualSize:   0,
+		ChunkList:    []int{},
+		Status:       "pending",
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		ExpiresAt:    now.Add(configs.Config.UploadSessionTTL),
+		Version:      version,
+
+		OrganizationID: file.OrganizationID,
+		OwnerID:        file.OwnerID,
+	}
+	if _, err := fvs.uploadSessionRepository.CreateSessionRecord(ctx, uploadSession); err != nil {
+		return nil, fmt.Errorf("failed to create upload session: %w", err)
+	}
+
+	return &models.CreateFileVersionResponse{
+		FileID:       file.ID.Hex(),
+		Version:      version,
+		UploadURL:    UploadSessionURL(uploadSession.SessionToken),
+		SessionToken: uploadSession.SessionToken,
+	}, nil
+}
+
+// RestoreFileVersion makes an older version the current version of a file
+func (fvs *FileVersionService) RestoreFileVersion(ctx context.Context, fileID string, version int) (*models.File, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	file, err := fvs.fileRepository.GetFileByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	return fvs.fileVersionRepository.RestoreVersion(ctx, file.ID, version)
+}
+
+// GetFileVersionDownloadURL returns the block server URL streaming a version of a file
+func (fvs *FileVersionService) GetFileVersionDownloadURL(ctx context.Context, fileID string, version int) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	file, err := fvs.fileRepository.GetFileByID(ctx, fileID)
+	if err != nil {
+		return "", err
+	}
+
+	fileVersion := file.GetVersion(version)
+	if fileVersion == nil {
+		return "", fmt.Errorf("file version %d not found", version)
+	}
+
+	return FileDownloadURL(file, fileVersion)
+}
@@ -18,11 +18,11 @@ func NewFolderService(fr models.FolderRepository) *FolderService {
 	}
 }
 
-func (fr *FolderService) CreateFolder(ctx context.Context, folder *models.Folder) (*models.Folder, error) {
+func (fr *FolderService) CreateFolder(ctx context.Context, folder *models.Folder, conflict string) (*models.Folder, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	return fr.folderRepository.CreateFolder(ctx, folder)
+	return fr.folderRepository.CreateFolder(ctx, folder, conflict)
 }
 
 func (fr *FolderService) GetFolderByID(ctx context.Context, id string) (*models.Folder, error) {
@@ -74,25 +74,31 @@ func (fr *FolderService) DeleteFolder(ctx context.Context, id string) error {
 	return fr.folderRepository.DeleteFolder(ctx, id)
 }
 
-func (fr *FolderService) RenameFolder(ctx context.Context, id string, newName string) error {
+func (fr *FolderService) RenameFolder(ctx context.Context, id string, newName string, conflict string) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	return fr.folderRepository.RenameFolder(ctx, id, newName)
+	return fr.folderRepository.RenameFolder(ctx, id, newName, conflict)
 }
 
-func (fr *FolderService) MoveFolder(ctx context.Context, id string, newParentID string) error {
+func (fr *FolderService) MoveFolder(ctx context.Context, id string, newParentID string, conflict string) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	return fr.folderRepository.MoveFolder(ctx, id, newParentID)
+	return fr.folderRepository.MoveFolder(ctx, id, newParentID, conflict)
 }
 
-func (fs *FolderService) GetFolderSharedUsers(ctx context.Context, folderID string) ([]*models.FolderSharedUser, error) {
+// GetFolderSharedUsers retrieves the users a folder is shared with, with the permissions given by their role
+func (fs *FolderService) GetFolderSharedUsers(ctx context.Context, folderID string) ([]*models.SharedUserResponse, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	return fs.folderRepository.GetFolderSharedUsers(ctx, folderID)
+	sharedUsers, err := fs.folderRepository.GetFolderSharedUsers(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return withRolePermissions(sharedUsers), nil
 }
 
 func (fs *FolderService) GetFolderShareInfo(ctx context.Context, folderID string) (bool, error) {
@@ -109,6 +115,13 @@ func (fs *FolderService) GetFolderSharedUser(ctx context.Context, folderID strin
 	return fs.folderRepository.GetFolderSharedUser(ctx, folderID, userID)
 }
 
+func (fs *FolderService) GetInheritedFolderShares(ctx context.Context, folder *models.Folder, userID string) ([]*models.FolderSharedUser, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	return fs.folderRepository.GetInheritedFolderShares(ctx, folder, userID)
+}
+
 func (fs *FolderService) UpdateFolderPublicStatus(ctx context.Context, folderID string, isPublic bool) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -123,11 +136,15 @@ func (fs *FolderService) UpdateFolderAndSubfoldersPublicStatus(ctx context.Conte
 	return fs.folderRepository.UpdateFolderAndAllSubfoldersPublicStatus(ctx, folderID, isPublic)
 }
 
-func (fs *FolderService) ShareFolder(ctx context.Context, folderID, userID string, permission bool) error {
+func (fs *FolderService) ShareFolder(ctx context.Context, folderID, userID string, role models.ShareRole) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	return fs.folderRepository.ShareFolder(ctx, folderID, userID, permission)
+	if !role.IsValid() {
+		return models.ErrInvalidShareRole
+	}
+
+	return fs.folderRepository.ShareFolder(ctx, folderID, userID, role)
 }
 
 func (fs *FolderService) RemoveFolderShare(ctx context.Context, folderID, userID string) error {
@@ -137,11 +154,15 @@ func (fs *FolderService) RemoveFolderShare(ctx context.Context, folderID, userID
 	return fs.folderRepository.RemoveFolderShare(ctx, folderID, userID)
 }
 
-func (fs *FolderService) ShareFolderAndSubfolders(ctx context.Context, folderID, userID string, permission bool) error {
+func (fs *FolderService) ShareFolderAndSubfolders(ctx context.Context, folderID, userID string, role models.ShareRole) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	return fs.folderRepository.ShareFolderAndAllSubfolders(ctx, folderID, userID, permission)
+	if !role.IsValid() {
+		return models.ErrInvalidShareRole
+	}
+
+	return fs.folderRepository.ShareFolderAndAllSubfolders(ctx, folderID, userID, role)
 }
 
 func (fs *FolderService) RevokeFolderAndSubfoldersShare(ctx context.Context, folderID, userID string) error {
@@ -150,3 +171,66 @@ func (fs *FolderService) RevokeFolderAndSubfoldersShare(ctx context.Context, fol
 
 	return fs.folderRepository.RevokeFolderAndAllSubfoldersShare(ctx, folderID, userID)
 }
+
+// GetFolderSharedGroups retrieves the groups a folder is shared with, with the permissions given by their role
+func (fs *FolderService) GetFolderSharedGroups(ctx context.Context, folderID string) ([]*models.SharedGroupResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sharedGroups, err := fs.folderRepository.GetFolderSharedGroups(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return withGroupRolePermissions(sharedGroups), nil
+}
+
+func (fs *FolderService) GetInheritedFolderGroupShares(ctx context.Context, folder *models.Folder, userID string) ([]*models.FolderSharedGroup, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	return fs.folderRepository.GetInheritedFolderGroupShares(ctx, folder, userID)
+}
+
+func (fs *FolderService) GetOrganizationFolderRole(ctx context.Context, folder *models.Folder, userID string) (models.ShareRole, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	return fs.folderRepository.GetOrganizationFolderRole(ctx, folder, userID)
+}
+
+func (fs *FolderService) ShareFolderWithGroup(ctx context.Context, folderID, groupID string, role models.ShareRole) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if !role.IsValid() {
+		return models.ErrInvalidShareRole
+	}
+
+	return fs.folderRepository.ShareFolderWithGroup(ctx, folderID, groupID, role)
+}
+
+func (fs *FolderService) RemoveFolderGroupShare(ctx context.Context, folderID, groupID string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	return fs.folderRepository.RemoveFolderGroupShare(ctx, folderID, groupID)
+}
+
+// withRolePermissions sets the permissions given by the role of every shared user
+func withRolePermissions(sharedUsers []*models.SharedUserResponse) []*models.SharedUserResponse {
+	for _, sharedUser := range sharedUsers {
+		sharedUser.Permissions = sharedUser.Role.Permissions()
+	}
+
+	return sharedUsers
+}
+
+// withGroupRolePermissions sets the permissions given by the role of every shared group
+func withGroupRolePermissions(sharedGroups []*models.SharedGroupResponse) []*models.SharedGroupResponse {
+	for _, sharedGroup := range sharedGroups {
+		sharedGroup.Permissions = sharedGroup.Role.Permissions()
+	}
+
+	return sharedGroups
+}
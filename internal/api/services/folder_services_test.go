@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"skybox-backend/internal/api/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFolderRepository records the shared roles and serves them back
+type fakeFolderRepository struct {
+	models.FolderRepository
+	roles map[string]models.ShareRole
+}
+
+func (f *fakeFolderRepository) ShareFolder(ctx context.Context, folderID, userID string, role models.ShareRole) error {
+	f.roles[userID] = role
+	return nil
+}
+
+func (f *fakeFolderRepository) GetFolderSharedUsers(ctx context.Context, folderID string) ([]*models.SharedUserResponse, error) {
+	sharedUsers := []*models.SharedUserResponse{}
+	for userID, role := range f.roles {
+		sharedUsers = append(sharedUsers, &models.SharedUserResponse{Username: userID, Role: role})
+	}
+	return sharedUsers, nil
+}
+
+func TestShareFolder_Roles(t *testing.T) {
+	folders := &fakeFolderRepository{roles: map[string]models.ShareRole{}}
+	folderService := NewFolderService(folders)
+
+	err := folderService.ShareFolder(context.Background(), "folder", "owner", "owner")
+	assert.ErrorIs(t, err, models.ErrInvalidShareRole)
+	assert.Empty(t, folders.roles)
+
+	require.NoError(t, folderService.ShareFolder(context.Background(), "folder", "uploader", models.RoleUploader))
+
+	// The uploaders can add files without seeing the content of the folder
+	sharedUsers, err := folderService.GetFolderSharedUsers(context.Background(), "folder")
+	require.NoError(t, err)
+	require.Len(t, sharedUsers, 1)
+	assert.Equal(t, []string{models.PermissionUpload}, sharedUsers[0].Permissions)
+}
+
+func TestShareRole_Allows(t *testing.T) {
+	assert.True(t, models.RoleViewer.Allows(models.PermissionView))
+	assert.False(t, models.RoleViewer.Allows(models.PermissionEdit))
+	assert.False(t, models.RoleUploader.Allows(models.PermissionView))
+	assert.False(t, models.RoleEditor.Allows(models.PermissionShare))
+	assert.True(t, models.RoleCoOwner.Allows(models.PermissionShare))
+	assert.False(t, models.RoleUploader.IsValidForFile())
+	assert.Equal(t, models.RoleEditor, models.RoleFromPermission(true))
+}
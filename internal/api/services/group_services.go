@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GroupService is the service managing the groups the files and folders can be shared with
+type GroupService struct {
+	groupRepository models.GroupRepository
+	userRepository  models.UserRepository
+}
+
+// NewGroupService creates a new instance of the GroupService
+func NewGroupService(gr models.GroupRepository, ur models.UserRepository) *GroupService {
+	return &GroupService{
+		groupRepository: gr,
+		userRepository:  ur,
+	}
+}
+
+// CreateGroup creates a group, the user becomes its first admin
+func (gs *GroupService) CreateGroup(ctx context.Context, request *models.CreateGroupRequest, userID primitive.ObjectID) (*models.Group, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return nil, fmt.Errorf("invalid group name: the name is required")
+	}
+
+	now := time.Now()
+	return gs.groupRepository.CreateGroup(ctx, &models.Group{
+		Name:        name,
+		Description: strings.TrimSpace(request.Description),
+		CreatedBy:   userID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+}
+
+// GetGroups retrieves the groups the user is a member of
+func (gs *GroupService) GetGroups(ctx context.Context, userID primitive.ObjectID) ([]*models.GroupResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	return gs.groupRepository.GetGroupsByUser(ctx, userID)
+}
+
+// getMember retrieves the membership of the user in a group
+// The groups the user is not a member of are not found
+func (gs *GroupService) getMember(ctx context.Context, groupID string, userID primitive.ObjectID) (*models.GroupMember, error) {
+	groupIDHex, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid group ID: %v", err)
+	}
+
+	member, err := gs.groupRepository.GetGroupMember(ctx, groupIDHex, userID)
+	if errors.Is(err, models.ErrGroupMemberNotFound) {
+		return nil, models.ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// getAdmin retrieves the membership of the user in a group the user is an admin of
+func (gs *GroupService) getAdmin(ctx context.Context, groupID string, userID primitive.ObjectID) (*models.GroupMember, error) {
+	member, err := gs.getMember(ctx, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role != models.GroupRoleAdmin {
+		return nil, models.ErrGroupNotAdmin
+	}
+
+	return member, nil
+}
+
+// CheckGroupMember checks that the user is a member of the group
+// A user can only share the files and folders with the groups the user is a member of
+func (gs *GroupService) CheckGroupMember(ctx context.Context, groupID string, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	_, err := gs.getMember(ctx, groupID, userID)
+	return err
+}
+
+// GetGroup retrieves a group the user is a member of
+func (gs *GroupService) GetGroup(ctx context.Context, groupID string, userID primitive.ObjectID) (*models.Group, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	member, err := gs.getMember(ctx, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return gs.groupRepository.GetGroupByID(ctx, member.GroupID)
+}
+
+// UpdateGroup changes the name or the description of a group the user is an admin of
+func (gs *GroupService) UpdateGroup(ctx context.Context, groupID string, request *models.UpdateGroupRequest, userID primitive.ObjectID) (*models.Group, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	member, err := gs.getAdmin(ctx, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := gs.groupRepository.GetGroupByID(ctx, member.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	if request.Name != nil {
+		name := strings.TrimSpace(*request.Name)
+		if name == "" {
+			return nil, fmt.Errorf("invalid group name: the name is required")
+		}
+		group.Name = name
+	}
+	if request.Description != nil {
+		group.Description = strings.TrimSpace(*request.Description)
+	}
+	group.UpdatedAt = time.Now()
+
+	if err := gs.groupRepository.UpdateGroup(ctx, group); err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// DeleteGroup deletes a group the user is an admin of with the grants given to it
+func (gs *GroupService) DeleteGroup(ctx context.Context, groupID string, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	member, err := gs.getAdmin(ctx, groupID, userID)
+	if err != nil {
+		return err
+	}
+
+	return gs.groupRepository.DeleteGroup(ctx, member.GroupID)
+}
+
+// GetGroupMembers retrieves the members of a group the user is a member of
+func (gs *GroupService) GetGroupMembers(ctx context.Context, groupID string, userID primitive.ObjectID) ([]*models.GroupMemberResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	member, err := gs.getMember(ctx, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return gs.groupRepository.GetGroupMembers(ctx, member.GroupID)
+}
+
+// SetGroupMember adds a user to a group the user is an admin of, or changes the role of a member
+func (gs *GroupService) SetGroupMember(ctx context.Context, groupID string, request *models.SetGroupMemberRequest, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	admin, err := gs.getAdmin(ctx, groupID, userID)
+	if err != nil {
+		return err
+	}
+
+	role := request.Role
+	if role == "" {
+		role = models.GroupRoleMember
+	}
+	if !models.IsValidGroupRole(role) {
+		return models.ErrInvalidGroupRole
+	}
+	memberID, err := primitive.ObjectIDFromHex(request.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %v", err)
+	}
+	if _, err := gs.userRepository.GetUserByID(ctx, request.UserID); err != nil {
+		return fmt.Errorf("user not found: %w", err)
+	}
+
+	return gs.groupRepository.SetGroupMember(ctx, &models.GroupMember{
+		GroupID: admin.GroupID,
+		UserID:  memberID,
+		Role:    role,
+		AddedAt: time.Now(),
+	})
+}
+
+// RemoveGroupMember removes a member from a group
+// The admins can remove any member, the other members can only leave the group
+func (gs *GroupService) RemoveGroupMember(ctx context.Context, groupID string, memberID string, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	memberIDHex, err := primitive.ObjectIDFromHex(memberID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %v", err)
+	}
+
+	var member *models.GroupMember
+	if memberIDHex == userID {
+		member, err = gs.getMember(ctx, groupID, userID)
+	} else {
+		member, err = gs.getAdmin(ctx, groupID, userID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return gs.groupRepository.RemoveGroupMember(ctx, member.GroupID, memberIDHex)
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"skybox-backend/internal/api/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeGroupRepository keeps the members of a single group in memory
+type fakeGroupRepository struct {
+	models.GroupRepository
+	groupID primitive.ObjectID
+	members map[primitive.ObjectID]*models.GroupMember
+}
+
+func (f *fakeGroupRepository) GetGroupMember(ctx context.Context, groupID primitive.ObjectID, userID primitive.ObjectID) (*models.GroupMember, error) {
+	member, ok := f.members[userID]
+	if groupID != f.groupID || !ok {
+		return nil, models.ErrGroupMemberNotFound
+	}
+	return member, nil
+}
+
+func (f *fakeGroupRepository) SetGroupMember(ctx context.Context, member *models.GroupMember) error {
+	f.members[member.UserID] = member
+	return nil
+}
+
+func (f *fakeGroupRepository) RemoveGroupMember(ctx context.Context, groupID primitive.ObjectID, userID primitive.ObjectID) error {
+	delete(f.members, userID)
+	return nil
+}
+
+func (f *fakeGroupRepository) DeleteGroup(ctx context.Context, id primitive.ObjectID) error {
+	f.members = map[primitive.ObjectID]*models.GroupMember{}
+	return nil
+}
+
+// newTestGroup creates a group with an admin and a member
+func newTestGroup() (*fakeGroupRepository, primitive.ObjectID, primitive.ObjectID) {
+	groups := &fakeGroupRepository{groupID: primitive.NewObjectID(), members: map[primitive.ObjectID]*models.GroupMember{}}
+	adminID, memberID := primitive.NewObjectID(), primitive.NewObjectID()
+	groups.members[adminID] = &models.GroupMember{GroupID: groups.groupID, UserID: adminID, Role: models.GroupRoleAdmin}
+	groups.members[memberID] = &models.GroupMember{GroupID: groups.groupID, UserID: memberID, Role: models.GroupRoleMember}
+
+	return groups, adminID, memberID
+}
+
+func TestGroupService_OnlyTheAdminsManageTheGroup(t *testing.T) {
+	groups, adminID, memberID := newTestGroup()
+	newUser := &models.User{ID: primitive.NewObjectID()}
+	groupService := NewGroupService(groups, &fakeUserRepository{user: newUser})
+	groupID := groups.groupID.Hex()
+	request := &models.SetGroupMemberRequest{UserID: newUser.ID.Hex()}
+
+	assert.ErrorIs(t, groupService.SetGroupMember(context.Background(), groupID, request, memberID), models.ErrGroupNotAdmin)
+	assert.ErrorIs(t, groupService.DeleteGroup(context.Background(), groupID, memberID), models.ErrGroupNotAdmin)
+	assert.ErrorIs(t, groupService.RemoveGroupMember(context.Background(), groupID, adminID.Hex(), memberID), models.ErrGroupNotAdmin)
+
+	require.NoError(t, groupService.SetGroupMember(context.Background(), groupID, request, adminID))
+	assert.Equal(t, models.GroupRoleMember, groups.members[newUser.ID].Role)
+
+	err := groupService.SetGroupMember(context.Background(), groupID, &models.SetGroupMemberRequest{UserID: newUser.ID.Hex(), Role: "owner"}, adminID)
+	assert.ErrorIs(t, err, models.ErrInvalidGroupRole)
+}
+
+func TestGroupService_NonMembersDoNotFindTheGroup(t *testing.T) {
+	groups, _, _ := newTestGroup()
+	groupService := NewGroupService(groups, &fakeUserRepository{})
+	outsiderID := primitive.NewObjectID()
+
+	_, err := groupService.GetGroupMembers(context.Background(), groups.groupID.Hex(), outsiderID)
+	assert.ErrorIs(t, err, models.ErrGroupNotFound)
+	assert.ErrorIs(t, groupService.CheckGroupMember(context.Background(), groups.groupID.Hex(), outsiderID), models.ErrGroupNotFound)
+	assert.ErrorIs(t, groupService.DeleteGroup(context.Background(), groups.groupID.Hex(), outsiderID), models.ErrGroupNotFound)
+}
+
+func TestGroupService_MembersCanLeave(t *testing.T) {
+	groups, _, memberID := newTestGroup()
+	groupService := NewGroupService(groups, &fakeUserRepository{})
+
+	require.NoError(t, groupService.RemoveGroupMember(context.Background(), groups.groupID.Hex(), memberID.Hex(), memberID))
+	assert.NotContains(t, groups.members, memberID)
+
+	// The former member no longer sees the group
+	assert.ErrorIs(t, groupService.CheckGroupMember(context.Background(), groups.groupID.Hex(), memberID), models.ErrGroupNotFound)
+}
+
+func TestShareFileWithGroup_RejectsUploaderRole(t *testing.T) {
+	fileService := NewFileService(nil, nil, nil, nil)
+
+	err := fileService.ShareFileWithGroup(context.Background(), primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex(), models.RoleUploader)
+	assert.ErrorIs(t, err, models.ErrInvalidShareRole)
+}
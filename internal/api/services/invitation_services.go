@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"skybox-backend/configs"
+	"skybox-backend/internal/api/mailer"
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InvitationService is the service sharing files and folders by email
+// The emails without an account receive an invitation, it becomes a grant when they give its emailed token
+type InvitationService struct {
+	invitationRepository models.InvitationRepository
+	userRepository       models.UserRepository
+	folderRepository     models.FolderRepository
+	fileRepository       models.FileRepository
+	mailer               mailer.Mailer
+}
+
+// NewInvitationService creates a new instance of the InvitationService
+func NewInvitationService(ir models.InvitationRepository, ur models.UserRepository, fr models.FolderRepository, flr models.FileRepository, m mailer.Mailer) *InvitationService {
+	return &InvitationService{
+		invitationRepository: ir,
+		userRepository:       ur,
+		folderRepository:     fr,
+		fileRepository:       flr,
+		mailer:               m,
+	}
+}
+
+// hashInvitationToken hashes the token of an invitation, only the hash is saved
+func hashInvitationToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// invitationMessage builds the email sent with an invitation and its token
+func invitationMessage(inviter string, itemType string, itemName string, invitation *models.ShareInvitation, token string) *mailer.Message {
+	// The names cannot add headers to the email
+	itemName = strings.NewReplacer("\r", " ", "\n", " ").Replace(itemName)
+	inviter = strings.NewReplacer("\r", " ", "\n", " ").Replace(inviter)
+
+	registerURL := fmt.Sprintf("%s/register?email=%s&invitation=%s", configs.Config.AppURL, url.QueryEscape(invitation.Email), url.QueryEscape(token))
+	body := fmt.Sprintf("%s shared the %s \"%s\" with you as %s.\n\n"+
+		"Create your SkyBox account with this email address from this link to open it:\n%s\n\n"+
+		"The invitation expires on %s.\n",
+		inviter, itemType, itemName, invitation.Role,
+		registerURL,
+		invitation.ExpiresAt.Format("January 2, 2006"),
+	)
+
+	return &mailer.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("%s shared \"%s\" with you", inviter, itemName),
+		Body:    body,
+	}
+}
+
+// getItemName retrieves the name of the shared file or folder for the email
+func (is *InvitationService) getItemName(ctx context.Context, itemType string, itemID string) (string, error) {
+	if itemType == models.TrashItemFile {
+		file, err := is.fileRepository.GetFileByID(ctx, itemID)
+		if err != nil {
+			return "", err
+		}
+		return file.FileName, nil
+	}
+
+	folder, err := is.folderRepository.GetFolderByID(ctx, itemID)
+	if err != nil {
+		return "", err
+	}
+	return folder.Name, nil
+}
+
+// ShareByEmail shares a file or a folder with the user of the email
+// The item is shared right away when the email has an account, an invitation is sent otherwise
+func (is *InvitationService) ShareByEmail(ctx context.Context, itemType string, itemID string, request *models.ShareByEmailRequest, invitedBy primitive.ObjectID) (*models.ShareByEmailResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if !request.Role.IsValid() || (itemType == models.TrashItemFile && !request.Role.IsValidForFile()) {
+		return nil, models.ErrInvalidShareRole
+	}
+	itemIDHex, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid item ID: %v", err)
+	}
+	address, err := mail.ParseAddress(strings.TrimSpace(request.Email))
+	if err != nil {
+		return nil, fmt.Errorf("invalid email: %v", err)
+	}
+
+	// The emails are saved as typed at registration
+	users, err := is.userRepository.GetUsersByEmails(ctx, []string{address.Address, strings.ToLower(address.Address)})
+	if err != nil {
+		return nil, err
+	}
+	if len(users) > 0 {
+		userID := users[0].ID.Hex()
+		if itemType == models.TrashItemFile {
+			err = is.fileRepository.ShareFile(ctx, itemID, userID, request.Role)
+		} else {
+			err = is.folderRepository.ShareFolder(ctx, itemID, userID, request.Role)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return &models.ShareByEmailResponse{Status: "shared", UserID: userID}, nil
+	}
+
+	itemName, err := is.getItemName(ctx, itemType, itemID)
+	if err != nil {
+		return nil, err
+	}
+	inviter, err := is.userRepository.GetUserByID(ctx, invitedBy.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	// The token proves that the user receives the emails of the address, it uses the generator of the share links
+	token, err := newShareLinkToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the invitation token: %v", err)
+	}
+
+	now := time.Now()
+	invitation, err := is.invitationRepository.CreateInvitation(ctx, &models.ShareInvitation{
+		Email:     strings.ToLower(address.Address),
+		ItemType:  itemType,
+		ItemID:    itemIDHex,
+		Role:      request.Role,
+		InvitedBy: invitedBy,
+		Status:    models.InvitationPending,
+		TokenHash: hashInvitationToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(configs.Config.InvitationTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// An invitation that was not delivered cannot be accepted knowingly
+	if err := is.mailer.Send(ctx, invitationMessage(inviter.Username, itemType, itemName, invitation, token)); err != nil {
+		if deleteErr := is.invitationRepository.DeleteInvitation(ctx, invitation.ID); deleteErr != nil {
+			return nil, fmt.Errorf("failed to send the invitation: %w, and to delete it: %v", err, deleteErr)
+		}
+		return nil, fmt.Errorf("failed to send the invitation: %w", err)
+	}
+
+	return &models.ShareByEmailResponse{Status: "invited", Invitation: invitation}, nil
+}
+
+// GetInvitations retrieves the pending invitations of a file or a folder
+func (is *InvitationService) GetInvitations(ctx context.Context, itemType string, itemID string) ([]*models.ShareInvitation, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	itemIDHex, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid item ID: %v", err)
+	}
+
+	return is.invitationRepository.GetPendingInvitationsByItem(ctx, itemType, itemIDHex)
+}
+
+// GetInvitationByID retrieves a pending invitation by ID
+func (is *InvitationService) GetInvitationByID(ctx context.Context, invitationID string) (*models.ShareInvitation, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	invitationIDHex, err := primitive.ObjectIDFromHex(invitationID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid invitation ID: %v", err)
+	}
+
+	return is.invitationRepository.GetInvitationByID(ctx, invitationIDHex)
+}
+
+// DeleteInvitation deletes a pending invitation
+func (is *InvitationService) DeleteInvitation(ctx context.Context, invitationID primitive.ObjectID) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	return is.invitationRepository.DeleteInvitation(ctx, invitationID)
+}
+
+// AcceptInvitations turns the pending invitations sent to the email of the user into grants
+// The token of one of the invitations must be given, an account registered with the email alone gets nothing
+func (is *InvitationService) AcceptInvitations(ctx context.Context, userID primitive.ObjectID, token string) ([]*models.ShareInvitation, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if token == "" {
+		return nil, models.ErrInvitationNotFound
+	}
+	user, err := is.userRepository.GetUserByID(ctx, userID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	return is.invitationRepository.AcceptInvitations(ctx, strings.ToLower(strings.TrimSpace(user.Email)), hashInvitationToken(token), user.ID, time.Now())
+}
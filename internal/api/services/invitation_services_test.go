@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"skybox-backend/internal/api/mailer"
+	"skybox-backend/internal/api/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeInvitationRepository keeps the pending invitations in memory
+type fakeInvitationRepository struct {
+	models.InvitationRepository
+	invitations map[primitive.ObjectID]*models.ShareInvitation
+}
+
+func (f *fakeInvitationRepository) CreateInvitation(ctx context.Context, invitation *models.ShareInvitation) (*models.ShareInvitation, error) {
+	invitation.ID = primitive.NewObjectID()
+	f.invitations[invitation.ID] = invitation
+	return invitation, nil
+}
+
+func (f *fakeInvitationRepository) AcceptInvitations(ctx context.Context, email string, tokenHash string, userID primitive.ObjectID, now time.Time) ([]*models.ShareInvitation, error) {
+	matched := false
+	for _, invitation := range f.invitations {
+		matched = matched || (invitation.Email == email && invitation.TokenHash == tokenHash && invitation.Status == models.InvitationPending)
+	}
+	if !matched {
+		return nil, models.ErrInvitationNotFound
+	}
+
+	accepted := []*models.ShareInvitation{}
+	for _, invitation := range f.invitations {
+		if invitation.Email == email && invitation.Status == models.InvitationPending {
+			invitation.Status = models.InvitationAccepted
+			invitation.AcceptedBy = userID
+			accepted = append(accepted, invitation)
+		}
+	}
+	return accepted, nil
+}
+
+func (f *fakeInvitationRepository) DeleteInvitation(ctx context.Context, id primitive.ObjectID) error {
+	delete(f.invitations, id)
+	return nil
+}
+
+// fakeUserDirectory serves the inviter and the registered emails
+type fakeUserDirectory struct {
+	fakeUserRepository
+	registered []*models.User
+}
+
+func (f *fakeUserDirectory) GetUsersByEmails(ctx context.Context, emails []string) ([]*models.User, error) {
+	users := []*models.User{}
+	for _, user := range f.registered {
+		for _, email := range emails {
+			if user.Email == email {
+				users = append(users, user)
+				break
+			}
+		}
+	}
+	return users, nil
+}
+
+// fakeMailer records the sent emails, or fails to send them
+type fakeMailer struct {
+	sent []*mailer.Message
+	err  error
+}
+
+func (f *fakeMailer) Send(ctx context.Context, message *mailer.Message) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, message)
+	return nil
+}
+
+// fakeSharedFolderRepository serves the name of the shared folder and records its grants
+type fakeSharedFolderRepository struct {
+	fakeFolderRepository
+	folder *models.Folder
+}
+
+func (f *fakeSharedFolderRepository) GetFolderByID(ctx context.Context, folderID string) (*models.Folder, error) {
+	return f.folder, nil
+}
+
+func newTestInvitationService(registered []*models.User, m *fakeMailer) (*InvitationService, *fakeInvitationRepository, *fakeSharedFolderRepository) {
+	invitations := &fakeInvitationRepository{invitations: map[primitive.ObjectID]*models.ShareInvitation{}}
+	users := &fakeUserDirectory{
+		fakeUserRepository: fakeUserRepository{user: &models.User{ID: primitive.NewObjectID(), Username: "alice"}},
+		registered:         registered,
+	}
+	folders := &fakeSharedFolderRepository{
+		fakeFolderRepository: fakeFolderRepository{roles: map[string]models.ShareRole{}},
+		folder:               &models.Folder{ID: primitive.NewObjectID(), Name: "Holidays"},
+	}
+
+	return NewInvitationService(invitations, users, folders, nil, m), invitations, folders
+}
+
+func TestShareByEmail_SharesWithExistingUser(t *testing.T) {
+	bob := &models.User{ID: primitive.NewObjectID(), Email: "bob@example.com"}
+	m := &fakeMailer{}
+	invitationService, invitations, folders := newTestInvitationService([]*models.User{bob}, m)
+
+	response, err := invitationService.ShareByEmail(context.Background(), models.TrashItemFolder, folders.folder.ID.Hex(),
+		&models.ShareByEmailRequest{Email: " Bob@Example.com ", Role: models.RoleEditor}, primitive.NewObjectID())
+	require.NoError(t, err)
+
+	assert.Equal(t, "shared", response.Status)
+	assert.Equal(t, bob.ID.Hex(), response.UserID)
+	assert.Equal(t, models.RoleEditor, folders.roles[bob.ID.Hex()])
+	assert.Empty(t, invitations.invitations)
+	assert.Empty(t, m.sent)
+}
+
+func TestShareByEmail_InvitesNewEmail(t *testing.T) {
+	m := &fakeMailer{}
+	invitationService, invitations, folders := newTestInvitationService(nil, m)
+
+	response, err := invitationService.ShareByEmail(context.Background(), models.TrashItemFolder, folders.folder.ID.Hex(),
+		&models.ShareByEmailRequest{Email: "Carol@Example.com", Role: models.RoleViewer}, primitive.NewObjectID())
+	require.NoError(t, err)
+
+	assert.Equal(t, "invited", response.Status)
+	require.NotNil(t, response.Invitation)
+	assert.Equal(t, "carol@example.com", response.Invitation.Email)
+	assert.Equal(t, models.InvitationPending, response.Invitation.Status)
+	assert.True(t, response.Invitation.ExpiresAt.After(response.Invitation.CreatedAt))
+	assert.Len(t, invitations.invitations, 1)
+	assert.Empty(t, folders.roles)
+
+	require.Len(t, m.sent, 1)
+	assert.Equal(t, "carol@example.com", m.sent[0].To)
+	assert.Contains(t, m.sent[0].Subject, "Holidays")
+	assert.Contains(t, m.sent[0].Body, "register?email=carol%40example.com&invitation=")
+
+	// Only the hash of the emailed token is saved
+	token := invitationToken(t, m.sent[0])
+	assert.Equal(t, hashInvitationToken(token), response.Invitation.TokenHash)
+}
+
+// invitationToken reads the token of the link sent in an invitation email
+func invitationToken(t *testing.T, message *mailer.Message) string {
+	start := strings.Index(message.Body, "http")
+	require.GreaterOrEqual(t, start, 0)
+	link, err := url.Parse(strings.Fields(message.Body[start:])[0])
+	require.NoError(t, err)
+	token := link.Query().Get("invitation")
+	require.NotEmpty(t, token)
+	return token
+}
+
+func TestAcceptInvitations_RequiresTheEmailedToken(t *testing.T) {
+	m := &fakeMailer{}
+	invitationService, invitations, folders := newTestInvitationService(nil, m)
+
+	_, err := invitationService.ShareByEmail(context.Background(), models.TrashItemFolder, folders.folder.ID.Hex(),
+		&models.ShareByEmailRequest{Email: "carol@example.com", Role: models.RoleViewer}, primitive.NewObjectID())
+	require.NoError(t, err)
+	require.Len(t, m.sent, 1)
+
+	// Registering with the email alone, or with a wrong token, does not accept the invitation
+	carol := &models.User{ID: primitive.NewObjectID(), Email: "Carol@Example.com"}
+	users := invitationService.userRepository.(*fakeUserDirectory)
+	users.user = carol
+	_, err = invitationService.AcceptInvitations(context.Background(), carol.ID, "")
+	assert.ErrorIs(t, err, models.ErrInvitationNotFound)
+	_, err = invitationService.AcceptInvitations(context.Background(), carol.ID, "guessed")
+	assert.ErrorIs(t, err, models.ErrInvitationNotFound)
+
+	// The token of another email does not match either
+	mallory := &models.User{ID: primitive.NewObjectID(), Email: "mallory@example.com"}
+	users.user = mallory
+	_, err = invitationService.AcceptInvitations(context.Background(), mallory.ID, invitationToken(t, m.sent[0]))
+	assert.ErrorIs(t, err, models.ErrInvitationNotFound)
+
+	// The emailed token accepts the invitation once
+	users.user = carol
+	accepted, err := invitationService.AcceptInvitations(context.Background(), carol.ID, invitationToken(t, m.sent[0]))
+	require.NoError(t, err)
+	require.Len(t, accepted, 1)
+	assert.Equal(t, carol.ID, accepted[0].AcceptedBy)
+	for _, invitation := range invitations.invitations {
+		assert.Equal(t, models.InvitationAccepted, invitation.Status)
+	}
+
+	_, err = invitationService.AcceptInvitations(context.Background(), carol.ID, invitationToken(t, m.sent[0]))
+	assert.ErrorIs(t, err, models.ErrInvitationNotFound)
+}
+
+func TestShareByEmail_DeletesUndeliveredInvitation(t *testing.T) {
+	m := &fakeMailer{err: errors.New("connection refused")}
+	invitationService, invitations, folders := newTestInvitationService(nil, m)
+
+	_, err := invitationService.ShareByEmail(context.Background(), models.TrashItemFolder, folders.folder.ID.Hex(),
+		&models.ShareByEmailRequest{Email: "carol@example.com", Role: models.RoleViewer}, primitive.NewObjectID())
+	assert.ErrorContains(t, err, "failed to send the invitation")
+	assert.Empty(t, invitations.invitations)
+}
+
+func TestShareByEmail_RejectsInvalidRequest(t *testing.T) {
+	m := &fakeMailer{}
+	invitationService, invitations, folders := newTestInvitationService(nil, m)
+
+	_, err := invitationService.ShareByEmail(context.Background(), models.TrashItemFolder, folders.folder.ID.Hex(),
+		&models.ShareByEmailRequest{Email: "carol@example.com", Role: "owner"}, primitive.NewObjectID())
+	assert.ErrorIs(t, err, models.ErrInvalidShareRole)
+
+	_, err = invitationService.ShareByEmail(context.Background(), models.TrashItemFolder, folders.folder.ID.Hex(),
+		&models.ShareByEmailRequest{Email: "not an email", Role: models.RoleViewer}, primitive.NewObjectID())
+	assert.ErrorContains(t, err, "invalid email")
+
+	assert.Empty(t, invitations.invitations)
+	assert.Empty(t, m.sent)
+}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"skybox-backend/configs"
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OrganizationService is the service managing the organizations, their members and their shared drives
+type OrganizationService struct {
+	organizationRepository models.OrganizationRepository
+	userRepository         models.UserRepository
+}
+
+// NewOrganizationService creates a new instance of the OrganizationService
+func NewOrganizationService(or models.OrganizationRepository, ur models.UserRepository) *OrganizationService {
+	return &OrganizationService{
+		organizationRepository: or,
+		userRepository:         ur,
+	}
+}
+
+// CreateOrganization creates an organization, the user becomes its first admin
+func (orgs *OrganizationService) CreateOrganization(ctx context.Context, request *models.CreateOrganizationRequest, userID primitive.ObjectID) (*models.OrganizationResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return nil, fmt.Errorf("invalid organization name: the name is required")
+	}
+
+	now := time.Now()
+	organization, err := orgs.organizationRepository.CreateOrganization(ctx, &models.Organization{
+		Name:      name,
+		CreatedBy: userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return organizationResponse(organization, models.OrganizationRoleAdmin, 1), nil
+}
+
+// GetOrganizations retrieves the organizations the user is a member of
+func (orgs *OrganizationService) GetOrganizations(ctx context.Context, userID primitive.ObjectID) ([]*models.OrganizationResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	organizations, err := orgs.organizationRepository.GetOrganizationsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, organization := range organizations {
+		organization.StorageQuota = (&models.Organization{StorageQuota: organization.StorageQuota}).GetStorageQuota(configs.Config.DefaultOrganizationStorageQuota)
+	}
+
+	return organizations, nil
+}
+
+// organizationResponse returns the organization as seen by a member with the given role
+func organizationResponse(organization *models.Organization, role string, memberCount int) *models.OrganizationResponse {
+	return &models.OrganizationResponse{
+		ID:           organization.ID,
+		Name:         organization.Name,
+		Role:         role,
+		MemberCount:  memberCount,
+		StorageQuota: organization.GetStorageQuota(configs.Config.DefaultOrganizationStorageQuota),
+		StorageUsed:  organization.StorageUsed,
+		CreatedAt:    organization.CreatedAt,
+		UpdatedAt:    organization.UpdatedAt,
+	}
+}
+
+// GetMember retrieves the membership of the user in an organization
+// The organizations the user is not a member of are not found
+func (orgs *OrganizationService) GetMember(ctx context.Context, organizationID string, userID primitive.ObjectID) (*models.OrganizationMember, error) {
+	organizationIDHex, err := primitive.ObjectIDFromHex(organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid organization ID: %v", err)
+	}
+
+	member, err := orgs.organizationRepository.GetOrganizationMember(ctx, organizationIDHex, userID)
+	if errors.Is(err, models.ErrOrganizationMemberNotFound) {
+		return nil, models.ErrOrganizationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// getAdmin retrieves the membership of the user in an organization the user is an admin of
+func (orgs *OrganizationService) getAdmin(ctx context.Context, organizationID string, userID primitive.ObjectID) (*models.OrganizationMember, error) {
+	member, err := orgs.GetMember(ctx, organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role != models.OrganizationRoleAdmin {
+		return nil, models.ErrOrganizationNotAdmin
+	}
+
+	return member, nil
+}
+
+// GetOrganization retrieves an organization the user is a member of
+func (orgs *OrganizationService) GetOrganization(ctx context.Context, organizationID string, userID primitive.ObjectID) (*models.OrganizationResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	member, err := orgs.GetMember(ctx, organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	organization, err := orgs.organizationRepository.GetOrganizationByID(ctx, member.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := orgs.organizationRepository.GetOrganizationMembers(ctx, member.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return organizationResponse(organization, member.Role, len(members)), nil
+}
+
+// UpdateOrganization renames an organization the user is an admin of
+func (orgs *OrganizationService) UpdateOrganization(ctx context.Context, organizationID string, request *models.UpdateOrganizationRequest, userID primitive.ObjectID) (*models.Organization, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	member, err := orgs.getAdmin(ctx, organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return nil, fmt.Errorf("invalid organization name: the name is required")
+	}
+	organization, err := orgs.organizationRepository.GetOrganizationByID(ctx, member.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	organization.Name = name
+	organization.UpdatedAt = time.Now()
+
+	if err := orgs.organizationRepository.UpdateOrganization(ctx, organization); err != nil {
+		return nil, err
+	}
+
+	return organization, nil
+}
+
+// GetOrganizationMembers retrieves the members of an organization the user is a member of
+func (orgs *OrganizationService) GetOrganizationMembers(ctx context.Context, organizationID string, userID primitive.ObjectID) ([]*models.OrganizationMemberResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	member, err := orgs.GetMember(ctx, organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return orgs.organizationRepository.GetOrganizationMembers(ctx, member.OrganizationID)
+}
+
+// SetOrganizationMember adds a user to an organization the user is an admin of, or changes the role of a member
+func (orgs *OrganizationService) SetOrganizationMember(ctx context.Context, organizationID string, request *models.SetOrganizationMemberRequest, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	admin, err := orgs.getAdmin(ctx, organizationID, userID)
+	if err != nil {
+		return err
+	}
+
+	role := request.Role
+	if role == "" {
+		role = models.OrganizationRoleMember
+	}
+	if !models.IsValidOrganizationRole(role) {
+		return models.ErrInvalidOrganizationRole
+	}
+	memberID, err := primitive.ObjectIDFromHex(request.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %v", err)
+	}
+	if _, err := orgs.userRepository.GetUserByID(ctx, request.UserID); err != nil {
+		return fmt.Errorf("user not found: %w", err)
+	}
+
+	return orgs.organizationRepository.SetOrganizationMember(ctx, &models.OrganizationMember{
+		OrganizationID: admin.OrganizationID,
+		UserID:         memberID,
+		Role:           role,
+		AddedAt:        time.Now(),
+	})
+}
+
+// RemoveOrganizationMember removes a member from an organization
+// The admins can remove any member, the other members can only leave the organization
+func (orgs *OrganizationService) RemoveOrganizationMember(ctx context.Context, organizationID string, memberID string, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	memberIDHex, err := primitive.ObjectIDFromHex(memberID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %v", err)
+	}
+
+	var member *models.OrganizationMember
+	if memberIDHex == userID {
+		member, err = orgs.GetMember(ctx, organizationID, userID)
+	} else {
+		member, err = orgs.getAdmin(ctx, organizationID, userID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return orgs.organizationRepository.RemoveOrganizationMember(ctx, member.OrganizationID, memberIDHex)
+}
+
+// CreateSharedDrive creates a shared drive in an organization the user is an admin of
+// The drive is a root folder owned by the organization, its content is shared with every member
+func (orgs *OrganizationService) CreateSharedDrive(ctx context.Context, organizationID string, request *models.CreateSharedDriveRequest, userID primitive.ObjectID) (*models.Folder, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	admin, err := orgs.getAdmin(ctx, organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return nil, fmt.Errorf("invalid shared drive name: the name is required")
+	}
+
+	now := time.Now()
+	return orgs.organizationRepository.CreateSharedDrive(ctx, &models.Folder{
+		OwnerID:        userID,
+		OrganizationID: &admin.OrganizationID,
+		Name:           name,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	})
+}
+
+// GetSharedDrives retrieves the shared drives of an organization the user is a member of
+func (orgs *OrganizationService) GetSharedDrives(ctx context.Context, organizationID string, userID primitive.ObjectID) ([]*models.Folder, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	member, err := orgs.GetMember(ctx, organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return orgs.organizationRepository.GetSharedDrives(ctx, member.OrganizationID)
+}
+
+// checkOrganizationStorageQuota returns models.ErrOrganizationStorageQuotaExceeded if the organization cannot store size more bytes
+func checkOrganizationStorageQuota(ctx context.Context, or models.OrganizationRepository, organizationID primitive.ObjectID, size int64) error {
+	organization, err := or.GetOrganizationByID(ctx, organizationID)
+	if err != nil {
+		return err
+	}
+	if !organization.HasStorageFor(size, configs.Config.DefaultOrganizationStorageQuota) {
+		return models.ErrOrganizationStorageQuotaExceeded
+	}
+
+	return nil
+}
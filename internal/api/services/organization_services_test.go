@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"skybox-backend/configs"
+	"skybox-backend/internal/api/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeOrganizationRepository keeps a single organization and its members in memory
+type fakeOrganizationRepository struct {
+	models.OrganizationRepository
+	organization *models.Organization
+	members      map[primitive.ObjectID]*models.OrganizationMember
+}
+
+func (f *fakeOrganizationRepository) GetOrganizationByID(ctx context.Context, id primitive.ObjectID) (*models.Organization, error) {
+	if id != f.organization.ID {
+		return nil, models.ErrOrganizationNotFound
+	}
+	return f.organization, nil
+}
+
+func (f *fakeOrganizationRepository) GetOrganizationMember(ctx context.Context, organizationID primitive.ObjectID, userID primitive.ObjectID) (*models.OrganizationMember, error) {
+	member, ok := f.members[userID]
+	if organizationID != f.organization.ID || !ok {
+		return nil, models.ErrOrganizationMemberNotFound
+	}
+	return member, nil
+}
+
+func (f *fakeOrganizationRepository) SetOrganizationMember(ctx context.Context, member *models.OrganizationMember) error {
+	f.members[member.UserID] = member
+	return nil
+}
+
+func (f *fakeOrganizationRepository) RemoveOrganizationMember(ctx context.Context, organizationID primitive.ObjectID, userID primitive.ObjectID) error {
+	delete(f.members, userID)
+	return nil
+}
+
+func (f *fakeOrganizationRepository) CreateSharedDrive(ctx context.Context, folder *models.Folder) (*models.Folder, error) {
+	folder.ID = primitive.NewObjectID()
+	folder.IsRoot = true
+	return folder, nil
+}
+
+// newTestOrganization creates an organization with an admin and a member
+func newTestOrganization() (*fakeOrganizationRepository, primitive.ObjectID, primitive.ObjectID) {
+	organizations := &fakeOrganizationRepository{
+		organization: &models.Organization{ID: primitive.NewObjectID(), Name: "Acme"},
+		members:      map[primitive.ObjectID]*models.OrganizationMember{},
+	}
+	organizationID := organizations.organization.ID
+	adminID, memberID := primitive.NewObjectID(), primitive.NewObjectID()
+	organizations.members[adminID] = &models.OrganizationMember{OrganizationID: organizationID, UserID: adminID, Role: models.OrganizationRoleAdmin}
+	organizations.members[memberID] = &models.OrganizationMember{OrganizationID: organizationID, UserID: memberID, Role: models.OrganizationRoleMember}
+
+	return organizations, adminID, memberID
+}
+
+func TestOrganizationService_OnlyTheAdminsManageTheOrganization(t *testing.T) {
+	organizations, adminID, memberID := newTestOrganization()
+	newUser := &models.User{ID: primitive.NewObjectID()}
+	organizationService := NewOrganizationService(organizations, &fakeUserRepository{user: newUser})
+	organizationID := organizations.organization.ID.Hex()
+	request := &models.SetOrganizationMemberRequest{UserID: newUser.ID.Hex()}
+	driveRequest := &models.CreateSharedDriveRequest{Name: "Marketing"}
+
+	assert.ErrorIs(t, organizationService.SetOrganizationMember(context.Background(), organizationID, request, memberID), models.ErrOrganizationNotAdmin)
+	assert.ErrorIs(t, organizationService.RemoveOrganizationMember(context.Background(), organizationID, adminID.Hex(), memberID), models.ErrOrganizationNotAdmin)
+	_, err := organizationService.CreateSharedDrive(context.Background(), organizationID, driveRequest, memberID)
+	assert.ErrorIs(t, err, models.ErrOrganizationNotAdmin)
+
+	require.NoError(t, organizationService.SetOrganizationMember(context.Background(), organizationID, request, adminID))
+	assert.Equal(t, models.OrganizationRoleMember, organizations.members[newUser.ID].Role)
+
+	err = organizationService.SetOrganizationMember(context.Background(), organizationID, &models.SetOrganizationMemberRequest{UserID: newUser.ID.Hex(), Role: "owner"}, adminID)
+	assert.ErrorIs(t, err, models.ErrInvalidOrganizationRole)
+
+	// The shared drive belongs to the organization
+	drive, err := organizationService.CreateSharedDrive(context.Background(), organizationID, driveRequest, adminID)
+	require.NoError(t, err)
+	require.NotNil(t, drive.OrganizationID)
+	assert.Equal(t, organizations.organization.ID, *drive.OrganizationID)
+}
+
+func TestOrganizationService_NonMembersDoNotFindTheOrganization(t *testing.T) {
+	organizations, _, _ := newTestOrganization()
+	organizationService := NewOrganizationService(organizations, &fakeUserRepository{})
+	outsiderID := primitive.NewObjectID()
+
+	_, err := organizationService.GetMember(context.Background(), organizations.organization.ID.Hex(), outsiderID)
+	assert.ErrorIs(t, err, models.ErrOrganizationNotFound)
+	_, err = organizationService.GetSharedDrives(context.Background(), organizations.organization.ID.Hex(), outsiderID)
+	assert.ErrorIs(t, err, models.ErrOrganizationNotFound)
+}
+
+func TestOrganizationService_MembersCanLeave(t *testing.T) {
+	organizations, _, memberID := newTestOrganization()
+	organizationService := NewOrganizationService(organizations, &fakeUserRepository{})
+	organizationID := organizations.organization.ID.Hex()
+
+	require.NoError(t, organizationService.RemoveOrganizationMember(context.Background(), organizationID, memberID.Hex(), memberID))
+	assert.NotContains(t, organizations.members, memberID)
+
+	// The former member no longer enters the organization
+	_, err := organizationService.GetMember(context.Background(), organizationID, memberID)
+	assert.ErrorIs(t, err, models.ErrOrganizationNotFound)
+}
+
+func TestAddChunkSessionRecord_OrganizationStorageQuota(t *testing.T) {
+	defaultQuota := configs.Config.DefaultOrganizationStorageQuota
+	configs.Config.DefaultOrganizationStorageQuota = 100
+	defer func() { configs.Config.DefaultOrganizationStorageQuota = defaultQuota }()
+
+	organizations, _, _ := newTestOrganization()
+	organizations.organization.StorageUsed = 60
+
+	// The user has no room left, the upload to the shared drive uses the quota of the organization
+	user := &models.User{ID: primitive.NewObjectID(), StorageQuota: 1, StorageUsed: 1}
+	sessions := &fakeUploadSessionRepository{session: &models.UploadSession{
+		UserID:         user.ID,
+		OrganizationID: &organizations.organization.ID,
+		ActualSize:     30,
+	}}
+	uploadSessionService := NewUploadSessionService(sessions, nil, &fakeUserRepository{user: user}, organizations, nil)
+
+	// 60 used + 30 uploaded + 10 fits in the default organization quota
+	require.NoError(t, addClaimedChunk(uploadSessionService, sessions.session, 0, 10))
+
+	// One more byte does not
+	err := addClaimedChunk(uploadSessionService, sessions.session, 1, 11)
+	assert.ErrorIs(t, err, models.ErrOrganizationStorageQuotaExceeded)
+
+	assert.Equal(t, []int{0}, sessions.added)
+}
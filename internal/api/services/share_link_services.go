@@ -0,0 +1,308 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ShareLinkService is the service managing the share links and serving their content to anonymous visitors
+type ShareLinkService struct {
+	shareLinkRepository models.ShareLinkRepository
+}
+
+// NewShareLinkService creates a new instance of the ShareLinkService
+func NewShareLinkService(slr models.ShareLinkRepository) *ShareLinkService {
+	return &ShareLinkService{
+		shareLinkRepository: slr,
+	}
+}
+
+// newShareLinkToken generates the unguessable token of a share link
+func newShareLinkToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// setShareLinkPassword hashes the password of a share link, an empty password removes it
+func setShareLinkPassword(link *models.ShareLink, password string) error {
+	if password == "" {
+		link.PasswordHash = ""
+		link.HasPassword = false
+		return nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	link.PasswordHash = string(hash)
+	link.HasPassword = true
+
+	return nil
+}
+
+// validateShareLinkLimits checks the expiry and the download limit given to a share link
+func validateShareLinkLimits(expiresAt *time.Time, maxDownloads int) error {
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return fmt.Errorf("invalid expiry: the expiry must be in the future")
+	}
+	if maxDownloads < 0 {
+		return fmt.Errorf("invalid download limit: the limit must be positive, or 0 for unlimited downloads")
+	}
+
+	return nil
+}
+
+// CreateShareLink creates a share link on a file or a folder
+func (sls *ShareLinkService) CreateShareLink(ctx context.Context, itemType string, itemID string, createdBy primitive.ObjectID, request *models.CreateShareLinkRequest) (*models.ShareLink, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	itemIDHex, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid item ID: %v", err)
+	}
+	if err := validateShareLinkLimits(request.ExpiresAt, request.MaxDownloads); err != nil {
+		return nil, err
+	}
+
+	token, err := newShareLinkToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	link := &models.ShareLink{
+		Token:         token,
+		ItemType:      itemType,
+		ItemID:        itemIDHex,
+		CreatedBy:     createdBy,
+		ExpiresAt:     request.ExpiresAt,
+		MaxDownloads:  request.MaxDownloads,
+		AllowDownload: request.AllowDownload == nil || *request.AllowDownload,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := setShareLinkPassword(link, request.Password); err != nil {
+		return nil, err
+	}
+
+	return sls.shareLinkRepository.CreateShareLink(ctx, link)
+}
+
+// GetShareLinks retrieves the share links of a file or a folder
+func (sls *ShareLinkService) GetShareLinks(ctx context.Context, itemType string, itemID string) ([]*models.ShareLink, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	itemIDHex, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid item ID: %v", err)
+	}
+
+	return sls.shareLinkRepository.GetShareLinksByItem(ctx, itemType, itemIDHex)
+}
+
+// GetShareLinkByID retrieves a share link by ID
+func (sls *ShareLinkService) GetShareLinkByID(ctx context.Context, linkID string) (*models.ShareLink, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	linkIDHex, err := primitive.ObjectIDFromHex(linkID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid share link ID: %v", err)
+	}
+
+	return sls.shareLinkRepository.GetShareLinkByID(ctx, linkIDHex)
+}
+
+// UpdateShareLink changes the settings of a share link, the fields missing from the request are kept
+func (sls *ShareLinkService) UpdateShareLink(ctx context.Context, link *models.ShareLink, request *models.UpdateShareLinkRequest) (*models.ShareLink, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if request.RemoveExpiry {
+		link.ExpiresAt = nil
+	} else if request.ExpiresAt != nil {
+		link.ExpiresAt = request.ExpiresAt
+	}
+	if request.MaxDownloads != nil {
+		link.MaxDownloads = *request.MaxDownloads
+	}
+	if request.AllowDownload != nil {
+		link.AllowDownload = *request.AllowDownload
+	}
+	if err := validateShareLinkLimits(request.ExpiresAt, link.MaxDownloads); err != nil {
+		return nil, err
+	}
+	if request.Password != nil {
+		if err := setShareLinkPassword(link, *request.Password); err != nil {
+			return nil, err
+		}
+	}
+	link.UpdatedAt = time.Now()
+
+	if err := sls.shareLinkRepository.UpdateShareLink(ctx, link); err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// DeleteShareLink deletes a share link
+func (sls *ShareLinkService) DeleteShareLink(ctx context.Context, linkID primitive.ObjectID) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	return sls.shareLinkRepository.DeleteShareLink(ctx, linkID)
+}
+
+// OpenShareLink retrieves the share link of a token for an anonymous visitor
+// The link must not be expired and the password must match when the link has one
+func (sls *ShareLinkService) OpenShareLink(ctx context.Context, token string, password string) (*models.ShareLink, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	link, err := sls.shareLinkRepository.GetShareLinkByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if link.IsExpired(time.Now()) {
+		return nil, models.ErrShareLinkExpired
+	}
+	if link.HasPassword {
+		if err := bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)); err != nil {
+			return nil, models.ErrShareLinkPassword
+		}
+	}
+
+	return link, nil
+}
+
+// publicFolderResponse builds the response of a folder seen through a share link
+func publicFolderResponse(folder *models.Folder) *models.FolderResponse {
+	return &models.FolderResponse{
+		ID:             folder.ID.Hex(),
+		ParentFolderID: folder.ParentFolderID.Hex(),
+		OwnerID:        folder.OwnerID.Hex(),
+		Name:           folder.Name,
+		Stats:          folder.Stats,
+		CreatedAt:      folder.CreatedAt,
+		UpdatedAt:      folder.UpdatedAt,
+	}
+}
+
+// publicFileResponse builds the response of a file seen through a share link
+func publicFileResponse(file *models.File) *models.FileResponse {
+	return &models.FileResponse{
+		ID:             file.ID.Hex(),
+		ParentFolderID: file.ParentFolderID.Hex(),
+		OwnerID:        file.OwnerID.Hex(),
+		Name:           file.FileName,
+		MimeType:       file.MimeType,
+		Size:           file.Size,
+		Status:         file.Status,
+		CreatedAt:      file.CreatedAt,
+		UpdatedAt:      file.UpdatedAt,
+	}
+}
+
+// GetShareLinkContent retrieves the item of a share link
+// For a folder link, folderID selects the listed subfolder, the linked folder is listed when it is empty.
+// The emails of the owners are not given to the anonymous visitors.
+func (sls *ShareLinkService) GetShareLinkContent(ctx context.Context, link *models.ShareLink, folderID string) (*models.ShareLinkResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	response := &models.ShareLinkResponse{
+		ItemType:      link.ItemType,
+		AllowDownload: link.AllowDownload,
+		ExpiresAt:     link.ExpiresAt,
+	}
+
+	if link.ItemType == models.TrashItemFile {
+		file, err := sls.shareLinkRepository.GetLinkedFile(ctx, link, link.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		response.File = publicFileResponse(file)
+		return response, nil
+	}
+
+	folderIDHex := link.ItemID
+	if folderID != "" {
+		var err error
+		folderIDHex, err = primitive.ObjectIDFromHex(folderID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid folder ID: %v", err)
+		}
+	}
+
+	folder, err := sls.shareLinkRepository.GetLinkedFolder(ctx, link, folderIDHex)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := sls.shareLinkRepository.GetLinkedFolderContents(ctx, folder.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, subfolder := range contents.FolderList {
+		subfolder.OwnerEmail = ""
+	}
+	for _, file := range contents.FileList {
+		file.OwnerEmail = ""
+	}
+
+	response.Folder = publicFolderResponse(folder)
+	response.Contents = contents
+	return response, nil
+}
+
+// GetShareLinkDownloadURL counts a download of a file reached through a share link and returns its block server URL
+// For a file link, fileID is empty or the linked file
+func (sls *ShareLinkService) GetShareLinkDownloadURL(ctx context.Context, link *models.ShareLink, fileID string) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if !link.AllowDownload {
+		return "", models.ErrShareLinkNoDownload
+	}
+
+	fileIDHex := link.ItemID
+	if fileID != "" {
+		var err error
+		fileIDHex, err = primitive.ObjectIDFromHex(fileID)
+		if err != nil {
+			return "", fmt.Errorf("invalid file ID: %v", err)
+		}
+	} else if link.ItemType != models.TrashItemFile {
+		return "", fmt.Errorf("file ID is required for a folder share link")
+	}
+
+	file, err := sls.shareLinkRepository.GetLinkedFile(ctx, link, fileIDHex)
+	if err != nil {
+		return "", err
+	}
+	version := file.GetVersion(0)
+	if version == nil {
+		return "", fmt.Errorf("file version not found")
+	}
+
+	if err := sls.shareLinkRepository.RecordDownload(ctx, link.ID); err != nil {
+		return "", err
+	}
+
+	return FileDownloadURL(file, version)
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"skybox-backend/internal/api/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeShareLinkRepository keeps the links in memory and serves a single uploaded file
+type fakeShareLinkRepository struct {
+	models.ShareLinkRepository
+	links map[string]*models.ShareLink
+	file  *models.File
+}
+
+func (f *fakeShareLinkRepository) CreateShareLink(ctx context.Context, link *models.ShareLink) (*models.ShareLink, error) {
+	link.ID = primitive.NewObjectID()
+	f.links[link.Token] = link
+	return link, nil
+}
+
+func (f *fakeShareLinkRepository) GetShareLinkByToken(ctx context.Context, token string) (*models.ShareLink, error) {
+	link, ok := f.links[token]
+	if !ok {
+		return nil, models.ErrShareLinkNotFound
+	}
+	return link, nil
+}
+
+func (f *fakeShareLinkRepository) GetLinkedFile(ctx context.Context, link *models.ShareLink, fileID primitive.ObjectID) (*models.File, error) {
+	return f.file, nil
+}
+
+func (f *fakeShareLinkRepository) UpdateShareLink(ctx context.Context, link *models.ShareLink) error {
+	f.links[link.Token] = link
+	return nil
+}
+
+func (f *fakeShareLinkRepository) RecordDownload(ctx context.Context, id primitive.ObjectID) error {
+	for _, link := range f.links {
+		if link.ID != id {
+			continue
+		}
+		if link.MaxDownloads > 0 && link.DownloadCount >= link.MaxDownloads {
+			return models.ErrShareLinkDownloadsLimit
+		}
+		link.DownloadCount++
+	}
+	return nil
+}
+
+func newTestShareLink(t *testing.T, request *models.CreateShareLinkRequest) (*ShareLinkService, *models.ShareLink) {
+	file := &models.File{ID: primitive.NewObjectID(), OwnerID: primitive.NewObjectID(), FileName: "report.pdf", Size: 10, TotalChunks: 1, Status: "uploaded"}
+	shareLinkService := NewShareLinkService(&fakeShareLinkRepository{links: map[string]*models.ShareLink{}, file: file})
+
+	link, err := shareLinkService.CreateShareLink(context.Background(), models.TrashItemFile, file.ID.Hex(), file.OwnerID, request)
+	require.NoError(t, err)
+	return shareLinkService, link
+}
+
+func TestCreateShareLink_HashesThePassword(t *testing.T) {
+	shareLinkService, link := newTestShareLink(t, &models.CreateShareLinkRequest{Password: "secret"})
+
+	assert.Len(t, link.Token, 43)
+	assert.True(t, link.HasPassword)
+	assert.NotEqual(t, "secret", link.PasswordHash)
+	assert.True(t, link.AllowDownload)
+
+	_, err := shareLinkService.OpenShareLink(context.Background(), link.Token, "")
+	assert.ErrorIs(t, err, models.ErrShareLinkPassword)
+	_, err = shareLinkService.OpenShareLink(context.Background(), link.Token, "wrong")
+	assert.ErrorIs(t, err, models.ErrShareLinkPassword)
+	_, err = shareLinkService.OpenShareLink(context.Background(), link.Token, "secret")
+	assert.NoError(t, err)
+	_, err = shareLinkService.OpenShareLink(context.Background(), "unknown", "secret")
+	assert.ErrorIs(t, err, models.ErrShareLinkNotFound)
+}
+
+func TestOpenShareLink_Expired(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	shareLinkService, link := newTestShareLink(t, &models.CreateShareLinkRequest{ExpiresAt: &expiresAt})
+
+	past := time.Now().Add(-time.Minute)
+	link.ExpiresAt = &past
+	_, err := shareLinkService.OpenShareLink(context.Background(), link.Token, "")
+	assert.ErrorIs(t, err, models.ErrShareLinkExpired)
+
+	_, err = shareLinkService.CreateShareLink(context.Background(), models.TrashItemFile, primitive.NewObjectID().Hex(), primitive.NewObjectID(), &models.CreateShareLinkRequest{ExpiresAt: &past})
+	assert.ErrorContains(t, err, "invalid expiry")
+}
+
+func TestGetShareLinkDownloadURL_Limits(t *testing.T) {
+	shareLinkService, link := newTestShareLink(t, &models.CreateShareLinkRequest{MaxDownloads: 1})
+
+	downloadURL, err := shareLinkService.GetShareLinkDownloadURL(context.Background(), link, "")
+	require.NoError(t, err)
+	assert.Contains(t, downloadURL, "/download/"+link.ItemID.Hex())
+
+	_, err = shareLinkService.GetShareLinkDownloadURL(context.Background(), link, "")
+	assert.ErrorIs(t, err, models.ErrShareLinkDownloadsLimit)
+
+	allowDownload := false
+	_, err = shareLinkService.UpdateShareLink(context.Background(), link, &models.UpdateShareLinkRequest{AllowDownload: &allowDownload, MaxDownloads: new(int)})
+	require.NoError(t, err)
+	_, err = shareLinkService.GetShareLinkDownloadURL(context.Background(), link, "")
+	assert.ErrorIs(t, err, models.ErrShareLinkNoDownload)
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"skybox-backend/internal/api/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SharedItemService is the service listing the items shared between the users
+type SharedItemService struct {
+	sharedItemRepository models.SharedItemRepository
+}
+
+// NewSharedItemService creates a new instance of the SharedItemService
+func NewSharedItemService(sir models.SharedItemRepository) *SharedItemService {
+	return &SharedItemService{
+		sharedItemRepository: sir,
+	}
+}
+
+// sharedItemsPage builds the response of a page of shared items
+func sharedItemsPage(items []*models.SharedItem, total int64, pagination models.Pagination) *models.SharedItemsResponse {
+	for _, item := range items {
+		withRolePermissions(item.SharedWith)
+	}
+
+	return &models.SharedItemsResponse{
+		Items:    items,
+		Page:     pagination.Page,
+		PageSize: pagination.PageSize,
+		Total:    total,
+	}
+}
+
+// GetItemsSharedWithUser retrieves a page of the folders and files shared with the user
+func (sis *SharedItemService) GetItemsSharedWithUser(ctx context.Context, userID primitive.ObjectID, includeHidden bool, pagination models.Pagination) (*models.SharedItemsResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pagination = pagination.Normalize()
+	items, total, err := sis.sharedItemRepository.GetItemsSharedWithUser(ctx, userID, includeHidden, pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	return sharedItemsPage(items, total, pagination), nil
+}
+
+// GetItemsSharedByUser retrieves a page of the folders and files of the user shared with other users
+func (sis *SharedItemService) GetItemsSharedByUser(ctx context.Context, ownerID primitive.ObjectID, pagination models.Pagination) (*models.SharedItemsResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pagination = pagination.Normalize()
+	items, total, err := sis.sharedItemRepository.GetItemsSharedByUser(ctx, ownerID, pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	return sharedItemsPage(items, total, pagination), nil
+}
+
+// SetShareHidden hides a folder or a file from the shared with me listing of the user, or shows it again
+func (sis *SharedItemService) SetShareHidden(ctx context.Context, userID primitive.ObjectID, itemType string, itemID string, hidden bool) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	itemIDHex, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return fmt.Errorf("invalid item ID: %v", err)
+	}
+
+	return sis.sharedItemRepository.SetShareHidden(ctx, userID, itemType, itemIDHex, hidden)
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"skybox-backend/internal/api/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeSharedItemRepository records the requested page and serves a single shared item
+type fakeSharedItemRepository struct {
+	models.SharedItemRepository
+	pagination models.Pagination
+}
+
+func (f *fakeSharedItemRepository) GetItemsSharedByUser(ctx context.Context, ownerID primitive.ObjectID, pagination models.Pagination) ([]*models.SharedItem, int64, error) {
+	f.pagination = pagination
+	return []*models.SharedItem{{
+		ItemType:   models.TrashItemFolder,
+		SharedWith: []*models.SharedUserResponse{{Role: models.RoleCommenter}},
+	}}, 120, nil
+}
+
+func TestGetItemsSharedByUser_Pagination(t *testing.T) {
+	repository := &fakeSharedItemRepository{}
+	sharedItemService := NewSharedItemService(repository)
+
+	response, err := sharedItemService.GetItemsSharedByUser(context.Background(), primitive.NewObjectID(), models.Pagination{})
+	require.NoError(t, err)
+	assert.Equal(t, models.Pagination{Page: 1, PageSize: models.DefaultPageSize}, repository.pagination)
+	assert.Equal(t, int64(120), response.Total)
+	assert.Equal(t, []string{models.PermissionView, models.PermissionComment}, response.Items[0].SharedWith[0].Permissions)
+
+	_, err = sharedItemService.GetItemsSharedByUser(context.Background(), primitive.NewObjectID(), models.Pagination{Page: 3, PageSize: 1000})
+	require.NoError(t, err)
+	assert.Equal(t, models.MaxPageSize, repository.pagination.PageSize)
+	assert.Equal(t, int64(2*models.MaxPageSize), repository.pagination.Skip())
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/shared/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OwnershipTransferService is the service giving files and folders to other users
+// The block store is needed to re-key the chunks stored under the key prefix of the previous owner
+type OwnershipTransferService struct {
+	transferRepository models.OwnershipTransferRepository
+	userRepository     models.UserRepository
+	folderRepository   models.FolderRepository
+	fileRepository     models.FileRepository
+	store              storage.BlockStore
+}
+
+// NewOwnershipTransferService creates a new instance of the OwnershipTransferService
+func NewOwnershipTransferService(otr models.OwnershipTransferRepository, ur models.UserRepository, fr models.FolderRepository, flr models.FileRepository, store storage.BlockStore) *OwnershipTransferService {
+	return &OwnershipTransferService{
+		transferRepository: otr,
+		userRepository:     ur,
+		folderRepository:   fr,
+		fileRepository:     flr,
+		store:              store,
+	}
+}
+
+// RequestTransfer asks a user to take the ownership of a file or a folder of the owner
+// The item does not change owner before the recipient accepts the transfer
+func (ots *OwnershipTransferService) RequestTransfer(ctx context.Context, request *models.TransferOwnershipRequest, fromUserID primitive.ObjectID) (*models.OwnershipTransfer, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	itemID, err := primitive.ObjectIDFromHex(request.ItemID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid item ID: %v", err)
+	}
+	toUserID, err := primitive.ObjectIDFromHex(request.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %v", err)
+	}
+	if toUserID == fromUserID {
+		return nil, fmt.Errorf("invalid user ID: the item is already owned by the user")
+	}
+	if _, err := ots.userRepository.GetUserByID(ctx, request.UserID); err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	var ownerID primitive.ObjectID
+	var itemName string
+	switch request.ItemType {
+	case models.TrashItemFolder:
+		folder, err := ots.folderRepository.GetFolderByID(ctx, request.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		if folder.IsRoot {
+			return nil, fmt.Errorf("cannot transfer a root folder")
+		}
+		if folder.OrganizationID != nil {
+			return nil, models.ErrTransferOrganizationItem
+		}
+		ownerID, itemName = folder.OwnerID, folder.Name
+	case models.TrashItemFile:
+		file, err := ots.fileRepository.GetFileByID(ctx, request.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		if file.OrganizationID != nil {
+			return nil, models.ErrTransferOrganizationItem
+		}
+		ownerID, itemName = file.OwnerID, file.FileName
+	default:
+		return nil, fmt.Errorf("invalid item type: %s", request.ItemType)
+	}
+	if ownerID != fromUserID {
+		return nil, models.ErrTransferNotOwner
+	}
+
+	return ots.transferRepository.CreateTransfer(ctx, &models.OwnershipTransfer{
+		ItemType:   request.ItemType,
+		ItemID:     itemID,
+		ItemName:   itemName,
+		FromUserID: fromUserID,
+		ToUserID:   toUserID,
+		Status:     models.TransferPending,
+		CreatedAt:  time.Now(),
+	})
+}
+
+// GetPendingTransfers retrieves the pending transfers sent or received by the user
+func (ots *OwnershipTransferService) GetPendingTransfers(ctx context.Context, userID primitive.ObjectID) ([]*models.OwnershipTransfer, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	return ots.transferRepository.GetPendingTransfers(ctx, userID)
+}
+
+// getTransfer retrieves a pending transfer sent by the user, or received by the user
+// The transfers of other users are not found
+func (ots *OwnershipTransferService) getTransfer(ctx context.Context, transferID string, userID primitive.ObjectID, received bool) (*models.OwnershipTransfer, error) {
+	transferIDHex, err := primitive.ObjectIDFromHex(transferID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transfer ID: %v", err)
+	}
+
+	transfer, err := ots.transferRepository.GetTransferByID(ctx, transferIDHex)
+	if err != nil {
+		return nil, err
+	}
+	if (received && transfer.ToUserID != userID) || (!received && transfer.FromUserID != userID) {
+		return nil, models.ErrTransferNotFound
+	}
+
+	return transfer, nil
+}
+
+// AcceptTransfer gives the item of a transfer received by the user to the user
+// The storage used by the item must fit in the quota of the user. The legacy chunk objects are copied
+// under the key prefix of the user before the owner changes, the objects of the previous owner are deleted after.
+func (ots *OwnershipTransferService) AcceptTransfer(ctx context.Context, transferID string, userID primitive.ObjectID) (*models.OwnershipTransfer, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	transfer, err := ots.getTransfer(ctx, transferID, userID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ots.transferRepository.GetTransferContent(ctx, transfer)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStorageQuota(ctx, ots.userRepository, userID.Hex(), content.StorageSize); err != nil {
+		return nil, err
+	}
+
+	fromOwnerID, toOwnerID := transfer.FromUserID.Hex(), transfer.ToUserID.Hex()
+	for i, file := range content.LegacyChunks {
+		if err := storage.CopyChunks(ctx, ots.store, fromOwnerID, toOwnerID, file.FileID, file.ChunkIndexes); err != nil {
+			ots.deleteLegacyChunks(ctx, toOwnerID, content.LegacyChunks[:i+1])
+			return nil, fmt.Errorf("failed to copy the chunks of file %s: %w", file.FileID, err)
+		}
+	}
+
+	accepted, err := ots.transferRepository.AcceptTransfer(ctx, transfer, time.Now())
+	if err != nil {
+		ots.deleteLegacyChunks(ctx, toOwnerID, content.LegacyChunks)
+		return nil, err
+	}
+	ots.deleteLegacyChunks(ctx, fromOwnerID, content.LegacyChunks)
+
+	return accepted, nil
+}
+
+// deleteLegacyChunks deletes the legacy chunk objects of the files under the key prefix of the owner
+// The objects are not referenced anymore, a failed deletion only leaves an orphan object behind
+func (ots *OwnershipTransferService) deleteLegacyChunks(ctx context.Context, ownerID string, files []*models.LegacyChunks) {
+	for _, file := range files {
+		if err := storage.DeleteChunks(ctx, ots.store, ownerID, file.FileID, file.ChunkIndexes); err != nil {
+			log.Printf("Failed to delete the chunks of file %s: %v", file.FileID, err)
+		}
+	}
+}
+
+// DeclineTransfer declines a transfer received by the user
+func (ots *OwnershipTransferService) DeclineTransfer(ctx context.Context, transferID string, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	transfer, err := ots.getTransfer(ctx, transferID, userID, true)
+	if err != nil {
+		return err
+	}
+
+	return ots.transferRepository.CloseTransfer(ctx, transfer.ID, models.TransferDeclined, time.Now())
+}
+
+// CancelTransfer cancels a transfer sent by the user
+func (ots *OwnershipTransferService) CancelTransfer(ctx context.Context, transferID string, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	transfer, err := ots.getTransfer(ctx, transferID, userID, false)
+	if err != nil {
+		return err
+	}
+
+	return ots.transferRepository.CloseTransfer(ctx, transfer.ID, models.TransferCancelled, time.Now())
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"skybox-backend/configs"
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/shared/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeTransferRepository keeps a single transfer and the content it gives
+type fakeTransferRepository struct {
+	models.OwnershipTransferRepository
+	transfer  *models.OwnershipTransfer
+	content   *models.TransferContent
+	acceptErr error
+}
+
+func (f *fakeTransferRepository) CreateTransfer(ctx context.Context, transfer *models.OwnershipTransfer) (*models.OwnershipTransfer, error) {
+	transfer.ID = primitive.NewObjectID()
+	f.transfer = transfer
+	return transfer, nil
+}
+
+func (f *fakeTransferRepository) GetTransferByID(ctx context.Context, id primitive.ObjectID) (*models.OwnershipTransfer, error) {
+	if f.transfer == nil || f.transfer.ID != id || f.transfer.Status != models.TransferPending {
+		return nil, models.ErrTransferNotFound
+	}
+	return f.transfer, nil
+}
+
+func (f *fakeTransferRepository) GetTransferContent(ctx context.Context, transfer *models.OwnershipTransfer) (*models.TransferContent, error) {
+	return f.content, nil
+}
+
+func (f *fakeTransferRepository) AcceptTransfer(ctx context.Context, transfer *models.OwnershipTransfer, now time.Time) (*models.OwnershipTransfer, error) {
+	if f.acceptErr != nil {
+		return nil, f.acceptErr
+	}
+	transfer.Status = models.TransferAccepted
+	return transfer, nil
+}
+
+func newTestTransfer(t *testing.T, recipient *models.User) (*OwnershipTransferService, *fakeTransferRepository, storage.BlockStore) {
+	store := storage.NewMemoryStore()
+	data := []byte("legacy chunk")
+	fromUserID := primitive.NewObjectID()
+	fileID := primitive.NewObjectID().Hex()
+	require.NoError(t, store.Put(context.Background(), storage.ChunkKey(fromUserID.Hex(), fileID, 0), bytes.NewReader(data), int64(len(data))))
+
+	transfers := &fakeTransferRepository{
+		transfer: &models.OwnershipTransfer{
+			ID:         primitive.NewObjectID(),
+			ItemType:   models.TrashItemFolder,
+			ItemID:     primitive.NewObjectID(),
+			FromUserID: fromUserID,
+			ToUserID:   recipient.ID,
+			Status:     models.TransferPending,
+		},
+		content: &models.TransferContent{
+			StorageSize:  100,
+			LegacyChunks: []*models.LegacyChunks{{FileID: fileID, ChunkIndexes: []int{0}}},
+		},
+	}
+	transferService := NewOwnershipTransferService(transfers, &fakeUserRepository{user: recipient}, nil, nil, store)
+
+	return transferService, transfers, store
+}
+
+func TestRequestTransfer_OnlyTheOwner(t *testing.T) {
+	ownerID := primitive.NewObjectID()
+	folders := &fakeSharedFolderRepository{folder: &models.Folder{ID: primitive.NewObjectID(), OwnerID: ownerID, Name: "Projects"}}
+	recipient := &models.User{ID: primitive.NewObjectID()}
+	transfers := &fakeTransferRepository{}
+	transferService := NewOwnershipTransferService(transfers, &fakeUserRepository{user: recipient}, folders, nil, storage.NewMemoryStore())
+
+	request := &models.TransferOwnershipRequest{ItemType: models.TrashItemFolder, ItemID: folders.folder.ID.Hex(), UserID: recipient.ID.Hex()}
+	_, err := transferService.RequestTransfer(context.Background(), request, primitive.NewObjectID())
+	assert.ErrorIs(t, err, models.ErrTransferNotOwner)
+
+	_, err = transferService.RequestTransfer(context.Background(), &models.TransferOwnershipRequest{
+		ItemType: models.TrashItemFolder, ItemID: folders.folder.ID.Hex(), UserID: ownerID.Hex(),
+	}, ownerID)
+	assert.ErrorContains(t, err, "invalid user ID")
+
+	transfer, err := transferService.RequestTransfer(context.Background(), request, ownerID)
+	require.NoError(t, err)
+	assert.Equal(t, models.TransferPending, transfer.Status)
+	assert.Equal(t, "Projects", transfer.ItemName)
+	assert.Equal(t, recipient.ID, transfer.ToUserID)
+
+	folders.folder.IsRoot = true
+	_, err = transferService.RequestTransfer(context.Background(), request, ownerID)
+	assert.ErrorContains(t, err, "cannot transfer a root folder")
+}
+
+func TestAcceptTransfer_ReKeysLegacyChunks(t *testing.T) {
+	recipient := &models.User{ID: primitive.NewObjectID()}
+	transferService, transfers, store := newTestTransfer(t, recipient)
+	transfer := transfers.transfer
+	legacy := transfers.content.LegacyChunks[0]
+
+	// Only the recipient can accept the transfer
+	_, err := transferService.AcceptTransfer(context.Background(), transfer.ID.Hex(), transfer.FromUserID)
+	assert.ErrorIs(t, err, models.ErrTransferNotFound)
+
+	accepted, err := transferService.AcceptTransfer(context.Background(), transfer.ID.Hex(), recipient.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.TransferAccepted, accepted.Status)
+
+	_, err = store.Stat(context.Background(), storage.ChunkKey(recipient.ID.Hex(), legacy.FileID, 0))
+	assert.NoError(t, err)
+	_, err = store.Stat(context.Background(), storage.ChunkKey(transfer.FromUserID.Hex(), legacy.FileID, 0))
+	assert.ErrorIs(t, err, storage.ErrObjectNotFound)
+}
+
+func TestAcceptTransfer_StorageQuota(t *testing.T) {
+	defaultQuota := configs.Config.DefaultStorageQuota
+	configs.Config.DefaultStorageQuota = 150
+	defer func() { configs.Config.DefaultStorageQuota = defaultQuota }()
+
+	recipient := &models.User{ID: primitive.NewObjectID(), StorageUsed: 60}
+	transferService, transfers, store := newTestTransfer(t, recipient)
+	transfer := transfers.transfer
+
+	_, err := transferService.AcceptTransfer(context.Background(), transfer.ID.Hex(), recipient.ID)
+	assert.ErrorIs(t, err, models.ErrStorageQuotaExceeded)
+	assert.Equal(t, models.TransferPending, transfer.Status)
+
+	_, err = store.Stat(context.Background(), storage.ChunkKey(recipient.ID.Hex(), transfers.content.LegacyChunks[0].FileID, 0))
+	assert.ErrorIs(t, err, storage.ErrObjectNotFound)
+}
+
+func TestAcceptTransfer_FailureKeepsTheChunks(t *testing.T) {
+	recipient := &models.User{ID: primitive.NewObjectID()}
+	transferService, transfers, store := newTestTransfer(t, recipient)
+	transfers.acceptErr = errors.New("write conflict")
+	transfer := transfers.transfer
+	legacy := transfers.content.LegacyChunks[0]
+
+	_, err := transferService.AcceptTransfer(context.Background(), transfer.ID.Hex(), recipient.ID)
+	assert.Error(t, err)
+
+	// The copies are removed, the previous owner still has the chunks
+	_, err = store.Stat(context.Background(), storage.ChunkKey(recipient.ID.Hex(), legacy.FileID, 0))
+	assert.ErrorIs(t, err, storage.ErrObjectNotFound)
+	_, err = store.Stat(context.Background(), storage.ChunkKey(transfer.FromUserID.Hex(), legacy.FileID, 0))
+	assert.NoError(t, err)
+}
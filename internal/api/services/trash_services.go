@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/shared/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TrashService struct {
+	trashRepository models.TrashRepository
+	store           storage.BlockStore
+}
+
+func NewTrashService(tr models.TrashRepository, store storage.BlockStore) *TrashService {
+	return &TrashService{
+		trashRepository: tr,
+		store:           store,
+	}
+}
+
+// parseTrashItem validates the type and the ID of a trash item
+func parseTrashItem(itemType string, itemID string) (primitive.ObjectID, error) {
+	if itemType != models.TrashItemFile && itemType != models.TrashItemFolder {
+		return primitive.NilObjectID, fmt.Errorf("invalid trash item type: %s", itemType)
+	}
+
+	id, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("invalid trash item ID")
+	}
+
+	return id, nil
+}
+
+// GetTrashItems retrieves the trashed files and folders of the user
+func (ts *TrashService) GetTrashItems(ctx context.Context, userID string) (*models.TrashListResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ownerID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID")
+	}
+
+	items, err := ts.trashRepository.GetTrashItems(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TrashListResponse{
+		Items: items,
+		Total: len(items),
+	}, nil
+}
+
+// RestoreItem restores a trashed item of the user
+func (ts *TrashService) RestoreItem(ctx context.Context, userID string, itemType string, itemID string) (*models.TrashItem, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ownerID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID")
+	}
+	id, err := parseTrashItem(itemType, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.trashRepository.RestoreItem(ctx, ownerID, itemType, id)
+}
+
+// PurgeItem permanently deletes a trashed item of the user and its stored chunks
+func (ts *TrashService) PurgeItem(ctx context.Context, userID string, itemType string, itemID string) (*models.PurgeTrashResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ownerID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID")
+	}
+	id, err := parseTrashItem(itemType, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	released, err := ts.trashRepository.PurgeItem(ctx, ownerID, itemType, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.deleteReleasedChunks(ctx, released), nil
+}
+
+// EmptyTrash permanently deletes every trashed item of the user and their stored chunks
+func (ts *TrashService) EmptyTrash(ctx context.Context, userID string) (*models.PurgeTrashResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ownerID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID")
+	}
+
+	released, err := ts.trashRepository.EmptyTrash(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.deleteReleasedChunks(ctx, released), nil
+}
+
+// deleteReleasedChunks deletes the released chunks from the block store
+// The records are already deleted, a failed deletion only leaves an orphan object behind
+func (ts *TrashService) deleteReleasedChunks(ctx context.Context, released []*models.ReleasedChunks) *models.PurgeTrashResponse {
+	if err := DeleteReleasedChunks(ctx, ts.store, released); err != nil {
+		log.Printf("Failed to delete the purged chunks: %v", err)
+	}
+
+	response := &models.PurgeTrashResponse{PurgedFiles: len(released)}
+	for _, file := range released {
+		response.ReleasedBlocks += len(file.ReleasedBlocks)
+	}
+
+	return response
+}
+
+// DeleteReleasedChunks deletes the chunks of purged files from the block store
+// Every file is attempted, the first error is returned
+func DeleteReleasedChunks(ctx context.Context, store storage.BlockStore, released []*models.ReleasedChunks) error {
+	var firstErr error
+	for _, file := range released {
+		err := storage.DeleteChunks(ctx, store, file.OwnerID, file.FileID, file.ChunkIndexes)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to delete the chunks of file %s: %w", file.FileID, err)
+		}
+	}
+
+	return firstErr
+}
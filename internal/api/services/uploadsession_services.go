@@ -2,16 +2,32 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"skybox-backend/configs"
 	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/shared/storage"
+	"skybox-backend/pkg/utils"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type UploadSessionService struct {
 	uploadSessionRepository models.UploadSessionRepository
+	chunkRepository         models.ChunkRepository
+	userRepository          models.UserRepository
+	organizationRepository  models.OrganizationRepository
+	blockRepository         models.BlockRepository
 }
 
-func NewUploadSessionService(ur models.UploadSessionRepository) *UploadSessionService {
+func NewUploadSessionService(ur models.UploadSessionRepository, cr models.ChunkRepository, userRepository models.UserRepository, organizationRepository models.OrganizationRepository, blockRepository models.BlockRepository) *UploadSessionService {
 	return &UploadSessionService{
 		uploadSessionRepository: ur,
+		chunkRepository:         cr,
+		userRepository:          userRepository,
+		organizationRepository:  organizationRepository,
+		blockRepository:         blockRepository,
 	}
 }
 
@@ -47,18 +63,193 @@ func (us *UploadSessionService) GetSessionRecordByUserID(ctx context.Context, us
 	return us.uploadSessionRepository.GetSessionRecordByUserID(ctx, userID)
 }
 
-// AddChunkSessionRecord adds a chunk to an existing upload session
-func (us *UploadSessionService) AddChunkSessionRecord(ctx context.Context, sessionToken string, chunkNumber int, chunkSize int, chunkHash string) error {
+// AddChunkSessionRecord adds a chunk to an upload session of the user
+// The chunk must come with the claim of the block server and the bytes uploaded by the session must fit in the storage quota
+func (us *UploadSessionService) AddChunkSessionRecord(ctx context.Context, sessionToken string, userID string, chunkNumber int, chunkSize int, chunkHash string, chunkClaim string) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	session, err := us.getOwnedSessionRecord(ctx, sessionToken, userID)
+	if err != nil {
+		return err
+	}
+	if err := checkChunkClaim(session, chunkNumber, chunkSize, chunkHash, chunkClaim); err != nil {
+		return err
+	}
+	if err := us.checkChunkQuota(ctx, session, chunkNumber, chunkSize); err != nil {
+		return err
+	}
+
 	return us.uploadSessionRepository.AddChunkSessionRecord(ctx, sessionToken, chunkNumber, chunkSize, chunkHash)
 }
 
-// AddChunkSessionRecordByFileID adds a chunk to an existing upload session by file ID
-func (us *UploadSessionService) AddChunkSessionRecordByFileID(ctx context.Context, fileID string, chunkNumber int, chunkSize int, chunkHash string) error {
+// AddChunkSessionRecordByFileID adds a chunk to an upload session of the user by file ID
+// The chunk must come with the claim of the block server and the bytes uploaded by the session must fit in the storage quota
+func (us *UploadSessionService) AddChunkSessionRecordByFileID(ctx context.Context, fileID string, userID string, chunkNumber int, chunkSize int, chunkHash string, chunkClaim string) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	session, err := us.uploadSessionRepository.GetSessionRecordByFileID(ctx, fileID)
+	if err != nil {
+		return fmt.Errorf("upload session not found: %w", err)
+	}
+	if session.UserID.Hex() != userID {
+		return fmt.Errorf("permission denied: the upload session belongs to another user")
+	}
+	if err := checkChunkClaim(session, chunkNumber, chunkSize, chunkHash, chunkClaim); err != nil {
+		return err
+	}
+	if err := us.checkChunkQuota(ctx, session, chunkNumber, chunkSize); err != nil {
+		return err
+	}
+
 	return us.uploadSessionRepository.AddChunkSessionRecordByFileID(ctx, fileID, chunkNumber, chunkSize, chunkHash)
 }
+
+// checkChunkClaim checks that the block server stored or verified the data of the chunk for the user of the session
+// A client cannot record a chunk by itself, otherwise it could reference the block of another user and read its data
+func checkChunkClaim(session *models.UploadSession, chunkNumber int, chunkSize int, chunkHash string, chunkClaim string) error {
+	if !storage.IsValidBlockHash(chunkHash) {
+		return fmt.Errorf("invalid chunk hash")
+	}
+	if chunkNumber < 0 || chunkSize <= 0 {
+		return fmt.Errorf("invalid chunk: the number and the size must be positive")
+	}
+
+	claim := models.ChunkClaim(session.UserID.Hex(), session.FileID.Hex(), chunkNumber, chunkSize, chunkHash)
+	if err := utils.VerifyBlockServerClaim(chunkClaim, configs.Config.JWTSecret, claim); err != nil {
+		return fmt.Errorf("permission denied: the chunk was not stored by the block server: %w", err)
+	}
+
+	return nil
+}
+
+// AcquireBlock keeps a block for a chunk upload of the user and tells the block server whether its data must be saved
+// The block is only reported as stored when a chunk of the user references it, otherwise the response would tell
+// anyone knowing a hash that another user stores the data: the block server then saves the data again.
+// The block cannot be deleted during the grace period, so the block server can skip the upload of a stored block
+// and record the chunk safely
+func (us *UploadSessionService) AcquireBlock(ctx context.Context, userID string, hash string, size int64) (*models.AcquireBlockResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if !storage.IsValidBlockHash(hash) {
+		return nil, fmt.Errorf("invalid block hash")
+	}
+	userIDHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID")
+	}
+
+	block, err := us.blockRepository.AcquireBlock(ctx, hash, size)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.AcquireBlockResponse{Hash: hash, Size: size}
+	if block.RefCount > 0 {
+		referenced, err := us.blockRepository.IsBlockReferencedByUser(ctx, hash, userIDHex)
+		if err != nil {
+			return nil, err
+		}
+		if referenced {
+			response.Size = block.Size
+			response.Stored = true
+			response.Referenced = true
+		}
+	}
+
+	return response, nil
+}
+
+// checkChunkQuota checks that the session and the new chunk fit in the storage quota of the user
+// The uploads to a shared drive use the storage quota of the organization instead.
+// A chunk already added to the session is not counted twice
+func (us *UploadSessionService) checkChunkQuota(ctx context.Context, session *models.UploadSession, chunkNumber int, chunkSize int) error {
+	if slices.Contains(session.ChunkList, chunkNumber) {
+		return nil
+	}
+
+	return checkUploadQuota(ctx, us.uploadSessionRepository, us.userRepository, us.organizationRepository, session, session.ActualSize+int64(chunkSize))
+}
+
+// checkUploadQuota returns the quota error when size more bytes uploaded by the session do not fit in the storage quota
+// of the owner, or of the organization in a shared drive. The sizes declared by the other pending uploads
+// are reserved, so parallel uploads cannot exceed the quota together
+func checkUploadQuota(ctx context.Context, usr models.UploadSessionRepository, ur models.UserRepository, or models.OrganizationRepository, session *models.UploadSession, size int64) error {
+	reserved, err := usr.GetReservedStorage(ctx, session.QuotaUserID(), session.OrganizationID, session.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	if session.OrganizationID != nil {
+		return checkOrganizationStorageQuota(ctx, or, *session.OrganizationID, reserved+size)
+	}
+
+	return checkStorageQuota(ctx, ur, session.QuotaUserID().Hex(), reserved+size)
+}
+
+// getOwnedSessionRecord retrieves an upload session and checks that it belongs to the user
+func (us *UploadSessionService) getOwnedSessionRecord(ctx context.Context, sessionToken string, userID string) (*models.UploadSession, error) {
+	session, err := us.uploadSessionRepository.GetSessionRecord(ctx, sessionToken)
+	if err != nil {
+		return nil, fmt.Errorf("upload session not found: %w", err)
+	}
+	if session.UserID.Hex() != userID {
+		return nil, fmt.Errorf("permission denied: the upload session belongs to another user")
+	}
+
+	return session, nil
+}
+
+// GetSessionChunks retrieves the uploaded chunks of an upload session, ordered by chunk index
+func (us *UploadSessionService) GetSessionChunks(ctx context.Context, sessionToken string, userID string) ([]models.Chunk, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	session, err := us.getOwnedSessionRecord(ctx, sessionToken, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return us.chunkRepository.GetChunksBySession(ctx, session)
+}
+
+// CompleteSessionRecord completes an upload session of the user
+// The completion must come with the claim of the block server that the data of every chunk matches its hash
+func (us *UploadSessionService) CompleteSessionRecord(ctx context.Context, sessionToken string, userID string, completionClaim string) (*models.UploadSession, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if _, err := us.getOwnedSessionRecord(ctx, sessionToken, userID); err != nil {
+		return nil, err
+	}
+	if err := utils.VerifyBlockServerClaim(completionClaim, configs.Config.JWTSecret, models.CompletionClaim(userID, sessionToken)); err != nil {
+		return nil, fmt.Errorf("permission denied: the chunks were not verified by the block server: %w", err)
+	}
+
+	return us.uploadSessionRepository.CompleteSessionRecord(ctx, sessionToken)
+}
+
+// CancelSessionRecord cancels an upload session of the user
+func (us *UploadSessionService) CancelSessionRecord(ctx context.Context, sessionToken string, userID string) (*models.CancelUploadSessionResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if _, err := us.getOwnedSessionRecord(ctx, sessionToken, userID); err != nil {
+		return nil, err
+	}
+
+	return us.uploadSessionRepository.CancelSessionRecord(ctx, sessionToken)
+}
+
+// ExtendSessionRecord extends a pending upload session of the user by the configured session TTL from now
+func (us *UploadSessionService) ExtendSessionRecord(ctx context.Context, sessionToken string, userID string) (*models.UploadSession, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if _, err := us.getOwnedSessionRecord(ctx, sessionToken, userID); err != nil {
+		return nil, err
+	}
+
+	return us.uploadSessionRepository.ExtendSessionRecord(ctx, sessionToken, time.Now().Add(configs.Config.UploadSessionTTL))
+}
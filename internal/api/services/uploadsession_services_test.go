@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"skybox-backend/configs"
+	"skybox-backend/internal/api/models"
+	"skybox-backend/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeUploadSessionRepository serves a single session and records the added chunks
+// The other pending sessions reserve the reserved bytes
+type fakeUploadSessionRepository struct {
+	models.UploadSessionRepository
+	session  *models.UploadSession
+	added    []int
+	reserved int64
+}
+
+func (f *fakeUploadSessionRepository) GetReservedStorage(ctx context.Context, userID primitive.ObjectID, organizationID *primitive.ObjectID, excludeSessionID primitive.ObjectID, now time.Time) (int64, error) {
+	return f.reserved, nil
+}
+
+func (f *fakeUploadSessionRepository) GetSessionRecord(ctx context.Context, sessionToken string) (*models.UploadSession, error) {
+	return f.session, nil
+}
+
+func (f *fakeUploadSessionRepository) AddChunkSessionRecord(ctx context.Context, sessionToken string, chunkNumber int, chunkSize int, chunkHash string) error {
+	f.added = append(f.added, chunkNumber)
+	return nil
+}
+
+func (f *fakeUploadSessionRepository) CompleteSessionRecord(ctx context.Context, sessionToken string) (*models.UploadSession, error) {
+	f.session.Status = "completed"
+	return f.session, nil
+}
+
+func (f *fakeUploadSessionRepository) CreateSessionRecord(ctx context.Context, session *models.UploadSession) (*models.UploadSession, error) {
+	f.session = session
+	return session, nil
+}
+
+// fakeFileRepository saves the uploaded files, or returns the replaced file
+type fakeFileRepository struct {
+	models.FileRepository
+	replaced *models.File
+	files    map[string]*models.File
+}
+
+func (f *fakeFileRepository) GetFileByID(ctx context.Context, id string) (*models.File, error) {
+	file, ok := f.files[id]
+	if !ok {
+		return nil, fmt.Errorf("file not found")
+	}
+	return file, nil
+}
+
+func (f *fakeFileRepository) UploadFileMetadata(ctx context.Context, file *models.File, conflict string) (*models.File, error) {
+	if f.replaced != nil {
+		return f.replaced, nil
+	}
+	file.ID = primitive.NewObjectID()
+	return file, nil
+}
+
+// fakeUserRepository serves a single user
+type fakeUserRepository struct {
+	models.UserRepository
+	user *models.User
+}
+
+func (f *fakeUserRepository) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	return f.user, nil
+}
+
+// addClaimedChunk adds a chunk to the session with the claim of the block server
+func addClaimedChunk(uploadSessionService *UploadSessionService, session *models.UploadSession, chunkNumber int, chunkSize int) error {
+	hash := utils.HashBytes([]byte(strconv.Itoa(chunkNumber)))
+	claim, err := utils.CreateBlockServerClaim(models.ChunkClaim(session.UserID.Hex(), session.FileID.Hex(), chunkNumber, chunkSize, hash), configs.Config.JWTSecret)
+	if err != nil {
+		return err
+	}
+
+	return uploadSessionService.AddChunkSessionRecord(context.Background(), "token", session.UserID.Hex(), chunkNumber, chunkSize, hash, claim)
+}
+
+func TestAddChunkSessionRecord_RequiresBlockServerClaim(t *testing.T) {
+	user := &models.User{ID: primitive.NewObjectID(), StorageQuota: -1}
+	session := &models.UploadSession{UserID: user.ID, FileID: primitive.NewObjectID()}
+	sessions := &fakeUploadSessionRepository{session: session}
+	uploadSessionService := NewUploadSessionService(sessions, nil, &fakeUserRepository{user: user}, nil, nil)
+
+	hash := utils.HashBytes([]byte("chunk"))
+	claim, err := utils.CreateBlockServerClaim(models.ChunkClaim(user.ID.Hex(), session.FileID.Hex(), 0, 5, hash), configs.Config.JWTSecret)
+	require.NoError(t, err)
+
+	// A client cannot record a chunk by itself
+	err = uploadSessionService.AddChunkSessionRecord(context.Background(), "token", user.ID.Hex(), 0, 5, hash, "")
+	assert.ErrorContains(t, err, "permission denied")
+
+	// The claim is only valid for its chunk
+	otherHash := utils.HashBytes([]byte("other"))
+	err = uploadSessionService.AddChunkSessionRecord(context.Background(), "token", user.ID.Hex(), 0, 5, otherHash, claim)
+	assert.ErrorContains(t, err, "permission denied")
+	err = uploadSessionService.AddChunkSessionRecord(context.Background(), "token", user.ID.Hex(), 0, 6, hash, claim)
+	assert.ErrorContains(t, err, "permission denied")
+
+	// A claim signed with another secret is rejected
+	forged, err := utils.CreateBlockServerClaim(models.ChunkClaim(user.ID.Hex(), session.FileID.Hex(), 0, 5, hash), "guessed")
+	require.NoError(t, err)
+	err = uploadSessionService.AddChunkSessionRecord(context.Background(), "token", user.ID.Hex(), 0, 5, hash, forged)
+	assert.ErrorContains(t, err, "permission denied")
+
+	// The session of another user and an invalid hash are rejected
+	err = uploadSessionService.AddChunkSessionRecord(context.Background(), "token", primitive.NewObjectID().Hex(), 0, 5, hash, claim)
+	assert.ErrorContains(t, err, "permission denied")
+	err = uploadSessionService.AddChunkSessionRecord(context.Background(), "token", user.ID.Hex(), 0, 5, "not-a-hash", claim)
+	assert.ErrorContains(t, err, "invalid chunk hash")
+	assert.Empty(t, sessions.added)
+
+	require.NoError(t, uploadSessionService.AddChunkSessionRecord(context.Background(), "token", user.ID.Hex(), 0, 5, hash, claim))
+	assert.Equal(t, []int{0}, sessions.added)
+}
+
+func TestAddChunkSessionRecord_StorageQuota(t *testing.T) {
+	defaultQuota := configs.Config.DefaultStorageQuota
+	configs.Config.DefaultStorageQuota = 100
+	defer func() { configs.Config.DefaultStorageQuota = defaultQuota }()
+
+	user := &models.User{ID: primitive.NewObjectID(), StorageUsed: 60}
+	sessions := &fakeUploadSessionRepository{session: &models.UploadSession{
+		UserID:     user.ID,
+		ChunkList:  []int{0},
+		ActualSize: 30,
+	}}
+	uploadSessionService := NewUploadSessionService(sessions, nil, &fakeUserRepository{user: user}, nil, nil)
+
+	// 60 used + 30 uploaded + 10 fits in the default quota
+	require.NoError(t, addClaimedChunk(uploadSessionService, sessions.session, 1, 10))
+
+	// One more byte does not
+	err := addClaimedChunk(uploadSessionService, sessions.session, 1, 11)
+	assert.ErrorIs(t, err, models.ErrStorageQuotaExceeded)
+
+	// A chunk already uploaded is not counted twice
+	require.NoError(t, addClaimedChunk(uploadSessionService, sessions.session, 0, 30))
+
+	// A quota of the user replaces the default plan, a negative quota is unlimited
+	user.StorageQuota = 200
+	require.NoError(t, addClaimedChunk(uploadSessionService, sessions.session, 2, 50))
+	user.StorageQuota = -1
+	require.NoError(t, addClaimedChunk(uploadSessionService, sessions.session, 3, 1000))
+
+	assert.Equal(t, []int{1, 0, 2, 3}, sessions.added)
+}
+
+func TestAddChunkSessionRecord_ReservedStorage(t *testing.T) {
+	defaultQuota := configs.Config.DefaultStorageQuota
+	configs.Config.DefaultStorageQuota = 100
+	defer func() { configs.Config.DefaultStorageQuota = defaultQuota }()
+
+	// The other pending uploads of the user declared 50 bytes
+	user := &models.User{ID: primitive.NewObjectID(), StorageUsed: 20}
+	sessions := &fakeUploadSessionRepository{reserved: 50, session: &models.UploadSession{
+		UserID:     user.ID,
+		ActualSize: 20,
+	}}
+	uploadSessionService := NewUploadSessionService(sessions, nil, &fakeUserRepository{user: user}, nil, nil)
+
+	// 20 used + 50 reserved + 20 uploaded + 10 fits in the default quota
+	require.NoError(t, addClaimedChunk(uploadSessionService, sessions.session, 0, 10))
+
+	// One more byte does not
+	err := addClaimedChunk(uploadSessionService, sessions.session, 1, 11)
+	assert.ErrorIs(t, err, models.ErrStorageQuotaExceeded)
+}
+
+func TestUploadFileMetadata_ReservedStorage(t *testing.T) {
+	defaultQuota := configs.Config.DefaultStorageQuota
+	configs.Config.DefaultStorageQuota = 100
+	defer func() { configs.Config.DefaultStorageQuota = defaultQuota }()
+
+	// A parallel upload declared 60 bytes, a second upload of 50 bytes does not fit next to it
+	user := &models.User{ID: primitive.NewObjectID()}
+	sessions := &fakeUploadSessionRepository{reserved: 60}
+	fileService := NewFileService(nil, sessions, &fakeUserRepository{user: user}, nil)
+
+	_, _, err := fileService.UploadFileMetadata(context.Background(), &models.File{OwnerID: user.ID, Size: 50}, "")
+	assert.ErrorIs(t, err, models.ErrStorageQuotaExceeded)
+}
+
+func TestUploadFileMetadata_SharedFolder(t *testing.T) {
+	uploader := &models.User{ID: primitive.NewObjectID()}
+	sessions := &fakeUploadSessionRepository{}
+	files := &fakeFileRepository{}
+	fileService := NewFileService(files, sessions, &fakeUserRepository{user: uploader}, nil)
+
+	// A new file in a folder of another user belongs to the uploader
+	file, session, err := fileService.UploadFileMetadata(context.Background(), &models.File{OwnerID: uploader.ID, Size: 10}, "")
+	require.NoError(t, err)
+	assert.Equal(t, uploader.ID, file.OwnerID)
+	assert.Equal(t, uploader.ID, session.UserID)
+	assert.Equal(t, uploader.ID, session.QuotaUserID())
+
+	// A replaced file keeps its owner, the uploader sends the new version
+	files.replaced = &models.File{ID: primitive.NewObjectID(), OwnerID: primitive.NewObjectID(), LatestVersion: 2}
+	file, session, err = fileService.UploadFileMetadata(context.Background(), &models.File{OwnerID: uploader.ID, Size: 10}, models.ConflictReplace)
+	require.NoError(t, err)
+	assert.Equal(t, files.replaced, file)
+	assert.Equal(t, uploader.ID, session.UserID)
+	assert.Equal(t, files.replaced.OwnerID, session.QuotaUserID())
+	assert.Equal(t, 2, session.Version)
+}
+
+// fakeBlockRepository keeps the blocks and the users referencing them
+type fakeBlockRepository struct {
+	models.BlockRepository
+	blocks     map[string]*models.Block
+	referenced map[string]primitive.ObjectID
+}
+
+func (f *fakeBlockRepository) AcquireBlock(ctx context.Context, hash string, size int64) (*models.Block, error) {
+	if block, ok := f.blocks[hash]; ok {
+		return block, nil
+	}
+	return &models.Block{Hash: hash, Size: size}, nil
+}
+
+func (f *fakeBlockRepository) IsBlockReferencedByUser(ctx context.Context, hash string, userID primitive.ObjectID) (bool, error) {
+	return f.referenced[hash] == userID, nil
+}
+
+func TestAcquireBlock_HidesTheBlocksOfOtherUsers(t *testing.T) {
+	owner := primitive.NewObjectID()
+	hash := utils.HashBytes([]byte("secret"))
+	blocks := &fakeBlockRepository{
+		blocks:     map[string]*models.Block{hash: {Hash: hash, Size: 6, RefCount: 1}},
+		referenced: map[string]primitive.ObjectID{hash: owner},
+	}
+	uploadSessionService := NewUploadSessionService(nil, nil, nil, nil, blocks)
+
+	// Another user sees a new block of the requested size and must upload the data
+	response, err := uploadSessionService.AcquireBlock(context.Background(), primitive.NewObjectID().Hex(), hash, 100)
+	require.NoError(t, err)
+	assert.Equal(t, &models.AcquireBlockResponse{Hash: hash, Size: 100}, response)
+
+	// The owner skips the upload
+	response, err = uploadSessionService.AcquireBlock(context.Background(), owner.Hex(), hash, 100)
+	require.NoError(t, err)
+	assert.Equal(t, &models.AcquireBlockResponse{Hash: hash, Size: 6, Stored: true, Referenced: true}, response)
+}
+
+func TestCompleteSessionRecord_RequiresBlockServerClaim(t *testing.T) {
+	user := &models.User{ID: primitive.NewObjectID()}
+	sessions := &fakeUploadSessionRepository{session: &models.UploadSession{UserID: user.ID, SessionToken: "token", Status: "pending"}}
+	uploadSessionService := NewUploadSessionService(sessions, nil, nil, nil, nil)
+
+	// A client cannot skip the verification of the chunk hashes by the block server
+	_, err := uploadSessionService.CompleteSessionRecord(context.Background(), "token", user.ID.Hex(), "")
+	assert.ErrorContains(t, err, "permission denied")
+	other, err := utils.CreateBlockServerClaim(models.CompletionClaim(user.ID.Hex(), "other"), configs.Config.JWTSecret)
+	require.NoError(t, err)
+	_, err = uploadSessionService.CompleteSessionRecord(context.Background(), "token", user.ID.Hex(), other)
+	assert.ErrorContains(t, err, "permission denied")
+	assert.Equal(t, "pending", sessions.session.Status)
+
+	claim, err := utils.CreateBlockServerClaim(models.CompletionClaim(user.ID.Hex(), "token"), configs.Config.JWTSecret)
+	require.NoError(t, err)
+	session, err := uploadSessionService.CompleteSessionRecord(context.Background(), "token", user.ID.Hex(), claim)
+	require.NoError(t, err)
+	assert.Equal(t, "completed", session.Status)
+}
@@ -2,7 +2,9 @@ package services
 
 import (
 	"context"
+	"fmt"
 
+	"skybox-backend/configs"
 	"skybox-backend/internal/api/models"
 )
 
@@ -50,3 +52,38 @@ func (us *UserService) GetUsersByEmails(ctx context.Context, emails []string) ([
 
 	return us.userRepository.GetUsersByEmails(ctx, emails)
 }
+
+// GetStorageUsage returns the storage quota and usage of the user with the usage of each mime type
+func (us *UserService) GetStorageUsage(ctx context.Context, id string) (*models.StorageUsageResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	user, err := us.userRepository.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	breakdown, err := us.userRepository.GetStorageBreakdown(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.StorageUsageResponse{
+		Quota:     user.GetStorageQuota(configs.Config.DefaultStorageQuota),
+		Used:      user.StorageUsed,
+		Breakdown: breakdown,
+	}, nil
+}
+
+// checkStorageQuota returns models.ErrStorageQuotaExceeded if the user cannot store size more bytes
+func checkStorageQuota(ctx context.Context, ur models.UserRepository, userID string, size int64) error {
+	user, err := ur.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("user not found: %w", err)
+	}
+	if !user.HasStorageFor(size, configs.Config.DefaultStorageQuota) {
+		return models.ErrStorageQuotaExceeded
+	}
+
+	return nil
+}
@@ -1,24 +1,24 @@
 package app
 
 import (
-	"skybox-backend/configs"
-	"skybox-backend/internal/blockserver/storage"
+	"fmt"
 
-	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"skybox-backend/internal/shared/storage"
 )
 
 type Application struct {
-	s3Client *s3.Client
+	store storage.BlockStore
 }
 
 func NewApplication() Application {
 	app := &Application{}
 
-	// Connect to AWS S3
-	app.s3Client = storage.GetS3Client()
-	if app.s3Client == nil && configs.Config.AWSEnabled {
-		panic("Failed to create AWS S3 client")
+	// Connect to the configured block store (AWS S3, local filesystem, ...)
+	store, err := storage.NewBlockStore()
+	if err != nil {
+		panic(fmt.Errorf("failed to create block store: %w", err))
 	}
+	app.store = store
 
 	return *app
 }
@@ -37,7 +37,7 @@ func StartServer() {
 	ginServer := NewServer()
 	ginServer.CorsMiddleware()
 	ginServer.SecurityMiddleware()
-	ginServer.RouteMiddleware()
+	ginServer.RouteMiddleware(application.store)
 	ginServer.GlobalErrorHandler()
 
 	ginServer.StartServer()
@@ -7,6 +7,7 @@ import (
 
 	"skybox-backend/configs"
 	"skybox-backend/internal/blockserver/routes"
+	"skybox-backend/internal/shared/storage"
 
 	"github.com/gin-gonic/gin"
 )
@@ -50,8 +51,8 @@ func (s *Server) SecurityMiddleware() {
 }
 
 // routeMiddleware sets up the routes and the corresponding handlers
-func (s *Server) RouteMiddleware() {
-	routes.SetupRouter(s.app)
+func (s *Server) RouteMiddleware(store storage.BlockStore) {
+	routes.SetupRouter(s.app, store)
 }
 
 func (s *Server) GlobalErrorHandler() {
@@ -75,12 +76,12 @@ func (s *Server) CorsMiddleware() {
 	s.app.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, Content-Range, Range")
-		c.Header("Access-Control-Expose-Headers", "Content-Length")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, Content-Range, Range, Tus-Resumable, Upload-Length, Upload-Metadata, Upload-Offset, Upload-Checksum")
+		c.Header("Access-Control-Expose-Headers", "Content-Length, Location, Tus-Resumable, Tus-Version, Tus-Extension, Tus-Checksum-Algorithm, Upload-Length, Upload-Offset")
 		c.Header("Access-Control-Allow-Credentials", "true")
 
-		// Handle preflight requests
-		if c.Request.Method == http.MethodOptions {
+		// Handle preflight requests, the other OPTIONS requests are tus capabilities discovery
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
 			c.AbortWithStatus(http.StatusNoContent)
 			return
 		}
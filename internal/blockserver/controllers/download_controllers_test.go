@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"skybox-backend/configs"
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/blockserver/services"
+	"skybox-backend/internal/shared/storage"
+	"skybox-backend/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testFileContent = "0123456789abcdefghij"
+
+// setupDownloadTest saves the test file in three chunks and serves its chunk list from a fake API Server
+func setupDownloadTest(t *testing.T) (*gin.Engine, string, string, time.Time) {
+	store := storage.NewMemoryStore()
+	chunks := []models.Chunk{}
+	for index, part := range []string{testFileContent[:8], testFileContent[8:16], testFileContent[16:]} {
+		hash := utils.HashString(part)
+		err := store.Put(context.Background(), storage.BlockKey(hash), bytes.NewReader([]byte(part)), int64(len(part)))
+		require.NoError(t, err)
+		chunks = append(chunks, models.Chunk{ChunkIndex: index, ChunkSize: int64(len(part)), ChunkHash: hash})
+	}
+
+	fileId := "1234567890abcdef12345678"
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(gin.H{
+			"status": "success",
+			"data":   models.FileChunksResponse{FileID: fileId, TotalChunks: len(chunks), Chunks: chunks},
+		})
+	}))
+	t.Cleanup(api.Close)
+
+	apiURL, err := url.Parse(api.URL)
+	require.NoError(t, err)
+	previous := configs.Config
+	configs.Config.ServerHost = apiURL.Hostname()
+	configs.Config.ServerPort = apiURL.Port()
+	t.Cleanup(func() { configs.Config = previous })
+
+	modifiedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	token, err := utils.GenerateToken(map[string]string{
+		"fileId":     fileId,
+		"ownerId":    "owner",
+		"fileName":   "file.txt",
+		"fileSize":   fmt.Sprintf("%d", len(testFileContent)),
+		"mimeType":   "text/plain",
+		"modifiedAt": fmt.Sprintf("%d", modifiedAt.Unix()),
+	}, configs.Config.JWTSecret, 1)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/download/:fileId", NewDownloadController(services.NewDownloadService(store)).DownloadFileHandler)
+
+	return router, "/download/" + fileId + "?token=" + token, services.ChunksETag(fileId, chunks), modifiedAt
+}
+
+func download(router *gin.Engine, target string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestParseRangeHeader(t *testing.T) {
+	ranges, err := parseRangeHeader("bytes=0-4, 10-, -3", 20)
+	require.NoError(t, err)
+	assert.Equal(t, []byteRange{{0, 4}, {10, 19}, {17, 19}}, ranges)
+
+	// The end and the suffix length are clamped to the file
+	ranges, err = parseRangeHeader("bytes=15-100", 20)
+	require.NoError(t, err)
+	assert.Equal(t, []byteRange{{15, 19}}, ranges)
+	ranges, err = parseRangeHeader("bytes=-50", 20)
+	require.NoError(t, err)
+	assert.Equal(t, []byteRange{{0, 19}}, ranges)
+
+	// Ranges outside the file are skipped, the request fails when none is left
+	ranges, err = parseRangeHeader("bytes=30-40,0-0", 20)
+	require.NoError(t, err)
+	assert.Equal(t, []byteRange{{0, 0}}, ranges)
+	_, err = parseRangeHeader("bytes=30-40", 20)
+	assert.ErrorIs(t, err, errInvalidRange)
+
+	_, err = parseRangeHeader("bytes=-0", 20)
+	assert.ErrorIs(t, err, errInvalidRange)
+
+	// Other units, invalid headers and ranges larger than the file send the whole file
+	for _, header := range []string{"items=0-4", "bytes=0-15,5-19", "bytes=5-2", "bytes=a-b", "bytes=5", "bytes=", "bytes=0-4,5-2"} {
+		ranges, err = parseRangeHeader(header, 20)
+		require.NoError(t, err)
+		assert.Nil(t, ranges, header)
+	}
+}
+
+func TestDownloadFileHandler_Ranges(t *testing.T) {
+	router, target, etag, modifiedAt := setupDownloadTest(t)
+
+	w := download(router, target, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, testFileContent, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+	assert.Equal(t, modifiedAt.Format(http.TimeFormat), w.Header().Get("Last-Modified"))
+
+	w = download(router, target, map[string]string{"Range": "bytes=-5"})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "fghij", w.Body.String())
+	assert.Equal(t, "bytes 15-19/20", w.Header().Get("Content-Range"))
+
+	w = download(router, target, map[string]string{"Range": "bytes=40-"})
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, w.Code)
+	assert.Equal(t, "bytes */20", w.Header().Get("Content-Range"))
+
+	// An invalid range is ignored
+	w = download(router, target, map[string]string{"Range": "bytes=5-2"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, testFileContent, w.Body.String())
+
+	// Several ranges are sent as a multipart/byteranges body
+	w = download(router, target, map[string]string{"Range": "bytes=6-9,-2"})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, fmt.Sprintf("%d", w.Body.Len()), w.Header().Get("Content-Length"))
+
+	mediaType, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/byteranges", mediaType)
+
+	reader := multipart.NewReader(w.Body, params["boundary"])
+	expected := []struct{ contentRange, content string }{
+		{"bytes 6-9/20", "6789"},
+		{"bytes 18-19/20", "ij"},
+	}
+	for _, part := range expected {
+		p, err := reader.NextPart()
+		require.NoError(t, err)
+		assert.Equal(t, "text/plain", p.Header.Get("Content-Type"))
+		assert.Equal(t, part.contentRange, p.Header.Get("Content-Range"))
+		content, err := io.ReadAll(p)
+		require.NoError(t, err)
+		assert.Equal(t, part.content, string(content))
+	}
+	_, err = reader.NextPart()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestDownloadFileHandler_Conditional(t *testing.T) {
+	router, target, etag, modifiedAt := setupDownloadTest(t)
+
+	w := download(router, target, map[string]string{"If-None-Match": `"other", W/` + etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	w = download(router, target, map[string]string{"If-Modified-Since": modifiedAt.Format(http.TimeFormat)})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	// If-None-Match takes precedence over If-Modified-Since
+	w = download(router, target, map[string]string{
+		"If-None-Match":     `"other"`,
+		"If-Modified-Since": modifiedAt.Format(http.TimeFormat),
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = download(router, target, map[string]string{"If-Modified-Since": modifiedAt.Add(-time.Hour).Format(http.TimeFormat)})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The range is only sent when the If-Range validator matches
+	for _, ifRange := range []string{etag, modifiedAt.Format(http.TimeFormat)} {
+		w = download(router, target, map[string]string{"Range": "bytes=0-1", "If-Range": ifRange})
+		assert.Equal(t, http.StatusPartialContent, w.Code)
+		assert.Equal(t, "01", w.Body.String())
+	}
+	for _, ifRange := range []string{`"other"`, "W/" + etag, modifiedAt.Add(time.Hour).Format(http.TimeFormat)} {
+		w = download(router, target, map[string]string{"Range": "bytes=0-1", "If-Range": ifRange})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, testFileContent, w.Body.String())
+	}
+}
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"skybox-backend/internal/blockserver/services"
+	"skybox-backend/internal/shared"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	TusVersion    = "1.0.0"
+	TusExtensions = "creation,termination,checksum"
+
+	// StatusChecksumMismatch is the status code defined by the tus checksum extension
+	StatusChecksumMismatch = 460
+)
+
+// TusController handles the tus 1.0 resumable upload protocol
+// See https://tus.io/protocols/resumable-upload for the protocol specification
+type TusController struct {
+	TusService *services.TusService
+}
+
+func NewTusController(tusService *services.TusService) *TusController {
+	return &TusController{
+		TusService: tusService,
+	}
+}
+
+// TusResumableMiddleware checks the Tus-Resumable header and adds it to every response
+func TusResumableMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Tus-Resumable", TusVersion)
+
+		// OPTIONS requests are the only ones without the header
+		if c.Request.Method != http.MethodOptions && c.GetHeader("Tus-Resumable") != TusVersion {
+			c.Header("Tus-Version", TusVersion)
+			shared.ErrorJSON(c, http.StatusPreconditionFailed, "Unsupported tus version")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// respondTusError writes the status code matching the error of a tus service call
+func respondTusError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTusOffsetMismatch):
+		shared.ErrorJSON(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrTusChecksumMismatch):
+		shared.ErrorJSON(c, StatusChecksumMismatch, err.Error())
+	case errors.Is(err, services.ErrTusUnsupportedChecksum):
+		shared.ErrorJSON(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrTusUploadTooLarge):
+		shared.ErrorJSON(c, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, services.ErrTusUploadLocked):
+		shared.ErrorJSON(c, http.StatusLocked, err.Error())
+	case errors.Is(err, services.ErrTusUploadGone):
+		shared.ErrorJSON(c, http.StatusGone, err.Error())
+	default:
+		respondServiceError(c, http.StatusBadRequest, "", err)
+	}
+}
+
+// TusOptionsHandler godoc
+//
+//	@Summary		Get the tus server capabilities
+//	@Description	Get the supported tus versions, extensions and checksum algorithms.
+//	@Tags			Tus
+//	@Success		204	{string}	string	"No Content"
+//	@Router			/tus [options]
+func (tc *TusController) TusOptionsHandler(c *gin.Context) {
+	c.Header("Tus-Version", TusVersion)
+	c.Header("Tus-Extension", TusExtensions)
+	c.Header("Tus-Checksum-Algorithm", strings.Join(services.TusChecksumAlgorithms, ","))
+	c.Status(http.StatusNoContent)
+}
+
+// TusCreateUploadHandler godoc
+//
+//	@Summary		Create a tus upload
+//	@Description	Create a file and its upload session (tus creation extension). The Upload-Metadata header must contain the `filename`, it can also contain the `filetype`, the `folder_id` and the `conflict` option (`fail`, `rename` or `replace`). The file is created in the root folder when the `folder_id` is missing. An empty upload (`Upload-Length: 0`) is completed right away.
+//	@Tags			Tus
+//	@Param			Tus-Resumable	header		string	true	"Tus version"	default(1.0.0)
+//	@Param			Upload-Length	header		int		true	"Size of the upload in bytes"
+//	@Param			Upload-Metadata	header		string	false	"Upload metadata"
+//	@Success		201				{string}	string	"Created, the Location header is the upload URL"
+//	@Failure		400				{string}	string	"Bad Request: Invalid Upload-Length or Upload-Metadata"
+//	@Router			/tus [post]
+func (tc *TusController) TusCreateUploadHandler(c *gin.Context) {
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		shared.ErrorJSON(c, http.StatusBadRequest, "Invalid Upload-Length header")
+		return
+	}
+
+	metadata, err := services.ParseTusMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		shared.ErrorJSON(c, http.StatusBadRequest, "Invalid Upload-Metadata header "+err.Error())
+		return
+	}
+
+	sessionToken, err := tc.TusService.CreateUpload(c, length, metadata)
+	if err != nil {
+		respondTusError(c, err)
+		return
+	}
+
+	c.Header("Location", path.Join(c.Request.URL.Path, sessionToken))
+	c.Status(http.StatusCreated)
+}
+
+// TusHeadUploadHandler godoc
+//
+//	@Summary		Get the offset of a tus upload
+//	@Description	Get the number of bytes received for the upload, the client resumes the upload from this offset.
+//	@Tags			Tus
+//	@Param			sessionToken	path		string	true	"Session Token"
+//	@Param			Tus-Resumable	header		string	true	"Tus version"	default(1.0.0)
+//	@Success		200				{string}	string	"OK, the offset is in the Upload-Offset header"
+//	@Failure		410				{string}	string	"Gone: The upload is cancelled"
+//	@Router			/tus/{sessionToken} [head]
+func (tc *TusController) TusHeadUploadHandler(c *gin.Context) {
+	upload, err := tc.TusService.GetUpload(c, c.Param("sessionToken"))
+	if err != nil {
+		respondTusError(c, err)
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+}
+
+// TusPatchUploadHandler godoc
+//
+//	@Summary		Append data to a tus upload
+//	@Description	Append the request body to the upload at the given offset. With the checksum extension, the body is rejected with 460 if its checksum does not match the Upload-Checksum header.
+//	@Tags			Tus
+//	@Accept			application/offset+octet-stream
+//	@Param			sessionToken	path		string	true	"Session Token"
+//	@Param			Tus-Resumable	header		string	true	"Tus version"	default(1.0.0)
+//	@Param			Upload-Offset	header		int		true	"Offset of the data"
+//	@Param			Upload-Checksum	header		string	false	"Checksum of the body, `<algorithm> <base64 digest>`"
+//	@Success		204				{string}	string	"No Content, the new offset is in the Upload-Offset header"
+//	@Failure		409				{string}	string	"Conflict: The offset does not match"
+//	@Failure		415				{string}	string	"Unsupported Media Type"
+//	@Failure		460				{string}	string	"Checksum Mismatch"
+//	@Router			/tus/{sessionToken} [patch]
+func (tc *TusController) TusPatchUploadHandler(c *gin.Context) {
+	if c.ContentType() != "application/offset+octet-stream" {
+		shared.ErrorJSON(c, http.StatusUnsupportedMediaType, "Content-Type must be application/offset+octet-stream")
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		shared.ErrorJSON(c, http.StatusBadRequest, "Invalid Upload-Offset header")
+		return
+	}
+
+	newOffset, err := tc.TusService.WriteUpload(c, c.Param("sessionToken"), offset, c.Request.Body, c.GetHeader("Upload-Checksum"))
+	if err != nil {
+		respondTusError(c, err)
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(newOffset, 10))
+	c.Status(http.StatusNoContent)
+}
+
+// TusDeleteUploadHandler godoc
+//
+//	@Summary		Terminate a tus upload
+//	@Description	Cancel the upload session and delete the received data (tus termination extension).
+//	@Tags			Tus
+//	@Param			sessionToken	path		string	true	"Session Token"
+//	@Param			Tus-Resumable	header		string	true	"Tus version"	default(1.0.0)
+//	@Success		204				{string}	string	"No Content"
+//	@Failure		400				{string}	string	"Bad Request: The upload is already completed"
+//	@Router			/tus/{sessionToken} [delete]
+func (tc *TusController) TusDeleteUploadHandler(c *gin.Context) {
+	if err := tc.TusService.TerminateUpload(c, c.Param("sessionToken")); err != nil {
+		respondTusError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
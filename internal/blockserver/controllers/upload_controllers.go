@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -78,6 +79,7 @@ func parseContentRange(header string) (start, end, total int64, err error) {
 //	@Param			file	formData	file	true	"File"		default("file")		example("file")
 //	@Success		200		{string}	string	"File uploaded successfully"
 //	@Failure		400		{string}	string	"Bad Request: Invalid file ID or Failed to get file from form or file size exceeds the maximum limit"
+//	@Failure		413		{string}	string	"Request Entity Too Large: Storage quota exceeded"
 //	@Failure		500		{string}	string	"Internal Server Error: Failed to save file"
 //	@Router			/upload/whole/{fileId} [post]
 //
@@ -90,7 +92,7 @@ func (uc *UploadController) UploadWholeFileHandler(c *gin.Context) {
 	}
 
 	// Validate the file
-	err := uc.UploadService.ValidateFile(c, fileId)
+	fileObject, err := uc.UploadService.ValidateFile(c, fileId)
 	if err != nil {
 		shared.ErrorJSON(c, http.StatusBadRequest, "Invalid file ID "+err.Error())
 		return
@@ -109,6 +111,9 @@ func (uc *UploadController) UploadWholeFileHandler(c *gin.Context) {
 		shared.ErrorJSON(c, http.StatusBadRequest, "File size exceeds the maximum limit")
 		return
 	}
+	if !uc.checkStorageQuota(c, fileObject.OrganizationID, header.Size) {
+		return
+	}
 
 	// Save the file as-is to S3 or any other storage
 	buf := new(bytes.Buffer)
@@ -123,7 +128,13 @@ func (uc *UploadController) UploadWholeFileHandler(c *gin.Context) {
 	// Save the file to S3 or any other storage
 	err = uc.UploadService.SaveChunk(c, fileId, fileName, ext, 0, buf.Bytes())
 	if err != nil {
-		shared.ErrorJSON(c, http.StatusInternalServerError, "Failed to save file. Error: "+err.Error())
+		respondServiceError(c, http.StatusInternalServerError, "Failed to save file. Error: ", err)
+		return
+	}
+
+	// Verify the saved chunk and mark the file as uploaded
+	if _, err := uc.UploadService.CompleteFileUpload(c, fileId); err != nil {
+		respondServiceError(c, http.StatusBadRequest, "Failed to complete the upload. Error: ", err)
 		return
 	}
 
@@ -149,6 +160,7 @@ func (uc *UploadController) UploadWholeFileHandler(c *gin.Context) {
 //	@Param			file		formData	file	true	"File"		default("file")		example("file")
 //	@Success		200			{string}	string	"File uploaded successfully"
 //	@Failure		400			{string}	string	"Bad Request: Invalid file ID or file size exceeds the maximum limit"
+//	@Failure		413			{string}	string	"Request Entity Too Large: Storage quota exceeded"
 //	@Failure		500			{string}	string	"Internal Server Error: Failed to save file"
 //	@Router			/upload/chunked/{fileId} [post]
 //
@@ -162,7 +174,7 @@ func (uc *UploadController) UploadAutoChunkHandler(c *gin.Context) {
 	}
 
 	// Validate the file
-	err := uc.UploadService.ValidateFile(c, fileId)
+	fileObject, err := uc.UploadService.ValidateFile(c, fileId)
 	if err != nil {
 		shared.ErrorJSON(c, http.StatusBadRequest, "Invalid file ID. Error: "+err.Error())
 		return
@@ -194,6 +206,9 @@ func (uc *UploadController) UploadAutoChunkHandler(c *gin.Context) {
 	fileName := header.Filename
 	fileExt := filepath.Ext(fileName)
 	totalSize := header.Size
+	if !uc.checkStorageQuota(c, fileObject.OrganizationID, totalSize) {
+		return
+	}
 	totalChunks := int(math.Ceil(float64(totalSize) / float64(chunkSize)))
 
 	// Worker pool for concurrent chunk uploads
@@ -253,6 +268,12 @@ func (uc *UploadController) UploadAutoChunkHandler(c *gin.Context) {
 		return
 	}
 
+	// Verify the saved chunks and mark the file as uploaded
+	if _, err := uc.UploadService.CompleteFileUpload(c, fileId); err != nil {
+		respondServiceError(c, http.StatusBadRequest, "Failed to complete the upload. Error: ", err)
+		return
+	}
+
 	// Return success response
 	response := &models.NonResumableUploadResponse{
 		FileID:     fileId,
@@ -275,6 +296,7 @@ func (uc *UploadController) UploadAutoChunkHandler(c *gin.Context) {
 //	@Param			body			body		[]byte	true	"Chunk data"
 //	@Success		200				{string}	string	"Chunk uploaded successfully"
 //	@Failure		400				{string}	string	"Bad Request: Invalid session ID or Content-Range header"
+//	@Failure		413				{string}	string	"Request Entity Too Large: Storage quota exceeded"
 //	@Failure		500				{string}	string	"Internal Server Error: Failed to save chunk"
 //	@Router			/upload/session/{sessionToken}/chunk [post]
 //
@@ -297,7 +319,11 @@ func (uc *UploadController) UploadChunkHandler(c *gin.Context) {
 	}
 
 	chunkIndex := int(start / DefaultChunkSize)
-	fileId, err := uc.UploadService.ValidateSession(c, sessionToken, chunkIndex)
+	fileId, err := uc.UploadService.ValidateSession(c, sessionToken, chunkIndex, end-start+1)
+	if errors.Is(err, services.ErrStorageQuotaExceeded) {
+		shared.ErrorJSON(c, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
 	if err != nil {
 		shared.ErrorJSON(c, http.StatusBadRequest, "Invalid session ID "+err.Error())
 		return
@@ -325,7 +351,7 @@ func (uc *UploadController) UploadChunkHandler(c *gin.Context) {
 	// Save the chunk to S3 or any other storage
 	err = uc.UploadService.SaveChunk(c, fileId, "", "", chunkIndex, chunkData)
 	if err != nil {
-		shared.ErrorJSON(c, http.StatusInternalServerError, "Failed to save chunk "+err.Error())
+		respondServiceError(c, http.StatusInternalServerError, "Failed to save chunk ", err)
 		return
 	}
 
@@ -333,6 +359,66 @@ func (uc *UploadController) UploadChunkHandler(c *gin.Context) {
 	shared.SuccessJSON(c, http.StatusOK, "Chunk uploaded successfully", nil)
 }
 
+// UploadKnownChunkHandler godoc
+//
+//	@Summary		Register an already stored chunk to a resumable session
+//	@Description	Register a chunk by its SHA-256 hash without sending its data. Chunks are stored once by hash, so when a file of the user already references the block the upload can be skipped entirely. For any other block, the server answers 404 and the client must upload the chunk data.
+//	@Tags			Upload
+//	@Accept			json
+//	@Produce		json
+//	@Param			sessionToken	path		string						true	"Session Token"
+//	@Param			request			body		models.KnownChunkRequest	true	"Known chunk request"
+//	@Success		200				{string}	string						"Chunk registered successfully"
+//	@Failure		400				{string}	string						"Bad Request: Invalid session ID or request body"
+//	@Failure		404				{string}	string						"Not Found: Chunk data is not known by the user"
+//	@Failure		413				{string}	string						"Request Entity Too Large: Storage quota exceeded"
+//	@Failure		500				{string}	string						"Internal Server Error: Failed to register chunk"
+//	@Router			/upload/session/{sessionToken}/known [post]
+func (uc *UploadController) UploadKnownChunkHandler(c *gin.Context) {
+	sessionToken := c.Param("sessionToken")
+	if sessionToken == "" {
+		shared.ErrorJSON(c, http.StatusBadRequest, "Missing session ID")
+		return
+	}
+
+	var request models.KnownChunkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.ErrorJSON(c, http.StatusBadRequest, "Invalid request body "+err.Error())
+		return
+	}
+	if int64(request.ChunkSize) > DefaultChunkSize {
+		shared.ErrorJSON(c, http.StatusBadRequest, "Chunk size exceeds the maximum limit")
+		return
+	}
+
+	fileId, err := uc.UploadService.ValidateSession(c, sessionToken, request.ChunkNumber, int64(request.ChunkSize))
+	if errors.Is(err, services.ErrStorageQuotaExceeded) {
+		shared.ErrorJSON(c, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	if err != nil {
+		shared.ErrorJSON(c, http.StatusBadRequest, "Invalid session ID "+err.Error())
+		return
+	}
+
+	err = uc.UploadService.SaveKnownChunk(c, fileId, models.AddChunkSessionRequest{
+		ChunkNumber: request.ChunkNumber,
+		ChunkSize:   request.ChunkSize,
+		ChunkHash:   request.ChunkHash,
+	})
+	if errors.Is(err, services.ErrUnknownBlock) {
+		shared.ErrorJSON(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		respondServiceError(c, http.StatusInternalServerError, "Failed to register chunk ", err)
+		return
+	}
+
+	// Return success response
+	shared.SuccessJSON(c, http.StatusOK, "Chunk registered successfully", nil)
+}
+
 // GetSessionStatusHandler godoc
 //
 //	@Summary		Get the status of an upload session
@@ -374,3 +460,100 @@ func (uc *UploadController) GetSessionStatusHandler(c *gin.Context) {
 	// Return the session
 	shared.SuccessJSON(c, http.StatusOK, "Session retrieved successfully", session)
 }
+
+// checkStorageQuota checks that a file of the given size fits in the storage quota of the user,
+// or of the organization of the shared drive the file is in
+// It writes the error response and returns false when the file must be rejected
+func (uc *UploadController) checkStorageQuota(c *gin.Context, organizationId string, size int64) bool {
+	err := uc.UploadService.CheckStorageQuota(c, organizationId, size)
+	if errors.Is(err, services.ErrStorageQuotaExceeded) {
+		shared.ErrorJSON(c, http.StatusRequestEntityTooLarge, err.Error())
+		return false
+	}
+	if err != nil {
+		respondServiceError(c, http.StatusInternalServerError, "Failed to check storage quota ", err)
+		return false
+	}
+
+	return true
+}
+
+// respondServiceError writes the error of an upload service call
+// Errors returned by the API Server keep their status code, the others use the fallback status
+func respondServiceError(c *gin.Context, fallbackStatus int, message string, err error) {
+	var apiErr *services.APIError
+	if errors.As(err, &apiErr) {
+		shared.ErrorJSON(c, apiErr.StatusCode, apiErr.Message)
+		return
+	}
+
+	shared.ErrorJSON(c, fallbackStatus, message+err.Error())
+}
+
+// CompleteSessionHandler godoc
+//
+//	@Summary		Complete a resumable session
+//	@Description	Complete a resumable session. Every chunk is read back from the storage and its size and SHA-256 hash are checked against the recorded values. The file is marked as uploaded only if no chunk index is missing. The client can optionally send the expected chunk hashes in order.
+//	@Tags			Upload
+//	@Accept			json
+//	@Produce		json
+//	@Param			sessionToken	path		string							true	"Session Token"
+//	@Param			request			body		models.CompleteSessionRequest	false	"Expected chunk hashes"
+//	@Success		200				{object}	models.UploadSession			"Session completed successfully"
+//	@Failure		400				{string}	string							"Bad Request: Missing chunks or hash mismatch"
+//	@Failure		403				{string}	string							"Forbidden: User ID does not match the session owner"
+//	@Failure		500				{string}	string							"Internal Server Error"
+//	@Router			/upload/session/{sessionToken}/complete [post]
+func (uc *UploadController) CompleteSessionHandler(c *gin.Context) {
+	sessionToken := c.Param("sessionToken")
+	if sessionToken == "" {
+		shared.ErrorJSON(c, http.StatusBadRequest, "Missing session ID")
+		return
+	}
+
+	// The request body is optional
+	var request models.CompleteSessionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			shared.ErrorJSON(c, http.StatusBadRequest, "Invalid request body "+err.Error())
+			return
+		}
+	}
+
+	session, err := uc.UploadService.CompleteSession(c, sessionToken, request.ChunkHashes)
+	if err != nil {
+		respondServiceError(c, http.StatusBadRequest, "Failed to complete session ", err)
+		return
+	}
+
+	shared.SuccessJSON(c, http.StatusOK, "Session completed successfully", session)
+}
+
+// CancelSessionHandler godoc
+//
+//	@Summary		Cancel a resumable session
+//	@Description	Cancel a resumable session. The uploaded chunks are deleted from the storage unless another file references them, and the session is marked as cancelled.
+//	@Tags			Upload
+//	@Accept			json
+//	@Produce		json
+//	@Param			sessionToken	path		string	true	"Session Token"
+//	@Success		200				{object}	models.CancelUploadSessionResponse	"Session cancelled successfully"
+//	@Failure		400				{string}	string	"Bad Request: The session is already completed"
+//	@Failure		403				{string}	string	"Forbidden: User ID does not match the session owner"
+//	@Failure		500				{string}	string	"Internal Server Error"
+//	@Router			/upload/session/{sessionToken} [delete]
+func (uc *UploadController) CancelSessionHandler(c *gin.Context) {
+	sessionToken := c.Param("sessionToken")
+	if sessionToken == "" {
+		shared.ErrorJSON(c, http.StatusBadRequest, "Missing session ID")
+		return
+	}
+
+	response, err := uc.UploadService.CancelSession(c, sessionToken)
+	if err != nil {
+		respondServiceError(c, http.StatusBadRequest, "Failed to cancel session ", err)
+		return
+	}
+
+	shared.SuccessJSON(c, http.StatusOK, "Session cancelled successfully", response)
+}
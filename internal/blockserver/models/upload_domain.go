@@ -11,4 +11,15 @@ type AddChunkSessionRequest struct {
 	ChunkNumber int    `json:"chunk_number"`
 	ChunkSize   int    `json:"chunk_size"`
 	ChunkHash   string `json:"chunk_hash"`
+	ChunkClaim  string `json:"chunk_claim"` // Signed once the chunk data is stored, the API Server only records claimed chunks
+}
+
+type KnownChunkRequest struct {
+	ChunkNumber int    `json:"chunk_number" binding:"min=0"`
+	ChunkSize   int    `json:"chunk_size" binding:"required,min=1"`
+	ChunkHash   string `json:"chunk_hash" binding:"required"` // SHA-256 hash of the chunk data, hex encoded
+}
+
+type CompleteSessionRequest struct {
+	ChunkHashes []string `json:"chunk_hashes"` // Optional SHA-256 hashes of the chunks in order, checked against the uploaded chunks
 }
@@ -3,14 +3,15 @@ package routes
 import (
 	"skybox-backend/internal/blockserver/controllers"
 	"skybox-backend/internal/blockserver/services"
+	"skybox-backend/internal/shared/storage"
 
 	"github.com/gin-gonic/gin"
 )
 
 // NewDownloadRouters sets up the routes and the corresponding handlers for file downloads
-func NewDownloadRouters(group *gin.RouterGroup) {
+func NewDownloadRouters(group *gin.RouterGroup, store storage.BlockStore) {
 	// Initialize the download service (if needed)
-	downloadService := services.NewDownloadService()
+	downloadService := services.NewDownloadService(store)
 	// Create a new instance of the DownloadController
 	downloadController := controllers.NewDownloadController(
 		downloadService,
@@ -21,5 +22,9 @@ func NewDownloadRouters(group *gin.RouterGroup) {
 	{
 		// Download a file by its ID
 		downloadGroup.GET("/:fileId", downloadController.DownloadFileHandler)
+		// Download a folder tree as a zip archive
+		downloadGroup.GET("/folders/:folderId", downloadController.DownloadFolderHandler)
+		// Download a selection of files and folders as a zip archive
+		downloadGroup.GET("/archive", downloadController.DownloadArchiveHandler)
 	}
 }
@@ -4,12 +4,13 @@ import (
 	"skybox-backend/configs"
 	"skybox-backend/internal/blockserver/controllers"
 	"skybox-backend/internal/shared/middlewares"
+	"skybox-backend/internal/shared/storage"
 
 	"github.com/gin-gonic/gin"
 )
 
 // SetupRouter sets up the routes and the corresponding handlers
-func SetupRouter(gin *gin.Engine) *gin.Engine {
+func SetupRouter(gin *gin.Engine, store storage.BlockStore) *gin.Engine {
 	// Swagger routes
 	// gin.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -24,21 +25,24 @@ func SetupRouter(gin *gin.Engine) *gin.Engine {
 		v1.GET("/hello", controllers.HelloWorldHandler)
 
 		// Download routes
-		NewDownloadRouters(v1)
+		NewDownloadRouters(v1, store)
 	}
 
 	// Private routes
 	protectedRouter := gin.Group("")
 	protectedRouter.Use(middlewares.JwtAuthMiddleware(configs.Config.JWTSecret))
 
-	v1 = protectedRouter.Group("")
+	protectedV1 := protectedRouter.Group("")
 
 	{
-		v1.GET("/protected/hello", controllers.HelloWorldHandler)
+		protectedV1.GET("/protected/hello", controllers.HelloWorldHandler)
 
 		// Upload routes
-		NewUploadRouters(v1)
+		NewUploadRouters(protectedV1, store)
 	}
 
+	// Tus routes, only the capabilities discovery is public
+	NewTusRouters(v1, protectedV1, store)
+
 	return gin
 }
@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"skybox-backend/internal/blockserver/controllers"
+	"skybox-backend/internal/blockserver/services"
+	"skybox-backend/internal/shared/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewTusRouters sets up the routes and the corresponding handlers for the tus resumable upload protocol
+// The OPTIONS route is public so clients can discover the server capabilities without a token
+func NewTusRouters(publicGroup *gin.RouterGroup, protectedGroup *gin.RouterGroup, store storage.BlockStore) {
+	// Initialize the tus service
+	tusService := services.NewTusService(store)
+	// Create a new instance of the TusController
+	tusController := controllers.NewTusController(
+		tusService,
+	)
+
+	publicTusGroup := publicGroup.Group("/tus", controllers.TusResumableMiddleware())
+	{
+		publicTusGroup.OPTIONS("", tusController.TusOptionsHandler)
+		publicTusGroup.OPTIONS("/", tusController.TusOptionsHandler)
+	}
+
+	tusGroup := protectedGroup.Group("/tus", controllers.TusResumableMiddleware())
+	{
+		// Creation extension
+		tusGroup.POST("", tusController.TusCreateUploadHandler)
+		tusGroup.POST("/", tusController.TusCreateUploadHandler)
+
+		// Core protocol
+		tusGroup.HEAD("/:sessionToken", tusController.TusHeadUploadHandler)
+		tusGroup.PATCH("/:sessionToken", tusController.TusPatchUploadHandler)
+
+		// Termination extension
+		tusGroup.DELETE("/:sessionToken", tusController.TusDeleteUploadHandler)
+	}
+}
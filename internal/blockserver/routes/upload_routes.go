@@ -3,14 +3,15 @@ package routes
 import (
 	"skybox-backend/internal/blockserver/controllers"
 	"skybox-backend/internal/blockserver/services"
+	"skybox-backend/internal/shared/storage"
 
 	"github.com/gin-gonic/gin"
 )
 
 // NewUploadRouters sets up the routes and the corresponding handlers for file uploads
-func NewUploadRouters(group *gin.RouterGroup) {
+func NewUploadRouters(group *gin.RouterGroup, store storage.BlockStore) {
 	// Initialize the upload service (if needed)
-	uploadService := services.NewUploadService()
+	uploadService := services.NewUploadService(store)
 	// Create a new instance of the UploadController
 	uploadController := controllers.NewUploadController(
 		uploadService,
@@ -28,12 +29,15 @@ func NewUploadRouters(group *gin.RouterGroup) {
 		// uploadGroup.POST("/session/start", nil)
 		// 2. Upload a chunk to a resumable session
 		uploadGroup.POST("/session/:sessionToken/chunk", uploadController.UploadChunkHandler)
+		// 2b. Register a chunk whose data is already stored, skipping the upload
+		uploadGroup.POST("/session/:sessionToken/known", uploadController.UploadKnownChunkHandler)
 		// 3. Get the status of a resumable session
 		uploadGroup.GET("/session/:sessionToken/status", uploadController.GetSessionStatusHandler)
 
-		// (Optional) Cancel session, merge chunks, etc.
-		uploadGroup.POST("/session/:sessionToken/complete", nil)
-		uploadGroup.DELETE("/session/:sessionToken", nil)
+		// 4. Complete the session once every chunk is uploaded
+		uploadGroup.POST("/session/:sessionToken/complete", uploadController.CompleteSessionHandler)
+		// 5. Cancel the session and delete the uploaded chunks
+		uploadGroup.DELETE("/session/:sessionToken", uploadController.CancelSessionHandler)
 
 		uploadGroup.POST("/session/:sessionToken/:chunkIndex", uploadController.UploadChunkHandler)
 	}
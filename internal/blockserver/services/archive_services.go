@@ -0,0 +1,80 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"skybox-backend/internal/api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FetchFolderArchive retrieves the content of a folder archive from the API Server
+// The request is authenticated by the download token
+func (ds *DownloadService) FetchFolderArchive(ctx *gin.Context, folderId string, token string) (*models.ArchiveResponse, error) {
+	return ds.fetchArchive(ctx, fmt.Sprintf("%s/api/v1/folders/%s/archive?token=%s", ds.uploadService.baseURL, folderId, url.QueryEscape(token)))
+}
+
+// FetchSelectionArchive retrieves the content of a selection archive from the API Server
+// The selection is carried by the download token
+func (ds *DownloadService) FetchSelectionArchive(ctx *gin.Context, token string) (*models.ArchiveResponse, error) {
+	return ds.fetchArchive(ctx, fmt.Sprintf("%s/api/v1/archives/content?token=%s", ds.uploadService.baseURL, url.QueryEscape(token)))
+}
+
+// fetchArchive retrieves the content of an archive from the given API Server URL
+func (ds *DownloadService) fetchArchive(ctx *gin.Context, apiServerURL string) (*models.ArchiveResponse, error) {
+	resp, err := requestAPIServer(ctx, http.MethodGet, apiServerURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	response := &struct {
+		Status  string                  `json:"status"`
+		Message string                  `json:"message"`
+		Data    *models.ArchiveResponse `json:"data"`
+	}{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON response: %w", err)
+	}
+	if response.Data == nil {
+		return nil, fmt.Errorf("failed to fetch archive: %s", response.Message)
+	}
+
+	return response.Data, nil
+}
+
+// WriteArchive streams the zip archive of the entries to w
+// The files are assembled from their chunks as they are written, nothing is staged to memory or disk.
+// The files are stored without compression, zip64 records are written when an entry or the archive is too large for zip.
+func (ds *DownloadService) WriteArchive(ctx context.Context, w io.Writer, archive *models.ArchiveResponse) error {
+	zw := zip.NewWriter(w)
+
+	for _, entry := range archive.Entries {
+		header := &zip.FileHeader{
+			Name:     entry.Path,
+			Method:   zip.Store,
+			Modified: entry.ModifiedAt,
+		}
+
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return fmt.Errorf("failed to create archive entry %s: %w", entry.Path, err)
+		}
+		if entry.FileID == "" || entry.Size == 0 {
+			continue // Folder paths end with a slash, the zip writer adds them without data
+		}
+
+		if err := ds.StreamRange(ctx, fw, entry.OwnerID, entry.FileID, entry.Chunks, 0, entry.Size-1); err != nil {
+			return fmt.Errorf("failed to write archive entry %s: %w", entry.Path, err)
+		}
+	}
+
+	return zw.Close()
+}
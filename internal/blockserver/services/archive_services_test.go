@@ -0,0 +1,80 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/shared/storage"
+	"skybox-backend/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteArchive(t *testing.T) {
+	store := storage.NewMemoryStore()
+	downloadService := NewDownloadService(store)
+	ctx := context.Background()
+
+	// The first file has two chunks, the second one is empty
+	chunks := []models.Chunk{}
+	for index, part := range []string{"hello ", "world"} {
+		hash := utils.HashString(part)
+		require.NoError(t, store.Put(ctx, storage.BlockKey(hash), bytes.NewReader([]byte(part)), int64(len(part))))
+		chunks = append(chunks, models.Chunk{ChunkIndex: index, ChunkSize: int64(len(part)), ChunkHash: hash})
+	}
+
+	modifiedAt := time.Date(2025, 1, 2, 3, 4, 6, 0, time.UTC)
+	archive := &models.ArchiveResponse{
+		Name: "docs.zip",
+		Entries: []models.ArchiveEntry{
+			{Path: "docs/", ModifiedAt: modifiedAt},
+			{Path: "docs/hello.txt", FileID: "file", OwnerID: "owner", Size: 11, ModifiedAt: modifiedAt, Chunks: chunks},
+			{Path: "docs/empty/", ModifiedAt: modifiedAt},
+			{Path: "docs/empty/empty.txt", FileID: "empty", OwnerID: "owner", ModifiedAt: modifiedAt},
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, downloadService.WriteArchive(ctx, buf, archive))
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, reader.File, 4)
+
+	contents := map[string]string{}
+	for _, file := range reader.File {
+		assert.True(t, file.Modified.Equal(modifiedAt), file.Name)
+
+		fr, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(fr)
+		require.NoError(t, err)
+		contents[file.Name] = string(content)
+	}
+	assert.Equal(t, map[string]string{
+		"docs/":                "",
+		"docs/hello.txt":       "hello world",
+		"docs/empty/":          "",
+		"docs/empty/empty.txt": "",
+	}, contents)
+}
+
+func TestWriteArchive_MissingChunk(t *testing.T) {
+	downloadService := NewDownloadService(storage.NewMemoryStore())
+
+	archive := &models.ArchiveResponse{
+		Name: "docs.zip",
+		Entries: []models.ArchiveEntry{
+			{Path: "docs/lost.txt", FileID: "file", OwnerID: "owner", Size: 4, Chunks: []models.Chunk{{ChunkIndex: 0, ChunkSize: 4}}},
+		},
+	}
+
+	err := downloadService.WriteArchive(context.Background(), io.Discard, archive)
+	assert.ErrorIs(t, err, storage.ErrObjectNotFound)
+}
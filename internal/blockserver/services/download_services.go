@@ -2,13 +2,19 @@ package services
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"net/http"
+	"net/url"
+
 	"skybox-backend/configs"
 	"skybox-backend/internal/api/models"
-	"skybox-backend/internal/blockserver/storage"
+	"skybox-backend/internal/shared/storage"
 
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,19 +22,16 @@ type DownloadService struct {
 	// Add any dependencies or configurations needed for the download service
 	// For example, a repository to fetch file metadata or a storage client to access files
 	uploadService *UploadService
-	s3Client      *s3.Client
+	store         storage.BlockStore
+	prefetch      int // Number of chunks fetched ahead of the one being written
 }
 
-func NewDownloadService() *DownloadService {
-	s3client := storage.GetS3Client()
-	if s3client == nil && configs.Config.AWSEnabled {
-		panic("Failed to create AWS S3 client")
-	}
-
+func NewDownloadService(store storage.BlockStore) *DownloadService {
 	return &DownloadService{
 		// Initialize any dependencies or configurations here
-		uploadService: NewUploadService(),
-		s3Client:      s3client,
+		uploadService: NewUploadService(store),
+		store:         store,
+		prefetch:      configs.Config.DownloadPrefetchChunks,
 	}
 }
 
@@ -44,43 +47,172 @@ func (ds *DownloadService) GetFileMetadata(ctx *gin.Context, fileId string) (*mo
 	return metadata, nil
 }
 
-func (ds *DownloadService) DownloadFile(ctx *gin.Context, ownerId string, fileId string, chunkNumber int) ([]byte, error) {
-	// Get the file data for the specified chunk number
-	key := fmt.Sprintf("%s/%s_%d", ownerId, fileId, chunkNumber)
-	data := []byte{} // Placeholder for the actual data retrieval
-	err := error(nil)
-
-	if configs.Config.AWSEnabled {
-		// TODO: Implement AWS S3 chunk retrieval
-		// Use the AWS SDK to retrieve the file data from S3
-
-		output, err := ds.s3Client.GetObject(ctx, &s3.GetObjectInput{
-			Bucket: &configs.Config.AWSBucket,
-			Key:    &key,
-		})
-		if err != nil {
-			return nil, fmt.Errorf("failed to retrieve file data from S3: %w", err)
-		}
-		defer output.Body.Close()
+// FetchFileChunks retrieves the ordered chunk list of a file from the API Server
+// The request is authenticated by the download token
+func (ds *DownloadService) FetchFileChunks(ctx *gin.Context, fileId string, token string) ([]models.Chunk, error) {
+	// Define the API Server URL
+	apiServerURL := fmt.Sprintf("%s/api/v1/files/%s/chunks?token=%s", ds.uploadService.baseURL, fileId, url.QueryEscape(token))
 
-		// Read the object content into memory
-		buf := new(bytes.Buffer)
-		_, err = buf.ReadFrom(output.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read chunk body: %w", err)
-		}
+	resp, err := requestAPIServer(ctx, http.MethodGet, apiServerURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
 
-		return buf.Bytes(), nil
+	type responseStruct struct {
+		Status  string                     `json:"status"`
+		Message string                     `json:"message"`
+		Data    *models.FileChunksResponse `json:"data"`
 	}
 
-	// Local file rtrieval
-	// Use local direectorty for testing purposes
-	full_key := fmt.Sprintf("tmp/%s", key)
+	response := &responseStruct{}
 
-	data, err = os.ReadFile(full_key)
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON response: %w", err)
+	}
+	if response.Data == nil {
+		return nil, fmt.Errorf("failed to fetch file chunks: %s", response.Message)
+	}
+
+	return response.Data.Chunks, nil
+}
+
+// DownloadChunk reads the whole chunk of a file from the block store
+func (ds *DownloadService) DownloadChunk(ctx context.Context, ownerId string, fileId string, chunk models.Chunk) ([]byte, error) {
+	reader, err := openChunk(ctx, ds.store, ownerId, fileId, chunk)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve file data: %w", err)
 	}
+	defer reader.Close()
+
+	// Read the object content into memory
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk body: %w", err)
+	}
 
 	return data, nil
 }
+
+// ChunksETag returns the strong entity tag of a file version
+// The tag is derived from the chunk hashes, it changes when any chunk of the content changes.
+// Legacy chunks have no hash, their upload session identifies the content instead.
+func ChunksETag(fileId string, chunks []models.Chunk) string {
+	hasher := sha256.New()
+	hasher.Write([]byte(fileId))
+	for _, chunk := range chunks {
+		fmt.Fprintf(hasher, "|%d:%d:%s:%s", chunk.ChunkIndex, chunk.ChunkSize, chunk.ChunkHash, chunk.SessionID.Hex())
+	}
+
+	return fmt.Sprintf("\"%x\"", hasher.Sum(nil)[:16])
+}
+
+// chunkSegment is the part of a chunk inside a requested byte range
+type chunkSegment struct {
+	chunk  models.Chunk
+	offset int64 // Offset of the segment in the chunk
+	length int64
+}
+
+// rangeSegments returns the chunk segments covering the bytes from start to end (inclusive) of the file
+// The chunks must be ordered by chunk index
+func rangeSegments(chunks []models.Chunk, start int64, end int64) []chunkSegment {
+	segments := []chunkSegment{}
+
+	var chunkStart int64
+	for _, chunk := range chunks {
+		chunkEnd := chunkStart + chunk.ChunkSize - 1
+		if chunkEnd >= start && chunkStart <= end && chunk.ChunkSize > 0 {
+			offset := max(0, start-chunkStart)
+			segments = append(segments, chunkSegment{
+				chunk:  chunk,
+				offset: offset,
+				length: min(chunkEnd, end) - chunkStart - offset + 1,
+			})
+		}
+		chunkStart += chunk.ChunkSize
+	}
+
+	return segments
+}
+
+// StreamRange writes the bytes from start to end (inclusive) of the file to w
+// Every chunk is read with a ranged request and written as it arrives, the whole range is never held in memory.
+// With prefetching, the next chunks are fetched concurrently while the current one is written.
+func (ds *DownloadService) StreamRange(ctx context.Context, w io.Writer, ownerId string, fileId string, chunks []models.Chunk, start int64, end int64) error {
+	segments := rangeSegments(chunks, start, end)
+
+	if ds.prefetch <= 0 {
+		for _, segment := range segments {
+			if err := ds.copySegment(ctx, w, ownerId, fileId, segment); err != nil {
+				return err
+			}
+			flush(w)
+		}
+		return nil
+	}
+
+	// Stop the prefetching when the writer fails or the client goes away
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type fetchedSegment struct {
+		data []byte
+		err  error
+	}
+	fetched := make(chan fetchedSegment, ds.prefetch)
+
+	go func() {
+		defer close(fetched)
+		for _, segment := range segments {
+			buf := bytes.NewBuffer(make([]byte, 0, segment.length))
+			err := ds.copySegment(ctx, buf, ownerId, fileId, segment)
+
+			select {
+			case fetched <- fetchedSegment{data: buf.Bytes(), err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for segment := range fetched {
+		if segment.err != nil {
+			return segment.err
+		}
+		if _, err := w.Write(segment.data); err != nil {
+			return fmt.Errorf("failed to write chunk data: %w", err)
+		}
+		flush(w)
+	}
+
+	return ctx.Err()
+}
+
+// copySegment copies a chunk segment from the block store to w
+func (ds *DownloadService) copySegment(ctx context.Context, w io.Writer, ownerId string, fileId string, segment chunkSegment) error {
+	reader, err := openChunkRange(ctx, ds.store, ownerId, fileId, segment.chunk, segment.offset, segment.length)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve chunk %d: %w", segment.chunk.ChunkIndex, err)
+	}
+	defer reader.Close()
+
+	if _, err := io.CopyN(w, reader, segment.length); err != nil {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("chunk %d is shorter than its recorded size", segment.chunk.ChunkIndex)
+		}
+		return fmt.Errorf("failed to copy chunk %d: %w", segment.chunk.ChunkIndex, err)
+	}
+
+	return nil
+}
+
+// flush sends the buffered response data to the client
+func flush(w io.Writer) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/shared/storage"
+	"skybox-backend/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadChunk_ReadsBlockByHash(t *testing.T) {
+	store := storage.NewMemoryStore()
+	downloadService := NewDownloadService(store)
+
+	data := []byte("chunk data")
+	hash := utils.HashBytes(data)
+	err := store.Put(context.Background(), storage.BlockKey(hash), bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	chunk := models.Chunk{ChunkIndex: 1, ChunkSize: int64(len(data)), ChunkHash: hash}
+	content, err := downloadService.DownloadChunk(context.Background(), "owner", "file", chunk)
+	require.NoError(t, err)
+	assert.Equal(t, data, content)
+}
+
+func TestDownloadChunk_FallsBackToLegacyKey(t *testing.T) {
+	store := storage.NewMemoryStore()
+	downloadService := NewDownloadService(store)
+
+	data := []byte("chunk data")
+	err := store.Put(context.Background(), storage.ChunkKey("owner", "file", 1), bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	chunk := models.Chunk{ChunkIndex: 1, ChunkSize: int64(len(data)), ChunkHash: utils.HashBytes(data)}
+	content, err := downloadService.DownloadChunk(context.Background(), "owner", "file", chunk)
+	require.NoError(t, err)
+	assert.Equal(t, data, content)
+}
+
+func TestDownloadChunk_MissingChunk(t *testing.T) {
+	downloadService := NewDownloadService(storage.NewMemoryStore())
+
+	_, err := downloadService.DownloadChunk(context.Background(), "owner", "file", models.Chunk{ChunkIndex: 0})
+	assert.ErrorIs(t, err, storage.ErrObjectNotFound)
+}
+
+func TestStreamRange(t *testing.T) {
+	store := storage.NewMemoryStore()
+	ctx := context.Background()
+
+	// Three chunks, the second one is only saved under the legacy key
+	parts := [][]byte{[]byte("0123"), []byte("4567"), []byte("89")}
+	chunks := []models.Chunk{}
+	for index, part := range parts {
+		hash := utils.HashBytes(part)
+		key := storage.BlockKey(hash)
+		if index == 1 {
+			key = storage.ChunkKey("owner", "file", index)
+		}
+		require.NoError(t, store.Put(ctx, key, bytes.NewReader(part), int64(len(part))))
+		chunks = append(chunks, models.Chunk{ChunkIndex: index, ChunkSize: int64(len(part)), ChunkHash: hash})
+	}
+
+	ranges := []struct {
+		start, end int64
+		expected   string
+	}{
+		{0, 9, "0123456789"},
+		{2, 6, "23456"},
+		{4, 7, "4567"},
+		{9, 9, "9"},
+	}
+
+	for _, prefetch := range []int{0, 1, 2} {
+		downloadService := NewDownloadService(store)
+		downloadService.prefetch = prefetch
+
+		for _, r := range ranges {
+			buf := new(bytes.Buffer)
+			err := downloadService.StreamRange(ctx, buf, "owner", "file", chunks, r.start, r.end)
+			require.NoError(t, err)
+			assert.Equal(t, r.expected, buf.String(), "prefetch %d, range %d-%d", prefetch, r.start, r.end)
+		}
+	}
+}
+
+func TestStreamRange_MissingChunk(t *testing.T) {
+	store := storage.NewMemoryStore()
+	data := []byte("0123")
+	hash := utils.HashBytes(data)
+	require.NoError(t, store.Put(context.Background(), storage.BlockKey(hash), bytes.NewReader(data), int64(len(data))))
+
+	chunks := []models.Chunk{
+		{ChunkIndex: 0, ChunkSize: 4, ChunkHash: hash},
+		{ChunkIndex: 1, ChunkSize: 4},
+	}
+
+	for _, prefetch := range []int{0, 2} {
+		downloadService := NewDownloadService(store)
+		downloadService.prefetch = prefetch
+
+		buf := new(bytes.Buffer)
+		err := downloadService.StreamRange(context.Background(), buf, "owner", "file", chunks, 0, 7)
+		assert.ErrorIs(t, err, storage.ErrObjectNotFound)
+		assert.Equal(t, "0123", buf.String())
+	}
+}
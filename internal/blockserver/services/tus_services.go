@@ -0,0 +1,403 @@
+package services
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"skybox-backend/configs"
+	"skybox-backend/internal/api/models"
+	"skybox-backend/internal/shared/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTusOffsetMismatch      = errors.New("upload offset does not match the current offset")
+	ErrTusChecksumMismatch    = errors.New("checksum mismatch")
+	ErrTusUnsupportedChecksum = errors.New("unsupported checksum algorithm")
+	ErrTusUploadTooLarge      = errors.New("request body exceeds the upload length")
+	ErrTusUploadLocked        = errors.New("upload is locked by another request")
+	ErrTusUploadGone          = errors.New("upload session is cancelled or expired")
+)
+
+// TusChecksumAlgorithms lists the algorithms supported by the checksum extension
+var TusChecksumAlgorithms = []string{"sha1", "sha256", "md5"}
+
+// TusService implements the tus 1.0 resumable upload protocol on top of the upload sessions
+// The received bytes are cut into chunks of DefaultChunkSize and saved with UploadService.SaveChunk,
+// the bytes that do not fill a chunk yet are kept in the block store until the next PATCH request
+type TusService struct {
+	uploadService *UploadService
+	store         storage.BlockStore
+	locks         uploadLocks // Only one PATCH per upload at a time
+}
+
+func NewTusService(store storage.BlockStore) *TusService {
+	return &TusService{
+		uploadService: NewUploadService(store),
+		store:         store,
+		locks:         uploadLocks{tokens: map[string]struct{}{}},
+	}
+}
+
+// uploadLocks holds the session tokens of the uploads receiving a PATCH request
+// A token is only kept while its request runs, so the abandoned uploads leave nothing behind
+type uploadLocks struct {
+	mu     sync.Mutex
+	tokens map[string]struct{}
+}
+
+// tryLock locks the upload, it returns false if another request holds the lock
+func (ul *uploadLocks) tryLock(sessionToken string) bool {
+	ul.mu.Lock()
+	defer ul.mu.Unlock()
+
+	if _, ok := ul.tokens[sessionToken]; ok {
+		return false
+	}
+	ul.tokens[sessionToken] = struct{}{}
+	return true
+}
+
+// unlock releases the lock of the upload
+func (ul *uploadLocks) unlock(sessionToken string) {
+	ul.mu.Lock()
+	defer ul.mu.Unlock()
+
+	delete(ul.tokens, sessionToken)
+}
+
+// TusUpload describes the state of a tus upload
+type TusUpload struct {
+	Session *models.UploadSession
+	Offset  int64 // Number of bytes received, including the bytes not saved as a chunk yet
+	Length  int64 // Total size of the upload
+}
+
+// ParseTusMetadata parses the Upload-Metadata header
+// The header is a comma separated list of `key base64(value)` pairs, the value is optional
+func ParseTusMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.Fields(pair)
+		switch len(parts) {
+		case 1:
+			metadata[parts[0]] = ""
+		case 2:
+			value, err := base64.StdEncoding.DecodeString(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid metadata value for key %s", parts[0])
+			}
+			metadata[parts[0]] = string(value)
+		default:
+			return nil, fmt.Errorf("invalid metadata pair %q", pair)
+		}
+	}
+
+	return metadata, nil
+}
+
+// ParseTusChecksum parses the Upload-Checksum header, `<algorithm> <base64(digest)>`
+func ParseTusChecksum(header string) (hash.Hash, []byte, error) {
+	parts := strings.Fields(header)
+	if len(parts) != 2 {
+		return nil, nil, fmt.Errorf("invalid Upload-Checksum header")
+	}
+
+	var hasher hash.Hash
+	switch parts[0] {
+	case "sha1":
+		hasher = sha1.New()
+	case "sha256":
+		hasher = sha256.New()
+	case "md5":
+		hasher = md5.New()
+	default:
+		return nil, nil, ErrTusUnsupportedChecksum
+	}
+
+	digest, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid Upload-Checksum header")
+	}
+
+	return hasher, digest, nil
+}
+
+// fetchUserRootFolder retrieves the root folder of the user from the API Server
+func (ts *TusService) fetchUserRootFolder(ctx *gin.Context, userId string) (string, error) {
+	apiServerURL := fmt.Sprintf("%s/api/v1/user/%s", ts.uploadService.baseURL, userId)
+
+	resp, err := requestAPIServer(ctx, http.MethodGet, apiServerURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch user: %w", err)
+	}
+	defer resp.Body.Close()
+
+	type responseStruct struct {
+		Status  string       `json:"status"`
+		Message string       `json:"message"`
+		Data    *models.User `json:"data"`
+	}
+
+	response := &responseStruct{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("failed to decode JSON response: %w", err)
+	}
+	if response.Data == nil {
+		return "", fmt.Errorf("failed to fetch user: %s", response.Message)
+	}
+
+	return response.Data.RootFolderID.Hex(), nil
+}
+
+// CreateUpload creates the file and its upload session on the API Server and returns the session token
+// The metadata keys `filename` (or `name`), `filetype` (or `type`), `folder_id` and `conflict` are used,
+// the file is created in the root folder of the user when `folder_id` is missing.
+// An empty upload has no data to receive, its session is completed right away
+func (ts *TusService) CreateUpload(ctx *gin.Context, length int64, metadata map[string]string) (string, error) {
+	fileName := metadata["filename"]
+	if fileName == "" {
+		fileName = metadata["name"]
+	}
+	if fileName == "" {
+		return "", fmt.Errorf("missing filename in Upload-Metadata")
+	}
+	mimeType := metadata["filetype"]
+	if mimeType == "" {
+		mimeType = metadata["type"]
+	}
+
+	folderId := metadata["folder_id"]
+	if folderId == "" {
+		rootFolderId, err := ts.fetchUserRootFolder(ctx, ctx.GetString("x-user-id"))
+		if err != nil {
+			return "", err
+		}
+		folderId = rootFolderId
+	}
+
+	// Create the file metadata and the upload session
+	apiServerURL := fmt.Sprintf("%s/api/v1/folders/%s/upload", ts.uploadService.baseURL, folderId)
+	request := models.UploadFileMetadataRequest{
+		FileName: fileName,
+		FileSize: length,
+		MimeType: mimeType,
+		Conflict: metadata["conflict"],
+	}
+
+	resp, err := requestAPIServer(ctx, http.MethodPost, apiServerURL, request)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	type responseStruct struct {
+		Status  string                             `json:"status"`
+		Message string                             `json:"message"`
+		Data    *models.UploadFileMetadataResponse `json:"data"`
+	}
+
+	response := &responseStruct{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("failed to decode JSON response: %w", err)
+	}
+	if response.Data == nil || response.Data.SessionToken == "" {
+		return "", fmt.Errorf("failed to create file: %s", response.Message)
+	}
+
+	if length == 0 {
+		if _, err := ts.uploadService.CompleteSession(ctx, response.Data.SessionToken, nil); err != nil {
+			return "", fmt.Errorf("failed to complete empty upload: %w", err)
+		}
+	}
+
+	return response.Data.SessionToken, nil
+}
+
+// partialSize returns the number of bytes received but not saved as a chunk yet
+func (ts *TusService) partialSize(ctx *gin.Context, sessionToken string) (int64, error) {
+	info, err := ts.store.Stat(ctx, storage.PartialUploadKey(sessionToken))
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return info.Size, nil
+}
+
+// GetUpload retrieves the state of an upload of the user
+func (ts *TusService) GetUpload(ctx *gin.Context, sessionToken string) (*TusUpload, error) {
+	if _, err := uuid.Parse(sessionToken); err != nil {
+		return nil, fmt.Errorf("invalid upload session")
+	}
+
+	session, err := ts.uploadService.fetchOwnedSession(ctx, sessionToken)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status == "cancelled" || session.IsExpired(time.Now()) {
+		return nil, ErrTusUploadGone
+	}
+
+	partial, err := ts.partialSize(ctx, sessionToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check received data: %w", err)
+	}
+
+	return &TusUpload{
+		Session: session,
+		Offset:  session.ActualSize + partial,
+		Length:  session.TotalSize,
+	}, nil
+}
+
+// WriteUpload appends the body of a PATCH request to the upload and returns the new offset
+// If a checksum is given, the whole body is verified before anything is saved
+// When the body is interrupted, the bytes received so far are kept so the client can resume
+func (ts *TusService) WriteUpload(ctx *gin.Context, sessionToken string, offset int64, body io.Reader, checksum string) (int64, error) {
+	if !ts.locks.tryLock(sessionToken) {
+		return 0, ErrTusUploadLocked
+	}
+	defer ts.locks.unlock(sessionToken)
+
+	upload, err := ts.GetUpload(ctx, sessionToken)
+	if err != nil {
+		return 0, err
+	}
+	if offset != upload.Offset {
+		return upload.Offset, ErrTusOffsetMismatch
+	}
+
+	// Never read past the declared length
+	remaining := upload.Length - upload.Offset
+	if ctx.Request.ContentLength > remaining {
+		return upload.Offset, ErrTusUploadTooLarge
+	}
+	body = io.LimitReader(body, remaining)
+
+	// The checksum covers the whole body, verify it before saving anything
+	if checksum != "" {
+		hasher, digest, err := ParseTusChecksum(checksum)
+		if err != nil {
+			return upload.Offset, err
+		}
+
+		data, err := io.ReadAll(io.LimitReader(body, configs.Config.MaxChunkSize+1))
+		if err != nil {
+			return upload.Offset, fmt.Errorf("failed to read request body: %w", err)
+		}
+		if int64(len(data)) > configs.Config.MaxChunkSize {
+			return upload.Offset, ErrTusUploadTooLarge
+		}
+
+		hasher.Write(data)
+		if !bytes.Equal(hasher.Sum(nil), digest) {
+			return upload.Offset, ErrTusChecksumMismatch
+		}
+		body = bytes.NewReader(data)
+	}
+
+	// Load the bytes left by the previous request
+	chunkSize := configs.Config.DefaultChunkSize
+	buf := make([]byte, 0, chunkSize)
+	if upload.Offset > upload.Session.ActualSize {
+		reader, err := ts.store.Get(ctx, storage.PartialUploadKey(sessionToken))
+		if err != nil {
+			return upload.Offset, fmt.Errorf("failed to read received data: %w", err)
+		}
+		partial, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return upload.Offset, fmt.Errorf("failed to read received data: %w", err)
+		}
+		buf = append(buf, partial...)
+	}
+
+	fileId := upload.Session.FileID.Hex()
+	chunkIndex := int(upload.Session.ActualSize / chunkSize)
+	newOffset := upload.Offset
+
+	var readErr error
+	for {
+		n, err := io.ReadFull(body, buf[len(buf):chunkSize])
+		buf = buf[:len(buf)+n]
+		newOffset += int64(n)
+
+		// Save the chunk once it is full or the upload is finished
+		if len(buf) > 0 && (int64(len(buf)) == chunkSize || newOffset == upload.Length) {
+			if err := ts.uploadService.SaveChunk(ctx, fileId, "", "", chunkIndex, buf); err != nil {
+				return newOffset - int64(len(buf)), fmt.Errorf("failed to save chunk: %w", err)
+			}
+			chunkIndex++
+			buf = buf[:0]
+		}
+
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+				readErr = err
+			}
+			break
+		}
+	}
+
+	// Keep the bytes that do not fill a chunk yet
+	if len(buf) > 0 {
+		if err := ts.store.Put(ctx, storage.PartialUploadKey(sessionToken), bytes.NewReader(buf), int64(len(buf))); err != nil {
+			return newOffset - int64(len(buf)), fmt.Errorf("failed to save received data: %w", err)
+		}
+	} else if err := ts.store.Delete(ctx, storage.PartialUploadKey(sessionToken)); err != nil {
+		return newOffset, fmt.Errorf("failed to delete received data: %w", err)
+	}
+
+	if readErr != nil {
+		return newOffset, fmt.Errorf("failed to read request body: %w", readErr)
+	}
+
+	// The last chunk is saved, verify the chunks and mark the file as uploaded
+	if newOffset == upload.Length {
+		if _, err := ts.uploadService.CompleteSession(ctx, sessionToken, nil); err != nil {
+			return newOffset, fmt.Errorf("failed to complete upload: %w", err)
+		}
+	}
+
+	return newOffset, nil
+}
+
+// TerminateUpload cancels the upload session and deletes the received data
+func (ts *TusService) TerminateUpload(ctx *gin.Context, sessionToken string) error {
+	if _, err := uuid.Parse(sessionToken); err != nil {
+		return fmt.Errorf("invalid upload session")
+	}
+
+	if _, err := ts.uploadService.CancelSession(ctx, sessionToken); err != nil {
+		return err
+	}
+
+	if err := ts.store.Delete(ctx, storage.PartialUploadKey(sessionToken)); err != nil {
+		return fmt.Errorf("failed to delete received data: %w", err)
+	}
+
+	return nil
+}
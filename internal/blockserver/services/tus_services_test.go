@@ -0,0 +1,231 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"skybox-backend/configs"
+	"skybox-backend/internal/api/models"
+	blockmodels "skybox-backend/internal/blockserver/models"
+	"skybox-backend/internal/shared/storage"
+	"skybox-backend/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeSessionAPI serves the upload session and block routes of the API Server used by the upload services
+type fakeSessionAPI struct {
+	mu          sync.Mutex
+	session     models.UploadSession
+	chunks      []blockmodels.AddChunkSessionRequest
+	refs        map[string]int // References of the blocks by the chunks of the user
+	foreignRefs map[string]int // References of the blocks by the chunks of other users
+
+	usage             models.StorageUsageResponse  // Storage usage of the user
+	organizationUsage *models.OrganizationResponse // Storage usage of the organization of the session
+}
+
+func (f *fakeSessionAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v1/upload/"+f.session.SessionToken:
+		json.NewEncoder(w).Encode(gin.H{"status": "success", "data": f.session})
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/api/v1/folders/") && strings.HasSuffix(r.URL.Path, "/upload"):
+		var request models.UploadFileMetadataRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		f.session.TotalSize = request.FileSize
+		json.NewEncoder(w).Encode(gin.H{"status": "success", "data": models.UploadFileMetadataResponse{SessionToken: f.session.SessionToken}})
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v1/upload/"+f.session.SessionToken+"/chunks":
+		json.NewEncoder(w).Encode(gin.H{"status": "success", "data": []models.Chunk{}})
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v1/upload/"+f.session.SessionToken+"/complete":
+		var request models.CompleteUploadSessionRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		claim := models.CompletionClaim(f.session.UserID.Hex(), f.session.SessionToken)
+		if err := utils.VerifyBlockServerClaim(request.CompletionClaim, configs.Config.JWTSecret, claim); err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(gin.H{"status": "error", "message": err.Error()})
+			return
+		}
+		f.session.Status = "completed"
+		json.NewEncoder(w).Encode(gin.H{"status": "success", "data": f.session})
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v1/user/usage":
+		json.NewEncoder(w).Encode(gin.H{"status": "success", "data": f.usage})
+	case r.Method == http.MethodGet && f.organizationUsage != nil && r.URL.Path == "/api/v1/organizations/"+f.organizationUsage.ID.Hex():
+		json.NewEncoder(w).Encode(gin.H{"status": "success", "data": f.organizationUsage})
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v1/upload/blocks":
+		var request models.AcquireBlockRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		json.NewEncoder(w).Encode(gin.H{"status": "success", "data": models.AcquireBlockResponse{
+			Hash:       request.Hash,
+			Size:       request.Size,
+			Stored:     f.refs[request.Hash] > 0, // The blocks of other users are answered as new blocks
+			Referenced: f.refs[request.Hash] > 0,
+		}})
+	case r.Method == http.MethodPut && r.URL.Path == "/api/v1/upload/file/"+f.session.FileID.Hex():
+		var chunk blockmodels.AddChunkSessionRequest
+		json.NewDecoder(r.Body).Decode(&chunk)
+		claim := models.ChunkClaim(f.session.UserID.Hex(), f.session.FileID.Hex(), chunk.ChunkNumber, chunk.ChunkSize, chunk.ChunkHash)
+		if err := utils.VerifyBlockServerClaim(chunk.ChunkClaim, configs.Config.JWTSecret, claim); err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(gin.H{"status": "error", "message": err.Error()})
+			return
+		}
+		f.chunks = append(f.chunks, chunk)
+		f.refs[chunk.ChunkHash]++
+		f.session.ChunkList = append(f.session.ChunkList, chunk.ChunkNumber)
+		f.session.ActualSize += int64(chunk.ChunkSize)
+		json.NewEncoder(w).Encode(gin.H{"status": "success"})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(gin.H{"status": "error", "message": "not found"})
+	}
+}
+
+// setupSessionAPI starts a fake API Server serving one pending session and points the configuration to it
+func setupSessionAPI(t *testing.T, totalSize int64, chunkSize int64) (*fakeSessionAPI, *gin.Context) {
+	userId := primitive.NewObjectID()
+	api := &fakeSessionAPI{
+		session: models.UploadSession{
+			UserID:       userId,
+			FileID:       primitive.NewObjectID(),
+			SessionToken: "4b1c1f0e-8f4e-4a53-9a37-2f1b2c3d4e5f",
+			TotalSize:    totalSize,
+			ChunkList:    []int{},
+			Status:       "pending",
+		},
+		refs:        map[string]int{},
+		foreignRefs: map[string]int{},
+	}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	previous := configs.Config
+	configs.Config.ServerHost = serverURL.Hostname()
+	configs.Config.ServerPort = serverURL.Port()
+	configs.Config.DefaultChunkSize = chunkSize
+	configs.Config.MaxChunkSize = 1 << 20
+	t.Cleanup(func() { configs.Config = previous })
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPatch, "/tus/"+api.session.SessionToken, nil)
+	ctx.Set("x-user-id", userId.Hex())
+
+	return api, ctx
+}
+
+func setupTusTest(t *testing.T, totalSize int64, chunkSize int64) (*TusService, *fakeSessionAPI, *gin.Context) {
+	api, ctx := setupSessionAPI(t, totalSize, chunkSize)
+	return NewTusService(storage.NewMemoryStore()), api, ctx
+}
+
+func TestParseTusMetadata(t *testing.T) {
+	header := "filename " + base64.StdEncoding.EncodeToString([]byte("report.pdf")) + ",is_confidential"
+	metadata, err := ParseTusMetadata(header)
+	require.NoError(t, err)
+	assert.Equal(t, "report.pdf", metadata["filename"])
+	assert.Contains(t, metadata, "is_confidential")
+
+	_, err = ParseTusMetadata("filename not-base64!")
+	assert.Error(t, err)
+}
+
+func TestWriteUpload_ResumesAcrossRequests(t *testing.T) {
+	tusService, api, ctx := setupTusTest(t, 10, 4)
+	token := api.session.SessionToken
+	data := []byte("0123456789")
+
+	// The first request ends in the middle of the second chunk
+	offset, err := tusService.WriteUpload(ctx, token, 0, bytes.NewReader(data[:6]), "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), offset)
+	require.Len(t, api.chunks, 1)
+	assert.Equal(t, "pending", api.session.Status)
+
+	upload, err := tusService.GetUpload(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), upload.Offset)
+
+	// A request at the wrong offset is rejected
+	_, err = tusService.WriteUpload(ctx, token, 4, bytes.NewReader(data[4:]), "")
+	assert.ErrorIs(t, err, ErrTusOffsetMismatch)
+
+	// The second request finishes the upload, the last chunk is shorter
+	offset, err = tusService.WriteUpload(ctx, token, 6, bytes.NewReader(data[6:]), "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), offset)
+	require.Len(t, api.chunks, 3)
+	assert.Equal(t, []int{4, 4, 2}, []int{api.chunks[0].ChunkSize, api.chunks[1].ChunkSize, api.chunks[2].ChunkSize})
+	assert.Equal(t, []int{0, 1, 2}, api.session.ChunkList)
+
+	// The chunks are verified and the session is completed once the last chunk is saved
+	assert.Equal(t, "completed", api.session.Status)
+}
+
+func TestWriteUpload_ChecksumMismatch(t *testing.T) {
+	tusService, api, ctx := setupTusTest(t, 4, 4)
+	data := []byte("abcd")
+
+	digest := sha1.Sum([]byte("dcba"))
+	checksum := "sha1 " + base64.StdEncoding.EncodeToString(digest[:])
+	_, err := tusService.WriteUpload(ctx, api.session.SessionToken, 0, bytes.NewReader(data), checksum)
+	assert.ErrorIs(t, err, ErrTusChecksumMismatch)
+	assert.Empty(t, api.chunks)
+
+	_, err = tusService.WriteUpload(ctx, api.session.SessionToken, 0, bytes.NewReader(data), "crc32 AAAA")
+	assert.ErrorIs(t, err, ErrTusUnsupportedChecksum)
+
+	digest = sha1.Sum(data)
+	checksum = "sha1 " + base64.StdEncoding.EncodeToString(digest[:])
+	offset, err := tusService.WriteUpload(ctx, api.session.SessionToken, 0, strings.NewReader(string(data)), checksum)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), offset)
+	assert.Len(t, api.chunks, 1)
+}
+
+func TestWriteUpload_ReleasesTheLock(t *testing.T) {
+	tusService, api, ctx := setupTusTest(t, 10, 4)
+	token := api.session.SessionToken
+
+	// Another request holds the lock
+	require.True(t, tusService.locks.tryLock(token))
+	_, err := tusService.WriteUpload(ctx, token, 0, strings.NewReader("0123"), "")
+	assert.ErrorIs(t, err, ErrTusUploadLocked)
+	tusService.locks.unlock(token)
+
+	// The lock is only kept while a request runs, an abandoned upload leaves nothing behind
+	_, err = tusService.WriteUpload(ctx, token, 0, strings.NewReader("0123"), "")
+	require.NoError(t, err)
+	_, err = tusService.WriteUpload(ctx, token, 0, strings.NewReader("0123"), "")
+	assert.ErrorIs(t, err, ErrTusOffsetMismatch)
+	assert.Empty(t, tusService.locks.tokens)
+}
+
+func TestCreateUpload_EmptyUploadIsCompleted(t *testing.T) {
+	tusService, api, ctx := setupTusTest(t, 0, 4)
+
+	token, err := tusService.CreateUpload(ctx, 0, map[string]string{"filename": "empty.txt", "folder_id": primitive.NewObjectID().Hex()})
+	require.NoError(t, err)
+	assert.Equal(t, api.session.SessionToken, token)
+	assert.Equal(t, "completed", api.session.Status)
+
+	upload, err := tusService.GetUpload(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), upload.Offset)
+	assert.Equal(t, int64(0), upload.Length)
+}
@@ -3,43 +3,39 @@ package services
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"os"
 	"slices"
 	"time"
 
 	"skybox-backend/configs"
 	"skybox-backend/internal/api/models"
 	blockmodels "skybox-backend/internal/blockserver/models"
-	"skybox-backend/internal/blockserver/storage"
+	"skybox-backend/internal/shared/storage"
 	"skybox-backend/pkg/utils"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gin-gonic/gin"
 )
 
 // Package services provides the service layer for the controllers
 type UploadService struct {
 	// Add any dependencies you need here, e.g. repositories, clients, etc.
-	baseURL  string
-	s3Client *s3.Client
+	baseURL string
+	store   storage.BlockStore
 }
 
-func NewUploadService() *UploadService {
-	s3client := storage.GetS3Client()
-	if s3client == nil && configs.Config.AWSEnabled {
-		panic("Failed to create AWS S3 client")
-	}
-
+func NewUploadService(store storage.BlockStore) *UploadService {
 	baseURL := fmt.Sprintf("http://%s:%s", configs.Config.ServerHost, configs.Config.ServerPort)
 
 	return &UploadService{
 		// Initialize dependencies here
-		baseURL:  baseURL,
-		s3Client: s3client,
+		baseURL: baseURL,
+		store:   store,
 	}
 }
 
@@ -79,12 +75,32 @@ func requestAPIServer(ctx *gin.Context, method string, url string, body interfac
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch file object: %s", resp.Status)
+		defer resp.Body.Close()
+
+		// Keep the message of the API Server so it can be forwarded to the client
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: resp.Status}
+		response := &struct {
+			Message string `json:"message"`
+		}{}
+		if err := json.NewDecoder(resp.Body).Decode(response); err == nil && response.Message != "" {
+			apiErr.Message = response.Message
+		}
+		return nil, apiErr
 	}
 
 	return resp, nil
 }
 
+// APIError is returned by requestAPIServer when the API server does not answer with 200 OK
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API server responded with %d: %s", e.StatusCode, e.Message)
+}
+
 // FetchFileObject is a helper function to retrieve FileObject from API Server
 // This helper function would be used in the controller to fetch the file object from the API server
 func (us *UploadService) FetchFileObject(ctx *gin.Context, fileId string) (*models.FileResponse, error) {
@@ -117,25 +133,26 @@ func (us *UploadService) FetchFileObject(ctx *gin.Context, fileId string) (*mode
 // This helper function would be used in the controller to fetch the file object from the API server
 // It checks if the file exists and if the user ID matches the file owner
 // It also checks if the file is already uploaded or not
-func (us *UploadService) ValidateFile(ctx *gin.Context, fileId string) error {
+// It returns the file if it is valid, or an error if it is not
+func (us *UploadService) ValidateFile(ctx *gin.Context, fileId string) (*models.FileResponse, error) {
 	// Fetch the file metadata from the API Server
 	file, err := us.FetchFileObject(ctx, fileId)
 	if err != nil {
-		return fmt.Errorf("failed to fetch file object: %w", err)
+		return nil, fmt.Errorf("failed to fetch file object: %w", err)
 	}
 
 	// Get the user ID from the context
 	userId, ok := ctx.Value("x-user-id").(string)
 	if !ok || userId != file.OwnerID {
-		return fmt.Errorf("user ID does not match the file owner")
+		return nil, fmt.Errorf("user ID does not match the file owner")
 	}
 
 	// Check the status of the file
 	if file.Status == "uploaded" {
-		return fmt.Errorf("file is already uploaded")
+		return nil, fmt.Errorf("file is already uploaded")
 	}
 
-	return nil
+	return file, nil
 }
 
 // FetchSessionObject is a helper function to retrieve SessionObject from API Server
@@ -166,12 +183,121 @@ func (us *UploadService) FetchSessionObject(ctx *gin.Context, sessionToken strin
 	return response.Data, nil
 }
 
+// FetchStorageUsage retrieves the storage quota and usage of the user from the API Server
+func (us *UploadService) FetchStorageUsage(ctx *gin.Context) (*models.StorageUsageResponse, error) {
+	// Define the API Server URL
+	apiServerURL := fmt.Sprintf("%s/api/v1/user/usage", us.baseURL)
+
+	resp, err := requestAPIServer(ctx, http.MethodGet, apiServerURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch storage usage: %w", err)
+	}
+	defer resp.Body.Close()
+
+	type responseStruct struct {
+		Status  string                       `json:"status"`
+		Message string                       `json:"message"`
+		Data    *models.StorageUsageResponse `json:"data"`
+	}
+
+	response := &responseStruct{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON response: %w", err)
+	}
+	if response.Data == nil {
+		return nil, fmt.Errorf("failed to fetch storage usage: %s", response.Message)
+	}
+
+	return response.Data, nil
+}
+
+// FetchOrganizationUsage retrieves the storage quota and usage of an organization of the user from the API Server
+func (us *UploadService) FetchOrganizationUsage(ctx *gin.Context, organizationId string) (*models.StorageUsageResponse, error) {
+	// Define the API Server URL
+	apiServerURL := fmt.Sprintf("%s/api/v1/organizations/%s", us.baseURL, organizationId)
+
+	resp, err := requestAPIServer(ctx, http.MethodGet, apiServerURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch organization storage usage: %w", err)
+	}
+	defer resp.Body.Close()
+
+	type responseStruct struct {
+		Status  string                       `json:"status"`
+		Message string                       `json:"message"`
+		Data    *models.OrganizationResponse `json:"data"`
+	}
+
+	response := &responseStruct{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON response: %w", err)
+	}
+	if response.Data == nil {
+		return nil, fmt.Errorf("failed to fetch organization storage usage: %s", response.Message)
+	}
+
+	return &models.StorageUsageResponse{
+		Quota: response.Data.StorageQuota,
+		Used:  response.Data.StorageUsed,
+	}, nil
+}
+
+// ErrStorageQuotaExceeded is returned when the uploaded data would exceed the storage quota of the user
+var ErrStorageQuotaExceeded = errors.New("storage quota exceeded")
+
+// ErrOrganizationStorageQuotaExceeded is returned when the data uploaded to a shared drive would exceed the storage quota of the organization
+// It wraps ErrStorageQuotaExceeded so both are answered the same way
+var ErrOrganizationStorageQuotaExceeded = fmt.Errorf("organization %w", ErrStorageQuotaExceeded)
+
+// CheckStorageQuota checks that size more bytes fit in the storage quota of the user,
+// or of the organization when organizationId is the organization of a shared drive
+// The data is rejected before it is saved, the API Server checks the quota again when the chunk is recorded
+func (us *UploadService) CheckStorageQuota(ctx *gin.Context, organizationId string, size int64) error {
+	if organizationId != "" {
+		usage, err := us.FetchOrganizationUsage(ctx, organizationId)
+		if err != nil {
+			return err
+		}
+		if usage.Quota > 0 && usage.Used+size > usage.Quota {
+			return ErrOrganizationStorageQuotaExceeded
+		}
+		return nil
+	}
+
+	usage, err := us.FetchStorageUsage(ctx)
+	if err != nil {
+		return err
+	}
+	if usage.Quota > 0 && usage.Used+size > usage.Quota {
+		return ErrStorageQuotaExceeded
+	}
+
+	return nil
+}
+
+// checkSessionQuota checks that size more bytes uploaded by the session fit in the storage quota it uses
+// The uploads to a shared drive use the quota of the organization. The API Server alone checks the quota
+// of the owner of a file replaced by another user, the block server cannot read the usage of another user
+func (us *UploadService) checkSessionQuota(ctx *gin.Context, session *models.UploadSession, size int64) error {
+	if session.OrganizationID != nil {
+		return us.CheckStorageQuota(ctx, session.OrganizationID.Hex(), size)
+	}
+	if session.QuotaUserID() != session.UserID {
+		return nil
+	}
+
+	return us.CheckStorageQuota(ctx, "", size)
+}
+
 // ValidateSession is a helper function to validate the session object
 // This helper function would be used in the controller to fetch the file object from the API server
 // It checks if the session exists and if the user ID matches the session owner
 // It also checks if the chunk index already exists in the session.ChunkList to prevent duplicate uploads
+// and if the bytes of the session with the chunk fit in the storage quota of the user, or of the organization of a shared drive
 // It returns the file ID if the session is valid, or an error if it is not
-func (us *UploadService) ValidateSession(ctx *gin.Context, sessionId string, chunkIndex int) (string, error) {
+func (us *UploadService) ValidateSession(ctx *gin.Context, sessionId string, chunkIndex int, chunkSize int64) (string, error) {
 	// Fetch the session metadata from the API Server
 	session, err := us.FetchSessionObject(ctx, sessionId)
 	if err != nil {
@@ -184,67 +310,182 @@ func (us *UploadService) ValidateSession(ctx *gin.Context, sessionId string, chu
 		return "", fmt.Errorf("user ID does not match the session owner")
 	}
 
+	// Check if the session can still receive chunks
+	if session.Status != "pending" {
+		return "", fmt.Errorf("session is %s", session.Status)
+	}
+	if session.IsExpired(time.Now()) {
+		return "", fmt.Errorf("session is expired")
+	}
+
 	// Check if the chunk in the session.ChunkList or not
 	if slices.Contains(session.ChunkList, chunkIndex) {
 		return "", fmt.Errorf("chunk %d already exists in the session", chunkIndex)
 	}
 
+	if err := us.checkSessionQuota(ctx, session, session.ActualSize+chunkSize); err != nil {
+		return "", err
+	}
+
 	return session.FileID.Hex(), nil
 }
 
+// ErrUnknownBlock is returned when a client registers a chunk whose block is not stored yet
+var ErrUnknownBlock = errors.New("chunk data is not known, upload the chunk instead")
+
+// AcquireBlock asks the API Server to keep the block of a chunk until the chunk is recorded
+// The response tells whether a chunk already references the block, so its upload can be skipped
+func (us *UploadService) AcquireBlock(ctx *gin.Context, hash string, size int64) (*models.AcquireBlockResponse, error) {
+	// Define the API Server URL
+	apiServerURL := fmt.Sprintf("%s/api/v1/upload/blocks", us.baseURL)
+
+	resp, err := requestAPIServer(ctx, http.MethodPost, apiServerURL, models.AcquireBlockRequest{Hash: hash, Size: size})
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire block: %w", err)
+	}
+	defer resp.Body.Close()
+
+	type responseStruct struct {
+		Status  string                       `json:"status"`
+		Message string                       `json:"message"`
+		Data    *models.AcquireBlockResponse `json:"data"`
+	}
+
+	response := &responseStruct{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON response: %w", err)
+	}
+	if response.Data == nil {
+		return nil, fmt.Errorf("failed to acquire block: %s", response.Message)
+	}
+
+	return response.Data, nil
+}
+
+// putBlock saves the chunk data as a content-addressed block and returns its hash
+// The block is acquired on the API Server first, so it cannot be deleted before the chunk is recorded.
+// The upload is skipped when a chunk of the user already references the block and its object is found
+func (us *UploadService) putBlock(ctx *gin.Context, buf []byte) (string, error) {
+	hash := utils.HashBytes(buf)
+	key := storage.BlockKey(hash)
+
+	block, err := us.AcquireBlock(ctx, hash, int64(len(buf)))
+	if err != nil {
+		return "", err
+	}
+	if block.Stored {
+		info, err := us.store.Stat(ctx, key)
+		if err == nil && info.Size == int64(len(buf)) {
+			return hash, nil // Already stored, nothing to upload
+		}
+		if err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+			return "", fmt.Errorf("failed to check block: %w", err)
+		}
+	}
+
+	if err := us.store.Put(ctx, key, bytes.NewReader(buf), int64(len(buf))); err != nil {
+		return "", fmt.Errorf("failed to save block: %w", err)
+	}
+
+	return hash, nil
+}
+
+// openChunk opens the data of a chunk from its content-addressed block
+// Chunks saved before the blocks were introduced are still read from their legacy key `<ownerId>/<fileId>_<chunkIndex>`
+func openChunk(ctx context.Context, store storage.BlockStore, ownerId string, fileId string, chunk models.Chunk) (io.ReadCloser, error) {
+	if storage.IsValidBlockHash(chunk.ChunkHash) {
+		reader, err := store.Get(ctx, storage.BlockKey(chunk.ChunkHash))
+		if !errors.Is(err, storage.ErrObjectNotFound) {
+			return reader, err
+		}
+	}
+
+	return store.Get(ctx, storage.ChunkKey(ownerId, fileId, chunk.ChunkIndex))
+}
+
+// openChunkRange opens `length` bytes of a chunk starting at `offset`
+// Like openChunk, the legacy chunk key is used when the block does not exist
+func openChunkRange(ctx context.Context, store storage.BlockStore, ownerId string, fileId string, chunk models.Chunk, offset int64, length int64) (io.ReadCloser, error) {
+	if storage.IsValidBlockHash(chunk.ChunkHash) {
+		reader, err := store.GetRange(ctx, storage.BlockKey(chunk.ChunkHash), offset, length)
+		if !errors.Is(err, storage.ErrObjectNotFound) {
+			return reader, err
+		}
+	}
+
+	return store.GetRange(ctx, storage.ChunkKey(ownerId, fileId, chunk.ChunkIndex), offset, length)
+}
+
 // SaveChunk is a helper function to save a chunk of the file
-// It would upload the chunk to S3 if in production or save it locally if in development
-// Key format: `<userId>/<fileId>_<chunkIndex>`
-// The fileId is the ID of the file being uploaded, and chunkIndex is the index of the chunk
+// The chunk is saved once in the configured BlockStore under its SHA-256 hash (see storage.BlockKey),
+// so identical chunks of any file and any user share the same block
+// The API Server then records the chunk and adds a reference to the block
 func (us *UploadService) SaveChunk(ctx *gin.Context, fileId string, fileName string, ext string, chunkIndex int, buf []byte) error {
 	// Get the user id from the ctx which passed from middleware
 	// From gin: ctx.GetHeader("x-user-id")
 	userId := ctx.Value("x-user-id").(string)
-	fmt.Printf("Uploader's user ID: %s\n", userId)
 	if userId == "" {
 		return fmt.Errorf("missing user ID in context")
 	}
 
-	if configs.Config.AWSEnabled {
-		// Save to S3
-		key := fmt.Sprintf("%s/%s_%d", userId, fileId, chunkIndex)
-		_, err := us.s3Client.PutObject(
-			context.TODO(),
-			&s3.PutObjectInput{
-				Bucket:      aws.String(configs.Config.AWSBucket),
-				Key:         aws.String(key),
-				Body:        bytes.NewReader(buf),
-				ContentType: aws.String("application/octet-stream"),
-			},
-		)
-		if err != nil {
-			return fmt.Errorf("failed to upload chunk to S3: %w", err)
-		}
-
-		fmt.Printf("Uploaded chunk %d of file %s to S3 bucket %s\n", chunkIndex, fileId, configs.Config.AWSBucket)
-	} else {
-		// Save locally (for development/testing purposes)
-		// Create the directory if it doesn't exist
-		localDir := fmt.Sprintf("tmp/%s", userId)
-		err := os.MkdirAll(localDir, os.ModePerm)
-		if err != nil {
-			return fmt.Errorf("failed to create local directory: %w", err)
-		}
-
-		localPath := fmt.Sprintf("tmp/%s/%s_%d", userId, fileId, chunkIndex)
-		err = os.WriteFile(localPath, buf, 0644)
-		if err != nil {
-			return fmt.Errorf("failed to save chunk locally: %w", err)
-		}
-
-		fmt.Printf("Saved chunk %d of file %s to %s\n", chunkIndex, fileId, localPath)
+	// Save to the block store
+	hash, err := us.putBlock(ctx, buf)
+	if err != nil {
+		return fmt.Errorf("failed to save chunk: %w", err)
 	}
 
+	fmt.Printf("Saved chunk %d of file %s as block %s\n", chunkIndex, fileId, hash)
+
 	// Call to API Server to update the session record
 	chunk := blockmodels.AddChunkSessionRequest{
 		ChunkNumber: chunkIndex,
 		ChunkSize:   len(buf),
-		ChunkHash:   utils.HashBytes(buf),
+		ChunkHash:   hash,
+	}
+	if err := signChunk(userId, fileId, &chunk); err != nil {
+		return err
+	}
+
+	if err := us.UpdateSessionRecordByFileId(ctx, fileId, chunk); err != nil {
+		return fmt.Errorf("failed to update session record: %w", err)
+	}
+
+	return nil
+}
+
+// signChunk signs the claim that the data of the chunk is stored, the API Server does not record a chunk without it
+func signChunk(userId string, fileId string, chunk *blockmodels.AddChunkSessionRequest) error {
+	claim, err := utils.CreateBlockServerClaim(models.ChunkClaim(userId, fileId, chunk.ChunkNumber, chunk.ChunkSize, chunk.ChunkHash), configs.Config.JWTSecret)
+	if err != nil {
+		return fmt.Errorf("failed to sign chunk: %w", err)
+	}
+	chunk.ChunkClaim = claim
+
+	return nil
+}
+
+// SaveKnownChunk records a chunk whose block is already stored, without receiving its data
+// Only the blocks already referenced by a file of the user can be recorded this way, otherwise anyone knowing
+// a hash could check that another user stores the data and read it. It returns ErrUnknownBlock for any other block,
+// the client must then upload the chunk
+func (us *UploadService) SaveKnownChunk(ctx *gin.Context, fileId string, chunk blockmodels.AddChunkSessionRequest) error {
+	if !storage.IsValidBlockHash(chunk.ChunkHash) {
+		return fmt.Errorf("invalid chunk hash")
+	}
+
+	block, err := us.AcquireBlock(ctx, chunk.ChunkHash, int64(chunk.ChunkSize))
+	if err != nil {
+		return err
+	}
+	if !block.Stored || !block.Referenced {
+		return ErrUnknownBlock
+	}
+	if block.Size != int64(chunk.ChunkSize) {
+		return fmt.Errorf("chunk size mismatch: expected %d, got %d", block.Size, chunk.ChunkSize)
+	}
+	if err := signChunk(ctx.GetString("x-user-id"), fileId, &chunk); err != nil {
+		return err
 	}
 
 	if err := us.UpdateSessionRecordByFileId(ctx, fileId, chunk); err != nil {
@@ -311,3 +552,206 @@ func (us *UploadService) UpdateSessionRecord(ctx *gin.Context, sessionToken stri
 
 	return nil
 }
+
+// FetchSessionChunks retrieves the uploaded chunks of a session from the API Server, ordered by chunk index
+func (us *UploadService) FetchSessionChunks(ctx *gin.Context, sessionToken string) ([]models.Chunk, error) {
+	// Define the API Server URL
+	apiServerURL := fmt.Sprintf("%s/api/v1/upload/%s/chunks", us.baseURL, sessionToken)
+
+	resp, err := requestAPIServer(ctx, http.MethodGet, apiServerURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch session chunks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	type responseStruct struct {
+		Status  string         `json:"status"`
+		Message string         `json:"message"`
+		Data    []models.Chunk `json:"data"`
+	}
+
+	response := &responseStruct{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON response: %w", err)
+	}
+
+	return response.Data, nil
+}
+
+// VerifyChunks checks that the stored data of every chunk matches its recorded size and hash
+// If the client sent the expected hashes, they must match the recorded hashes in order
+func (us *UploadService) VerifyChunks(ctx context.Context, ownerId string, fileId string, chunks []models.Chunk, expectedHashes []string) error {
+	if expectedHashes != nil && len(expectedHashes) != len(chunks) {
+		return fmt.Errorf("expected %d chunks, %d uploaded", len(expectedHashes), len(chunks))
+	}
+
+	for i, chunk := range chunks {
+		if expectedHashes != nil && expectedHashes[i] != chunk.ChunkHash {
+			return fmt.Errorf("chunk %d hash mismatch", chunk.ChunkIndex)
+		}
+
+		reader, err := openChunk(ctx, us.store, ownerId, fileId, chunk)
+		if err != nil {
+			return fmt.Errorf("failed to open chunk %d: %w", chunk.ChunkIndex, err)
+		}
+
+		hasher := sha256.New()
+		size, err := io.Copy(hasher, reader)
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read chunk %d: %w", chunk.ChunkIndex, err)
+		}
+
+		if size != chunk.ChunkSize {
+			return fmt.Errorf("chunk %d size mismatch: expected %d, got %d", chunk.ChunkIndex, chunk.ChunkSize, size)
+		}
+		if hex.EncodeToString(hasher.Sum(nil)) != chunk.ChunkHash {
+			return fmt.Errorf("chunk %d hash mismatch", chunk.ChunkIndex)
+		}
+	}
+
+	return nil
+}
+
+// fetchOwnedSession retrieves a session from the API Server and checks that it belongs to the user
+func (us *UploadService) fetchOwnedSession(ctx *gin.Context, sessionToken string) (*models.UploadSession, error) {
+	session, err := us.FetchSessionObject(ctx, sessionToken)
+	if err != nil {
+		return nil, err
+	}
+
+	userId, ok := ctx.Value("x-user-id").(string)
+	if !ok || userId != session.UserID.Hex() {
+		return nil, fmt.Errorf("user ID does not match the session owner")
+	}
+
+	return session, nil
+}
+
+// CompleteSession verifies the uploaded chunks of a session and completes it on the API Server
+// The request carries the claim that the hashes are verified, the API Server does not complete a session without it.
+// The API Server checks that no chunk index is missing before marking the file as uploaded
+func (us *UploadService) CompleteSession(ctx *gin.Context, sessionToken string, expectedHashes []string) (*models.UploadSession, error) {
+	session, err := us.fetchOwnedSession(ctx, sessionToken)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := us.FetchSessionChunks(ctx, sessionToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := us.VerifyChunks(ctx, session.UserID.Hex(), session.FileID.Hex(), chunks, expectedHashes); err != nil {
+		return nil, err
+	}
+	claim, err := utils.CreateBlockServerClaim(models.CompletionClaim(session.UserID.Hex(), sessionToken), configs.Config.JWTSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign completion: %w", err)
+	}
+
+	// Define the API Server URL
+	apiServerURL := fmt.Sprintf("%s/api/v1/upload/%s/complete", us.baseURL, sessionToken)
+
+	resp, err := requestAPIServer(ctx, http.MethodPost, apiServerURL, models.CompleteUploadSessionRequest{CompletionClaim: claim})
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete session: %w", err)
+	}
+	defer resp.Body.Close()
+
+	type responseStruct struct {
+		Status  string                `json:"status"`
+		Message string                `json:"message"`
+		Data    *models.UploadSession `json:"data"`
+	}
+
+	response := &responseStruct{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON response: %w", err)
+	}
+
+	return response.Data, nil
+}
+
+// FetchSessionObjectByFileId retrieves the upload session of a file from the API Server
+func (us *UploadService) FetchSessionObjectByFileId(ctx *gin.Context, fileId string) (*models.UploadSession, error) {
+	// Define the API Server URL
+	apiServerURL := fmt.Sprintf("%s/api/v1/upload/file/%s", us.baseURL, fileId)
+
+	resp, err := requestAPIServer(ctx, http.MethodGet, apiServerURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch session object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	type responseStruct struct {
+		Status  string                `json:"status"`
+		Message string                `json:"message"`
+		Data    *models.UploadSession `json:"data"`
+	}
+
+	response := &responseStruct{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON response: %w", err)
+	}
+	if response.Data == nil {
+		return nil, fmt.Errorf("failed to fetch session object: %s", response.Message)
+	}
+
+	return response.Data, nil
+}
+
+// CompleteFileUpload completes the upload session of a file whose chunks are all saved
+// The whole and the auto-chunked uploads call it once their last chunk is saved, like the resumable uploads
+func (us *UploadService) CompleteFileUpload(ctx *gin.Context, fileId string) (*models.UploadSession, error) {
+	session, err := us.FetchSessionObjectByFileId(ctx, fileId)
+	if err != nil {
+		return nil, err
+	}
+
+	return us.CompleteSession(ctx, session.SessionToken, nil)
+}
+
+// CancelSession cancels a session on the API Server and deletes its legacy chunk objects
+// The blocks that are no longer referenced are deleted by the block collector of the API Server
+func (us *UploadService) CancelSession(ctx *gin.Context, sessionToken string) (*models.CancelUploadSessionResponse, error) {
+	session, err := us.fetchOwnedSession(ctx, sessionToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Define the API Server URL
+	apiServerURL := fmt.Sprintf("%s/api/v1/upload/%s", us.baseURL, sessionToken)
+
+	resp, err := requestAPIServer(ctx, http.MethodDelete, apiServerURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel session: %w", err)
+	}
+	defer resp.Body.Close()
+
+	type responseStruct struct {
+		Status  string                              `json:"status"`
+		Message string                              `json:"message"`
+		Data    *models.CancelUploadSessionResponse `json:"data"`
+	}
+
+	response := &responseStruct{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON response: %w", err)
+	}
+	if response.Data == nil {
+		return nil, fmt.Errorf("failed to cancel session: %s", response.Message)
+	}
+
+	// The records are already deleted, a failed deletion only leaves an orphan object behind
+	err = storage.DeleteChunks(ctx, us.store, session.UserID.Hex(), session.FileID.Hex(), response.Data.ChunkIndexes)
+	if err != nil {
+		fmt.Printf("Failed to delete the chunks of file %s: %v\n", session.FileID.Hex(), err)
+	}
+
+	return response.Data, nil
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"skybox-backend/internal/api/models"
+	blockmodels "skybox-backend/internal/blockserver/models"
+	"skybox-backend/internal/shared/storage"
+	"skybox-backend/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// countingStore counts the objects saved in the wrapped store
+type countingStore struct {
+	storage.BlockStore
+	puts int
+}
+
+func (cs *countingStore) Put(ctx context.Context, key string, data io.Reader, size int64) error {
+	cs.puts++
+	return cs.BlockStore.Put(ctx, key, data, size)
+}
+
+func TestSaveChunk_StoresIdenticalChunksOnce(t *testing.T) {
+	api, ctx := setupSessionAPI(t, 100, 4)
+	store := &countingStore{BlockStore: storage.NewMemoryStore()}
+	uploadService := NewUploadService(store)
+	fileId := api.session.FileID.Hex()
+
+	data := []byte("same")
+	require.NoError(t, uploadService.SaveChunk(ctx, fileId, "", "", 0, data))
+	require.NoError(t, uploadService.SaveChunk(ctx, fileId, "", "", 1, data))
+
+	// The second chunk references the block recorded for the first one, its upload is skipped
+	assert.Equal(t, 1, store.puts)
+	require.Len(t, api.chunks, 2)
+	assert.Equal(t, utils.HashBytes(data), api.chunks[0].ChunkHash)
+	assert.Equal(t, api.chunks[0].ChunkHash, api.chunks[1].ChunkHash)
+
+	objects, err := store.List(context.Background(), "blocks/")
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, storage.BlockKey(utils.HashBytes(data)), objects[0].Key)
+}
+
+func TestSaveChunk_DecidesFromReferenceCount(t *testing.T) {
+	api, ctx := setupSessionAPI(t, 100, 4)
+	store := &countingStore{BlockStore: storage.NewMemoryStore()}
+	uploadService := NewUploadService(store)
+	fileId := api.session.FileID.Hex()
+
+	// The object of a released block is still in the store, the data is saved again
+	released := []byte("gone")
+	require.NoError(t, store.BlockStore.Put(context.Background(), storage.BlockKey(utils.HashBytes(released)), bytes.NewReader(released), 4))
+	require.NoError(t, uploadService.SaveChunk(ctx, fileId, "", "", 0, released))
+	assert.Equal(t, 1, store.puts)
+
+	// A referenced block whose object is missing is saved again
+	missing := []byte("lost")
+	api.refs[utils.HashBytes(missing)] = 1
+	require.NoError(t, uploadService.SaveChunk(ctx, fileId, "", "", 1, missing))
+	assert.Equal(t, 2, store.puts)
+	_, err := store.Stat(context.Background(), storage.BlockKey(utils.HashBytes(missing)))
+	assert.NoError(t, err)
+
+	// The block of another user is answered as a new block, the data is saved again
+	foreign := []byte("them")
+	api.foreignRefs[utils.HashBytes(foreign)] = 1
+	require.NoError(t, store.BlockStore.Put(context.Background(), storage.BlockKey(utils.HashBytes(foreign)), bytes.NewReader(foreign), 4))
+	require.NoError(t, uploadService.SaveChunk(ctx, fileId, "", "", 2, foreign))
+	assert.Equal(t, 3, store.puts)
+}
+
+func TestSaveKnownChunk_OnlyBlocksOfTheUser(t *testing.T) {
+	api, ctx := setupSessionAPI(t, 100, 4)
+	uploadService := NewUploadService(storage.NewMemoryStore())
+	fileId := api.session.FileID.Hex()
+
+	foreign := utils.HashBytes([]byte("them"))
+	api.foreignRefs[foreign] = 1
+	err := uploadService.SaveKnownChunk(ctx, fileId, blockmodels.AddChunkSessionRequest{ChunkNumber: 0, ChunkSize: 4, ChunkHash: foreign})
+	assert.ErrorIs(t, err, ErrUnknownBlock, "a block stored by another user needs the chunk data")
+
+	unknown := utils.HashBytes([]byte("none"))
+	err = uploadService.SaveKnownChunk(ctx, fileId, blockmodels.AddChunkSessionRequest{ChunkNumber: 0, ChunkSize: 4, ChunkHash: unknown})
+	assert.ErrorIs(t, err, ErrUnknownBlock)
+	assert.Empty(t, api.chunks)
+
+	own := utils.HashBytes([]byte("mine"))
+	api.refs[own] = 1
+	require.NoError(t, uploadService.SaveKnownChunk(ctx, fileId, blockmodels.AddChunkSessionRequest{ChunkNumber: 0, ChunkSize: 4, ChunkHash: own}))
+	require.Len(t, api.chunks, 1)
+	assert.Equal(t, own, api.chunks[0].ChunkHash)
+}
+
+func TestVerifyChunks(t *testing.T) {
+	store := storage.NewMemoryStore()
+	uploadService := NewUploadService(store)
+
+	var chunks []models.Chunk
+	for i, data := range [][]byte{[]byte("first chunk"), []byte("second chunk")} {
+		hash := utils.HashBytes(data)
+		require.NoError(t, store.Put(context.Background(), storage.BlockKey(hash), bytes.NewReader(data), int64(len(data))))
+		chunks = append(chunks, models.Chunk{ChunkIndex: i, ChunkSize: int64(len(data)), ChunkHash: hash})
+	}
+
+	// Stored data matches the records
+	assert.NoError(t, uploadService.VerifyChunks(context.Background(), "owner", "file", chunks, nil))
+	assert.NoError(t, uploadService.VerifyChunks(context.Background(), "owner", "file", chunks, []string{chunks[0].ChunkHash, chunks[1].ChunkHash}))
+
+	// Expected hashes from the client do not match
+	assert.Error(t, uploadService.VerifyChunks(context.Background(), "owner", "file", chunks, []string{chunks[1].ChunkHash, chunks[0].ChunkHash}))
+	assert.Error(t, uploadService.VerifyChunks(context.Background(), "owner", "file", chunks, []string{chunks[0].ChunkHash}))
+
+	// Stored data is corrupted
+	corrupted := []byte("third chunk!")
+	err := store.Put(context.Background(), storage.BlockKey(chunks[1].ChunkHash), bytes.NewReader(corrupted), int64(len(corrupted)))
+	require.NoError(t, err)
+	assert.Error(t, uploadService.VerifyChunks(context.Background(), "owner", "file", chunks, nil))
+
+	// Stored data is missing
+	require.NoError(t, store.Delete(context.Background(), storage.BlockKey(chunks[0].ChunkHash)))
+	assert.ErrorIs(t, uploadService.VerifyChunks(context.Background(), "owner", "file", chunks[:1], nil), storage.ErrObjectNotFound)
+}
+
+func TestValidateSession_OrganizationQuota(t *testing.T) {
+	api, ctx := setupSessionAPI(t, 100, 4)
+	uploadService := NewUploadService(storage.NewMemoryStore())
+
+	// The personal storage of the user is full
+	api.usage = models.StorageUsageResponse{Quota: 100, Used: 100}
+	_, err := uploadService.ValidateSession(ctx, api.session.SessionToken, 0, 10)
+	assert.ErrorIs(t, err, ErrStorageQuotaExceeded)
+
+	// The upload to a shared drive uses the quota of the organization
+	organizationId := primitive.NewObjectID()
+	api.session.OrganizationID = &organizationId
+	api.organizationUsage = &models.OrganizationResponse{ID: organizationId, StorageQuota: 100, StorageUsed: 90}
+	fileId, err := uploadService.ValidateSession(ctx, api.session.SessionToken, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, api.session.FileID.Hex(), fileId)
+
+	_, err = uploadService.ValidateSession(ctx, api.session.SessionToken, 0, 11)
+	assert.ErrorIs(t, err, ErrOrganizationStorageQuotaExceeded)
+	assert.ErrorIs(t, err, ErrStorageQuotaExceeded)
+}
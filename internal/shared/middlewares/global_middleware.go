@@ -30,9 +30,18 @@ func GlobalErrorMiddleware() gin.HandlerFunc {
 			} else if strings.Contains(errorMessage, "validation") || strings.Contains(errorMessage, "invalid") || strings.Contains(errorMessage, "required") {
 				// Handle validation errors
 				shared.ErrorJSON(c, http.StatusBadRequest, errorMessage)
+			} else if strings.Contains(errorMessage, "already exists") {
+				// Handle name conflicts
+				shared.ErrorJSON(c, http.StatusConflict, errorMessage)
+			} else if strings.Contains(errorMessage, "quota") {
+				// Handle storage quota errors
+				shared.ErrorJSON(c, http.StatusRequestEntityTooLarge, errorMessage)
 			} else if strings.Contains(errorMessage, "unauthorized") {
 				// Handle unauthorized errors
 				shared.ErrorJSON(c, http.StatusUnauthorized, errorMessage)
+			} else if strings.Contains(errorMessage, "expired") {
+				// Handle expired share links
+				shared.ErrorJSON(c, http.StatusGone, errorMessage)
 			} else {
 				// Handle system errors
 				shared.ErrorJSON(c, http.StatusInternalServerError, "Internal server error. Error: "+errorMessage)
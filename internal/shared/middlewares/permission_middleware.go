@@ -25,7 +25,7 @@ func FolderPermissionMiddleware(fc *controllers.FolderController, permission str
 	}
 }
 
-// FilePermissionMiddleware checks if the user has the required permission for the file's parent folder
+// FilePermissionMiddleware checks if the user has the required permission for the file, from its own grants or its parent folder
 func FilePermissionMiddleware(fc *controllers.FolderController, requiredPermission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		fileID := c.Param("fileId")
@@ -35,7 +35,7 @@ func FilePermissionMiddleware(fc *controllers.FolderController, requiredPermissi
 			return
 		}
 
-		// Get the file metadata to retrieve the parent folder ID
+		// Get the file metadata to retrieve the owner and the parent folder ID
 		file, err := fc.FileService.GetFileByID(c, fileID)
 		if err != nil {
 			shared.RespondJson(c, http.StatusForbidden, "error", "File not found or access denied.", nil)
@@ -43,9 +43,9 @@ func FilePermissionMiddleware(fc *controllers.FolderController, requiredPermissi
 			return
 		}
 
-		// Check folder permission for the parent folder
+		// Check the file permission, the parent folder permission applies without a file grant
 		userID := c.MustGet("x-user-id-hex").(primitive.ObjectID).Hex()
-		hasPermission, err := fc.CheckFolderPermission(c, file.ParentFolderID.Hex(), userID, requiredPermission)
+		hasPermission, err := fc.CheckFilePermission(c, file, userID, requiredPermission)
 		if err != nil || !hasPermission {
 			shared.RespondJson(c, http.StatusForbidden, "error", "Permission denied.", nil)
 			c.Abort()
@@ -0,0 +1,38 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"skybox-backend/internal/api/controllers"
+	"skybox-backend/internal/shared"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TenantMiddleware selects the workspace of the request from the X-Organization-ID header or the organization_id query
+// Without an organization the request works in the personal workspace of the user. The user must be a member of the
+// selected organization, the repositories then only list, search and look up the items of that organization.
+func TenantMiddleware(oc *controllers.OrganizationController) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		organizationID := c.GetHeader("X-Organization-ID")
+		if organizationID == "" {
+			organizationID = c.Query("organization_id")
+		}
+		if organizationID == "" {
+			c.Next()
+			return
+		}
+
+		userID := c.MustGet("x-user-id-hex").(primitive.ObjectID)
+		member, err := oc.OrganizationService.GetMember(c, organizationID, userID)
+		if err != nil {
+			shared.RespondJson(c, http.StatusForbidden, "error", "Organization not found or access denied.", nil)
+			c.Abort()
+			return
+		}
+
+		c.Set("x-organization-id", member.OrganizationID)
+		c.Next()
+	}
+}
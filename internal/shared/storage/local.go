@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LocalStore is a BlockStore that saves the objects in a directory of the local filesystem
+// It is used for development and testing purposes when AWS S3 is not enabled
+type LocalStore struct {
+	root string
+}
+
+// NewLocalStore creates a LocalStore rooted at the given directory
+func NewLocalStore(root string) *LocalStore {
+	return &LocalStore{
+		root: root,
+	}
+}
+
+// path converts an object key to a path on the local filesystem
+func (ls *LocalStore) path(key string) string {
+	return filepath.Join(ls.root, filepath.FromSlash(key))
+}
+
+func (ls *LocalStore) Put(ctx context.Context, key string, data io.Reader, size int64) error {
+	path := ls.path(key)
+
+	// Create the directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create local directory: %w", err)
+	}
+
+	// Write to a temporary file first so a reader never sees a partially written object
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	written, err := io.Copy(tmpFile, data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save object locally: %w", err)
+	}
+	if size >= 0 && written != size {
+		return fmt.Errorf("object size mismatch: expected %d, got %d", size, written)
+	}
+
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return fmt.Errorf("failed to save object locally: %w", err)
+	}
+
+	return nil
+}
+
+func (ls *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	file, err := os.Open(ls.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open object: %w", err)
+	}
+
+	return file, nil
+}
+
+// limitedFile closes the underlying file of a ranged read
+type limitedFile struct {
+	io.Reader
+	file *os.File
+}
+
+func (lf *limitedFile) Close() error {
+	return lf.file.Close()
+}
+
+func (ls *LocalStore) GetRange(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	if offset < 0 || length < 0 {
+		return nil, fmt.Errorf("invalid range %d-%d", offset, offset+length-1)
+	}
+
+	file, err := os.Open(ls.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open object: %w", err)
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to seek object: %w", err)
+	}
+
+	return &limitedFile{
+		Reader: io.LimitReader(file, length),
+		file:   file,
+	}, nil
+}
+
+func (ls *LocalStore) Delete(ctx context.Context, key string) error {
+	err := os.Remove(ls.path(key))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+
+	return nil
+}
+
+func (ls *LocalStore) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := os.Stat(ls.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat object: %w", err)
+	}
+
+	return &ObjectInfo{
+		Key:          key,
+		Size:         info.Size(),
+		LastModified: info.ModTime(),
+	}, nil
+}
+
+func (ls *LocalStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	objects := []ObjectInfo{}
+
+	err := filepath.WalkDir(ls.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil // Nothing has been stored yet
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".upload-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(ls.root, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, ObjectInfo{
+			Key:          key,
+			Size:         info.Size(),
+			LastModified: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+
+	return objects, nil
+}
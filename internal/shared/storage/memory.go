@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryObject struct {
+	data         []byte
+	lastModified time.Time
+}
+
+// MemoryStore is a BlockStore that keeps every object in memory
+// It is meant for tests and local experiments, the data is lost when the process exits
+type MemoryStore struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		objects: make(map[string]memoryObject),
+	}
+}
+
+func (ms *MemoryStore) Put(ctx context.Context, key string, data io.Reader, size int64) error {
+	buf, err := io.ReadAll(data)
+	if err != nil {
+		return fmt.Errorf("failed to read object data: %w", err)
+	}
+	if size >= 0 && int64(len(buf)) != size {
+		return fmt.Errorf("object size mismatch: expected %d, got %d", size, len(buf))
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.objects[key] = memoryObject{
+		data:         buf,
+		lastModified: time.Now(),
+	}
+
+	return nil
+}
+
+func (ms *MemoryStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	object, ok := ms.objects[key]
+	if !ok {
+		return nil, ErrObjectNotFound
+	}
+
+	return io.NopCloser(bytes.NewReader(object.data)), nil
+}
+
+func (ms *MemoryStore) GetRange(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	object, ok := ms.objects[key]
+	if !ok {
+		return nil, ErrObjectNotFound
+	}
+
+	size := int64(len(object.data))
+	if offset < 0 || offset > size || length < 0 {
+		return nil, fmt.Errorf("invalid range %d-%d for object of size %d", offset, offset+length-1, size)
+	}
+	end := min(offset+length, size)
+
+	return io.NopCloser(bytes.NewReader(object.data[offset:end])), nil
+}
+
+func (ms *MemoryStore) Delete(ctx context.Context, key string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	delete(ms.objects, key)
+	return nil
+}
+
+func (ms *MemoryStore) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	object, ok := ms.objects[key]
+	if !ok {
+		return nil, ErrObjectNotFound
+	}
+
+	return &ObjectInfo{
+		Key:          key,
+		Size:         int64(len(object.data)),
+		LastModified: object.lastModified,
+	}, nil
+}
+
+func (ms *MemoryStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	objects := []ObjectInfo{}
+	for key, object := range ms.objects {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		objects = append(objects, ObjectInfo{
+			Key:          key,
+			Size:         int64(len(object.data)),
+			LastModified: object.lastModified,
+		})
+	}
+
+	// Keep the same lexicographic order as S3
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+
+	return objects, nil
+}
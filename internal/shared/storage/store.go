@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"skybox-backend/configs"
+)
+
+// ErrObjectNotFound is returned by a BlockStore when the requested key does not exist
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectInfo describes an object saved in a BlockStore
+type ObjectInfo struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// BlockStore is the interface implemented by every block storage backend.
+// The block server and the jobs of the API server only talk to the storage through this interface, so new backends
+// can be added without touching the services and the services can be tested with MemoryStore.
+type BlockStore interface {
+	Put(ctx context.Context, key string, data io.Reader, size int64) error                       // Save an object under the key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)                                  // Read the whole object
+	GetRange(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) // Read up to `length` bytes starting at `offset`, a zero length reads nothing
+	Delete(ctx context.Context, key string) error                                                // Delete the object, deleting a missing key is not an error
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)                                   // Get the object information
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)                               // List the objects whose key starts with prefix
+}
+
+// processMemoryStore is the MemoryStore of the process
+// cmd/server runs both servers in one process, they must see the same objects
+var (
+	processMemoryStore     *MemoryStore
+	processMemoryStoreOnce sync.Once
+)
+
+// NewBlockStore creates the BlockStore selected by configs.Config.StorageBackend
+// Every call of a process returns the same MemoryStore with the memory backend
+func NewBlockStore() (BlockStore, error) {
+	switch configs.Config.StorageBackend {
+	case "s3":
+		client := GetS3Client()
+		if client == nil {
+			return nil, fmt.Errorf("failed to create AWS S3 client")
+		}
+		return NewS3Store(client, configs.Config.AWSBucket), nil
+	case "local", "":
+		return NewLocalStore(configs.Config.LocalStoragePath), nil
+	case "memory":
+		processMemoryStoreOnce.Do(func() {
+			processMemoryStore = NewMemoryStore()
+		})
+		return processMemoryStore, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", configs.Config.StorageBackend)
+	}
+}
+
+// CheckSeparateProcess checks that the backend can be used by a server running without the other one
+// The memory backend cannot be shared between processes, the API server would purge the blocks of another store
+func CheckSeparateProcess() error {
+	if configs.Config.StorageBackend == "memory" {
+		return fmt.Errorf("the memory storage backend needs both servers in the same process, start them with cmd/server")
+	}
+	return nil
+}
+
+// ChunkKey returns the key of a file chunk
+// Key format: `<userId>/<fileId>_<chunkIndex>`
+func ChunkKey(userId string, fileId string, chunkIndex int) string {
+	return fmt.Sprintf("%s/%s_%d", userId, fileId, chunkIndex)
+}
+
+// BlockKey returns the key of a content-addressed block
+// Key format: `blocks/<hash[0:2]>/<hash>`, the prefix keeps the local directories small
+func BlockKey(hash string) string {
+	return fmt.Sprintf("blocks/%s/%s", hash[:2], hash)
+}
+
+// IsValidBlockHash checks if the hash is a lowercase hex encoded SHA-256 digest
+// Hashes sent by clients must be checked before being used in a key
+func IsValidBlockHash(hash string) bool {
+	if len(hash) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil && hash == strings.ToLower(hash)
+}
+
+// PartialUploadKey returns the key of the bytes of a tus upload that do not fill a chunk yet
+// Key format: `tus/<sessionToken>.part`
+func PartialUploadKey(sessionToken string) string {
+	return fmt.Sprintf("tus/%s.part", sessionToken)
+}
+
+// DeleteChunks deletes the legacy chunk objects of a file
+// The content-addressed blocks are never deleted here: a released block is kept during a grace period
+// and deleted by the block collector once no chunk references it again
+// Every object is attempted, the first error is returned
+func DeleteChunks(ctx context.Context, store BlockStore, ownerId string, fileId string, chunkIndexes []int) error {
+	var firstErr error
+	for _, chunkIndex := range chunkIndexes {
+		if err := store.Delete(ctx, ChunkKey(ownerId, fileId, chunkIndex)); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// CopyChunks copies the legacy chunk objects of a file to the key prefix of another owner
+// The missing objects are skipped, the first other error stops the copy
+func CopyChunks(ctx context.Context, store BlockStore, fromOwnerId string, toOwnerId string, fileId string, chunkIndexes []int) error {
+	for _, chunkIndex := range chunkIndexes {
+		err := copyObject(ctx, store, ChunkKey(fromOwnerId, fileId, chunkIndex), ChunkKey(toOwnerId, fileId, chunkIndex))
+		if errors.Is(err, ErrObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyObject copies an object of the store to another key
+func copyObject(ctx context.Context, store BlockStore, fromKey string, toKey string) error {
+	info, err := store.Stat(ctx, fromKey)
+	if err != nil {
+		return err
+	}
+
+	reader, err := store.Get(ctx, fromKey)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	return store.Put(ctx, toKey, reader, info.Size)
+}
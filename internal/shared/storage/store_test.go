@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBlockStore runs the same checks against every BlockStore implementation
+func testBlockStore(t *testing.T, store BlockStore) {
+	ctx := context.Background()
+	data := []byte("hello skybox block store")
+
+	// Put and Get
+	err := store.Put(ctx, "user/file_0", bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	reader, err := store.Get(ctx, "user/file_0")
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	reader.Close()
+	require.NoError(t, err)
+	assert.Equal(t, data, content)
+
+	// GetRange
+	reader, err = store.GetRange(ctx, "user/file_0", 6, 6)
+	require.NoError(t, err)
+	content, err = io.ReadAll(reader)
+	reader.Close()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("skybox"), content)
+
+	// GetRange with a zero length reads nothing
+	reader, err = store.GetRange(ctx, "user/file_0", 6, 0)
+	require.NoError(t, err)
+	content, err = io.ReadAll(reader)
+	reader.Close()
+	require.NoError(t, err)
+	assert.Empty(t, content)
+
+	// GetRange past the end is truncated
+	reader, err = store.GetRange(ctx, "user/file_0", 19, 100)
+	require.NoError(t, err)
+	content, err = io.ReadAll(reader)
+	reader.Close()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("store"), content)
+
+	// GetRange rejects a negative range and reports a missing key even with a zero length
+	_, err = store.GetRange(ctx, "user/file_0", 0, -1)
+	assert.Error(t, err)
+	_, err = store.GetRange(ctx, "user/missing", 0, 0)
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+
+	// Stat
+	info, err := store.Stat(ctx, "user/file_0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), info.Size)
+	assert.Equal(t, "user/file_0", info.Key)
+
+	// List
+	err = store.Put(ctx, "user/file_1", bytes.NewReader(data[:5]), 5)
+	require.NoError(t, err)
+	err = store.Put(ctx, "other/file_0", bytes.NewReader(data[:5]), 5)
+	require.NoError(t, err)
+
+	objects, err := store.List(ctx, "user/")
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	assert.Equal(t, "user/file_0", objects[0].Key)
+	assert.Equal(t, "user/file_1", objects[1].Key)
+
+	// Size mismatch is rejected
+	err = store.Put(ctx, "user/file_2", bytes.NewReader(data), 3)
+	assert.Error(t, err)
+
+	// Delete
+	require.NoError(t, store.Delete(ctx, "user/file_0"))
+	_, err = store.Get(ctx, "user/file_0")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+	_, err = store.Stat(ctx, "user/file_0")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+
+	// Deleting a missing key is not an error
+	assert.NoError(t, store.Delete(ctx, "user/file_0"))
+}
+
+func TestMemoryStore(t *testing.T) {
+	testBlockStore(t, NewMemoryStore())
+}
+
+func TestLocalStore(t *testing.T) {
+	testBlockStore(t, NewLocalStore(t.TempDir()))
+}
+
+func TestChunkKey(t *testing.T) {
+	assert.Equal(t, "user/file_3", ChunkKey("user", "file", 3))
+}
+
+func TestBlockKey(t *testing.T) {
+	hash := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	assert.Equal(t, "blocks/9f/"+hash, BlockKey(hash))
+	assert.True(t, IsValidBlockHash(hash))
+	assert.False(t, IsValidBlockHash("../../etc/passwd"))
+	assert.False(t, IsValidBlockHash(strings.ToUpper(hash)))
+}
+
+func TestCopyChunks(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	data := []byte("legacy chunk")
+	require.NoError(t, store.Put(ctx, ChunkKey("alice", "file", 0), bytes.NewReader(data), int64(len(data))))
+
+	// The second chunk is missing, it is skipped
+	require.NoError(t, CopyChunks(ctx, store, "alice", "bob", "file", []int{0, 1}))
+
+	reader, err := store.Get(ctx, ChunkKey("bob", "file", 0))
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	reader.Close()
+	require.NoError(t, err)
+	assert.Equal(t, data, content)
+
+	_, err = store.Stat(ctx, ChunkKey("alice", "file", 0))
+	assert.NoError(t, err, "the copy keeps the original object")
+}
@@ -166,3 +166,36 @@ func GetKeysFromToken(requestToken string, secret string) (map[string]string, er
 
 	return data, nil
 }
+
+// blockServerIssuer marks the claims signed by the block server
+const blockServerIssuer = "block-server"
+
+// CreateBlockServerClaim signs a claim of the block server about the data it stored or verified
+// The claim is sent to the API server with the request of the user, which cannot forge it without the secret
+func CreateBlockServerClaim(data map[string]string, secret string) (string, error) {
+	claim := map[string]string{"issuer": blockServerIssuer}
+	for key, value := range data {
+		claim[key] = value
+	}
+
+	return GenerateToken(claim, secret, 1) // 1 hour
+}
+
+// VerifyBlockServerClaim checks that the claim was signed by the block server, is not expired,
+// and carries the expected values
+func VerifyBlockServerClaim(claim string, secret string, expected map[string]string) error {
+	data, err := GetKeysFromToken(claim, secret)
+	if err != nil {
+		return fmt.Errorf("invalid block server claim: %w", err)
+	}
+	if data["issuer"] != blockServerIssuer {
+		return fmt.Errorf("invalid block server claim: wrong issuer")
+	}
+	for key, value := range expected {
+		if data[key] != value {
+			return fmt.Errorf("invalid block server claim: %s does not match", key)
+		}
+	}
+
+	return nil
+}